@@ -22,6 +22,10 @@ import SecretsView from './views/SecretsView.vue'
 import SessionsView from './views/SessionsView.vue'
 import ProvidersView from './views/ProvidersView.vue'
 import SchedulesView from './views/SchedulesView.vue'
+import ApprovalsView from './views/ApprovalsView.vue'
+import PeopleView from './views/PeopleView.vue'
+import PairingView from './views/PairingView.vue'
+import UsageView from './views/UsageView.vue'
 import SettingsView from './views/SettingsView.vue'
 
 const routes = [
@@ -38,6 +42,10 @@ const routes = [
       { path: 'providers', name: 'providers', component: ProvidersView, meta: { requiresAuth: true, title: 'Providers' } },
       { path: 'secrets', name: 'secrets', component: SecretsView, meta: { requiresAuth: true, title: 'Secrets' } },
       { path: 'schedules', name: 'schedules', component: SchedulesView, meta: { requiresAuth: true, title: 'Schedules' } },
+      { path: 'approvals', name: 'approvals', component: ApprovalsView, meta: { requiresAuth: true, title: 'Approvals' } },
+      { path: 'people', name: 'people', component: PeopleView, meta: { requiresAuth: true, title: 'People' } },
+      { path: 'pairing', name: 'pairing', component: PairingView, meta: { requiresAuth: true, title: 'Pairing' } },
+      { path: 'usage', name: 'usage', component: UsageView, meta: { requiresAuth: true, title: 'Usage' } },
       { path: 'engine-auth', name: 'engine-auth', component: EngineAuthView, meta: { requiresAuth: true, title: 'Engine Auth' } },
       { path: 'settings', name: 'settings', component: SettingsView, meta: { requiresAuth: true, title: 'Settings' } },
     ],
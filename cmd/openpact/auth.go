@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/open-pact/openpact/internal/auth"
+	"github.com/open-pact/openpact/internal/config"
+)
+
+// runAuth runs the engine's interactive login attached to the current terminal
+// and reports the resulting authentication status.
+func runAuth(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: openpact auth [engine]")
+	}
+
+	engineType := ""
+	if len(args) == 1 {
+		engineType = args[0]
+	} else {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		engineType = cfg.Engine.Type
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cmd, err := auth.LoginCommand(ctx, engineType)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s login failed: %w", engineType, err)
+	}
+
+	status := auth.CheckAuth(engineType)
+	if !status.Authenticated {
+		return fmt.Errorf("login finished but no credentials were found for %s", engineType)
+	}
+	fmt.Printf("Authenticated with %s (method: %s)\n", engineType, status.Method)
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/open-pact/openpact/internal/admin"
+	"github.com/open-pact/openpact/internal/auth"
+	"github.com/open-pact/openpact/internal/config"
+	"github.com/open-pact/openpact/internal/engine"
+)
+
+// doctorReport collects check results and prints them as they arrive.
+type doctorReport struct {
+	failures int
+	warnings int
+}
+
+func (r *doctorReport) ok(format string, args ...interface{}) {
+	fmt.Printf("  [ok]   %s\n", fmt.Sprintf(format, args...))
+}
+
+func (r *doctorReport) warn(format string, args ...interface{}) {
+	r.warnings++
+	fmt.Printf("  [warn] %s\n", fmt.Sprintf(format, args...))
+}
+
+func (r *doctorReport) fail(format string, args ...interface{}) {
+	r.failures++
+	fmt.Printf("  [fail] %s\n", fmt.Sprintf(format, args...))
+}
+
+// runDoctor checks workspace directories, engine auth and reachability, and
+// chat provider tokens. It returns an error if any check failed.
+func runDoctor(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	r := &doctorReport{}
+
+	fmt.Println("Workspace:")
+	checkWorkspace(r, cfg.Workspace)
+
+	fmt.Println("Encryption:")
+	checkEncryption(r, cfg.Workspace)
+
+	fmt.Println("Engine:")
+	checkEngine(r, cfg.Engine)
+
+	fmt.Println("Chat providers:")
+	checkProviders(r, admin.NewProviderStore(cfg.Workspace.DataDir()))
+
+	fmt.Printf("\n%d failure(s), %d warning(s)\n", r.failures, r.warnings)
+	if r.failures > 0 {
+		return fmt.Errorf("%d check(s) failed", r.failures)
+	}
+	return nil
+}
+
+// checkWorkspace verifies each required directory exists and is writable.
+func checkWorkspace(r *doctorReport, ws config.WorkspaceConfig) {
+	dirs := []string{
+		ws.Path,
+		ws.SecureDir(),
+		ws.DataDir(),
+		ws.AIDataDir(),
+		ws.ScriptsDir(),
+		filepath.Join(ws.AIDataDir(), "memory"),
+	}
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil {
+			r.fail("%s: %v", dir, err)
+			continue
+		}
+		if !info.IsDir() {
+			r.fail("%s: not a directory", dir)
+			continue
+		}
+		probe, err := os.CreateTemp(dir, ".doctor-*")
+		if err != nil {
+			r.warn("%s: not writable by this user (%v)", dir, err)
+			continue
+		}
+		probe.Close()
+		os.Remove(probe.Name())
+		r.ok("%s", dir)
+	}
+
+	if _, err := os.Stat(filepath.Join(ws.DataDir(), "mcp_token")); err != nil {
+		r.warn("no MCP token file; run `openpact opencode-config` (the container entrypoint does this)")
+	} else {
+		r.ok("MCP token present")
+	}
+}
+
+// checkEncryption reports whether a master key is configured and that the
+// secret stores can be read with it.
+func checkEncryption(r *doctorReport, ws config.WorkspaceConfig) {
+	key, err := admin.MasterKeyFromEnv()
+	if err != nil {
+		r.fail("master key: %v", err)
+		return
+	}
+	if key == nil {
+		r.warn("no master key; secrets and bot tokens are stored in plaintext (set %s or %s)", admin.MasterKeyEnv, admin.MasterKeyFileEnv)
+		return
+	}
+	r.ok("master key %s configured", key.ID())
+
+	if _, err := admin.NewSecretStore(ws.DataDir()).All(); err != nil {
+		r.fail("secret store: %v", err)
+	} else {
+		r.ok("secret store readable")
+	}
+	if _, err := admin.NewProviderStore(ws.DataDir()).List(); err != nil {
+		r.fail("provider store: %v", err)
+	} else {
+		r.ok("provider store readable")
+	}
+}
+
+// checkEngine verifies engine credentials and that the engine backend answers
+// its health endpoint (opencode serve, or the models endpoint of an
+// OpenAI-compatible API).
+func checkEngine(r *doctorReport, ec config.EngineConfig) {
+	status := auth.CheckAuth(ec.Type)
+	if status.Authenticated {
+		r.ok("%s authenticated (method: %s)", ec.Type, status.Method)
+	} else {
+		r.fail("%s not authenticated: %s (run: openpact auth %s)", ec.Type, status.Error, ec.Type)
+	}
+
+	var healthURL string
+	var req *http.Request
+	var err error
+	if ec.Type == "openai" {
+		baseURL := strings.TrimRight(ec.BaseURL, "/")
+		if baseURL == "" {
+			baseURL = engine.DefaultOpenAIBaseURL
+		}
+		healthURL = baseURL + "/models"
+		req, err = http.NewRequest(http.MethodGet, healthURL, nil)
+		if err == nil {
+			apiKey := ec.APIKey
+			if apiKey == "" {
+				apiKey = os.Getenv("OPENAI_API_KEY")
+			}
+			if apiKey != "" {
+				req.Header.Set("Authorization", "Bearer "+apiKey)
+			}
+		}
+	} else {
+		port := ec.Port
+		if port == 0 {
+			port = engine.DefaultPort
+		}
+		hostname := ec.Hostname
+		if hostname == "" {
+			hostname = "127.0.0.1"
+		}
+		healthURL = fmt.Sprintf("http://%s:%d/global/health", hostname, port)
+		req, err = http.NewRequest(http.MethodGet, healthURL, nil)
+		if err == nil && ec.Password != "" {
+			req.SetBasicAuth("opencode", ec.Password)
+		}
+	}
+	if err != nil {
+		r.fail("engine health request: %v", err)
+		return
+	}
+
+	client := &http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		r.fail("engine unreachable at %s: %v", healthURL, err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		r.fail("engine at %s returned %s", healthURL, resp.Status)
+		return
+	}
+	r.ok("engine reachable at %s", healthURL)
+}
+
+// checkProviders verifies that every enabled provider has its required tokens,
+// from either the store or the environment.
+func checkProviders(r *doctorReport, store *admin.ProviderStore) {
+	configs, err := store.List()
+	if err != nil {
+		r.fail("failed to read provider store: %v", err)
+		return
+	}
+
+	enabled := 0
+	for _, cfg := range configs {
+		if !cfg.Enabled {
+			continue
+		}
+		enabled++
+
+		missing := []string{}
+		for _, key := range admin.RequiredTokenKeys(cfg.Name) {
+			if store.ResolveToken(cfg.Name, key) == "" {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			r.fail("%s: missing token(s) %v (set via admin UI or env var)", cfg.Name, missing)
+			continue
+		}
+		r.ok("%s: tokens available", cfg.Name)
+	}
+
+	if enabled == 0 {
+		r.warn("no chat providers enabled")
+	}
+}
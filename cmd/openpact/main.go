@@ -0,0 +1,76 @@
+// Command openpact is the unified OpenPact binary. It runs the orchestrator
+// (chat providers, engine connection, MCP HTTP server, scheduler) together
+// with the admin UI, and provides operational subcommands.
+//
+// Usage:
+//
+//	openpact start             Run the orchestrator and admin server
+//	openpact auth [engine]     Sign in to the AI engine interactively
+//	openpact doctor            Check workspace, engine and provider setup
+//	openpact rotate-key FILE   Re-encrypt the secret stores under a new master key
+//	openpact test-scripts      Run Starlark script tests against recorded fixtures
+//	openpact version           Print the version
+//	openpact opencode-config   Print OpenCode config JSON (used by the entrypoint)
+package main
+
+import (
+	"fmt"
+	"os"
+
+	version "github.com/open-pact/openpact"
+)
+
+const usage = `Usage: openpact <command> [arguments]
+
+Commands:
+  start             Run the orchestrator and admin server
+  auth [engine]     Sign in to the AI engine (default: configured engine type)
+  doctor            Check workspace directories, engine reachability and provider tokens
+  rotate-key FILE   Re-encrypt the secret and provider stores under the master key in
+                    FILE (generated if missing); stop OpenPact first
+  test-scripts [NAME...]
+                    Run the _test.star tests of the named scripts (default: all)
+                    against the HTTP fixtures in scripts/testdata
+  version           Print the version
+  opencode-config   Print the OpenCode config JSON and persist the MCP token
+
+Configuration is read from $CONFIG_PATH or <workspace>/secure/config.yaml,
+with environment variable overrides (see docs).
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cmd, args := os.Args[1], os.Args[2:]
+
+	var err error
+	switch cmd {
+	case "start":
+		err = runStart(args)
+	case "auth":
+		err = runAuth(args)
+	case "doctor":
+		err = runDoctor(args)
+	case "rotate-key":
+		err = runRotateKey(args)
+	case "test-scripts":
+		err = runTestScripts(args)
+	case "version", "--version", "-v":
+		fmt.Println(version.Get())
+	case "opencode-config":
+		err = runOpenCodeConfig(args)
+	case "help", "--help", "-h":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "openpact %s: %v\n", cmd, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-pact/openpact/internal/config"
+	"github.com/open-pact/openpact/internal/engine"
+	"github.com/open-pact/openpact/internal/mcp"
+)
+
+// runOpenCodeConfig prints the OpenCode config JSON to stdout. It also makes
+// sure secure/data/mcp_token exists so the orchestrator (started afterwards by
+// the entrypoint) and OpenCode share the same MCP bearer token.
+func runOpenCodeConfig(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	token, err := loadOrCreateMCPToken(cfg.Workspace.DataDir())
+	if err != nil {
+		return err
+	}
+
+	engineCfg := engine.Config{
+		Type:     cfg.Engine.Type,
+		Provider: cfg.Engine.Provider,
+		Model:    cfg.Engine.Model,
+		WorkDir:  cfg.Workspace.Path,
+		Port:     cfg.Engine.Port,
+		Hostname: cfg.Engine.Hostname,
+		Password: cfg.Engine.Password,
+	}
+
+	data, err := json.Marshal(engine.BuildOpenCodeConfig(engineCfg, token))
+	if err != nil {
+		return fmt.Errorf("failed to marshal OpenCode config: %w", err)
+	}
+
+	fmt.Println(string(data))
+	return nil
+}
+
+// loadOrCreateMCPToken returns the persisted MCP token, generating and writing
+// a new one if none exists yet.
+func loadOrCreateMCPToken(dataDir string) (string, error) {
+	tokenPath := filepath.Join(dataDir, "mcp_token")
+	if data, err := os.ReadFile(tokenPath); err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	}
+
+	token, err := mcp.GenerateToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate MCP token: %w", err)
+	}
+
+	if err := os.MkdirAll(dataDir, 0750); err != nil {
+		return "", fmt.Errorf("failed to create data dir: %w", err)
+	}
+	if err := os.WriteFile(tokenPath, []byte(token), 0600); err != nil {
+		return "", fmt.Errorf("failed to write MCP token: %w", err)
+	}
+
+	return token, nil
+}
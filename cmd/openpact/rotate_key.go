@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-pact/openpact/internal/admin"
+	"github.com/open-pact/openpact/internal/config"
+)
+
+// runRotateKey re-encrypts the secret and provider stores under the key in
+// the given file, generating the key first if the file does not exist. The
+// current key comes from the usual environment variables; if none is set the
+// stores are assumed to be plaintext and are encrypted for the first time.
+// OpenPact should be stopped while this runs.
+func runRotateKey(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: openpact rotate-key <new-key-file>")
+	}
+	newKeyPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	oldKey, err := admin.MasterKeyFromEnv()
+	if err != nil {
+		return fmt.Errorf("current master key: %w", err)
+	}
+
+	var newKey *admin.MasterKey
+	if _, err := os.Stat(newKeyPath); err == nil {
+		if newKey, err = admin.LoadMasterKeyFile(newKeyPath); err != nil {
+			return err
+		}
+	} else {
+		if newKey, err = admin.GenerateMasterKey(); err != nil {
+			return err
+		}
+		if err := admin.WriteMasterKeyFile(newKeyPath, newKey); err != nil {
+			return err
+		}
+		fmt.Printf("Generated new master key in %s\n", newKeyPath)
+	}
+
+	if oldKey != nil && oldKey.ID() == newKey.ID() {
+		return fmt.Errorf("new key is the same as the current key")
+	}
+
+	if err := admin.RotateMasterKey(cfg.Workspace.DataDir(), oldKey, newKey); err != nil {
+		return err
+	}
+
+	fmt.Printf("Stores re-encrypted with key %s.\n", newKey.ID())
+	if rel, err := filepath.Rel(cfg.Workspace.Path, newKeyPath); err == nil && !strings.HasPrefix(rel, "..") {
+		fmt.Println("Warning: the key file is inside the workspace; move it elsewhere so backups do not include it.")
+	}
+	fmt.Printf("Before restarting, set %s=%s and unset %s.\n", admin.MasterKeyFileEnv, newKeyPath, admin.MasterKeyEnv)
+	return nil
+}
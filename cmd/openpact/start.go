@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	version "github.com/open-pact/openpact"
+	"github.com/open-pact/openpact/internal/admin"
+	"github.com/open-pact/openpact/internal/config"
+	"github.com/open-pact/openpact/internal/orchestrator"
+	"github.com/open-pact/openpact/internal/starlark"
+)
+
+// shutdownTimeout bounds how long the admin server may take to drain on exit.
+const shutdownTimeout = 10 * time.Second
+
+// runStart loads config, builds the admin server and orchestrator, wires them
+// together and blocks until SIGINT/SIGTERM.
+func runStart(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if err := cfg.Workspace.EnsureDirs(); err != nil {
+		return err
+	}
+
+	log.Printf("OpenPact %s (workspace=%s, engine=%s)", version.Get(), cfg.Workspace.Path, cfg.Engine.Type)
+
+	// The admin server owns the provider store; when the admin UI is disabled
+	// the orchestrator still needs one to resolve tokens and enabled providers.
+	var adminServer *admin.Server
+	var providerStore *admin.ProviderStore
+	if cfg.Admin.Enabled {
+		defaults := admin.DefaultConfig()
+		adminServer, err = admin.NewServer(admin.Config{
+			Bind:          cfg.Admin.Bind,
+			DataDir:       cfg.Workspace.DataDir(),
+			ScriptsDir:    cfg.Workspace.ScriptsDir(),
+			WorkspacePath: cfg.Workspace.Path,
+			AIDataDir:     cfg.Workspace.AIDataDir(),
+			Allowlist:     cfg.Admin.Allowlist,
+			AccessExpiry:  defaults.AccessExpiry,
+			RefreshExpiry: defaults.RefreshExpiry,
+			EngineType:    cfg.Engine.Type,
+			ScriptLimits: starlark.Config{
+				MaxExecutionMs: cfg.Starlark.MaxExecutionMs,
+				MaxSteps:       cfg.Starlark.MaxSteps,
+				MaxMemoryMB:    cfg.Starlark.MaxMemoryMB,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create admin server: %w", err)
+		}
+		providerStore = adminServer.ProviderStore()
+	} else {
+		providerStore = admin.NewProviderStore(cfg.Workspace.DataDir())
+	}
+
+	orch, err := orchestrator.New(cfg, providerStore)
+	if err != nil {
+		return fmt.Errorf("failed to create orchestrator: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var httpServer *http.Server
+	if adminServer != nil {
+		adminServer.SetSessionAPI(orch)
+		adminServer.SetProviderManagerAPI(orch)
+		adminServer.SetChannelModeAPI(orch)
+		adminServer.SetSchedulerAPI(orch)
+		adminServer.SetScriptRunner(orch)
+		adminServer.SetToolApprovalAPI(orch)
+		adminServer.SetPairingNotifier(orch)
+		adminServer.SetBudgetReporter(orch)
+
+		handler, err := adminServer.HandlerWithUI()
+		if err != nil {
+			return fmt.Errorf("failed to create admin handler: %w", err)
+		}
+
+		httpServer = &http.Server{Addr: cfg.Admin.Bind, Handler: handler}
+		go func() {
+			log.Printf("Admin UI listening on http://%s", cfg.Admin.Bind)
+			if adminServer.SetupRequired() {
+				log.Printf("First-run setup required - visit http://%s/setup", cfg.Admin.Bind)
+			}
+			if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Admin server error: %v", err)
+				stop()
+			}
+		}()
+	}
+
+	// Blocks until the context is cancelled, then shuts the orchestrator down.
+	runErr := orch.Start(ctx)
+
+	if httpServer != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Warning: admin server shutdown: %v", err)
+		}
+	}
+
+	return runErr
+}
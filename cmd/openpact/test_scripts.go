@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/open-pact/openpact/internal/config"
+	"github.com/open-pact/openpact/internal/starlark"
+)
+
+// runTestScripts runs the _test.star tests of the named scripts, or of every
+// script with a test file, in dry-run mode: HTTP requests are answered from
+// the fixtures in scripts/testdata and secrets are placeholders. It returns
+// an error if any test failed.
+func runTestScripts(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	dir := cfg.Workspace.ScriptsDir()
+
+	scripts := args
+	if len(scripts) == 0 {
+		if scripts, err = starlark.TestedScripts(dir); err != nil {
+			return err
+		}
+		if len(scripts) == 0 {
+			fmt.Printf("No %s files in %s\n", starlark.TestSuffix, dir)
+			return nil
+		}
+	}
+
+	sandboxCfg := starlark.Config{
+		MaxExecutionMs: cfg.Starlark.MaxExecutionMs,
+		MaxSteps:       cfg.Starlark.MaxSteps,
+		MaxMemoryMB:    cfg.Starlark.MaxMemoryMB,
+	}
+
+	failures := 0
+	for _, script := range scripts {
+		report, err := starlark.RunTests(context.Background(), sandboxCfg, dir, script)
+		if err != nil {
+			failures++
+			fmt.Printf("%s\n  [fail] %v\n", script, err)
+			continue
+		}
+
+		fmt.Printf("%s (%s)\n", report.Script, report.TestFile)
+		if report.Error != "" {
+			failures++
+			fmt.Printf("  [fail] %s\n", report.Error)
+			continue
+		}
+		for _, test := range report.Tests {
+			if test.Passed {
+				fmt.Printf("  [pass] %s (%d ms)\n", test.Name, test.DurationMs)
+				continue
+			}
+			failures++
+			fmt.Printf("  [fail] %s: %s\n", test.Name, test.Error)
+			for _, entry := range test.Logs {
+				fmt.Printf("         %s: %s\n", entry.Level, entry.Message)
+			}
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d test(s) failed", failures)
+	}
+	return nil
+}
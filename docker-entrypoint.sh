@@ -7,11 +7,12 @@
 #   1. Orchestrator starts (as openpact-system) → MCP HTTP server listens on :3100
 #   2. Wait for MCP server to be ready
 #   3. OpenCode starts (as openpact-ai) → connects to MCP server
+#      (skipped when engine.type is "openai", which calls MCP tools in-process)
 #
 # Directory structure:
 #   /workspace/secure/       — SYSTEM-ONLY (config, secrets, system data)
 #   /workspace/engine/       — ENGINE data (OpenCode auth, sessions — AI user needs access)
-#   /workspace/ai-data/      — AI-ACCESSIBLE (context files, memory, scripts, skills)
+#   /workspace/ai-data/      — AI-ACCESSIBLE (context files, memory, scripts, skills, inbox, prompts)
 #
 # Both users are in the 'openpact' group. File permissions use group
 # membership to give the AI user access to ai-data/ while keeping
@@ -34,13 +35,15 @@ chown -R openpact-ai:openpact /workspace/engine
 chmod -R 775 /workspace/engine
 
 # Create AI-accessible area (group-readable/writable for AI user)
-mkdir -p /workspace/ai-data/memory /workspace/ai-data/skills /workspace/ai-data/scripts
+mkdir -p /workspace/ai-data/memory /workspace/ai-data/skills /workspace/ai-data/scripts /workspace/ai-data/inbox /workspace/ai-data/prompts
 
 chown -R openpact-system:openpact /workspace/ai-data
 chmod 775 /workspace/ai-data
 chmod 775 /workspace/ai-data/memory
 chmod 755 /workspace/ai-data/skills
 chmod 755 /workspace/ai-data/scripts
+# Chat attachments are quarantined: readable by the AI user, never writable
+chmod 750 /workspace/ai-data/inbox
 # Ensure AI user (openpact-ai, same group) can write files created by either user
 find /workspace/ai-data/memory -type f -exec chmod g+w {} + 2>/dev/null || true
 
@@ -77,6 +80,9 @@ if [ $? -ne 0 ]; then
     echo "FATAL: failed to generate OpenCode config" >&2
     exit 1
 fi
+# The token is written as root; the orchestrator reads it as openpact-system.
+chown openpact-system:openpact /workspace/secure/data/mcp_token
+chmod 600 /workspace/secure/data/mcp_token
 
 # Build the allowlisted environment for the AI process.
 # Only system basics and LLM provider keys are passed through.
@@ -99,6 +105,16 @@ OC_PASSWORD=$(grep -oP '^\s*password:\s*\K\S+' /workspace/secure/config.yaml 2>/
 gosu openpact-system /app/openpact "$@" &
 ORCHESTRATOR_PID=$!
 
+# The openai engine talks to an OpenAI-compatible API directly and calls MCP
+# tools in-process, so there is no OpenCode sidecar to launch.
+ENGINE=${ENGINE_TYPE:-$(awk '/^engine:/{f=1;next} /^[^ #]/{f=0} f && $1=="type:"{gsub(/["\047]/,"",$2); print $2; exit}' /workspace/secure/config.yaml 2>/dev/null)}
+if [ "$ENGINE" = "openai" ]; then
+    echo "Engine type is openai, not starting OpenCode"
+    trap 'kill $ORCHESTRATOR_PID 2>/dev/null; wait $ORCHESTRATOR_PID 2>/dev/null' EXIT INT TERM
+    wait $ORCHESTRATOR_PID
+    exit $?
+fi
+
 # Wait for MCP HTTP server to be listening (up to 15 seconds)
 echo "Waiting for MCP HTTP server on port 3100..."
 MCP_READY=0
@@ -0,0 +1,343 @@
+package admin
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Environment variables that supply the master key used to encrypt the
+// secret and provider stores at rest. OPENPACT_MASTER_KEY takes priority.
+// The key file should live outside the workspace so that a workspace backup
+// does not contain both the ciphertext and the key.
+const (
+	MasterKeyEnv     = "OPENPACT_MASTER_KEY"
+	MasterKeyFileEnv = "OPENPACT_MASTER_KEY_FILE"
+)
+
+const (
+	masterKeySize     = 32 // AES-256
+	envelopeVersion   = 1
+	envelopeAlgorithm = "AES-256-GCM"
+)
+
+var (
+	ErrNoMasterKey    = errors.New("store is encrypted but no master key is configured (set " + MasterKeyEnv + " or " + MasterKeyFileEnv + ")")
+	ErrWrongMasterKey = errors.New("store was encrypted with a different master key")
+)
+
+// MasterKey is the key-encryption key for the at-rest store encryption. Each
+// save generates a fresh data key, encrypts the file contents with it, and
+// stores the data key wrapped (encrypted) with the master key alongside.
+type MasterKey struct {
+	key []byte
+	id  string
+}
+
+// NewMasterKey wraps raw key bytes, which must be 32 bytes long.
+func NewMasterKey(raw []byte) (*MasterKey, error) {
+	if len(raw) != masterKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", masterKeySize, len(raw))
+	}
+	sum := sha256.Sum256(raw)
+	key := make([]byte, masterKeySize)
+	copy(key, raw)
+	return &MasterKey{key: key, id: hex.EncodeToString(sum[:8])}, nil
+}
+
+// GenerateMasterKey creates a new random master key.
+func GenerateMasterKey() (*MasterKey, error) {
+	raw := make([]byte, masterKeySize)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate master key: %w", err)
+	}
+	return NewMasterKey(raw)
+}
+
+// ParseMasterKey decodes a master key from its text form: base64 or hex of
+// 32 bytes.
+func ParseMasterKey(s string) (*MasterKey, error) {
+	s = strings.TrimSpace(s)
+	if raw, err := base64.StdEncoding.DecodeString(s); err == nil && len(raw) == masterKeySize {
+		return NewMasterKey(raw)
+	}
+	if raw, err := hex.DecodeString(s); err == nil && len(raw) == masterKeySize {
+		return NewMasterKey(raw)
+	}
+	return nil, fmt.Errorf("master key must be %d bytes encoded as base64 or hex", masterKeySize)
+}
+
+// LoadMasterKeyFile reads a master key from a file containing its base64 or
+// hex encoding.
+func LoadMasterKeyFile(path string) (*MasterKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %w", err)
+	}
+	key, err := ParseMasterKey(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// MasterKeyFromEnv loads the master key from OPENPACT_MASTER_KEY or the file
+// named by OPENPACT_MASTER_KEY_FILE. It returns nil, nil if neither is set,
+// in which case the stores are written in plaintext.
+func MasterKeyFromEnv() (*MasterKey, error) {
+	if v := os.Getenv(MasterKeyEnv); v != "" {
+		key, err := ParseMasterKey(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", MasterKeyEnv, err)
+		}
+		return key, nil
+	}
+	if path := os.Getenv(MasterKeyFileEnv); path != "" {
+		return LoadMasterKeyFile(path)
+	}
+	return nil, nil
+}
+
+// WriteMasterKeyFile writes key's base64 encoding to path with mode 0600. It
+// refuses to overwrite an existing file.
+func WriteMasterKeyFile(path string, key *MasterKey) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create master key file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(key.String() + "\n"); err != nil {
+		return fmt.Errorf("failed to write master key file: %w", err)
+	}
+	return nil
+}
+
+// ID returns a short fingerprint of the key, recorded in encrypted files so a
+// mismatched key can be reported clearly.
+func (k *MasterKey) ID() string {
+	return k.id
+}
+
+// String returns the base64 encoding of the key.
+func (k *MasterKey) String() string {
+	return base64.StdEncoding.EncodeToString(k.key)
+}
+
+// envelope is the on-disk form of an encrypted store file.
+type envelope struct {
+	Encryption *envelopeHeader `json:"encryption"`
+}
+
+type envelopeHeader struct {
+	Version    int    `json:"version"`
+	Algorithm  string `json:"algorithm"`
+	KeyID      string `json:"key_id"`
+	WrappedKey string `json:"wrapped_key"` // data key sealed with the master key
+	Ciphertext string `json:"ciphertext"`  // file contents sealed with the data key
+}
+
+// sealEnvelope encrypts plaintext into an envelope under a fresh data key.
+func sealEnvelope(key *MasterKey, plaintext []byte) ([]byte, error) {
+	dataKey := make([]byte, masterKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrapped, err := gcmSeal(key.key, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := gcmSeal(dataKey, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(envelope{Encryption: &envelopeHeader{
+		Version:    envelopeVersion,
+		Algorithm:  envelopeAlgorithm,
+		KeyID:      key.ID(),
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	}}, "", "  ")
+}
+
+// openEnvelope decrypts an envelope header.
+func openEnvelope(key *MasterKey, h *envelopeHeader) ([]byte, error) {
+	if h.Version != envelopeVersion || h.Algorithm != envelopeAlgorithm {
+		return nil, fmt.Errorf("unsupported encryption format %s v%d", h.Algorithm, h.Version)
+	}
+	if key == nil {
+		return nil, ErrNoMasterKey
+	}
+	if h.KeyID != key.ID() {
+		return nil, fmt.Errorf("%w (file key %s, configured key %s)", ErrWrongMasterKey, h.KeyID, key.ID())
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(h.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wrapped key: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(h.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %w", err)
+	}
+
+	dataKey, err := gcmOpen(key.key, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	plaintext, err := gcmOpen(dataKey, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// gcmSeal encrypts with AES-GCM, prefixing the random nonce.
+func gcmSeal(key, plaintext []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// gcmOpen reverses gcmSeal.
+func gcmOpen(key, data []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readStoreFile reads a store file, decrypting it if it is an envelope. It
+// reports whether the file was encrypted so callers can migrate plaintext
+// files. A missing file is returned as os.ErrNotExist.
+func readStoreFile(path string, key *MasterKey) (data []byte, encrypted bool, err error) {
+	data, err = os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var env envelope
+	if bytes.Contains(data, []byte(`"encryption"`)) && json.Unmarshal(data, &env) == nil && env.Encryption != nil {
+		plaintext, err := openEnvelope(key, env.Encryption)
+		if err != nil {
+			return nil, true, err
+		}
+		return plaintext, true, nil
+	}
+	return data, false, nil
+}
+
+// writeStoreFile writes a store file with mode 0600, encrypting it when a
+// master key is configured. The write goes through a temp file and rename so
+// a crash never leaves a half-written store.
+func writeStoreFile(path string, key *MasterKey, plaintext []byte) error {
+	data := plaintext
+	if key != nil {
+		sealed, err := sealEnvelope(key, plaintext)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt: %w", err)
+		}
+		data = sealed
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// migrateStoreFile encrypts a plaintext store file in place. It is a no-op if
+// no key is configured, the file does not exist, or it is already encrypted.
+func migrateStoreFile(path string, key *MasterKey) error {
+	if key == nil {
+		return nil
+	}
+	data, encrypted, err := readStoreFile(path, key)
+	if encrypted {
+		// Already migrated; a key mismatch is reported by the store's load
+		return nil
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return writeStoreFile(path, key, data)
+}
+
+// RotateMasterKey re-encrypts the secret and provider stores in dataDir from
+// oldKey to newKey. oldKey may be nil if the stores are currently plaintext.
+// Every file is decrypted before any is rewritten, so a wrong old key leaves
+// the stores untouched.
+func RotateMasterKey(dataDir string, oldKey, newKey *MasterKey) error {
+	if newKey == nil {
+		return errors.New("new master key is required")
+	}
+
+	paths := []string{
+		filepath.Join(dataDir, secretsFileName),
+		filepath.Join(dataDir, providersFileName),
+	}
+
+	contents := make(map[string][]byte, len(paths))
+	for _, path := range paths {
+		data, _, err := readStoreFile(path, oldKey)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		contents[path] = data
+	}
+
+	for _, path := range paths {
+		data, ok := contents[path]
+		if !ok {
+			continue
+		}
+		if err := writeStoreFile(path, newKey, data); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
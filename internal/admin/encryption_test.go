@@ -0,0 +1,183 @@
+package admin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testMasterKey(t *testing.T) *MasterKey {
+	t.Helper()
+	key, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("GenerateMasterKey failed: %v", err)
+	}
+	return key
+}
+
+func TestParseMasterKey(t *testing.T) {
+	key := testMasterKey(t)
+
+	parsed, err := ParseMasterKey(key.String() + "\n")
+	if err != nil {
+		t.Fatalf("ParseMasterKey(base64) failed: %v", err)
+	}
+	if parsed.ID() != key.ID() {
+		t.Errorf("parsed key ID %s, want %s", parsed.ID(), key.ID())
+	}
+
+	if _, err := ParseMasterKey(strings.Repeat("ab", 32)); err != nil {
+		t.Errorf("ParseMasterKey(hex) failed: %v", err)
+	}
+	if _, err := ParseMasterKey("too-short"); err == nil {
+		t.Error("expected error for short key")
+	}
+}
+
+func TestSecretStore_EncryptedAtRest(t *testing.T) {
+	dir := t.TempDir()
+	key := testMasterKey(t)
+	t.Setenv(MasterKeyEnv, key.String())
+
+	store := NewSecretStore(dir)
+	if err := store.Set("API_KEY", "super-secret-value"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, secretsFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "super-secret-value") || strings.Contains(string(data), "API_KEY") {
+		t.Errorf("secrets file contains plaintext: %s", data)
+	}
+	if !strings.Contains(string(data), key.ID()) {
+		t.Error("expected key ID in envelope")
+	}
+
+	got, err := store.Get("API_KEY")
+	if err != nil || got != "super-secret-value" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+}
+
+func TestSecretStore_MigratesPlaintext(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(MasterKeyEnv, "")
+
+	if err := NewSecretStore(dir).Set("API_KEY", "plain-value"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := NewProviderStore(dir).SetTokens("discord", map[string]string{"token": "bot-token-value"}); err != nil {
+		t.Fatalf("SetTokens failed: %v", err)
+	}
+
+	key := testMasterKey(t)
+	t.Setenv(MasterKeyEnv, key.String())
+
+	secrets := NewSecretStore(dir)
+	providers := NewProviderStore(dir)
+
+	for _, name := range []string{secretsFileName, providersFileName} {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		if strings.Contains(string(data), "value") {
+			t.Errorf("%s was not encrypted on construction: %s", name, data)
+		}
+	}
+
+	if got, _ := secrets.Get("API_KEY"); got != "plain-value" {
+		t.Errorf("secret after migration = %q", got)
+	}
+	if got := providers.ResolveToken("discord", "token"); got != "bot-token-value" {
+		t.Errorf("token after migration = %q", got)
+	}
+}
+
+func TestSecretStore_KeyErrors(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(MasterKeyEnv, testMasterKey(t).String())
+	if err := NewSecretStore(dir).Set("API_KEY", "value"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	t.Setenv(MasterKeyEnv, "")
+	if _, err := NewSecretStore(dir).All(); !errors.Is(err, ErrNoMasterKey) {
+		t.Errorf("without key: err = %v, want ErrNoMasterKey", err)
+	}
+
+	t.Setenv(MasterKeyEnv, testMasterKey(t).String())
+	if _, err := NewSecretStore(dir).All(); !errors.Is(err, ErrWrongMasterKey) {
+		t.Errorf("with other key: err = %v, want ErrWrongMasterKey", err)
+	}
+
+	t.Setenv(MasterKeyEnv, "not-a-key")
+	if _, err := NewSecretStore(dir).All(); err == nil {
+		t.Error("expected error for malformed key")
+	}
+}
+
+func TestMasterKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "master.key")
+	key := testMasterKey(t)
+
+	if err := WriteMasterKeyFile(path, key); err != nil {
+		t.Fatalf("WriteMasterKeyFile failed: %v", err)
+	}
+	if err := WriteMasterKeyFile(path, key); err == nil {
+		t.Error("expected WriteMasterKeyFile to refuse to overwrite")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("key file mode = %o, want 0600", info.Mode().Perm())
+	}
+
+	t.Setenv(MasterKeyEnv, "")
+	t.Setenv(MasterKeyFileEnv, path)
+	loaded, err := MasterKeyFromEnv()
+	if err != nil {
+		t.Fatalf("MasterKeyFromEnv failed: %v", err)
+	}
+	if loaded.ID() != key.ID() {
+		t.Errorf("loaded key ID %s, want %s", loaded.ID(), key.ID())
+	}
+}
+
+func TestRotateMasterKey(t *testing.T) {
+	dir := t.TempDir()
+	oldKey := testMasterKey(t)
+	newKey := testMasterKey(t)
+
+	t.Setenv(MasterKeyEnv, oldKey.String())
+	if err := NewSecretStore(dir).Set("API_KEY", "value1"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := NewProviderStore(dir).SetTokens("telegram", map[string]string{"token": "tg-token"}); err != nil {
+		t.Fatalf("SetTokens failed: %v", err)
+	}
+
+	if err := RotateMasterKey(dir, newKey, newKey); !errors.Is(err, ErrWrongMasterKey) {
+		t.Fatalf("rotation with wrong old key: err = %v, want ErrWrongMasterKey", err)
+	}
+
+	if err := RotateMasterKey(dir, oldKey, newKey); err != nil {
+		t.Fatalf("RotateMasterKey failed: %v", err)
+	}
+
+	if _, err := NewSecretStore(dir).All(); !errors.Is(err, ErrWrongMasterKey) {
+		t.Errorf("old key should no longer decrypt: err = %v", err)
+	}
+
+	t.Setenv(MasterKeyEnv, newKey.String())
+	if got, _ := NewSecretStore(dir).Get("API_KEY"); got != "value1" {
+		t.Errorf("secret after rotation = %q", got)
+	}
+	if got := NewProviderStore(dir).ResolveToken("telegram", "token"); got != "tg-token" {
+		t.Errorf("token after rotation = %q", got)
+	}
+}
@@ -1,19 +1,31 @@
 package admin
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
+
+	"github.com/open-pact/openpact/internal/starlark"
 )
 
+// ScriptRunner runs approved scripts for the run endpoint.
+type ScriptRunner interface {
+	RunScript(ctx context.Context, name string, args map[string]any) (starlark.Result, error)
+}
+
 // ScriptHandlers provides HTTP handlers for script management.
 type ScriptHandlers struct {
-	store *ScriptStore
+	store  *ScriptStore
+	runner ScriptRunner
+	limits starlark.Config // sandbox limits for test runs
 }
 
-// NewScriptHandlers creates new script handlers.
-func NewScriptHandlers(store *ScriptStore) *ScriptHandlers {
-	return &ScriptHandlers{store: store}
+// NewScriptHandlers creates new script handlers. Tests run with limits, the
+// same sandbox limits the scheduler and openpact test-scripts use.
+func NewScriptHandlers(store *ScriptStore, limits starlark.Config) *ScriptHandlers {
+	return &ScriptHandlers{store: store, limits: limits}
 }
 
 // ListScripts handles GET /api/scripts
@@ -101,6 +113,14 @@ func (h *ScriptHandlers) CreateScript(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if err == ErrScriptIsTest {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":   "invalid_request",
+			"message": "Names ending in " + starlark.TestSuffix + " are reserved for test files",
+		})
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -211,6 +231,22 @@ func (h *ScriptHandlers) ApproveScript(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if errors.Is(err, ErrScriptLibraries) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":   "invalid_libraries",
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrScriptManifest) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":   "invalid_manifest",
+			"message": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -223,6 +259,109 @@ func (h *ScriptHandlers) ApproveScript(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(script)
 }
 
+// RunScript handles POST /api/scripts/:name/run
+func (h *ScriptHandlers) RunScript(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extract name from /api/scripts/:name/run
+	path := strings.TrimPrefix(r.URL.Path, "/api/scripts/")
+	name := strings.TrimSuffix(path, "/run")
+
+	if h.runner == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":   "unavailable",
+			"message": "Script runner not available",
+		})
+		return
+	}
+
+	var req struct {
+		Args map[string]any `json:"args"`
+	}
+	json.NewDecoder(r.Body).Decode(&req) // Args are optional
+	if req.Args == nil {
+		req.Args = map[string]any{}
+	}
+	req.Args["trigger"] = "test"
+
+	result, err := h.runner.RunScript(r.Context(), name, req.Args)
+	if errors.Is(err, ErrScriptNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":   "not_found",
+			"message": "Script not found",
+		})
+		return
+	}
+	if errors.Is(err, ErrScriptNotApproved) || errors.Is(err, ErrScriptModified) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":   "script_not_approved",
+			"message": "Only approved scripts can be run",
+		})
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":   "run_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        result.Error == "",
+		"result":         result.Value,
+		"error":          result.Error,
+		"error_kind":     result.ErrorKind,
+		"duration_ms":    result.Duration.Milliseconds(),
+		"logs":           result.Logs,
+		"logs_truncated": result.LogsTruncated,
+	})
+}
+
+// TestScript handles POST /api/scripts/:name/test. It runs the script's
+// _test.star file in dry-run mode and works whatever the script's status, so
+// reviewers can see the tests pass before approving.
+func (h *ScriptHandlers) TestScript(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extract name from /api/scripts/:name/test
+	path := strings.TrimPrefix(r.URL.Path, "/api/scripts/")
+	name := strings.TrimSuffix(path, "/test")
+
+	if _, err := h.store.Get(name, false); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":   "not_found",
+			"message": "Script not found",
+		})
+		return
+	}
+
+	report, err := starlark.RunTests(r.Context(), h.limits, h.store.scriptsDir, name)
+	if errors.Is(err, starlark.ErrNoTests) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":   "no_tests",
+			"message": "Script has no " + starlark.TestFileName(name),
+		})
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":   "test_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(report)
+}
+
 // RejectScript handles POST /api/scripts/:name/reject
 func (h *ScriptHandlers) RejectScript(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
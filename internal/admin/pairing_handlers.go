@@ -0,0 +1,140 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultPairingLogLimit is the number of audit trail entries returned by
+// GET /api/pairing without a limit.
+const defaultPairingLogLimit = 100
+
+// PairingNotifier is told when a pairing request is answered in the admin
+// API, so that the running provider's allowlist is updated and the user is
+// told the outcome.
+type PairingNotifier interface {
+	PairingAnswered(req PairingRequest, approved bool, allowedUsers []string)
+}
+
+// PairingHandlers handles HTTP requests for pairing requests.
+type PairingHandlers struct {
+	store    *ProviderStore
+	notifier PairingNotifier
+}
+
+// NewPairingHandlers creates new pairing handlers.
+func NewPairingHandlers(store *ProviderStore) *PairingHandlers {
+	return &PairingHandlers{store: store}
+}
+
+// SetNotifier sets the pairing notifier (called after orchestrator is created).
+func (h *PairingHandlers) SetNotifier(n PairingNotifier) {
+	h.notifier = n
+}
+
+// ListPairings handles GET /api/pairing. It returns the pending requests and
+// the newest entries of the audit trail; the limit query parameter sets how
+// many.
+func (h *PairingHandlers) ListPairings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := defaultPairingLogLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, `{"error":"bad_request","message":"limit must be a non-negative integer"}`, http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	pending, err := h.store.PendingPairings()
+	if err != nil {
+		http.Error(w, `{"error":"internal","message":"Failed to list pairing requests"}`, http.StatusInternalServerError)
+		return
+	}
+	log, err := h.store.PairingLog(limit)
+	if err != nil {
+		http.Error(w, `{"error":"internal","message":"Failed to read pairing log"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"pending": pending, "log": log})
+}
+
+// HandlePairingByCode handles POST /api/pairing/:code/approve and
+// POST /api/pairing/:code/deny. An approval may carry an expires_at time,
+// after which the user loses access again.
+func (h *PairingHandlers) HandlePairingByCode(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/pairing/")
+	code, action, _ := strings.Cut(rest, "/")
+	if code == "" || (action != "approve" && action != "deny") {
+		http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	username, _ := UsernameFromContext(r.Context())
+
+	var req PairingRequest
+	var allowed []string
+	var err error
+	approved := action == "approve"
+	if approved {
+		var body struct {
+			ExpiresAt *time.Time `json:"expires_at"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, `{"error":"bad_request","message":"Invalid JSON"}`, http.StatusBadRequest)
+			return
+		}
+		var expires time.Time
+		if body.ExpiresAt != nil {
+			if !body.ExpiresAt.After(time.Now()) {
+				http.Error(w, `{"error":"bad_request","message":"expires_at must be in the future"}`, http.StatusBadRequest)
+				return
+			}
+			expires = *body.ExpiresAt
+		}
+		req, allowed, err = h.store.ApprovePairing(code, username, expires)
+	} else {
+		req, err = h.store.DenyPairing(code, username)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPairingNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error":   "not_found",
+				"message": "Pairing request not found, expired or already answered",
+			})
+		case errors.Is(err, ErrAllowlistIsEmpty), errors.Is(err, ErrProviderNotFound):
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error":   "bad_request",
+				"message": err.Error(),
+			})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{
+				"error":   "internal",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	if h.notifier != nil {
+		h.notifier.PairingAnswered(req, approved, allowed)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"code": req.Code, "approved": approved})
+}
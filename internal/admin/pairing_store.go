@@ -0,0 +1,318 @@
+package admin
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPairingNotFound  = errors.New("pairing request not found or expired")
+	ErrTooManyPairings  = errors.New("too many pending pairing requests")
+	ErrAllowlistIsEmpty = errors.New("the allowlist is empty, so everyone is already allowed")
+)
+
+// maxPairingLog caps the pairing audit trail; the oldest entries are dropped.
+const maxPairingLog = 500
+
+// Pairing audit trail actions.
+const (
+	PairingRequested      = "requested"
+	PairingApproved       = "approved"
+	PairingDenied         = "denied"
+	PairingRequestExpired = "request_expired"
+	PairingAccessExpired  = "access_expired"
+)
+
+// PairingRequest is a request for access from a user who messaged the bot
+// but isn't on its allowlist.
+type PairingRequest struct {
+	Code        string    `json:"code"`
+	Provider    string    `json:"provider"`
+	UserID      string    `json:"user_id"`
+	UserName    string    `json:"user_name,omitempty"`
+	ChannelID   string    `json:"channel_id"` // Direct message channel the request came from
+	RequestedAt time.Time `json:"requested_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// PairingEvent is an entry in the pairing audit trail.
+type PairingEvent struct {
+	Action          string     `json:"action"`
+	Provider        string     `json:"provider"`
+	UserID          string     `json:"user_id"`
+	UserName        string     `json:"user_name,omitempty"`
+	Code            string     `json:"code,omitempty"`
+	By              string     `json:"by,omitempty"`                // Admin username or approver account; empty if automatic
+	AccessExpiresAt *time.Time `json:"access_expires_at,omitempty"` // For approvals with an expiry
+	At              time.Time  `json:"at"`
+}
+
+// logPairing appends an event to the audit trail.
+func (pf *providerFile) logPairing(ev PairingEvent) {
+	pf.PairingLog = append(pf.PairingLog, ev)
+	if over := len(pf.PairingLog) - maxPairingLog; over > 0 {
+		pf.PairingLog = pf.PairingLog[over:]
+	}
+}
+
+// expirePairings drops requests that have expired, and reports whether any
+// were dropped.
+func (pf *providerFile) expirePairings(now time.Time) bool {
+	kept := pf.Pairings[:0]
+	for _, req := range pf.Pairings {
+		if now.After(req.ExpiresAt) {
+			pf.logPairing(PairingEvent{Action: PairingRequestExpired, Provider: req.Provider, UserID: req.UserID, UserName: req.UserName, Code: req.Code, At: now})
+			continue
+		}
+		kept = append(kept, req)
+	}
+	changed := len(kept) != len(pf.Pairings)
+	pf.Pairings = kept
+	return changed
+}
+
+// takePairing removes and returns the pending request with the given code.
+func (pf *providerFile) takePairing(code string, now time.Time) (PairingRequest, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	for i, req := range pf.Pairings {
+		if req.Code == code && !now.After(req.ExpiresAt) {
+			pf.Pairings = append(pf.Pairings[:i], pf.Pairings[i+1:]...)
+			return req, true
+		}
+	}
+	return PairingRequest{}, false
+}
+
+// RequestPairing records a request for access from a user who isn't on a
+// provider's allowlist. A user with a pending request gets the same request
+// back, with created set to false, so repeated messages don't pile up.
+func (s *ProviderStore) RequestPairing(provider, channelID, userID, userName string, ttl time.Duration, maxPending int) (req PairingRequest, created bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pf, err := s.load()
+	if err != nil {
+		return PairingRequest{}, false, err
+	}
+
+	now := time.Now().UTC()
+	if pf.expirePairings(now) {
+		if err := s.save(pf); err != nil {
+			return PairingRequest{}, false, err
+		}
+	}
+	for _, existing := range pf.Pairings {
+		if existing.Provider == provider && existing.UserID == userID {
+			return existing, false, nil
+		}
+	}
+	if maxPending > 0 && len(pf.Pairings) >= maxPending {
+		return PairingRequest{}, false, ErrTooManyPairings
+	}
+
+	code, err := generateCode()
+	if err != nil {
+		return PairingRequest{}, false, err
+	}
+	req = PairingRequest{
+		Code:        code,
+		Provider:    provider,
+		UserID:      userID,
+		UserName:    userName,
+		ChannelID:   channelID,
+		RequestedAt: now,
+		ExpiresAt:   now.Add(ttl),
+	}
+	pf.Pairings = append(pf.Pairings, req)
+	pf.logPairing(PairingEvent{Action: PairingRequested, Provider: provider, UserID: userID, UserName: userName, Code: code, At: now})
+
+	if err := s.save(pf); err != nil {
+		return PairingRequest{}, false, err
+	}
+	return req, true, nil
+}
+
+// PendingPairings returns the pairing requests waiting for an answer, oldest
+// first.
+func (s *ProviderStore) PendingPairings() ([]PairingRequest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pf, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	pending := make([]PairingRequest, 0, len(pf.Pairings))
+	for _, req := range pf.Pairings {
+		if !now.After(req.ExpiresAt) {
+			pending = append(pending, req)
+		}
+	}
+	return pending, nil
+}
+
+// PairingLog returns the pairing audit trail, newest first. A limit of 0
+// returns every entry.
+func (s *ProviderStore) PairingLog(limit int) ([]PairingEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pf, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]PairingEvent, 0, len(pf.PairingLog))
+	for i := len(pf.PairingLog) - 1; i >= 0; i-- {
+		events = append(events, pf.PairingLog[i])
+		if limit > 0 && len(events) == limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+// ApprovePairing answers a pairing request by adding the user to the
+// provider's allowlist. If accessExpires is not zero, the user loses access
+// then (see ExpireAccess). It returns the request and the new allowlist.
+func (s *ProviderStore) ApprovePairing(code, by string, accessExpires time.Time) (PairingRequest, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pf, err := s.load()
+	if err != nil {
+		return PairingRequest{}, nil, err
+	}
+
+	now := time.Now().UTC()
+	req, ok := pf.takePairing(code, now)
+	if !ok {
+		return PairingRequest{}, nil, ErrPairingNotFound
+	}
+	cfg, ok := pf.Providers[req.Provider]
+	if !ok {
+		return PairingRequest{}, nil, ErrProviderNotFound
+	}
+	if len(cfg.AllowedUsers) == 0 {
+		// Adding one user would lock everyone else out
+		return PairingRequest{}, nil, ErrAllowlistIsEmpty
+	}
+
+	if !containsString(cfg.AllowedUsers, req.UserID) {
+		cfg.AllowedUsers = append(cfg.AllowedUsers, req.UserID)
+	}
+	ev := PairingEvent{Action: PairingApproved, Provider: req.Provider, UserID: req.UserID, UserName: req.UserName, Code: req.Code, By: by, At: now}
+	if accessExpires.IsZero() {
+		delete(cfg.UserExpiry, req.UserID)
+	} else {
+		if cfg.UserExpiry == nil {
+			cfg.UserExpiry = make(map[string]time.Time)
+		}
+		accessExpires = accessExpires.UTC()
+		cfg.UserExpiry[req.UserID] = accessExpires
+		ev.AccessExpiresAt = &accessExpires
+	}
+	cfg.UpdatedAt = now
+	pf.Providers[req.Provider] = cfg
+	pf.logPairing(ev)
+
+	if err := s.save(pf); err != nil {
+		return PairingRequest{}, nil, err
+	}
+	return req, cfg.AllowedUsers, nil
+}
+
+// DenyPairing answers a pairing request without giving access.
+func (s *ProviderStore) DenyPairing(code, by string) (PairingRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pf, err := s.load()
+	if err != nil {
+		return PairingRequest{}, err
+	}
+
+	now := time.Now().UTC()
+	req, ok := pf.takePairing(code, now)
+	if !ok {
+		return PairingRequest{}, ErrPairingNotFound
+	}
+	pf.logPairing(PairingEvent{Action: PairingDenied, Provider: req.Provider, UserID: req.UserID, UserName: req.UserName, Code: req.Code, By: by, At: now})
+
+	if err := s.save(pf); err != nil {
+		return PairingRequest{}, err
+	}
+	return req, nil
+}
+
+// ExpireAccess removes users whose access has expired from the allowlists,
+// and drops expired pairing requests. It returns the new allowlist of each
+// provider that changed. A provider left with an empty allowlist would let
+// everyone in, so it is disabled.
+func (s *ProviderStore) ExpireAccess(now time.Time) (map[string][]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pf, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	now = now.UTC()
+	changed := pf.expirePairings(now)
+	allowlists := make(map[string][]string)
+	for name, cfg := range pf.Providers {
+		removed := false
+		for userID, expires := range cfg.UserExpiry {
+			if now.Before(expires) {
+				continue
+			}
+			delete(cfg.UserExpiry, userID)
+			changed = true
+			if !containsString(cfg.AllowedUsers, userID) {
+				continue // Removed by hand already
+			}
+			cfg.AllowedUsers = removeString(cfg.AllowedUsers, userID)
+			removed = true
+			pf.logPairing(PairingEvent{Action: PairingAccessExpired, Provider: name, UserID: userID, At: now})
+		}
+		if removed {
+			if len(cfg.AllowedUsers) == 0 {
+				cfg.Enabled = false
+			}
+			cfg.UpdatedAt = now
+			allowlists[name] = cfg.AllowedUsers
+		}
+		pf.Providers[name] = cfg
+	}
+
+	if !changed {
+		return allowlists, nil
+	}
+	if err := s.save(pf); err != nil {
+		return nil, err
+	}
+	return allowlists, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(list []string, s string) []string {
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
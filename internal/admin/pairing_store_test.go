@@ -0,0 +1,151 @@
+package admin
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newPairingTestStore(t *testing.T, allowed ...string) *ProviderStore {
+	t.Helper()
+	store := NewProviderStore(t.TempDir())
+	if err := store.Set("telegram", ProviderConfig{Enabled: true, AllowedUsers: allowed}); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestProviderStore_RequestPairing(t *testing.T) {
+	store := newPairingTestStore(t, "owner")
+
+	req, created, err := store.RequestPairing("telegram", "42", "42", "alice", time.Hour, 2)
+	if err != nil || !created {
+		t.Fatalf("RequestPairing = %+v, %v, %v", req, created, err)
+	}
+	if len(req.Code) != 8 || req.UserID != "42" || req.ChannelID != "42" {
+		t.Errorf("unexpected request %+v", req)
+	}
+
+	// Asking again returns the same request
+	again, created, err := store.RequestPairing("telegram", "42", "42", "alice", time.Hour, 2)
+	if err != nil || created || again.Code != req.Code {
+		t.Errorf("repeat RequestPairing = %+v, %v, %v", again, created, err)
+	}
+
+	if _, _, err := store.RequestPairing("telegram", "43", "43", "", time.Hour, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.RequestPairing("telegram", "44", "44", "", time.Hour, 2); !errors.Is(err, ErrTooManyPairings) {
+		t.Errorf("expected ErrTooManyPairings, got %v", err)
+	}
+
+	pending, _ := store.PendingPairings()
+	if len(pending) != 2 {
+		t.Errorf("expected 2 pending requests, got %+v", pending)
+	}
+}
+
+func TestProviderStore_ApprovePairing(t *testing.T) {
+	store := newPairingTestStore(t, "owner")
+	req, _, _ := store.RequestPairing("telegram", "42", "42", "alice", time.Hour, 0)
+
+	expires := time.Now().Add(24 * time.Hour)
+	approved, allowed, err := store.ApprovePairing(req.Code, "admin", expires)
+	if err != nil {
+		t.Fatalf("ApprovePairing: %v", err)
+	}
+	if approved.UserID != "42" || len(allowed) != 2 || allowed[1] != "42" {
+		t.Errorf("unexpected approval %+v, allowlist %v", approved, allowed)
+	}
+
+	cfg, _ := store.Get("telegram")
+	if !cfg.UserExpiry["42"].Equal(expires.UTC()) {
+		t.Errorf("expected access to expire at %s, got %v", expires, cfg.UserExpiry)
+	}
+	if _, _, err := store.ApprovePairing(req.Code, "admin", time.Time{}); !errors.Is(err, ErrPairingNotFound) {
+		t.Errorf("expected a used code to be refused, got %v", err)
+	}
+
+	log, _ := store.PairingLog(0)
+	if len(log) != 2 || log[0].Action != PairingApproved || log[0].By != "admin" || log[0].AccessExpiresAt == nil || log[1].Action != PairingRequested {
+		t.Errorf("unexpected audit trail %+v", log)
+	}
+}
+
+func TestProviderStore_DenyPairing(t *testing.T) {
+	store := newPairingTestStore(t, "owner")
+	req, _, _ := store.RequestPairing("telegram", "42", "42", "alice", time.Hour, 0)
+
+	if _, err := store.DenyPairing(req.Code, "telegram:owner"); err != nil {
+		t.Fatalf("DenyPairing: %v", err)
+	}
+	cfg, _ := store.Get("telegram")
+	if len(cfg.AllowedUsers) != 1 {
+		t.Errorf("expected the allowlist to be unchanged, got %v", cfg.AllowedUsers)
+	}
+	if log, _ := store.PairingLog(1); len(log) != 1 || log[0].Action != PairingDenied {
+		t.Errorf("unexpected audit trail %+v", log)
+	}
+}
+
+func TestProviderStore_ApprovePairingEmptyAllowlist(t *testing.T) {
+	store := newPairingTestStore(t)
+	req, _, _ := store.RequestPairing("telegram", "42", "42", "", time.Hour, 0)
+
+	if _, _, err := store.ApprovePairing(req.Code, "admin", time.Time{}); !errors.Is(err, ErrAllowlistIsEmpty) {
+		t.Errorf("expected ErrAllowlistIsEmpty, got %v", err)
+	}
+}
+
+func TestProviderStore_ExpireAccess(t *testing.T) {
+	store := newPairingTestStore(t, "owner")
+	req, _, _ := store.RequestPairing("telegram", "42", "42", "", time.Hour, 0)
+	if _, _, err := store.ApprovePairing(req.Code, "admin", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	stale, _, _ := store.RequestPairing("telegram", "43", "43", "", time.Minute, 0)
+
+	// Nothing has expired yet
+	if changed, err := store.ExpireAccess(time.Now()); err != nil || len(changed) != 0 {
+		t.Errorf("ExpireAccess = %v, %v; want no changes", changed, err)
+	}
+
+	changed, err := store.ExpireAccess(time.Now().Add(2 * time.Hour))
+	if err != nil {
+		t.Fatalf("ExpireAccess: %v", err)
+	}
+	if users := changed["telegram"]; len(users) != 1 || users[0] != "owner" {
+		t.Errorf("expected only the owner to stay allowed, got %v", changed)
+	}
+	cfg, _ := store.Get("telegram")
+	if len(cfg.UserExpiry) != 0 {
+		t.Errorf("expected the expiry to be cleared, got %v", cfg.UserExpiry)
+	}
+	if _, err := store.DenyPairing(stale.Code, "admin"); !errors.Is(err, ErrPairingNotFound) {
+		t.Errorf("expected the stale request to be gone, got %v", err)
+	}
+
+	log, _ := store.PairingLog(2)
+	actions := map[string]bool{log[0].Action: true, log[1].Action: true}
+	if !actions[PairingAccessExpired] || !actions[PairingRequestExpired] {
+		t.Errorf("unexpected audit trail %+v", log)
+	}
+}
+
+func TestProviderStore_ExpireLastUser(t *testing.T) {
+	store := newPairingTestStore(t, "owner")
+	cfg, _ := store.Get("telegram")
+	cfg.UserExpiry = map[string]time.Time{"owner": time.Now().Add(time.Hour)}
+	if err := store.Set("telegram", cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// An empty allowlist would let everyone in, so the provider is disabled
+	changed, err := store.ExpireAccess(time.Now().Add(2 * time.Hour))
+	if users, ok := changed["telegram"]; err != nil || !ok || len(users) != 0 {
+		t.Errorf("ExpireAccess = %v, %v; want an empty telegram allowlist", changed, err)
+	}
+	if cfg, _ := store.Get("telegram"); cfg.Enabled {
+		t.Error("expected telegram to be disabled once its allowlist emptied")
+	}
+}
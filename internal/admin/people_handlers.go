@@ -0,0 +1,155 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// PeopleHandlers handles HTTP requests for the people registry.
+type PeopleHandlers struct {
+	store *PeopleStore
+}
+
+// NewPeopleHandlers creates new people handlers.
+func NewPeopleHandlers(store *PeopleStore) *PeopleHandlers {
+	return &PeopleHandlers{store: store}
+}
+
+// ListPeople handles GET /api/people.
+func (h *PeopleHandlers) ListPeople(w http.ResponseWriter, r *http.Request) {
+	people, err := h.store.List()
+	if err != nil {
+		http.Error(w, `{"error":"internal","message":"Failed to list people"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"people": people})
+}
+
+// CreatePerson handles POST /api/people.
+func (h *PeopleHandlers) CreatePerson(w http.ResponseWriter, r *http.Request) {
+	var req Person
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"bad_request","message":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	person, err := h.store.Create(&req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":   "bad_request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, person)
+}
+
+// HandlePersonByID handles /api/people/:id requests.
+func (h *PeopleHandlers) HandlePersonByID(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/people/")
+	id, action, _ := strings.Cut(rest, "/")
+	if id == "" {
+		http.Error(w, `{"error":"bad_request","message":"Person ID required"}`, http.StatusBadRequest)
+		return
+	}
+
+	switch action {
+	case "link-code":
+		if r.Method == http.MethodPost {
+			h.CreateLinkCode(w, r, id)
+			return
+		}
+		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		return
+	case "":
+		// Fall through to standard CRUD
+	default:
+		http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.GetPerson(w, r, id)
+	case http.MethodPut:
+		h.UpdatePerson(w, r, id)
+	case http.MethodDelete:
+		h.DeletePerson(w, r, id)
+	default:
+		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+// GetPerson handles GET /api/people/:id.
+func (h *PeopleHandlers) GetPerson(w http.ResponseWriter, r *http.Request, id string) {
+	person, err := h.store.Get(id)
+	if err != nil {
+		if errors.Is(err, ErrPersonNotFound) {
+			http.Error(w, `{"error":"not_found","message":"Person not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"internal","message":"Failed to get person"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, person)
+}
+
+// UpdatePerson handles PUT /api/people/:id. Removing an account from the
+// accounts list unlinks it.
+func (h *PeopleHandlers) UpdatePerson(w http.ResponseWriter, r *http.Request, id string) {
+	var req Person
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"bad_request","message":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	person, err := h.store.Update(id, &req)
+	if err != nil {
+		if errors.Is(err, ErrPersonNotFound) {
+			http.Error(w, `{"error":"not_found","message":"Person not found"}`, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":   "bad_request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, person)
+}
+
+// DeletePerson handles DELETE /api/people/:id.
+func (h *PeopleHandlers) DeletePerson(w http.ResponseWriter, r *http.Request, id string) {
+	if err := h.store.Delete(id); err != nil {
+		if errors.Is(err, ErrPersonNotFound) {
+			http.Error(w, `{"error":"not_found","message":"Person not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"internal","message":"Failed to delete person"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateLinkCode handles POST /api/people/:id/link-code. The person sends
+// "/link <code>" from the account they want to add.
+func (h *PeopleHandlers) CreateLinkCode(w http.ResponseWriter, r *http.Request, id string) {
+	code, expires, err := h.store.CreateLinkCode(id)
+	if err != nil {
+		if errors.Is(err, ErrPersonNotFound) {
+			http.Error(w, `{"error":"not_found","message":"Person not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"internal","message":"Failed to create link code"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"code": code, "expires_at": expires})
+}
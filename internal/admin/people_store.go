@@ -0,0 +1,422 @@
+package admin
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrPersonNotFound   = errors.New("person not found")
+	ErrAccountLinked    = errors.New("account is already linked to someone else")
+	ErrLinkCodeNotFound = errors.New("link code not found or expired")
+)
+
+// LinkCodeTTL is how long a link code can be redeemed.
+const LinkCodeTTL = 10 * time.Minute
+
+// codeAlphabet leaves out letters and digits that are easy to confuse.
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Person is someone who talks to the bot, with the chat accounts they use.
+type Person struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`               // Display name
+	Timezone       string    `json:"timezone,omitempty"` // IANA time zone, e.g. "Europe/London"
+	Role           string    `json:"role,omitempty"`     // Permissions role; overrides permissions.users
+	Profile        string    `json:"profile,omitempty"`  // Profile file, relative to ai-data/
+	Accounts       []string  `json:"accounts"`           // "provider:userID"
+	FollowSessions bool      `json:"follow_sessions"`    // Share one session across all their accounts
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// linkCode is a one-time code for linking another account to a person.
+type linkCode struct {
+	PersonID  string    `json:"person_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// peopleFile is the on-disk JSON format.
+type peopleFile struct {
+	People    map[string]*Person   `json:"people"`
+	LinkCodes map[string]*linkCode `json:"link_codes,omitempty"`
+}
+
+// PeopleStore manages the people registry, which links the chat accounts of
+// one person across providers.
+type PeopleStore struct {
+	dataDir string
+	mu      sync.RWMutex
+}
+
+// NewPeopleStore creates a new people store.
+func NewPeopleStore(dataDir string) *PeopleStore {
+	return &PeopleStore{dataDir: dataDir}
+}
+
+// AccountKey returns the account identifier of a provider user.
+func AccountKey(provider, userID string) string {
+	return provider + ":" + userID
+}
+
+func (s *PeopleStore) filePath() string {
+	return filepath.Join(s.dataDir, "people.json")
+}
+
+func (s *PeopleStore) load() (*peopleFile, error) {
+	pf := &peopleFile{People: make(map[string]*Person), LinkCodes: make(map[string]*linkCode)}
+
+	data, err := os.ReadFile(s.filePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return pf, nil
+		}
+		return nil, fmt.Errorf("failed to read people: %w", err)
+	}
+
+	if err := json.Unmarshal(data, pf); err != nil {
+		return nil, fmt.Errorf("failed to parse people: %w", err)
+	}
+
+	if pf.People == nil {
+		pf.People = make(map[string]*Person)
+	}
+	if pf.LinkCodes == nil {
+		pf.LinkCodes = make(map[string]*linkCode)
+	}
+
+	return pf, nil
+}
+
+func (s *PeopleStore) save(pf *peopleFile) error {
+	if err := os.MkdirAll(s.dataDir, 0750); err != nil {
+		return fmt.Errorf("failed to create data dir: %w", err)
+	}
+
+	// Expired link codes are dropped whenever the file is written
+	now := time.Now()
+	for code, lc := range pf.LinkCodes {
+		if now.After(lc.ExpiresAt) {
+			delete(pf.LinkCodes, code)
+		}
+	}
+
+	data, err := json.MarshalIndent(pf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal people: %w", err)
+	}
+
+	if err := os.WriteFile(s.filePath(), data, 0600); err != nil {
+		return fmt.Errorf("failed to write people: %w", err)
+	}
+
+	return nil
+}
+
+// validatePerson checks a person's fields, and that none of their accounts
+// belong to someone else.
+func validatePerson(pf *peopleFile, p *Person) error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", p.Timezone)
+		}
+	}
+	if p.Profile != "" {
+		clean := filepath.ToSlash(filepath.Clean(p.Profile))
+		if filepath.IsAbs(p.Profile) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("profile must be a path inside ai-data/")
+		}
+		p.Profile = clean
+	}
+
+	seen := make(map[string]bool, len(p.Accounts))
+	for _, account := range p.Accounts {
+		provider, userID, ok := strings.Cut(account, ":")
+		if !ok || !validProviderNames[provider] || userID == "" {
+			return fmt.Errorf("invalid account %q (want provider:userID)", account)
+		}
+		if seen[account] {
+			return fmt.Errorf("account %s is listed twice", account)
+		}
+		seen[account] = true
+		if other := findAccount(pf, account); other != nil && other.ID != p.ID {
+			return fmt.Errorf("%w: %s belongs to %s", ErrAccountLinked, account, other.Name)
+		}
+	}
+	return nil
+}
+
+// findAccount returns the person with the given account, or nil.
+func findAccount(pf *peopleFile, account string) *Person {
+	for _, p := range pf.People {
+		for _, a := range p.Accounts {
+			if a == account {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// List returns all people sorted by name.
+func (s *PeopleStore) List() ([]*Person, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pf, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	people := make([]*Person, 0, len(pf.People))
+	for _, p := range pf.People {
+		copy := *p
+		people = append(people, &copy)
+	}
+
+	sort.Slice(people, func(i, j int) bool {
+		return people[i].Name < people[j].Name
+	})
+
+	return people, nil
+}
+
+// Get returns a single person by ID.
+func (s *PeopleStore) Get(id string) (*Person, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pf, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	p, ok := pf.People[id]
+	if !ok {
+		return nil, ErrPersonNotFound
+	}
+
+	copy := *p
+	return &copy, nil
+}
+
+// FindByAccount returns the person a provider user belongs to.
+func (s *PeopleStore) FindByAccount(provider, userID string) (*Person, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pf, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	p := findAccount(pf, AccountKey(provider, userID))
+	if p == nil {
+		return nil, ErrPersonNotFound
+	}
+
+	copy := *p
+	return &copy, nil
+}
+
+// Create adds a new person.
+func (s *PeopleStore) Create(p *Person) (*Person, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pf, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := generateID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ID: %w", err)
+	}
+
+	person := *p
+	person.ID = id
+	if person.Accounts == nil {
+		person.Accounts = []string{}
+	}
+	if err := validatePerson(pf, &person); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	person.CreatedAt = now
+	person.UpdatedAt = now
+	pf.People[id] = &person
+
+	if err := s.save(pf); err != nil {
+		return nil, err
+	}
+
+	copy := person
+	return &copy, nil
+}
+
+// Update replaces the editable fields of a person.
+func (s *PeopleStore) Update(id string, updates *Person) (*Person, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pf, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	existing, ok := pf.People[id]
+	if !ok {
+		return nil, ErrPersonNotFound
+	}
+
+	person := *updates
+	person.ID = id
+	person.CreatedAt = existing.CreatedAt
+	if person.Accounts == nil {
+		person.Accounts = []string{}
+	}
+	if err := validatePerson(pf, &person); err != nil {
+		return nil, err
+	}
+	person.UpdatedAt = time.Now()
+	pf.People[id] = &person
+
+	if err := s.save(pf); err != nil {
+		return nil, err
+	}
+
+	copy := person
+	return &copy, nil
+}
+
+// Delete removes a person and their link codes.
+func (s *PeopleStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pf, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := pf.People[id]; !ok {
+		return ErrPersonNotFound
+	}
+	delete(pf.People, id)
+	for code, lc := range pf.LinkCodes {
+		if lc.PersonID == id {
+			delete(pf.LinkCodes, code)
+		}
+	}
+
+	return s.save(pf)
+}
+
+// CreateLinkCode returns a one-time code that links another account to the
+// person when redeemed with Link within LinkCodeTTL.
+func (s *PeopleStore) CreateLinkCode(personID string) (string, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pf, err := s.load()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if _, ok := pf.People[personID]; !ok {
+		return "", time.Time{}, ErrPersonNotFound
+	}
+
+	code, err := generateCode()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expires := time.Now().Add(LinkCodeTTL)
+	pf.LinkCodes[code] = &linkCode{PersonID: personID, ExpiresAt: expires}
+
+	if err := s.save(pf); err != nil {
+		return "", time.Time{}, err
+	}
+	return code, expires, nil
+}
+
+// Link redeems a link code, adding the provider user's account to the
+// person the code was made for. The code can't be used again.
+func (s *PeopleStore) Link(code, provider, userID string) (*Person, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pf, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	code = strings.ToUpper(strings.TrimSpace(code))
+	lc, ok := pf.LinkCodes[code]
+	if !ok || time.Now().After(lc.ExpiresAt) {
+		return nil, ErrLinkCodeNotFound
+	}
+	person, ok := pf.People[lc.PersonID]
+	if !ok {
+		return nil, ErrLinkCodeNotFound
+	}
+
+	account := AccountKey(provider, userID)
+	if other := findAccount(pf, account); other != nil && other.ID != person.ID {
+		return nil, fmt.Errorf("%w: %s belongs to %s", ErrAccountLinked, account, other.Name)
+	} else if other == nil {
+		person.Accounts = append(person.Accounts, account)
+		person.UpdatedAt = time.Now()
+	}
+	delete(pf.LinkCodes, code)
+
+	if err := s.save(pf); err != nil {
+		return nil, err
+	}
+
+	copy := *person
+	return &copy, nil
+}
+
+// RevokeLinkCode drops a link code so it can't be redeemed. Unknown codes
+// are ignored.
+func (s *PeopleStore) RevokeLinkCode(code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pf, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if _, ok := pf.LinkCodes[code]; !ok {
+		return nil
+	}
+	delete(pf.LinkCodes, code)
+	return s.save(pf)
+}
+
+// generateCode returns a random 8-character code for people to type in chat.
+func generateCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	for i := range b {
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+	return string(b), nil
+}
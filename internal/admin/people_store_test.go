@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPeopleStore_CreateAndFind(t *testing.T) {
+	dir := t.TempDir()
+	store := NewPeopleStore(dir)
+
+	alice, err := store.Create(&Person{
+		Name:     "Alice",
+		Timezone: "Europe/London",
+		Profile:  "people/alice.md",
+		Accounts: []string{"discord:111", "telegram:222"},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if alice.ID == "" {
+		t.Fatal("expected non-empty ID")
+	}
+
+	found, err := store.FindByAccount("telegram", "222")
+	if err != nil || found.ID != alice.ID {
+		t.Errorf("FindByAccount = %+v, %v; want Alice", found, err)
+	}
+	if _, err := store.FindByAccount("slack", "U1"); !errors.Is(err, ErrPersonNotFound) {
+		t.Errorf("expected ErrPersonNotFound, got %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "people.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %o", info.Mode().Perm())
+	}
+}
+
+func TestPeopleStore_Validation(t *testing.T) {
+	store := NewPeopleStore(t.TempDir())
+	if _, err := store.Create(&Person{Name: "Alice", Accounts: []string{"discord:111"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		person Person
+		want   string
+	}{
+		{"no name", Person{}, "name is required"},
+		{"bad timezone", Person{Name: "Bob", Timezone: "Mars/Olympus"}, "invalid timezone"},
+		{"absolute profile", Person{Name: "Bob", Profile: "/etc/passwd"}, "inside ai-data"},
+		{"escaping profile", Person{Name: "Bob", Profile: "../secure/x.md"}, "inside ai-data"},
+		{"bad account", Person{Name: "Bob", Accounts: []string{"irc:bob"}}, "invalid account"},
+		{"duplicate account", Person{Name: "Bob", Accounts: []string{"slack:U1", "slack:U1"}}, "listed twice"},
+		{"taken account", Person{Name: "Bob", Accounts: []string{"discord:111"}}, "belongs to Alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.Create(&tt.person)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestPeopleStore_Link(t *testing.T) {
+	store := NewPeopleStore(t.TempDir())
+	alice, _ := store.Create(&Person{Name: "Alice", Accounts: []string{"discord:111"}})
+	bob, _ := store.Create(&Person{Name: "Bob", Accounts: []string{"slack:U2"}})
+
+	code, _, err := store.CreateLinkCode(alice.ID)
+	if err != nil {
+		t.Fatalf("CreateLinkCode failed: %v", err)
+	}
+	if len(code) != 8 {
+		t.Errorf("expected an 8-character code, got %q", code)
+	}
+
+	// An account that belongs to someone else can't be linked
+	if _, err := store.Link(code, "slack", "U2"); !errors.Is(err, ErrAccountLinked) {
+		t.Errorf("expected ErrAccountLinked, got %v", err)
+	}
+
+	linked, err := store.Link(strings.ToLower(code), "telegram", "222")
+	if err != nil {
+		t.Fatalf("Link failed: %v", err)
+	}
+	if len(linked.Accounts) != 2 || linked.Accounts[1] != "telegram:222" {
+		t.Errorf("unexpected accounts %v", linked.Accounts)
+	}
+
+	// Codes are single use
+	if _, err := store.Link(code, "telegram", "333"); !errors.Is(err, ErrLinkCodeNotFound) {
+		t.Errorf("expected ErrLinkCodeNotFound, got %v", err)
+	}
+
+	// Deleting a person drops their codes
+	code, _, _ = store.CreateLinkCode(bob.ID)
+	if err := store.Delete(bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Link(code, "telegram", "333"); !errors.Is(err, ErrLinkCodeNotFound) {
+		t.Errorf("expected ErrLinkCodeNotFound, got %v", err)
+	}
+	if _, _, err := store.CreateLinkCode(bob.ID); !errors.Is(err, ErrPersonNotFound) {
+		t.Errorf("expected ErrPersonNotFound, got %v", err)
+	}
+}
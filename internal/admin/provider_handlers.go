@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/open-pact/openpact/internal/chat"
 )
@@ -58,6 +59,7 @@ type providerResponse struct {
 	Enabled      bool                         `json:"enabled"`
 	AllowedUsers []string                     `json:"allowed_users"`
 	AllowedChans []string                     `json:"allowed_chans"`
+	UserExpiry   map[string]time.Time         `json:"user_expiry,omitempty"`
 	Status       *ProviderStatusInfo          `json:"status,omitempty"`
 	Tokens       map[string]ProviderTokenInfo `json:"tokens"`
 }
@@ -336,6 +338,7 @@ func (h *ProviderHandlers) buildProviderResponse(name string, statuses map[strin
 		if cfg.AllowedChans != nil {
 			resp.AllowedChans = cfg.AllowedChans
 		}
+		resp.UserExpiry = cfg.UserExpiry
 	}
 
 	// Add token info for each required key
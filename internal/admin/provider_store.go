@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"sync"
@@ -17,13 +18,14 @@ var (
 
 // ProviderConfig is the stored configuration for a chat provider.
 type ProviderConfig struct {
-	Name         string            `json:"name"`
-	Enabled      bool              `json:"enabled"`
-	Tokens       map[string]string `json:"tokens"`
-	AllowedUsers []string          `json:"allowed_users"`
-	AllowedChans []string          `json:"allowed_chans"`
-	CreatedAt    time.Time         `json:"created_at"`
-	UpdatedAt    time.Time         `json:"updated_at"`
+	Name         string               `json:"name"`
+	Enabled      bool                 `json:"enabled"`
+	Tokens       map[string]string    `json:"tokens"`
+	AllowedUsers []string             `json:"allowed_users"`
+	AllowedChans []string             `json:"allowed_chans"`
+	UserExpiry   map[string]time.Time `json:"user_expiry,omitempty"` // When paired users lose access, by user ID
+	CreatedAt    time.Time            `json:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at"`
 }
 
 // ProviderTokenInfo describes token availability without exposing values.
@@ -43,28 +45,46 @@ var providerEnvKeys = map[string]map[string]string{
 
 // providerFile is the on-disk JSON format.
 type providerFile struct {
-	Providers map[string]ProviderConfig `json:"providers"`
+	Providers  map[string]ProviderConfig `json:"providers"`
+	Pairings   []PairingRequest          `json:"pairings,omitempty"`    // Pending pairing requests
+	PairingLog []PairingEvent            `json:"pairing_log,omitempty"` // Pairing audit trail, oldest first
 }
 
-// ProviderStore manages provider config persistence.
+const providersFileName = "chat_providers.json"
+
+// ProviderStore manages provider config persistence. Like SecretStore, the
+// file is encrypted at rest when a master key is configured.
 type ProviderStore struct {
 	dataDir string
+	key     *MasterKey
+	keyErr  error
 	mu      sync.RWMutex
 }
 
 // NewProviderStore creates a new provider store.
 func NewProviderStore(dataDir string) *ProviderStore {
-	return &ProviderStore{dataDir: dataDir}
+	s := &ProviderStore{dataDir: dataDir}
+	s.key, s.keyErr = MasterKeyFromEnv()
+	if s.keyErr == nil {
+		if err := migrateStoreFile(s.filePath(), s.key); err != nil {
+			log.Printf("Warning: failed to encrypt %s: %v", providersFileName, err)
+		}
+	}
+	return s
 }
 
 func (s *ProviderStore) filePath() string {
-	return filepath.Join(s.dataDir, "chat_providers.json")
+	return filepath.Join(s.dataDir, providersFileName)
 }
 
 func (s *ProviderStore) load() (*providerFile, error) {
+	if s.keyErr != nil {
+		return nil, fmt.Errorf("invalid master key: %w", s.keyErr)
+	}
+
 	pf := &providerFile{Providers: make(map[string]ProviderConfig)}
 
-	data, err := os.ReadFile(s.filePath())
+	data, _, err := readStoreFile(s.filePath(), s.key)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return pf, nil
@@ -93,7 +113,7 @@ func (s *ProviderStore) save(pf *providerFile) error {
 		return fmt.Errorf("failed to marshal providers: %w", err)
 	}
 
-	if err := os.WriteFile(s.filePath(), data, 0600); err != nil {
+	if err := writeStoreFile(s.filePath(), s.key, data); err != nil {
 		return fmt.Errorf("failed to write providers: %w", err)
 	}
 
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	version "github.com/open-pact/openpact"
+	"github.com/open-pact/openpact/internal/starlark"
 )
 
 // Config holds the admin server configuration.
@@ -19,7 +20,8 @@ type Config struct {
 	Allowlist     []string
 	AccessExpiry  time.Duration
 	RefreshExpiry time.Duration
-	EngineType    string // "opencode"
+	EngineType    string          // "opencode"
+	ScriptLimits  starlark.Config // Sandbox limits of script test runs
 }
 
 // DefaultConfig returns a default configuration.
@@ -48,6 +50,10 @@ type Server struct {
 	providerHandlers   *ProviderHandlers
 	scheduleStore      *ScheduleStore
 	scheduleHandlers   *ScheduleHandlers
+	approvalHandlers   *ToolApprovalHandlers
+	peopleHandlers     *PeopleHandlers
+	pairingHandlers    *PairingHandlers
+	usageHandlers      *UsageHandlers
 	secureCookie       bool
 }
 
@@ -96,12 +102,16 @@ func NewServer(config Config) (*Server, error) {
 		jwt:                jwt,
 		setupHandler:       NewSetupHandler(users, config.DataDir, config.AIDataDir),
 		sessionHandler:     NewSessionHandler(users, jwt, secureCookie),
-		scriptHandlers:     NewScriptHandlers(scripts),
+		scriptHandlers:     NewScriptHandlers(scripts, config.ScriptLimits),
 		engineAuthHandlers: NewEngineAuthHandlers(engineType),
 		secretHandlers:     NewSecretHandlers(secretStore, nil),
 		providerHandlers:   NewProviderHandlers(providerStore),
 		scheduleStore:      scheduleStore,
 		scheduleHandlers:   NewScheduleHandlers(scheduleStore),
+		approvalHandlers:   NewToolApprovalHandlers(),
+		peopleHandlers:     NewPeopleHandlers(NewPeopleStore(config.DataDir)),
+		pairingHandlers:    NewPairingHandlers(providerStore),
+		usageHandlers:      NewUsageHandlers(NewUsageStore(config.DataDir)),
 		secureCookie:       secureCookie,
 	}, nil
 }
@@ -148,6 +158,30 @@ func (s *Server) Handler() http.Handler {
 	// Schedule management endpoints
 	s.registerScheduleRoutes(mux)
 
+	// Tool approval endpoints
+	mux.HandleFunc("/api/tool-approvals", s.withAuth(s.approvalHandlers.ListApprovals))
+	mux.HandleFunc("/api/tool-approvals/", s.withAuth(s.approvalHandlers.HandleApprovalByID))
+
+	// People registry endpoints
+	mux.HandleFunc("/api/people", s.withAuth(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			s.peopleHandlers.ListPeople(w, r)
+		case http.MethodPost:
+			s.peopleHandlers.CreatePerson(w, r)
+		default:
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.HandleFunc("/api/people/", s.withAuth(s.peopleHandlers.HandlePersonByID))
+
+	// Pairing request endpoints
+	mux.HandleFunc("/api/pairing", s.withAuth(s.pairingHandlers.ListPairings))
+	mux.HandleFunc("/api/pairing/", s.withAuth(s.pairingHandlers.HandlePairingByCode))
+
+	// Usage ledger endpoint
+	mux.HandleFunc("/api/usage", s.withAuth(s.usageHandlers.Report))
+
 	// Apply setup middleware to the entire API
 	return RequireSetupMiddleware(s.users, s.config.DataDir)(mux)
 }
@@ -241,6 +275,24 @@ func (s *Server) handleScriptByName(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if strings.HasSuffix(path, "/run") {
+		if r.Method == http.MethodPost {
+			s.scriptHandlers.RunScript(w, r)
+			return
+		}
+		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	if strings.HasSuffix(path, "/test") {
+		if r.Method == http.MethodPost {
+			s.scriptHandlers.TestScript(w, r)
+			return
+		}
+		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
 	if strings.HasSuffix(path, "/reject") {
 		if r.Method == http.MethodPost {
 			s.scriptHandlers.RejectScript(w, r)
@@ -288,6 +340,11 @@ func (s *Server) SetOnSecretsChanged(fn func()) {
 	s.secretHandlers.onChange = fn
 }
 
+// SetScriptRunner sets the runner used to run approved scripts on demand.
+func (s *Server) SetScriptRunner(runner ScriptRunner) {
+	s.scriptHandlers.runner = runner
+}
+
 // SetSessionAPI sets the session API for AI session management endpoints.
 func (s *Server) SetSessionAPI(api SessionAPI) {
 	s.aiSessionHandlers = NewSessionHandlers(api)
@@ -364,6 +421,23 @@ func (s *Server) SetChannelModeAPI(api ChannelModeAPI) {
 	s.providerHandlers.SetModeAPI(api)
 }
 
+// SetToolApprovalAPI sets the API for answering pending tool approvals.
+func (s *Server) SetToolApprovalAPI(api ToolApprovalAPI) {
+	s.approvalHandlers.SetAPI(api)
+}
+
+// SetPairingNotifier sets the notifier for pairing requests answered in the
+// admin API.
+func (s *Server) SetPairingNotifier(n PairingNotifier) {
+	s.pairingHandlers.SetNotifier(n)
+}
+
+// SetBudgetReporter sets the reporter of usage budgets shown with the usage
+// report.
+func (s *Server) SetBudgetReporter(r BudgetReporter) {
+	s.usageHandlers.SetReporter(r)
+}
+
 // ProviderStore returns the provider store.
 func (s *Server) ProviderStore() *ProviderStore {
 	return s.providerHandlers.store
@@ -394,6 +468,8 @@ func (s *Server) registerScheduleRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/schedules/", s.withAuth(func(w http.ResponseWriter, r *http.Request) {
 		s.scheduleHandlers.HandleScheduleByID(w, r)
 	}))
+	// Webhook triggers authenticate with a per-schedule HMAC signature
+	mux.HandleFunc("/hooks/", s.scheduleHandlers.HandleWebhook)
 }
 
 // handleVersion returns the application version.
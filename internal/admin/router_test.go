@@ -2,12 +2,18 @@ package admin
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/open-pact/openpact/internal/starlark"
 )
 
 func setupTestServer(t *testing.T) *Server {
@@ -422,3 +428,529 @@ func TestServer_RejectScript(t *testing.T) {
 		t.Errorf("Expected reject_reason 'Contains suspicious code', got '%s'", script.RejectReason)
 	}
 }
+
+type fakeScriptRunner struct {
+	args map[string]any
+}
+
+func (f *fakeScriptRunner) RunScript(ctx context.Context, name string, args map[string]any) (starlark.Result, error) {
+	switch name {
+	case "missing.star":
+		return starlark.Result{}, ErrScriptNotFound
+	case "pending.star":
+		return starlark.Result{}, fmt.Errorf("script not approved: %w", ErrScriptNotApproved)
+	}
+	f.args = args
+	return starlark.Result{
+		Value: "ok",
+		Logs:  []starlark.LogEntry{{Level: starlark.LogPrint, Message: "hello"}},
+	}, nil
+}
+
+func TestServer_RunScript(t *testing.T) {
+	server := setupTestServer(t)
+	handler := server.Handler()
+	completeSetup(t, handler)
+
+	// Login
+	body := `{"username": "admin", "password": "verysecurepassword1"}`
+	req := httptest.NewRequest("POST", "/api/auth/login", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var refreshCookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "refresh" {
+			refreshCookie = c
+			break
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/api/session", nil)
+	req.AddCookie(refreshCookie)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var sessionResp SessionResponse
+	json.NewDecoder(rec.Body).Decode(&sessionResp)
+	token := sessionResp.AccessToken
+
+	run := func(name, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/scripts/"+name+"/run", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// No runner configured
+	if rec := run("ok.star", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rec.Code)
+	}
+
+	runner := &fakeScriptRunner{}
+	server.SetScriptRunner(runner)
+
+	rec = run("ok.star", `{"args": {"city": "London"}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Success bool                `json:"success"`
+		Result  any                 `json:"result"`
+		Logs    []starlark.LogEntry `json:"logs"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if !resp.Success || resp.Result != "ok" || len(resp.Logs) != 1 || resp.Logs[0].Message != "hello" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if runner.args["city"] != "London" || runner.args["trigger"] != "test" {
+		t.Errorf("unexpected args: %v", runner.args)
+	}
+
+	if rec := run("missing.star", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+	if rec := run("pending.star", ""); rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", rec.Code)
+	}
+}
+
+func TestServer_TestScript(t *testing.T) {
+	server := setupTestServer(t)
+	handler := server.Handler()
+	completeSetup(t, handler)
+
+	// Login
+	body := `{"username": "admin", "password": "verysecurepassword1"}`
+	req := httptest.NewRequest("POST", "/api/auth/login", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var refreshCookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "refresh" {
+			refreshCookie = c
+			break
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/api/session", nil)
+	req.AddCookie(refreshCookie)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var sessionResp SessionResponse
+	json.NewDecoder(rec.Body).Decode(&sessionResp)
+	token := sessionResp.AccessToken
+
+	dir := server.scriptHandlers.store.scriptsDir
+	os.WriteFile(filepath.Join(dir, "double.star"), []byte("def double(x):\n    return x * 2\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "double_test.star"), []byte("def test_double():\n    assert.eq(double(2), 4)\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "plain.star"), []byte("result = 1\n"), 0644)
+
+	test := func(name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/scripts/"+name+"/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Tests run for pending scripts
+	rec = test("double.star")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report starlark.TestReport
+	json.NewDecoder(rec.Body).Decode(&report)
+	if !report.Passed || len(report.Tests) != 1 || report.Tests[0].Name != "test_double" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	if rec := test("plain.star"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a script without tests, got %d", rec.Code)
+	}
+	if rec := test("missing.star"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+
+	// Tests run with the configured sandbox limits
+	os.WriteFile(filepath.Join(dir, "spin.star"), []byte("def spin():\n    for i in range(100000):\n        pass\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "spin_test.star"), []byte("def test_spin():\n    spin()\n"), 0644)
+	if rec := test("spin.star"); !strings.Contains(rec.Body.String(), `"passed":true`) {
+		t.Errorf("expected the test to pass with the default limits: %s", rec.Body.String())
+	}
+	server.scriptHandlers.limits = starlark.Config{MaxSteps: 1000}
+	if rec := test("spin.star"); strings.Contains(rec.Body.String(), `"passed":true`) {
+		t.Errorf("expected the step limit to fail the test: %s", rec.Body.String())
+	}
+
+	// Test files are not listed as scripts
+	req = httptest.NewRequest("GET", "/api/scripts", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var listResp struct {
+		Scripts []*Script `json:"scripts"`
+	}
+	json.NewDecoder(rec.Body).Decode(&listResp)
+	for _, script := range listResp.Scripts {
+		if script.Name == "double_test.star" {
+			t.Error("test file listed as a script")
+		}
+	}
+}
+
+type fakeToolApprovals struct {
+	pending  []ToolApproval
+	answered map[string]bool
+	by       string
+}
+
+func (f *fakeToolApprovals) PendingToolApprovals() []ToolApproval {
+	return f.pending
+}
+
+func (f *fakeToolApprovals) ResolveToolApproval(id string, approved bool, by string) error {
+	if id != "abc123" {
+		return ErrToolApprovalNotFound
+	}
+	f.answered[id] = approved
+	f.by = by
+	return nil
+}
+
+func TestServer_ToolApprovals(t *testing.T) {
+	server := setupTestServer(t)
+	handler := server.Handler()
+	completeSetup(t, handler)
+
+	// Login
+	body := `{"username": "admin", "password": "verysecurepassword1"}`
+	req := httptest.NewRequest("POST", "/api/auth/login", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var refreshCookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "refresh" {
+			refreshCookie = c
+			break
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/api/session", nil)
+	req.AddCookie(refreshCookie)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var sessionResp SessionResponse
+	json.NewDecoder(rec.Body).Decode(&sessionResp)
+	token := sessionResp.AccessToken
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// No approval API configured
+	if rec := do("GET", "/api/tool-approvals"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rec.Code)
+	}
+
+	approvals := &fakeToolApprovals{
+		pending:  []ToolApproval{{ID: "abc123", Tool: "chat_send", Provider: "discord"}},
+		answered: map[string]bool{},
+	}
+	server.SetToolApprovalAPI(approvals)
+
+	rec = do("GET", "/api/tool-approvals")
+	var listResp struct {
+		Approvals []ToolApproval `json:"approvals"`
+	}
+	json.NewDecoder(rec.Body).Decode(&listResp)
+	if rec.Code != http.StatusOK || len(listResp.Approvals) != 1 || listResp.Approvals[0].Tool != "chat_send" {
+		t.Errorf("unexpected list response %d: %+v", rec.Code, listResp)
+	}
+
+	if rec := do("POST", "/api/tool-approvals/abc123/deny"); rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if approved, ok := approvals.answered["abc123"]; !ok || approved || approvals.by != "admin" {
+		t.Errorf("expected a denial by admin, got %v by %q", approvals.answered, approvals.by)
+	}
+
+	if rec := do("POST", "/api/tool-approvals/gone/approve"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+	if rec := do("POST", "/api/tool-approvals/abc123/maybe"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+	if rec := do("GET", "/api/tool-approvals/abc123/approve"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", rec.Code)
+	}
+}
+
+func TestServer_People(t *testing.T) {
+	server := setupTestServer(t)
+	handler := server.Handler()
+	completeSetup(t, handler)
+
+	// Login
+	body := `{"username": "admin", "password": "verysecurepassword1"}`
+	req := httptest.NewRequest("POST", "/api/auth/login", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var refreshCookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "refresh" {
+			refreshCookie = c
+			break
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/api/session", nil)
+	req.AddCookie(refreshCookie)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var sessionResp SessionResponse
+	json.NewDecoder(rec.Body).Decode(&sessionResp)
+	token := sessionResp.AccessToken
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = do("POST", "/api/people", `{"name": "Alice", "timezone": "Europe/London", "accounts": ["discord:111"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var alice Person
+	json.NewDecoder(rec.Body).Decode(&alice)
+
+	if rec := do("POST", "/api/people", `{"name": "Bob", "accounts": ["discord:111"]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a taken account, got %d", rec.Code)
+	}
+
+	rec = do("GET", "/api/people", "")
+	var listResp struct {
+		People []Person `json:"people"`
+	}
+	json.NewDecoder(rec.Body).Decode(&listResp)
+	if rec.Code != http.StatusOK || len(listResp.People) != 1 || listResp.People[0].Name != "Alice" {
+		t.Errorf("unexpected list response %d: %+v", rec.Code, listResp)
+	}
+
+	rec = do("POST", "/api/people/"+alice.ID+"/link-code", "")
+	var codeResp struct {
+		Code string `json:"code"`
+	}
+	json.NewDecoder(rec.Body).Decode(&codeResp)
+	if rec.Code != http.StatusOK || codeResp.Code == "" {
+		t.Errorf("unexpected link code response %d: %+v", rec.Code, codeResp)
+	}
+
+	rec = do("PUT", "/api/people/"+alice.ID, `{"name": "Alice", "follow_sessions": true, "accounts": []}`)
+	var updated Person
+	json.NewDecoder(rec.Body).Decode(&updated)
+	if rec.Code != http.StatusOK || !updated.FollowSessions || len(updated.Accounts) != 0 {
+		t.Errorf("unexpected update response %d: %+v", rec.Code, updated)
+	}
+
+	if rec := do("DELETE", "/api/people/"+alice.ID, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", rec.Code)
+	}
+	if rec := do("GET", "/api/people/"+alice.ID, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+	if rec := do("POST", "/api/people/"+alice.ID+"/link-code", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+}
+
+type fakePairingNotifier struct {
+	answered map[string]bool
+}
+
+func (f *fakePairingNotifier) PairingAnswered(req PairingRequest, approved bool, allowedUsers []string) {
+	f.answered[req.UserID] = approved
+}
+
+func TestServer_Pairing(t *testing.T) {
+	server := setupTestServer(t)
+	handler := server.Handler()
+	completeSetup(t, handler)
+
+	// Login
+	body := `{"username": "admin", "password": "verysecurepassword1"}`
+	req := httptest.NewRequest("POST", "/api/auth/login", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var refreshCookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "refresh" {
+			refreshCookie = c
+			break
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/api/session", nil)
+	req.AddCookie(refreshCookie)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var sessionResp SessionResponse
+	json.NewDecoder(rec.Body).Decode(&sessionResp)
+	token := sessionResp.AccessToken
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	store := server.ProviderStore()
+	store.Set("discord", ProviderConfig{Enabled: true, AllowedUsers: []string{"owner"}})
+	alice, _, _ := store.RequestPairing("discord", "dm1", "111", "alice", time.Hour, 0)
+	bob, _, _ := store.RequestPairing("discord", "dm2", "222", "bob", time.Hour, 0)
+
+	notifier := &fakePairingNotifier{answered: map[string]bool{}}
+	server.SetPairingNotifier(notifier)
+
+	rec = do("GET", "/api/pairing", "")
+	var listResp struct {
+		Pending []PairingRequest `json:"pending"`
+		Log     []PairingEvent   `json:"log"`
+	}
+	json.NewDecoder(rec.Body).Decode(&listResp)
+	if rec.Code != http.StatusOK || len(listResp.Pending) != 2 || len(listResp.Log) != 2 {
+		t.Errorf("unexpected list response %d: %+v", rec.Code, listResp)
+	}
+
+	if rec := do("POST", "/api/pairing/"+alice.Code+"/approve", `{"expires_at": "2000-01-01T00:00:00Z"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a past expiry, got %d", rec.Code)
+	}
+	if rec := do("POST", "/api/pairing/"+alice.Code+"/approve", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do("POST", "/api/pairing/"+bob.Code+"/deny", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if approved, ok := notifier.answered["111"]; !ok || !approved {
+		t.Errorf("expected alice's approval to be notified, got %v", notifier.answered)
+	}
+	if approved, ok := notifier.answered["222"]; !ok || approved {
+		t.Errorf("expected bob's denial to be notified, got %v", notifier.answered)
+	}
+
+	cfg, _ := store.Get("discord")
+	if len(cfg.AllowedUsers) != 2 || cfg.AllowedUsers[1] != "111" {
+		t.Errorf("unexpected allowlist %v", cfg.AllowedUsers)
+	}
+
+	if rec := do("POST", "/api/pairing/"+alice.Code+"/approve", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+	if rec := do("POST", "/api/pairing/"+alice.Code+"/maybe", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+	if rec := do("GET", "/api/pairing/"+alice.Code+"/approve", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", rec.Code)
+	}
+}
+
+type fakeBudgetReporter struct{}
+
+func (fakeBudgetReporter) BudgetStatuses(now time.Time) ([]BudgetStatus, error) {
+	return []BudgetStatus{{Scope: "global", Period: "day", Cost: 2, MaxCost: 1, Action: "block", Exceeded: true}}, nil
+}
+
+func TestServer_Usage(t *testing.T) {
+	server := setupTestServer(t)
+	server.SetBudgetReporter(fakeBudgetReporter{})
+	handler := server.Handler()
+	completeSetup(t, handler)
+
+	// Login
+	body := `{"username": "admin", "password": "verysecurepassword1"}`
+	req := httptest.NewRequest("POST", "/api/auth/login", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var refreshCookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "refresh" {
+			refreshCookie = c
+			break
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/api/session", nil)
+	req.AddCookie(refreshCookie)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var sessionResp SessionResponse
+	json.NewDecoder(rec.Body).Decode(&sessionResp)
+	token := sessionResp.AccessToken
+
+	do := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	store := NewUsageStore(server.config.DataDir)
+	store.Append(&UsageRecord{Source: UsageChat, Provider: "discord", UserID: "111", InputTokens: 100, OutputTokens: 10, Cost: 0.5})
+	store.Append(&UsageRecord{Source: UsageSchedule, Schedule: "nightly", InputTokens: 1000, OutputTokens: 100, Cost: 1.5})
+	store.Append(&UsageRecord{Source: UsageChat, Provider: "discord", UserID: "111", InputTokens: 50, Cost: 0.25})
+
+	rec = do("/api/usage?period=day&group_by=user")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report struct {
+		Total   UsageTotals    `json:"total"`
+		Groups  []UsageGroup   `json:"groups"`
+		Budgets []BudgetStatus `json:"budgets"`
+	}
+	json.NewDecoder(rec.Body).Decode(&report)
+	if report.Total.Turns != 3 || report.Total.Tokens != 1260 || report.Total.Cost != 2.25 {
+		t.Errorf("unexpected total %+v", report.Total)
+	}
+	if len(report.Groups) != 2 || report.Groups[0].Key != "" || report.Groups[1].Key != "discord:111" || report.Groups[1].Turns != 2 {
+		t.Errorf("expected the schedule's usage first, then discord:111's, got %+v", report.Groups)
+	}
+	if len(report.Budgets) != 1 || !report.Budgets[0].Exceeded {
+		t.Errorf("expected the reported budgets, got %+v", report.Budgets)
+	}
+
+	// Last month has no usage
+	lastMonth := MonthStart(time.Now()).AddDate(0, -1, 0).Format("2006-01")
+	rec = do("/api/usage?date=" + lastMonth)
+	json.NewDecoder(rec.Body).Decode(&report)
+	if rec.Code != http.StatusOK || report.Total.Turns != 0 {
+		t.Errorf("expected an empty month, got %d %+v", rec.Code, report.Total)
+	}
+
+	for _, path := range []string{"/api/usage?period=week", "/api/usage?group_by=color", "/api/usage?period=day&date=2026-10"} {
+		if rec := do(path); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", path, rec.Code)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/robfig/cron/v3"
@@ -12,6 +13,8 @@ import (
 // SchedulerAPI is the interface for triggering immediate schedule runs.
 type SchedulerAPI interface {
 	RunNow(id string) error
+	// Fire starts an event-triggered run, passing payload to the job.
+	Fire(id, trigger string, payload map[string]any) error
 	Reload() error
 }
 
@@ -121,6 +124,13 @@ func (h *ScheduleHandlers) HandleScheduleByID(w http.ResponseWriter, r *http.Req
 		}
 		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
 		return
+	case "/runs":
+		if r.Method == http.MethodGet {
+			h.ListRuns(w, r, id)
+			return
+		}
+		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		return
 	case "":
 		// Fall through to standard CRUD
 	default:
@@ -276,6 +286,37 @@ func (h *ScheduleHandlers) RunSchedule(w http.ResponseWriter, r *http.Request, i
 	writeJSON(w, http.StatusOK, map[string]string{"status": "triggered"})
 }
 
+// ListRuns handles GET /api/schedules/:id/runs. The optional limit query
+// parameter caps the number of runs returned (newest first).
+func (h *ScheduleHandlers) ListRuns(w http.ResponseWriter, r *http.Request, id string) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, `{"error":"bad_request","message":"limit must be a non-negative integer"}`, http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	if _, err := h.store.Get(id); err != nil {
+		if errors.Is(err, ErrScheduleNotFound) {
+			http.Error(w, `{"error":"not_found","message":"Schedule not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"internal","message":"Failed to get schedule"}`, http.StatusInternalServerError)
+		return
+	}
+
+	runs, err := h.store.ListRuns(id, limit)
+	if err != nil {
+		http.Error(w, `{"error":"internal","message":"Failed to list runs"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"runs": runs})
+}
+
 // extractScheduleID extracts the schedule ID from /api/schedules/:id[/action] path.
 func extractScheduleID(path string) string {
 	prefix := "/api/schedules/"
@@ -0,0 +1,104 @@
+package admin
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxWebhookBody caps the payload accepted by a schedule webhook.
+const maxWebhookBody = 1 << 20
+
+// Headers that can carry a webhook signature: "sha256=" followed by the hex
+// HMAC-SHA256 of the request body, keyed with the schedule's webhook secret.
+// The GitHub header is accepted so repository webhooks work unchanged.
+const (
+	WebhookSignatureHeader = "X-OpenPact-Signature"
+	githubSignatureHeader  = "X-Hub-Signature-256"
+)
+
+// SignWebhook returns the signature header value for a webhook body.
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyWebhook checks a request's signature against the schedule secret.
+func verifyWebhook(r *http.Request, secret string, body []byte) bool {
+	sig := r.Header.Get(WebhookSignatureHeader)
+	if sig == "" {
+		sig = r.Header.Get(githubSignatureHeader)
+	}
+	if secret == "" || !strings.HasPrefix(sig, "sha256=") {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(SignWebhook(secret, body)))
+}
+
+// HandleWebhook handles POST /hooks/:id. It needs no session: the body must
+// be signed with the schedule's webhook secret. The payload reaches the job
+// as its trigger args. Unknown, disabled and non-webhook schedules all
+// answer 404, so the endpoint does not reveal which schedules exist.
+func (h *ScheduleHandlers) HandleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/hooks/"), "/")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+		return
+	}
+	if h.scheduler == nil {
+		http.Error(w, `{"error":"service_unavailable","message":"Scheduler not available"}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, `{"error":"bad_request","message":"Payload too large"}`, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	sched, err := h.store.Get(id)
+	if err != nil || !sched.Enabled || sched.Trigger != TriggerWebhook {
+		http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+		return
+	}
+	if !verifyWebhook(r, sched.WebhookSecret, body) {
+		http.Error(w, `{"error":"unauthorized","message":"Invalid signature"}`, http.StatusUnauthorized)
+		return
+	}
+
+	// JSON bodies are passed decoded, anything else as text
+	payload := map[string]any{"content_type": r.Header.Get("Content-Type")}
+	var data any
+	if json.Unmarshal(body, &data) == nil {
+		payload["json"] = data
+	} else {
+		payload["body"] = string(body)
+	}
+	if event := r.Header.Get("X-GitHub-Event"); event != "" {
+		payload["event"] = event
+	}
+
+	if err := h.scheduler.Fire(id, TriggerWebhook, payload); err != nil {
+		if errors.Is(err, ErrScheduleNotFound) {
+			http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusConflict, map[string]string{
+			"error":   "conflict",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "triggered"})
+}
@@ -0,0 +1,105 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeScheduler records the runs fired through SchedulerAPI.
+type fakeScheduler struct {
+	fired    []map[string]any
+	triggers []string
+}
+
+func (f *fakeScheduler) RunNow(id string) error { return nil }
+func (f *fakeScheduler) Reload() error          { return nil }
+func (f *fakeScheduler) Fire(id, trigger string, payload map[string]any) error {
+	f.triggers = append(f.triggers, trigger)
+	f.fired = append(f.fired, payload)
+	return nil
+}
+
+func TestScheduleHandlers_Webhook(t *testing.T) {
+	store := NewScheduleStore(t.TempDir())
+	handlers := NewScheduleHandlers(store)
+	sched := &fakeScheduler{}
+	handlers.SetSchedulerAPI(sched)
+
+	hook, err := store.Create(&Schedule{
+		Name:    "ci-failed",
+		Trigger: TriggerWebhook,
+		Type:    "agent",
+		Prompt:  "Summarise the failing CI logs",
+		Enabled: true,
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	cronJob, _ := store.Create(&Schedule{Name: "nightly", CronExpr: "0 0 * * *", Type: "script", ScriptName: "x.star", Enabled: true})
+
+	post := func(id, body string, header http.Header) int {
+		req := httptest.NewRequest(http.MethodPost, "/hooks/"+id, strings.NewReader(body))
+		for k, v := range header {
+			req.Header.Set(k, v[0])
+		}
+		w := httptest.NewRecorder()
+		handlers.HandleWebhook(w, req)
+		return w.Code
+	}
+	signed := func(secret, body string) http.Header {
+		return http.Header{WebhookSignatureHeader: {SignWebhook(secret, []byte(body))}}
+	}
+
+	body := `{"workflow_run":{"conclusion":"failure"}}`
+	if code := post(hook.ID, body, signed(hook.WebhookSecret, body)); code != http.StatusAccepted {
+		t.Fatalf("signed webhook: expected 202, got %d", code)
+	}
+	if len(sched.fired) != 1 || sched.triggers[0] != TriggerWebhook {
+		t.Fatalf("expected one webhook run, got %v", sched.triggers)
+	}
+	data, _ := sched.fired[0]["json"].(map[string]any)
+	if run, _ := data["workflow_run"].(map[string]any); run["conclusion"] != "failure" {
+		t.Errorf("expected decoded JSON payload, got %v", sched.fired[0])
+	}
+
+	// GitHub's signature header is accepted, and text bodies are passed as-is
+	github := http.Header{"X-Hub-Signature-256": {SignWebhook(hook.WebhookSecret, []byte("build failed"))}}
+	if code := post(hook.ID, "build failed", github); code != http.StatusAccepted {
+		t.Errorf("GitHub signature: expected 202, got %d", code)
+	}
+	if len(sched.fired) != 2 || sched.fired[1]["body"] != "build failed" {
+		t.Errorf("expected text payload, got %v", sched.fired)
+	}
+
+	for name, tc := range map[string]struct {
+		id, body string
+		header   http.Header
+		code     int
+	}{
+		"unsigned":      {hook.ID, body, nil, http.StatusUnauthorized},
+		"wrong secret":  {hook.ID, body, signed("not-the-secret", body), http.StatusUnauthorized},
+		"tampered body": {hook.ID, body + " ", signed(hook.WebhookSecret, body), http.StatusUnauthorized},
+		"cron schedule": {cronJob.ID, body, signed(hook.WebhookSecret, body), http.StatusNotFound},
+		"unknown":       {"nope", body, signed(hook.WebhookSecret, body), http.StatusNotFound},
+	} {
+		if code := post(tc.id, tc.body, tc.header); code != tc.code {
+			t.Errorf("%s: expected %d, got %d", name, tc.code, code)
+		}
+	}
+
+	store.SetEnabled(hook.ID, false)
+	if code := post(hook.ID, body, signed(hook.WebhookSecret, body)); code != http.StatusNotFound {
+		t.Errorf("disabled: expected 404, got %d", code)
+	}
+	if len(sched.fired) != 2 {
+		t.Errorf("rejected webhooks should not fire, got %d runs", len(sched.fired))
+	}
+
+	w := httptest.NewRecorder()
+	handlers.HandleWebhook(w, httptest.NewRequest(http.MethodGet, "/hooks/"+hook.ID, nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: expected 405, got %d", w.Code)
+	}
+}
@@ -0,0 +1,259 @@
+package admin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/open-pact/openpact/internal/starlark"
+)
+
+// Run triggers, recorded on each ScheduleRun. TriggerCron, TriggerWebhook,
+// TriggerFileChange and TriggerCalendar are also the values of
+// Schedule.Trigger.
+const (
+	TriggerCron       = "cron"        // fired by the schedule's cron expression
+	TriggerRunNow     = "run_now"     // started from the admin UI, API or an MCP tool
+	TriggerCatchUp    = "catch_up"    // a run missed while the scheduler was down
+	TriggerWebhook    = "webhook"     // a signed POST to /hooks/{id}
+	TriggerFileChange = "file_change" // a watched file was added, changed or removed
+	TriggerCalendar   = "calendar"    // a calendar event is about to start
+)
+
+var (
+	// maxRunOutputLen caps the output kept per run. It is much larger than
+	// the last-run summary on the schedule, so the history has the full
+	// output of all but the most verbose jobs.
+	maxRunOutputLen = 256 << 10
+
+	// scheduleIDPattern guards the run log path against IDs that are not
+	// produced by generateID.
+	scheduleIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// ScheduleRun is one execution of a schedule, kept in the run history.
+type ScheduleRun struct {
+	ID            string              `json:"id"`
+	ScheduleID    string              `json:"schedule_id"`
+	Trigger       string              `json:"trigger"`           // one of the Trigger constants
+	Attempt       int                 `json:"attempt,omitempty"` // 1 for the first try, higher for retries
+	StartedAt     time.Time           `json:"started_at"`
+	DurationMs    int64               `json:"duration_ms"`
+	Status        string              `json:"status"` // "success" or "error"
+	Error         string              `json:"error,omitempty"`
+	Output        string              `json:"output,omitempty"`
+	Truncated     bool                `json:"truncated,omitempty"`      // Output was cut at maxRunOutputLen
+	Logs          []starlark.LogEntry `json:"logs,omitempty"`           // print() and log.* output of script jobs
+	SessionID     string              `json:"session_id,omitempty"`     // Engine session used by agent jobs
+	CorrelationID string              `json:"correlation_id,omitempty"` // Matches the run's log lines
+}
+
+// SetRunRetention limits the run history kept per schedule: at most maxRuns
+// runs, none older than maxAge. Zero disables a limit.
+func (s *ScheduleStore) SetRunRetention(maxRuns int, maxAge time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxRuns = maxRuns
+	s.maxRunAge = maxAge
+}
+
+func (s *ScheduleStore) runsDir() string {
+	return filepath.Join(s.dataDir, "schedule_runs")
+}
+
+// runsPath returns the run log of a schedule. Each schedule has its own
+// append-only JSON Lines file, oldest run first.
+func (s *ScheduleStore) runsPath(scheduleID string) (string, error) {
+	if !scheduleIDPattern.MatchString(scheduleID) {
+		return "", ErrScheduleNotFound
+	}
+	return filepath.Join(s.runsDir(), scheduleID+".jsonl"), nil
+}
+
+// AppendRun adds a run to its schedule's history, then applies the
+// retention limits. The run ID is generated if empty.
+func (s *ScheduleStore) AppendRun(run *ScheduleRun) error {
+	path, err := s.runsPath(run.ScheduleID)
+	if err != nil {
+		return err
+	}
+
+	if run.ID == "" {
+		id, err := generateID()
+		if err != nil {
+			return fmt.Errorf("failed to generate ID: %w", err)
+		}
+		run.ID = id
+	}
+	record := *run
+	if len(record.Output) > maxRunOutputLen {
+		record.Output = record.Output[:maxRunOutputLen]
+		record.Truncated = true
+	}
+	line, err := json.Marshal(&record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.runsDir(), 0750); err != nil {
+		return fmt.Errorf("failed to create runs dir: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open run log: %w", err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+
+	return s.pruneRunsLocked(path)
+}
+
+// ListRuns returns up to limit runs of a schedule, newest first. A limit of
+// zero returns every run kept.
+func (s *ScheduleStore) ListRuns(scheduleID string, limit int) ([]*ScheduleRun, error) {
+	path, err := s.runsPath(scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	runs, err := readRuns(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].StartedAt.After(runs[j].StartedAt)
+	})
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// GetRun returns a single run of a schedule.
+func (s *ScheduleStore) GetRun(scheduleID, runID string) (*ScheduleRun, error) {
+	runs, err := s.ListRuns(scheduleID, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		if run.ID == runID {
+			return run, nil
+		}
+	}
+	return nil, ErrRunNotFound
+}
+
+// pruneRunsLocked rewrites a run log without the runs that fall outside the
+// retention limits. The file is left alone when nothing needs to go.
+// Caller must hold s.mu.
+func (s *ScheduleStore) pruneRunsLocked(path string) error {
+	if s.maxRuns <= 0 && s.maxRunAge <= 0 {
+		return nil
+	}
+
+	runs, err := readRuns(path)
+	if err != nil {
+		return err
+	}
+
+	keep := runs
+	if s.maxRunAge > 0 {
+		cutoff := time.Now().Add(-s.maxRunAge)
+		keep = keep[:0:0]
+		for _, run := range runs {
+			if run.StartedAt.After(cutoff) {
+				keep = append(keep, run)
+			}
+		}
+	}
+	if s.maxRuns > 0 && len(keep) > s.maxRuns {
+		keep = keep[len(keep)-s.maxRuns:]
+	}
+	if len(keep) == len(runs) {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, run := range keep {
+		line, err := json.Marshal(run)
+		if err != nil {
+			return fmt.Errorf("failed to marshal run: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write run log: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace run log: %w", err)
+	}
+	return nil
+}
+
+// deleteRunsLocked removes a schedule's run history. Caller must hold s.mu.
+func (s *ScheduleStore) deleteRunsLocked(scheduleID string) error {
+	path, err := s.runsPath(scheduleID)
+	if err != nil {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete run history: %w", err)
+	}
+	return nil
+}
+
+// readRuns reads a run log, oldest run first. A missing log is an empty
+// history; a torn last line (from a crash mid-append) is skipped.
+func readRuns(path string) ([]*ScheduleRun, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*ScheduleRun{}, nil
+		}
+		return nil, fmt.Errorf("failed to read run log: %w", err)
+	}
+	defer f.Close()
+
+	runs := []*ScheduleRun{}
+	scanner := bufio.NewScanner(f)
+	// JSON escaping can grow output and logs up to six times (\u003c and
+	// friends), and each log entry adds its level and keys
+	scanner.Buffer(make([]byte, 64<<10), 6*(maxRunOutputLen+starlark.MaxLogBytes)+starlark.MaxLogEntries*64+64<<10)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var run ScheduleRun
+		if err := json.Unmarshal(line, &run); err != nil {
+			continue
+		}
+		runs = append(runs, &run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read run log: %w", err)
+	}
+	return runs, nil
+}
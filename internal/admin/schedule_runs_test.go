@@ -0,0 +1,174 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newRunTestStore(t *testing.T) (*ScheduleStore, *Schedule) {
+	t.Helper()
+	store := NewScheduleStore(t.TempDir())
+	sched, err := store.Create(&Schedule{
+		Name:     "nightly",
+		CronExpr: "0 2 * * *",
+		Type:     "agent",
+		Prompt:   "Write the report",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	return store, sched
+}
+
+func TestScheduleStore_AppendAndListRuns(t *testing.T) {
+	store, sched := newRunTestStore(t)
+
+	start := time.Now().UTC().Add(-time.Hour)
+	for i, status := range []string{"error", "error", "success"} {
+		err := store.AppendRun(&ScheduleRun{
+			ScheduleID: sched.ID,
+			Trigger:    TriggerCron,
+			StartedAt:  start.Add(time.Duration(i) * time.Minute),
+			Status:     status,
+			SessionID:  "session-" + status,
+		})
+		if err != nil {
+			t.Fatalf("AppendRun failed: %v", err)
+		}
+	}
+
+	runs, err := store.ListRuns(sched.ID, 0)
+	if err != nil {
+		t.Fatalf("ListRuns failed: %v", err)
+	}
+	if len(runs) != 3 {
+		t.Fatalf("expected 3 runs, got %d", len(runs))
+	}
+	if runs[0].Status != "success" || runs[2].Status != "error" {
+		t.Errorf("expected newest run first, got %s then %s", runs[0].Status, runs[2].Status)
+	}
+	if runs[0].ID == "" || runs[0].ID == runs[1].ID {
+		t.Errorf("expected unique run IDs, got %q and %q", runs[0].ID, runs[1].ID)
+	}
+
+	limited, _ := store.ListRuns(sched.ID, 2)
+	if len(limited) != 2 || limited[0].ID != runs[0].ID {
+		t.Errorf("expected the 2 newest runs, got %+v", limited)
+	}
+
+	got, err := store.GetRun(sched.ID, runs[1].ID)
+	if err != nil || got.ID != runs[1].ID {
+		t.Errorf("GetRun = %+v, %v", got, err)
+	}
+	if _, err := store.GetRun(sched.ID, "missing"); err != ErrRunNotFound {
+		t.Errorf("expected ErrRunNotFound, got %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(store.runsDir(), sched.ID+".jsonl"))
+	if err != nil {
+		t.Fatalf("run log missing: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected run log mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestScheduleStore_RunRetention(t *testing.T) {
+	store, sched := newRunTestStore(t)
+	store.SetRunRetention(3, 24*time.Hour)
+
+	now := time.Now().UTC()
+	store.AppendRun(&ScheduleRun{ScheduleID: sched.ID, StartedAt: now.Add(-48 * time.Hour), Status: "success", Output: "too old"})
+	for i := 0; i < 5; i++ {
+		store.AppendRun(&ScheduleRun{ScheduleID: sched.ID, StartedAt: now.Add(time.Duration(i) * time.Second), Status: "success"})
+	}
+
+	runs, _ := store.ListRuns(sched.ID, 0)
+	if len(runs) != 3 {
+		t.Fatalf("expected 3 runs kept, got %d", len(runs))
+	}
+	for _, run := range runs {
+		if run.Output == "too old" {
+			t.Error("expected run older than max age to be pruned")
+		}
+	}
+	if !runs[2].StartedAt.Equal(now.Add(2 * time.Second)) {
+		t.Errorf("expected oldest kept run to be the third, got %v", runs[2].StartedAt)
+	}
+}
+
+func TestScheduleStore_RunOutputTruncated(t *testing.T) {
+	store, sched := newRunTestStore(t)
+
+	store.AppendRun(&ScheduleRun{ScheduleID: sched.ID, StartedAt: time.Now(), Output: strings.Repeat("<", maxRunOutputLen+10)})
+
+	runs, err := store.ListRuns(sched.ID, 0)
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("ListRuns = %v, %v", runs, err)
+	}
+	if len(runs[0].Output) != maxRunOutputLen || !runs[0].Truncated {
+		t.Errorf("expected output truncated to %d, got %d (truncated=%v)", maxRunOutputLen, len(runs[0].Output), runs[0].Truncated)
+	}
+}
+
+func TestScheduleStore_DeleteRemovesRuns(t *testing.T) {
+	store, sched := newRunTestStore(t)
+	store.AppendRun(&ScheduleRun{ScheduleID: sched.ID, StartedAt: time.Now(), Status: "success"})
+
+	if err := store.Delete(sched.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(store.runsDir(), sched.ID+".jsonl")); !os.IsNotExist(err) {
+		t.Errorf("expected run log to be removed, got %v", err)
+	}
+
+	if _, err := store.ListRuns("../schedules", 0); err != ErrScheduleNotFound {
+		t.Errorf("expected invalid ID to be rejected, got %v", err)
+	}
+}
+
+func TestScheduleHandlers_ListRuns(t *testing.T) {
+	store, sched := newRunTestStore(t)
+	handlers := NewScheduleHandlers(store)
+	for i := 0; i < 3; i++ {
+		store.AppendRun(&ScheduleRun{ScheduleID: sched.ID, Trigger: TriggerRunNow, StartedAt: time.Now(), Status: "success"})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/schedules/"+sched.ID+"/runs?limit=2", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleScheduleByID(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Runs []*ScheduleRun `json:"runs"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Runs) != 2 || resp.Runs[0].Trigger != TriggerRunNow {
+		t.Errorf("unexpected runs: %+v", resp.Runs)
+	}
+
+	for path, code := range map[string]int{
+		"/api/schedules/unknown/runs":                  http.StatusNotFound,
+		"/api/schedules/" + sched.ID + "/runs?limit=x": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		handlers.HandleScheduleByID(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != code {
+			t.Errorf("GET %s: expected %d, got %d", path, code, w.Code)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handlers.HandleScheduleByID(w, httptest.NewRequest(http.MethodPost, "/api/schedules/"+sched.ID+"/runs", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST runs: expected 405, got %d", w.Code)
+	}
+}
@@ -2,42 +2,257 @@ package admin
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"time"
+	_ "time/tzdata" // schedule timezones must resolve in images without tzdata
 )
 
 var (
 	ErrScheduleNotFound = errors.New("schedule not found")
 	ErrScheduleExists   = errors.New("schedule already exists")
+	ErrRunNotFound      = errors.New("run not found")
 	maxScheduleNameLen  = 128
 	maxPromptLen        = 8192
 	maxOutputLen        = 2000
+	maxTimeoutSeconds   = 24 * 60 * 60
+	maxJitterSeconds    = 60 * 60
+	maxRetryAttempts    = 10
+	maxBackoffSeconds   = 60 * 60
+	maxFailureThreshold = 100
+	maxWatchPaths       = 20
+	maxLeadMinutes      = 7 * 24 * 60
+	maxSessionNameLen   = 64
 )
 
+// WatchRoots are the directories file_change schedules can watch. A watch
+// path starts with one of them, e.g. "ai-data/memory/*.md".
+var WatchRoots = []string{"ai-data", "vault"}
+
+// Overlap policies: what happens when a schedule fires while its previous
+// run is still going.
+const (
+	OverlapSkip  = "skip"  // drop the new run (default)
+	OverlapQueue = "queue" // run it when the current run finishes
+	OverlapAllow = "allow" // run both at once
+)
+
+// Catch-up policies: what happens on startup to runs missed while the
+// scheduler was not running.
+const (
+	CatchUpNone = "none" // forget missed runs (default)
+	CatchUpOnce = "once" // run once if any run was missed
+	CatchUpAll  = "all"  // replay every missed run, oldest first
+)
+
+// Output modes: which runs are delivered to a schedule's output target.
+const (
+	OutputAlways   = "always"    // every run that produced output (default)
+	OutputOnChange = "on_change" // runs whose status or output differs from the previous run
+	OutputOnError  = "on_error"  // failed runs only
+)
+
+// SessionChannel makes an agent schedule continue the session bound to its
+// output channel, the same one people chatting there talk to. Any other
+// non-empty session value names a session kept across runs.
+const SessionChannel = "channel"
+
 // Schedule represents a scheduled job.
 type Schedule struct {
-	ID            string        `json:"id"`
-	Name          string        `json:"name"`
-	CronExpr      string        `json:"cron_expr"`
-	Type          string        `json:"type"` // "script" or "agent"
-	Enabled       bool          `json:"enabled"`
-	RunOnce       bool          `json:"run_once,omitempty"`
-	ScriptName    string        `json:"script_name,omitempty"`
-	Prompt        string        `json:"prompt,omitempty"`
-	OutputTarget  *OutputTarget `json:"output_target,omitempty"`
-	CreatedAt     time.Time     `json:"created_at"`
-	UpdatedAt     time.Time     `json:"updated_at"`
-	LastRunAt     *time.Time    `json:"last_run_at,omitempty"`
-	LastRunStatus string        `json:"last_run_status,omitempty"`
-	LastRunError  string        `json:"last_run_error,omitempty"`
-	LastRunOutput string        `json:"last_run_output,omitempty"`
+	ID                  string        `json:"id"`
+	Name                string        `json:"name"`
+	CronExpr            string        `json:"cron_expr"`
+	Type                string        `json:"type"` // "script" or "agent"
+	Enabled             bool          `json:"enabled"`
+	RunOnce             bool          `json:"run_once,omitempty"`
+	ScriptName          string        `json:"script_name,omitempty"`
+	Prompt              string        `json:"prompt,omitempty"`
+	OutputTarget        *OutputTarget `json:"output_target,omitempty"`
+	Trigger             string        `json:"trigger,omitempty"`         // "cron", "webhook", "file_change" or "calendar"; empty = cron
+	Timezone            string        `json:"timezone,omitempty"`        // IANA name the cron expression is read in; empty = server time
+	Overlap             string        `json:"overlap,omitempty"`         // "skip", "queue" or "allow"; empty = skip
+	CatchUp             string        `json:"catch_up,omitempty"`        // "none", "once" or "all"; empty = none
+	TimeoutSeconds      int           `json:"timeout_seconds,omitempty"` // 0 = default for the job type
+	JitterSeconds       int           `json:"jitter_seconds,omitempty"`  // max random delay before cron and catch-up runs
+	Retry               *RetryPolicy  `json:"retry,omitempty"`
+	OutputMode          string        `json:"output_mode,omitempty"`       // "always", "on_change" or "on_error"; empty = always
+	FailureTarget       *OutputTarget `json:"failure_target,omitempty"`    // alerted when a run fails for good
+	FailureThreshold    int           `json:"failure_threshold,omitempty"` // consecutive failed runs before alerting; 0 = 1
+	WebhookSecret       string        `json:"webhook_secret,omitempty"`    // HMAC-SHA256 key for webhook payloads; generated if empty
+	WatchPaths          []string      `json:"watch_paths,omitempty"`       // globs under ai-data/ or vault/ for file_change
+	Calendar            string        `json:"calendar,omitempty"`          // calendar name for calendar triggers; empty = all
+	LeadMinutes         int           `json:"lead_minutes,omitempty"`      // minutes before an event starts to run
+	Session             string        `json:"session,omitempty"`           // agent session to continue: "channel" or a name; empty = new each run
+	CreatedAt           time.Time     `json:"created_at"`
+	UpdatedAt           time.Time     `json:"updated_at"`
+	LastRunAt           *time.Time    `json:"last_run_at,omitempty"`
+	LastRunStatus       string        `json:"last_run_status,omitempty"`
+	LastRunError        string        `json:"last_run_error,omitempty"`
+	LastRunOutput       string        `json:"last_run_output,omitempty"`
+	LastOutputHash      string        `json:"last_output_hash,omitempty"` // status and full output of the last run, for on_change delivery
+	ConsecutiveFailures int           `json:"consecutive_failures,omitempty"`
+}
+
+// RetryPolicy re-runs a failed job. Attempt n waits BackoffSeconds * 2^(n-2)
+// seconds after the previous one fails.
+type RetryPolicy struct {
+	MaxAttempts    int `json:"max_attempts"`              // total attempts, including the first
+	BackoffSeconds int `json:"backoff_seconds,omitempty"` // delay before the first retry; 0 = 30
+}
+
+// CronSpec returns the cron expression with the schedule's timezone applied,
+// in the form accepted by the cron parser.
+func (s *Schedule) CronSpec() string {
+	if s.Timezone == "" {
+		return s.CronExpr
+	}
+	return "CRON_TZ=" + s.Timezone + " " + s.CronExpr
+}
+
+// IsCron reports whether the schedule is fired by its cron expression
+// rather than by an event.
+func (s *Schedule) IsCron() bool {
+	return s.Trigger == "" || s.Trigger == TriggerCron
+}
+
+// validateScheduleOptions checks the trigger, timing and concurrency options
+// of a schedule.
+func validateScheduleOptions(sched *Schedule) error {
+	switch sched.Trigger {
+	case "", TriggerCron, TriggerWebhook, TriggerCalendar:
+	case TriggerFileChange:
+		if len(sched.WatchPaths) == 0 {
+			return fmt.Errorf("watch_paths is required for trigger 'file_change'")
+		}
+	default:
+		return fmt.Errorf("trigger must be 'cron', 'webhook', 'file_change' or 'calendar'")
+	}
+	if len(sched.WatchPaths) > maxWatchPaths {
+		return fmt.Errorf("watch_paths exceeds %d entries", maxWatchPaths)
+	}
+	for _, p := range sched.WatchPaths {
+		if err := validateWatchPath(p); err != nil {
+			return err
+		}
+	}
+	if sched.LeadMinutes < 0 || sched.LeadMinutes > maxLeadMinutes {
+		return fmt.Errorf("lead_minutes must be between 0 and %d", maxLeadMinutes)
+	}
+	if sched.Timezone != "" {
+		if _, err := time.LoadLocation(sched.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", sched.Timezone)
+		}
+	}
+	switch sched.Overlap {
+	case "", OverlapSkip, OverlapQueue, OverlapAllow:
+	default:
+		return fmt.Errorf("overlap must be 'skip', 'queue' or 'allow'")
+	}
+	switch sched.CatchUp {
+	case "", CatchUpNone, CatchUpOnce, CatchUpAll:
+	default:
+		return fmt.Errorf("catch_up must be 'none', 'once' or 'all'")
+	}
+	if sched.TimeoutSeconds < 0 || sched.TimeoutSeconds > maxTimeoutSeconds {
+		return fmt.Errorf("timeout_seconds must be between 0 and %d", maxTimeoutSeconds)
+	}
+	if sched.JitterSeconds < 0 || sched.JitterSeconds > maxJitterSeconds {
+		return fmt.Errorf("jitter_seconds must be between 0 and %d", maxJitterSeconds)
+	}
+	if r := sched.Retry; r != nil {
+		if r.MaxAttempts < 0 || r.MaxAttempts > maxRetryAttempts {
+			return fmt.Errorf("retry.max_attempts must be between 0 and %d", maxRetryAttempts)
+		}
+		if r.BackoffSeconds < 0 || r.BackoffSeconds > maxBackoffSeconds {
+			return fmt.Errorf("retry.backoff_seconds must be between 0 and %d", maxBackoffSeconds)
+		}
+	}
+	switch sched.OutputMode {
+	case "", OutputAlways, OutputOnChange, OutputOnError:
+	default:
+		return fmt.Errorf("output_mode must be 'always', 'on_change' or 'on_error'")
+	}
+	if t := sched.FailureTarget; t != nil && (t.Provider == "" || t.ChannelID == "") {
+		return fmt.Errorf("failure_target needs a provider and channel_id")
+	}
+	if sched.FailureThreshold < 0 || sched.FailureThreshold > maxFailureThreshold {
+		return fmt.Errorf("failure_threshold must be between 0 and %d", maxFailureThreshold)
+	}
+	if sched.Session == SessionChannel && sched.OutputTarget == nil {
+		return fmt.Errorf("session 'channel' needs an output_target")
+	}
+	if err := validateSessionName(sched.Session); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateSessionName checks a schedule's session value. Names are kept to
+// letters, digits, '-', '_' and '.' so they read well in logs and the UI.
+func validateSessionName(name string) error {
+	if len(name) > maxSessionNameLen {
+		return fmt.Errorf("session exceeds %d characters", maxSessionNameLen)
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return fmt.Errorf("session may only contain letters, digits, '-', '_' and '.'")
+		}
+	}
+	return nil
+}
+
+// validateWatchPath checks that a watch path is a well-formed glob under one
+// of the WatchRoots.
+func validateWatchPath(p string) error {
+	root, _, _ := strings.Cut(p, "/")
+	if !slices.Contains(WatchRoots, root) {
+		return fmt.Errorf("watch path %q must start with %s/", p, strings.Join(WatchRoots, "/ or "))
+	}
+	if path.Clean(p) != p {
+		return fmt.Errorf("watch path %q is not a clean relative path", p)
+	}
+	if _, err := path.Match(p, ""); err != nil {
+		return fmt.Errorf("watch path %q: %w", p, err)
+	}
+	return nil
+}
+
+// MatchWatchPath reports whether file, a slash-separated path starting with
+// its watch root, matches a watch path. "*" matches within one path segment;
+// a trailing "/**" matches everything below a directory.
+func MatchWatchPath(pattern, file string) bool {
+	if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+		if ok, _ := path.Match(dir, file); ok {
+			return false // the directory itself
+		}
+		for d := path.Dir(file); d != "." && d != "/"; d = path.Dir(d) {
+			if ok, _ := path.Match(dir, d); ok {
+				return true
+			}
+		}
+		return false
+	}
+	ok, _ := path.Match(pattern, file)
+	return ok
+}
+
+// generateWebhookSecret returns a random key for signing webhook payloads.
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
 }
 
 // OutputTarget specifies where to send job output.
@@ -49,12 +264,15 @@ type OutputTarget struct {
 // schedulesFile is the on-disk JSON format.
 type schedulesFile struct {
 	Schedules map[string]*Schedule `json:"schedules"`
+	Sessions  map[string]string    `json:"sessions,omitempty"` // named session -> engine session ID
 }
 
-// ScheduleStore manages schedule persistence.
+// ScheduleStore manages schedule persistence and run history.
 type ScheduleStore struct {
-	dataDir string
-	mu      sync.RWMutex
+	dataDir   string
+	mu        sync.RWMutex
+	maxRuns   int           // runs kept per schedule (0 = unlimited)
+	maxRunAge time.Duration // age after which runs are dropped (0 = forever)
 }
 
 // NewScheduleStore creates a new schedule store.
@@ -163,7 +381,7 @@ func (s *ScheduleStore) Create(sched *Schedule) (*Schedule, error) {
 	if len(sched.Name) > maxScheduleNameLen {
 		return nil, fmt.Errorf("name exceeds %d characters", maxScheduleNameLen)
 	}
-	if sched.CronExpr == "" {
+	if sched.CronExpr == "" && sched.IsCron() {
 		return nil, fmt.Errorf("cron_expr is required")
 	}
 	if sched.Type != "script" && sched.Type != "agent" {
@@ -178,6 +396,17 @@ func (s *ScheduleStore) Create(sched *Schedule) (*Schedule, error) {
 	if sched.Type == "agent" && len(sched.Prompt) > maxPromptLen {
 		return nil, fmt.Errorf("prompt exceeds %d characters", maxPromptLen)
 	}
+	if err := validateScheduleOptions(sched); err != nil {
+		return nil, err
+	}
+	webhookSecret := sched.WebhookSecret
+	if sched.Trigger == TriggerWebhook && webhookSecret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		webhookSecret = secret
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -194,17 +423,32 @@ func (s *ScheduleStore) Create(sched *Schedule) (*Schedule, error) {
 
 	now := time.Now().UTC()
 	newSched := &Schedule{
-		ID:           id,
-		Name:         sched.Name,
-		CronExpr:     sched.CronExpr,
-		Type:         sched.Type,
-		Enabled:      sched.Enabled,
-		RunOnce:      sched.RunOnce,
-		ScriptName:   sched.ScriptName,
-		Prompt:       sched.Prompt,
-		OutputTarget: sched.OutputTarget,
-		CreatedAt:    now,
-		UpdatedAt:    now,
+		ID:               id,
+		Name:             sched.Name,
+		CronExpr:         sched.CronExpr,
+		Trigger:          sched.Trigger,
+		Type:             sched.Type,
+		Enabled:          sched.Enabled,
+		RunOnce:          sched.RunOnce,
+		ScriptName:       sched.ScriptName,
+		Prompt:           sched.Prompt,
+		OutputTarget:     sched.OutputTarget,
+		Timezone:         sched.Timezone,
+		Overlap:          sched.Overlap,
+		CatchUp:          sched.CatchUp,
+		TimeoutSeconds:   sched.TimeoutSeconds,
+		JitterSeconds:    sched.JitterSeconds,
+		Retry:            sched.Retry,
+		OutputMode:       sched.OutputMode,
+		FailureTarget:    sched.FailureTarget,
+		FailureThreshold: sched.FailureThreshold,
+		WebhookSecret:    webhookSecret,
+		WatchPaths:       sched.WatchPaths,
+		Calendar:         sched.Calendar,
+		LeadMinutes:      sched.LeadMinutes,
+		Session:          sched.Session,
+		CreatedAt:        now,
+		UpdatedAt:        now,
 	}
 
 	sf.Schedules[id] = newSched
@@ -216,8 +460,15 @@ func (s *ScheduleStore) Create(sched *Schedule) (*Schedule, error) {
 	return &copy, nil
 }
 
-// Update modifies an existing schedule.
+// Update modifies an existing schedule. Empty strings leave a field
+// unchanged, except for the output and failure targets and the trigger,
+// timing, retry, delivery and session options, which are always replaced. The
+// webhook secret is kept unless a new one is given.
 func (s *ScheduleStore) Update(id string, updates *Schedule) (*Schedule, error) {
+	if err := validateScheduleOptions(updates); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -258,6 +509,33 @@ func (s *ScheduleStore) Update(id string, updates *Schedule) (*Schedule, error)
 	// OutputTarget can be set to nil to clear it
 	existing.OutputTarget = updates.OutputTarget
 	existing.RunOnce = updates.RunOnce
+	existing.Timezone = updates.Timezone
+	existing.Overlap = updates.Overlap
+	existing.CatchUp = updates.CatchUp
+	existing.TimeoutSeconds = updates.TimeoutSeconds
+	existing.JitterSeconds = updates.JitterSeconds
+	existing.Retry = updates.Retry
+	existing.OutputMode = updates.OutputMode
+	existing.FailureTarget = updates.FailureTarget
+	existing.FailureThreshold = updates.FailureThreshold
+	existing.Trigger = updates.Trigger
+	existing.WatchPaths = updates.WatchPaths
+	existing.Calendar = updates.Calendar
+	existing.LeadMinutes = updates.LeadMinutes
+	existing.Session = updates.Session
+	if updates.WebhookSecret != "" {
+		existing.WebhookSecret = updates.WebhookSecret
+	}
+	if existing.Trigger == TriggerWebhook && existing.WebhookSecret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		existing.WebhookSecret = secret
+	}
+	if existing.IsCron() && existing.CronExpr == "" {
+		return nil, fmt.Errorf("cron_expr is required")
+	}
 	existing.UpdatedAt = time.Now().UTC()
 
 	sf.Schedules[id] = existing
@@ -269,7 +547,7 @@ func (s *ScheduleStore) Update(id string, updates *Schedule) (*Schedule, error)
 	return &copy, nil
 }
 
-// Delete removes a schedule.
+// Delete removes a schedule and its run history.
 func (s *ScheduleStore) Delete(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -284,7 +562,10 @@ func (s *ScheduleStore) Delete(id string) error {
 	}
 
 	delete(sf.Schedules, id)
-	return s.save(sf)
+	if err := s.save(sf); err != nil {
+		return err
+	}
+	return s.deleteRunsLocked(id)
 }
 
 // SetEnabled enables or disables a schedule.
@@ -309,7 +590,8 @@ func (s *ScheduleStore) SetEnabled(id string, enabled bool) error {
 	return s.save(sf)
 }
 
-// UpdateLastRun records the result of a job execution.
+// UpdateLastRun records the result of a job execution and updates the
+// schedule's consecutive failure count.
 func (s *ScheduleStore) UpdateLastRun(id, status, errMsg, output string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -328,6 +610,12 @@ func (s *ScheduleStore) UpdateLastRun(id, status, errMsg, output string) error {
 	sched.LastRunAt = &now
 	sched.LastRunStatus = status
 	sched.LastRunError = errMsg
+	sched.LastOutputHash = outputHash(status, output)
+	if status == "error" {
+		sched.ConsecutiveFailures++
+	} else {
+		sched.ConsecutiveFailures = 0
+	}
 	if len(output) > maxOutputLen {
 		output = output[:maxOutputLen]
 	}
@@ -336,3 +624,39 @@ func (s *ScheduleStore) UpdateLastRun(id, status, errMsg, output string) error {
 	sf.Schedules[id] = sched
 	return s.save(sf)
 }
+
+// outputHash fingerprints a run's status and full output.
+func outputHash(status, output string) string {
+	sum := sha256.Sum256([]byte(status + "\x00" + output))
+	return hex.EncodeToString(sum[:16])
+}
+
+// NamedSession returns the engine session ID kept for a named schedule
+// session, or "" if none has been started yet.
+func (s *ScheduleStore) NamedSession(name string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sf, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	return sf.Sessions[name], nil
+}
+
+// SetNamedSession records the engine session ID for a named schedule
+// session. Schedules naming the same session share it.
+func (s *ScheduleStore) SetNamedSession(name, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sf, err := s.load()
+	if err != nil {
+		return err
+	}
+	if sf.Sessions == nil {
+		sf.Sessions = make(map[string]string)
+	}
+	sf.Sessions[name] = sessionID
+	return s.save(sf)
+}
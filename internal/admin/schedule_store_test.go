@@ -378,3 +378,235 @@ func TestScheduleStore_WithOutputTarget(t *testing.T) {
 		t.Errorf("expected channel_id 'channel:123456', got %q", got.OutputTarget.ChannelID)
 	}
 }
+
+func TestScheduleStore_TimingOptions(t *testing.T) {
+	store := NewScheduleStore(t.TempDir())
+
+	sched, err := store.Create(&Schedule{
+		Name:           "london-report",
+		CronExpr:       "0 9 * * 1-5",
+		Type:           "script",
+		ScriptName:     "report.star",
+		Timezone:       "Europe/London",
+		Overlap:        OverlapQueue,
+		CatchUp:        CatchUpOnce,
+		TimeoutSeconds: 120,
+		JitterSeconds:  30,
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if got := sched.CronSpec(); got != "CRON_TZ=Europe/London 0 9 * * 1-5" {
+		t.Errorf("unexpected cron spec %q", got)
+	}
+
+	got, _ := store.Get(sched.ID)
+	if got.Timezone != "Europe/London" || got.Overlap != OverlapQueue || got.CatchUp != CatchUpOnce || got.TimeoutSeconds != 120 || got.JitterSeconds != 30 {
+		t.Errorf("options not persisted: %+v", got)
+	}
+
+	// Timing options are replaced on update
+	updated, err := store.Update(sched.ID, &Schedule{Overlap: OverlapAllow})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated.Timezone != "" || updated.Overlap != OverlapAllow || updated.TimeoutSeconds != 0 {
+		t.Errorf("expected options to be replaced, got %+v", updated)
+	}
+	if updated.CronSpec() != "0 9 * * 1-5" {
+		t.Errorf("unexpected cron spec %q", updated.CronSpec())
+	}
+
+	for _, bad := range []*Schedule{
+		{Timezone: "Mars/Olympus_Mons"},
+		{Overlap: "sometimes"},
+		{CatchUp: "later"},
+		{TimeoutSeconds: -1},
+		{JitterSeconds: maxJitterSeconds + 1},
+	} {
+		bad.Name, bad.CronExpr, bad.Type, bad.ScriptName = "bad", "* * * * *", "script", "x.star"
+		if _, err := store.Create(bad); err == nil {
+			t.Errorf("expected Create to reject %+v", bad)
+		}
+		if _, err := store.Update(sched.ID, bad); err == nil {
+			t.Errorf("expected Update to reject %+v", bad)
+		}
+	}
+}
+
+func TestScheduleStore_FailureTracking(t *testing.T) {
+	store := NewScheduleStore(t.TempDir())
+
+	sched, err := store.Create(&Schedule{
+		Name:          "backup",
+		CronExpr:      "0 3 * * *",
+		Type:          "script",
+		ScriptName:    "backup.star",
+		Retry:         &RetryPolicy{MaxAttempts: 3, BackoffSeconds: 60},
+		OutputMode:    OutputOnChange,
+		FailureTarget: &OutputTarget{Provider: "slack", ChannelID: "C-ALERTS"},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	store.UpdateLastRun(sched.ID, "error", "boom", "")
+	store.UpdateLastRun(sched.ID, "error", "boom", "")
+	got, _ := store.Get(sched.ID)
+	if got.ConsecutiveFailures != 2 {
+		t.Errorf("expected 2 consecutive failures, got %d", got.ConsecutiveFailures)
+	}
+	failedHash := got.LastOutputHash
+
+	store.UpdateLastRun(sched.ID, "success", "", "done")
+	got, _ = store.Get(sched.ID)
+	if got.ConsecutiveFailures != 0 || got.LastOutputHash == failedHash {
+		t.Errorf("expected success to reset the streak and change the hash, got %+v", got)
+	}
+	if got.Retry == nil || got.Retry.MaxAttempts != 3 || got.FailureTarget == nil {
+		t.Errorf("retry and failure options not persisted: %+v", got)
+	}
+
+	for _, bad := range []*Schedule{
+		{Retry: &RetryPolicy{MaxAttempts: maxRetryAttempts + 1}},
+		{Retry: &RetryPolicy{MaxAttempts: 2, BackoffSeconds: -1}},
+		{OutputMode: "sometimes"},
+		{FailureTarget: &OutputTarget{Provider: "slack"}},
+		{FailureThreshold: -1},
+	} {
+		bad.Name, bad.CronExpr, bad.Type, bad.ScriptName = "bad", "* * * * *", "script", "x.star"
+		if _, err := store.Create(bad); err == nil {
+			t.Errorf("expected Create to reject %+v", bad)
+		}
+	}
+}
+
+func TestScheduleStore_EventTriggers(t *testing.T) {
+	store := NewScheduleStore(t.TempDir())
+
+	hook, err := store.Create(&Schedule{
+		Name:    "ci-failed",
+		Trigger: TriggerWebhook,
+		Type:    "agent",
+		Prompt:  "Summarise the failing CI logs",
+	})
+	if err != nil {
+		t.Fatalf("Create webhook schedule failed: %v", err)
+	}
+	if len(hook.WebhookSecret) != 64 {
+		t.Errorf("expected a generated webhook secret, got %q", hook.WebhookSecret)
+	}
+
+	// The secret survives updates that do not set one
+	updated, err := store.Update(hook.ID, &Schedule{Trigger: TriggerWebhook})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated.WebhookSecret != hook.WebhookSecret {
+		t.Error("expected the webhook secret to be kept")
+	}
+	if _, err := store.Update(hook.ID, &Schedule{}); err == nil {
+		t.Error("expected switching to cron without a cron expression to fail")
+	}
+
+	watch, err := store.Create(&Schedule{
+		Name:       "inbox",
+		Trigger:    TriggerFileChange,
+		WatchPaths: []string{"ai-data/inbox/*.md", "vault/Projects/**"},
+		Type:       "script",
+		ScriptName: "inbox.star",
+	})
+	if err != nil {
+		t.Fatalf("Create file_change schedule failed: %v", err)
+	}
+	if watch.CronExpr != "" || watch.IsCron() || watch.WebhookSecret != "" {
+		t.Errorf("unexpected file_change schedule: %+v", watch)
+	}
+
+	for _, bad := range []*Schedule{
+		{},
+		{Trigger: "sometimes"},
+		{Trigger: TriggerFileChange},
+		{Trigger: TriggerFileChange, WatchPaths: []string{"/etc/passwd"}},
+		{Trigger: TriggerFileChange, WatchPaths: []string{"ai-data/../secure/x"}},
+		{Trigger: TriggerFileChange, WatchPaths: []string{"vault/[bad"}},
+		{Trigger: TriggerCalendar, LeadMinutes: -5},
+	} {
+		bad.Name, bad.Type, bad.ScriptName = "bad", "script", "x.star"
+		if _, err := store.Create(bad); err == nil {
+			t.Errorf("expected Create to reject %+v", bad)
+		}
+	}
+}
+
+func TestScheduleStore_Sessions(t *testing.T) {
+	store := NewScheduleStore(t.TempDir())
+
+	base := Schedule{Name: "standup", CronExpr: "0 9 * * 1-5", Type: "agent", Prompt: "Run the standup"}
+
+	bad := base
+	bad.Session = SessionChannel
+	if _, err := store.Create(&bad); err == nil {
+		t.Error("expected error for a channel session without an output target")
+	}
+	bad.Session = "daily standup"
+	if _, err := store.Create(&bad); err == nil {
+		t.Error("expected error for a session name with a space")
+	}
+
+	named := base
+	named.Session = "standup"
+	sched, err := store.Create(&named)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if sched.Session != "standup" {
+		t.Errorf("expected session to be saved, got %q", sched.Session)
+	}
+
+	if id, err := store.NamedSession("standup"); err != nil || id != "" {
+		t.Errorf("expected no session yet, got %q, %v", id, err)
+	}
+	if err := store.SetNamedSession("standup", "ses_123"); err != nil {
+		t.Fatalf("SetNamedSession failed: %v", err)
+	}
+	if id, _ := store.NamedSession("standup"); id != "ses_123" {
+		t.Errorf("expected ses_123, got %q", id)
+	}
+
+	// Saving schedules keeps the named sessions
+	updated, err := store.Update(sched.ID, &Schedule{
+		Session:      SessionChannel,
+		OutputTarget: &OutputTarget{Provider: "slack", ChannelID: "C123"},
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated.Session != SessionChannel {
+		t.Errorf("expected session to be replaced, got %q", updated.Session)
+	}
+	if id, _ := store.NamedSession("standup"); id != "ses_123" {
+		t.Errorf("expected named session to survive an update, got %q", id)
+	}
+}
+
+func TestMatchWatchPath(t *testing.T) {
+	tests := []struct {
+		pattern, file string
+		want          bool
+	}{
+		{"ai-data/inbox/*.md", "ai-data/inbox/note.md", true},
+		{"ai-data/inbox/*.md", "ai-data/inbox/sub/note.md", false},
+		{"ai-data/inbox/*.md", "ai-data/inbox/note.txt", false},
+		{"vault/Projects/**", "vault/Projects/a.md", true},
+		{"vault/Projects/**", "vault/Projects/x/y/z.md", true},
+		{"vault/Projects/**", "vault/Other/a.md", false},
+		{"vault/*/**", "vault/Projects/a.md", true},
+		{"ai-data/MEMORY.md", "ai-data/MEMORY.md", true},
+	}
+	for _, tt := range tests {
+		if got := MatchWatchPath(tt.pattern, tt.file); got != tt.want {
+			t.Errorf("MatchWatchPath(%q, %q) = %v, want %v", tt.pattern, tt.file, got, tt.want)
+		}
+	}
+}
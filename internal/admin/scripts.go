@@ -6,11 +6,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/open-pact/openpact/internal/starlark"
 )
 
 var (
@@ -18,6 +22,9 @@ var (
 	ErrScriptExists      = errors.New("script already exists")
 	ErrScriptNotApproved = errors.New("script not approved")
 	ErrScriptModified    = errors.New("script modified since approval")
+	ErrScriptLibraries   = errors.New("script libraries cannot be resolved")
+	ErrScriptManifest    = errors.New("script capability manifest is invalid")
+	ErrScriptIsTest      = errors.New("test files are not scripts")
 )
 
 // ScriptStatus represents the approval status of a script.
@@ -44,6 +51,17 @@ type Script struct {
 	RejectedAt      *time.Time   `json:"rejected_at,omitempty"`
 	RejectedBy      string       `json:"rejected_by,omitempty"`
 	RejectReason    string       `json:"reject_reason,omitempty"`
+
+	// Libraries loaded from lib/, directly or through other libraries.
+	// ModifiedLibraries lists those changed, added or removed since approval.
+	Libraries         []string `json:"libraries,omitempty"`
+	ModifiedLibraries []string `json:"modified_libraries,omitempty"`
+	LibraryError      string   `json:"library_error,omitempty"`
+
+	// Capabilities declared in the script header (@hosts, @secrets,
+	// @max_runtime, @max_response_bytes), enforced on every run.
+	Capabilities  *starlark.Capabilities `json:"capabilities,omitempty"`
+	ManifestError string                 `json:"manifest_error,omitempty"`
 }
 
 // Approval represents the approval state of a script.
@@ -57,6 +75,10 @@ type Approval struct {
 	RejectReason string       `json:"reject_reason,omitempty"`
 	CreatedAt    time.Time    `json:"created_at"`
 	ModifiedAt   time.Time    `json:"modified_at"`
+
+	// Libraries maps each library in the script's load closure to its hash
+	// when approved; approval covers them as well as the script.
+	Libraries map[string]string `json:"libraries,omitempty"`
 }
 
 // ScriptStore manages scripts and their approval states.
@@ -159,7 +181,7 @@ func (s *ScriptStore) List() ([]*Script, error) {
 
 	var scripts []*Script
 	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".star") {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".star") || starlark.IsTestFile(entry.Name()) {
 			continue
 		}
 
@@ -182,18 +204,29 @@ func (s *ScriptStore) Get(name string, includeSource bool) (*Script, error) {
 }
 
 func (s *ScriptStore) getScript(name string, includeSource bool) (*Script, error) {
+	script, _, err := s.readScript(name, includeSource)
+	return script, err
+}
+
+// readScript reads a script and determines its status. It also returns the
+// script's library closure with each library's hash.
+func (s *ScriptStore) readScript(name string, includeSource bool) (*Script, map[string]string, error) {
+	if starlark.IsTestFile(name) {
+		return nil, nil, ErrScriptNotFound
+	}
 	path := filepath.Join(s.scriptsDir, name)
 	source, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, ErrScriptNotFound
+			return nil, nil, ErrScriptNotFound
 		}
-		return nil, err
+		return nil, nil, err
 	}
 
 	info, _ := os.Stat(path)
 	hash := computeHash(string(source))
 	description, secrets := parseScriptMetadata(string(source))
+	libraries, libErr := starlark.LibraryClosure(filepath.Join(s.scriptsDir, starlark.LibDirName), name, string(source))
 
 	script := &Script{
 		Name:            name,
@@ -201,6 +234,15 @@ func (s *ScriptStore) getScript(name string, includeSource bool) (*Script, error
 		Description:     description,
 		RequiredSecrets: secrets,
 		ModifiedAt:      info.ModTime(),
+		Libraries:       slices.Sorted(maps.Keys(libraries)),
+	}
+	if libErr != nil {
+		script.LibraryError = libErr.Error()
+	}
+	if caps, err := starlark.ParseCapabilities(string(source)); err != nil {
+		script.ManifestError = err.Error()
+	} else {
+		script.Capabilities = caps
 	}
 
 	if includeSource {
@@ -219,15 +261,19 @@ func (s *ScriptStore) getScript(name string, includeSource bool) (*Script, error
 		script.RejectReason = approval.RejectReason
 		script.CreatedAt = approval.CreatedAt
 
-		// If hash doesn't match approval, status is pending (modified)
-		if approval.Status == StatusApproved && approval.Hash != hash {
-			script.Status = StatusPending
+		// If the script or one of its libraries doesn't match the
+		// approval, status is pending (modified)
+		if approval.Status == StatusApproved {
+			script.ModifiedLibraries = changedLibraries(approval.Libraries, libraries)
+			if approval.Hash != hash || len(script.ModifiedLibraries) > 0 || libErr != nil {
+				script.Status = StatusPending
+			}
 		}
 	} else {
 		script.Status = StatusPending
 	}
 
-	return script, nil
+	return script, libraries, nil
 }
 
 // Create creates a new script.
@@ -238,6 +284,9 @@ func (s *ScriptStore) Create(name, source, createdBy string) (*Script, error) {
 	if !strings.HasSuffix(name, ".star") {
 		name = name + ".star"
 	}
+	if starlark.IsTestFile(name) {
+		return nil, ErrScriptIsTest
+	}
 
 	path := filepath.Join(s.scriptsDir, name)
 	if _, err := os.Stat(path); err == nil {
@@ -273,7 +322,7 @@ func (s *ScriptStore) Update(name, source, updatedBy string) (*Script, error) {
 	defer s.mu.Unlock()
 
 	path := filepath.Join(s.scriptsDir, name)
-	if _, err := os.Stat(path); os.IsNotExist(err) {
+	if _, err := os.Stat(path); os.IsNotExist(err) || starlark.IsTestFile(name) {
 		return nil, ErrScriptNotFound
 	}
 
@@ -331,16 +380,23 @@ func (s *ScriptStore) Approve(name, approvedBy string) (*Script, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	script, err := s.getScript(name, false)
+	script, libraries, err := s.readScript(name, false)
 	if err != nil {
 		return nil, err
 	}
+	if script.LibraryError != "" {
+		return nil, fmt.Errorf("%w: %s", ErrScriptLibraries, script.LibraryError)
+	}
+	if script.ManifestError != "" {
+		return nil, fmt.Errorf("%w: %s", ErrScriptManifest, script.ManifestError)
+	}
 
 	now := time.Now()
 
 	if approval, ok := s.approvals[name]; ok {
 		approval.Status = StatusApproved
 		approval.Hash = script.Hash
+		approval.Libraries = libraries
 		approval.ApprovedAt = &now
 		approval.ApprovedBy = approvedBy
 		approval.ModifiedAt = now
@@ -351,6 +407,7 @@ func (s *ScriptStore) Approve(name, approvedBy string) (*Script, error) {
 		s.approvals[name] = &Approval{
 			Hash:       script.Hash,
 			Status:     StatusApproved,
+			Libraries:  libraries,
 			ApprovedAt: &now,
 			ApprovedBy: approvedBy,
 			CreatedAt:  now,
@@ -418,19 +475,34 @@ func (s *ScriptStore) CanExecute(name string) error {
 	}
 
 	if script.Status != StatusApproved {
-		return ErrScriptNotApproved
-	}
-
-	// Check hash matches (script wasn't modified since approval)
-	if approval, ok := s.approvals[name]; ok {
-		if approval.Hash != script.Hash {
+		// Approved, but the script or a library changed since
+		if approval, ok := s.approvals[name]; ok && approval.Status == StatusApproved {
 			return ErrScriptModified
 		}
+		return ErrScriptNotApproved
 	}
 
 	return nil
 }
 
+// changedLibraries lists the libraries whose hash differs between an
+// approval and the current closure, including ones added or removed.
+func changedLibraries(approved, current map[string]string) []string {
+	var changed []string
+	for module, hash := range current {
+		if approved[module] != hash {
+			changed = append(changed, module)
+		}
+	}
+	for module := range approved {
+		if _, ok := current[module]; !ok {
+			changed = append(changed, module)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
 // IsAllowlisted returns true if the script is in the allowlist.
 func (s *ScriptStore) IsAllowlisted(name string) bool {
 	s.mu.RLock()
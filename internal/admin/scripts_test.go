@@ -1,6 +1,7 @@
 package admin
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -350,3 +351,65 @@ func TestScriptStore_DuplicateCreate(t *testing.T) {
 		t.Errorf("Expected ErrScriptExists, got %v", err)
 	}
 }
+
+func TestScriptStore_LibraryApproval(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptsDir := filepath.Join(tmpDir, "scripts")
+	libDir := filepath.Join(scriptsDir, "lib")
+
+	store, _ := NewScriptStore(scriptsDir, filepath.Join(tmpDir, "data"), nil)
+	os.MkdirAll(libDir, 0755)
+	os.WriteFile(filepath.Join(libDir, "github.star"), []byte("load(\"//lib/http.star\", \"get\")\ndef issues(): return get()\n"), 0644)
+	os.WriteFile(filepath.Join(libDir, "http.star"), []byte("def get(): return []\n"), 0644)
+
+	store.Create("report.star", "load(\"//lib/github.star\", \"issues\")\nresult = issues()\n", "admin")
+	store.Create("other.star", "result = 1\n", "admin")
+	store.Approve("report.star", "admin")
+	store.Approve("other.star", "admin")
+
+	script, _ := store.Get("report.star", false)
+	if len(script.Libraries) != 2 || script.Status != StatusApproved {
+		t.Fatalf("expected an approved script with 2 libraries, got %+v", script)
+	}
+
+	// Changing a library loaded indirectly needs re-approval
+	os.WriteFile(filepath.Join(libDir, "http.star"), []byte("def get(): return ['changed']\n"), 0644)
+
+	if err := store.CanExecute("report.star"); err != ErrScriptModified {
+		t.Errorf("expected ErrScriptModified, got %v", err)
+	}
+	script, _ = store.Get("report.star", false)
+	if script.Status != StatusPending || len(script.ModifiedLibraries) != 1 || script.ModifiedLibraries[0] != "//lib/http.star" {
+		t.Errorf("expected report.star to be pending with http.star modified, got %+v", script)
+	}
+	if err := store.CanExecute("other.star"); err != nil {
+		t.Errorf("expected scripts without libraries to be unaffected, got %v", err)
+	}
+
+	store.Approve("report.star", "admin")
+	if err := store.CanExecute("report.star"); err != nil {
+		t.Errorf("expected re-approved script to be executable, got %v", err)
+	}
+
+	// A script whose libraries cannot be resolved cannot be approved
+	store.Create("broken.star", "load(\"//lib/missing.star\", \"x\")\n", "admin")
+	if _, err := store.Approve("broken.star", "admin"); !errors.Is(err, ErrScriptLibraries) {
+		t.Errorf("expected ErrScriptLibraries, got %v", err)
+	}
+}
+
+func TestScriptStore_Capabilities(t *testing.T) {
+	tmpDir := t.TempDir()
+	store, _ := NewScriptStore(filepath.Join(tmpDir, "scripts"), filepath.Join(tmpDir, "data"), nil)
+
+	store.Create("weather.star", "# @hosts: api.weatherapi.com\n# @secrets: WEATHER_API_KEY\n# @max_runtime: 5s\nresult = 1\n", "admin")
+	script, _ := store.Get("weather.star", false)
+	if script.Capabilities == nil || len(script.Capabilities.Hosts) != 1 || script.Capabilities.MaxRuntimeMs != 5000 {
+		t.Errorf("expected the manifest to be parsed, got %+v", script.Capabilities)
+	}
+
+	store.Create("bad.star", "# @max_runtime: soon\nresult = 1\n", "admin")
+	if _, err := store.Approve("bad.star", "admin"); !errors.Is(err, ErrScriptManifest) {
+		t.Errorf("expected ErrScriptManifest, got %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -40,25 +41,42 @@ type secretRecord struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// SecretStore manages secret persistence.
+const secretsFileName = "starlark_secrets.json"
+
+// SecretStore manages secret persistence. When a master key is configured
+// (see MasterKeyFromEnv) the file is encrypted at rest, and an existing
+// plaintext file is encrypted on construction.
 type SecretStore struct {
 	dataDir string
+	key     *MasterKey
+	keyErr  error
 	mu      sync.RWMutex
 }
 
 // NewSecretStore creates a new secret store.
 func NewSecretStore(dataDir string) *SecretStore {
-	return &SecretStore{dataDir: dataDir}
+	s := &SecretStore{dataDir: dataDir}
+	s.key, s.keyErr = MasterKeyFromEnv()
+	if s.keyErr == nil {
+		if err := migrateStoreFile(s.filePath(), s.key); err != nil {
+			log.Printf("Warning: failed to encrypt %s: %v", secretsFileName, err)
+		}
+	}
+	return s
 }
 
 func (s *SecretStore) filePath() string {
-	return filepath.Join(s.dataDir, "starlark_secrets.json")
+	return filepath.Join(s.dataDir, secretsFileName)
 }
 
 func (s *SecretStore) load() (*secretsFile, error) {
+	if s.keyErr != nil {
+		return nil, fmt.Errorf("invalid master key: %w", s.keyErr)
+	}
+
 	sf := &secretsFile{Secrets: make(map[string]secretRecord)}
 
-	data, err := os.ReadFile(s.filePath())
+	data, _, err := readStoreFile(s.filePath(), s.key)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return sf, nil
@@ -87,7 +105,7 @@ func (s *SecretStore) save(sf *secretsFile) error {
 		return fmt.Errorf("failed to marshal secrets: %w", err)
 	}
 
-	if err := os.WriteFile(s.filePath(), data, 0600); err != nil {
+	if err := writeStoreFile(s.filePath(), s.key, data); err != nil {
 		return fmt.Errorf("failed to write secrets: %w", err)
 	}
 
@@ -0,0 +1,102 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrToolApprovalNotFound is returned when an approval does not exist, has
+// expired or was already answered.
+var ErrToolApprovalNotFound = errors.New("approval not found")
+
+// ToolApproval is a tool call waiting for the owner's approval.
+type ToolApproval struct {
+	ID          string                 `json:"id"`
+	Tool        string                 `json:"tool"`
+	Args        map[string]interface{} `json:"args,omitempty"`
+	Provider    string                 `json:"provider,omitempty"`   // Chat the call came from, if known
+	ChannelID   string                 `json:"channel_id,omitempty"` // Channel the call came from
+	UserID      string                 `json:"user_id,omitempty"`    // User whose message led to the call
+	RequestedAt time.Time              `json:"requested_at"`
+	ExpiresAt   time.Time              `json:"expires_at"`
+}
+
+// ToolApprovalAPI is the interface for answering pending tool approvals.
+type ToolApprovalAPI interface {
+	PendingToolApprovals() []ToolApproval
+	// ResolveToolApproval answers an approval on behalf of an admin user.
+	ResolveToolApproval(id string, approved bool, by string) error
+}
+
+// ToolApprovalHandlers handles HTTP requests for tool approvals.
+type ToolApprovalHandlers struct {
+	api ToolApprovalAPI
+}
+
+// NewToolApprovalHandlers creates new tool approval handlers.
+func NewToolApprovalHandlers() *ToolApprovalHandlers {
+	return &ToolApprovalHandlers{}
+}
+
+// SetAPI sets the approval API (called after orchestrator is created).
+func (h *ToolApprovalHandlers) SetAPI(api ToolApprovalAPI) {
+	h.api = api
+}
+
+// ListApprovals handles GET /api/tool-approvals.
+func (h *ToolApprovalHandlers) ListApprovals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	if h.api == nil {
+		http.Error(w, `{"error":"tool approval API not available"}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	approvals := h.api.PendingToolApprovals()
+	if approvals == nil {
+		approvals = []ToolApproval{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"approvals": approvals})
+}
+
+// HandleApprovalByID handles POST /api/tool-approvals/:id/approve and
+// POST /api/tool-approvals/:id/deny.
+func (h *ToolApprovalHandlers) HandleApprovalByID(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/tool-approvals/")
+	id, action, _ := strings.Cut(rest, "/")
+	if id == "" || (action != "approve" && action != "deny") {
+		http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	if h.api == nil {
+		http.Error(w, `{"error":"tool approval API not available"}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	approved := action == "approve"
+	username, _ := UsernameFromContext(r.Context())
+	if err := h.api.ResolveToolApproval(id, approved, username); err != nil {
+		if errors.Is(err, ErrToolApprovalNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error":   "not_found",
+				"message": "Approval not found, expired or already answered",
+			})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error":   "internal",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": id, "approved": approved})
+}
@@ -0,0 +1,174 @@
+package admin
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// usageGroupings are the values of GET /api/usage's group_by parameter.
+var usageGroupings = map[string]func(r *UsageRecord) string{
+	"source":   func(r *UsageRecord) string { return r.Source },
+	"provider": func(r *UsageRecord) string { return r.Provider },
+	"channel": func(r *UsageRecord) string {
+		if r.ChannelID == "" {
+			return ""
+		}
+		return r.Provider + ":" + r.ChannelID
+	},
+	"user": func(r *UsageRecord) string {
+		if r.UserID == "" {
+			return ""
+		}
+		return AccountKey(r.Provider, r.UserID)
+	},
+	"person":   func(r *UsageRecord) string { return r.Person },
+	"schedule": func(r *UsageRecord) string { return r.Schedule },
+	"model":    func(r *UsageRecord) string { return r.Model },
+}
+
+// BudgetReporter reports how much of each usage budget is used.
+type BudgetReporter interface {
+	BudgetStatuses(now time.Time) ([]BudgetStatus, error)
+}
+
+// BudgetStatus is a usage budget and how much of it is used.
+type BudgetStatus struct {
+	Scope     string  `json:"scope"`         // "global", "user", "channel" or "schedule"
+	Key       string  `json:"key,omitempty"` // e.g. "discord:123", "person:Alice" or a schedule name
+	Period    string  `json:"period"`        // "day" or "month"
+	Cost      float64 `json:"cost"`
+	Tokens    int     `json:"tokens"`
+	MaxCost   float64 `json:"max_cost,omitempty"`
+	MaxTokens int     `json:"max_tokens,omitempty"`
+	Action    string  `json:"action"` // "block" or "downgrade"
+	Exceeded  bool    `json:"exceeded"`
+}
+
+// UsageGroup is the usage of one group in a report.
+type UsageGroup struct {
+	Key string `json:"key"`
+	UsageTotals
+}
+
+// UsageHandlers handles HTTP requests for usage reports.
+type UsageHandlers struct {
+	store    *UsageStore
+	reporter BudgetReporter
+}
+
+// NewUsageHandlers creates new usage handlers.
+func NewUsageHandlers(store *UsageStore) *UsageHandlers {
+	return &UsageHandlers{store: store}
+}
+
+// SetReporter sets the budget reporter (called after orchestrator is created).
+func (h *UsageHandlers) SetReporter(r BudgetReporter) {
+	h.reporter = r
+}
+
+// Report handles GET /api/usage. period is "day" or "month" (the default),
+// date picks which one (YYYY-MM-DD or YYYY-MM, default the current one), and
+// group_by splits the totals by source, provider, channel, user, person,
+// schedule or model.
+func (h *UsageHandlers) Report(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	now := time.Now().UTC()
+	period := q.Get("period")
+	if period == "" {
+		period = "month"
+	}
+	var from, to time.Time
+	switch period {
+	case "day":
+		from = DayStart(now)
+		if date := q.Get("date"); date != "" {
+			d, err := time.Parse("2006-01-02", date)
+			if err != nil {
+				http.Error(w, `{"error":"bad_request","message":"date must be YYYY-MM-DD for a day"}`, http.StatusBadRequest)
+				return
+			}
+			from = d
+		}
+		to = from.AddDate(0, 0, 1)
+	case "month":
+		from = MonthStart(now)
+		if date := q.Get("date"); date != "" {
+			d, err := time.Parse("2006-01", date)
+			if err != nil {
+				http.Error(w, `{"error":"bad_request","message":"date must be YYYY-MM for a month"}`, http.StatusBadRequest)
+				return
+			}
+			from = d
+		}
+		to = from.AddDate(0, 1, 0)
+	default:
+		http.Error(w, `{"error":"bad_request","message":"period must be day or month"}`, http.StatusBadRequest)
+		return
+	}
+
+	groupBy := q.Get("group_by")
+	if groupBy == "" {
+		groupBy = "source"
+	}
+	keyOf, ok := usageGroupings[groupBy]
+	if !ok {
+		http.Error(w, `{"error":"bad_request","message":"group_by must be source, provider, channel, user, person, schedule or model"}`, http.StatusBadRequest)
+		return
+	}
+
+	records, err := h.store.Between(from, to)
+	if err != nil {
+		http.Error(w, `{"error":"internal","message":"Failed to read usage ledger"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var total UsageTotals
+	groups := make(map[string]*UsageGroup)
+	for _, rec := range records {
+		total.Add(rec)
+		key := keyOf(rec)
+		g, ok := groups[key]
+		if !ok {
+			g = &UsageGroup{Key: key}
+			groups[key] = g
+		}
+		g.Add(rec)
+	}
+	list := make([]*UsageGroup, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Cost != list[j].Cost {
+			return list[i].Cost > list[j].Cost
+		}
+		if list[i].Tokens != list[j].Tokens {
+			return list[i].Tokens > list[j].Tokens
+		}
+		return list[i].Key < list[j].Key
+	})
+
+	budgets := []BudgetStatus{}
+	if h.reporter != nil {
+		if budgets, err = h.reporter.BudgetStatuses(now); err != nil {
+			http.Error(w, `{"error":"internal","message":"Failed to check budgets"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"period":   period,
+		"from":     from,
+		"to":       to,
+		"group_by": groupBy,
+		"total":    total,
+		"groups":   list,
+		"budgets":  budgets,
+	})
+}
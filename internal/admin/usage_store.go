@@ -0,0 +1,185 @@
+package admin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Usage sources, recorded on each UsageRecord.
+const (
+	UsageChat     = "chat"     // a message from a chat provider
+	UsageSchedule = "schedule" // an agent schedule run
+	UsageAdmin    = "admin"    // a message sent from the admin UI
+)
+
+// UsageRecord is one engine turn in the usage ledger.
+type UsageRecord struct {
+	At               time.Time `json:"at"`
+	Source           string    `json:"source"` // one of the Usage constants
+	SessionID        string    `json:"session_id"`
+	Model            string    `json:"model,omitempty"`
+	Downgraded       bool      `json:"downgraded,omitempty"` // Answered by the downgrade model because a budget was used up
+	Provider         string    `json:"provider,omitempty"`
+	ChannelID        string    `json:"channel_id,omitempty"`
+	UserID           string    `json:"user_id,omitempty"`
+	Person           string    `json:"person,omitempty"` // Name of the person the user belongs to
+	ScheduleID       string    `json:"schedule_id,omitempty"`
+	Schedule         string    `json:"schedule,omitempty"` // Name of the schedule
+	InputTokens      int       `json:"input_tokens"`
+	OutputTokens     int       `json:"output_tokens"`
+	ReasoningTokens  int       `json:"reasoning_tokens"`
+	CacheReadTokens  int       `json:"cache_read_tokens"`
+	CacheWriteTokens int       `json:"cache_write_tokens"`
+	Cost             float64   `json:"cost"`
+}
+
+// Tokens returns the tokens that count against budgets: input, output and
+// reasoning. Cache reads and writes are already part of the cost.
+func (r *UsageRecord) Tokens() int {
+	return r.InputTokens + r.OutputTokens + r.ReasoningTokens
+}
+
+// UsageTotals sums usage records.
+type UsageTotals struct {
+	Turns            int     `json:"turns"`
+	Tokens           int     `json:"tokens"`
+	InputTokens      int     `json:"input_tokens"`
+	OutputTokens     int     `json:"output_tokens"`
+	ReasoningTokens  int     `json:"reasoning_tokens"`
+	CacheReadTokens  int     `json:"cache_read_tokens"`
+	CacheWriteTokens int     `json:"cache_write_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// Add adds a record to the totals.
+func (t *UsageTotals) Add(r *UsageRecord) {
+	t.Turns++
+	t.Tokens += r.Tokens()
+	t.InputTokens += r.InputTokens
+	t.OutputTokens += r.OutputTokens
+	t.ReasoningTokens += r.ReasoningTokens
+	t.CacheReadTokens += r.CacheReadTokens
+	t.CacheWriteTokens += r.CacheWriteTokens
+	t.Cost += r.Cost
+}
+
+// DayStart returns the start of the UTC day containing t. Budgets and
+// reports count days and months in UTC.
+func DayStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// MonthStart returns the start of the UTC month containing t.
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// UsageStore is the usage ledger. Each UTC month has its own append-only
+// JSON Lines file in <DataDir>/usage, oldest turn first.
+type UsageStore struct {
+	mu      sync.RWMutex
+	dataDir string
+}
+
+// NewUsageStore creates a new usage store.
+func NewUsageStore(dataDir string) *UsageStore {
+	return &UsageStore{dataDir: dataDir}
+}
+
+func (s *UsageStore) dir() string {
+	return filepath.Join(s.dataDir, "usage")
+}
+
+func (s *UsageStore) monthPath(t time.Time) string {
+	return filepath.Join(s.dir(), t.UTC().Format("2006-01")+".jsonl")
+}
+
+// Append adds a turn to the ledger. The time is set if zero.
+func (s *UsageStore) Append(rec *UsageRecord) error {
+	if rec.At.IsZero() {
+		rec.At = time.Now().UTC()
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir(), 0750); err != nil {
+		return fmt.Errorf("failed to create usage dir: %w", err)
+	}
+	f, err := os.OpenFile(s.monthPath(rec.At), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write usage: %w", err)
+	}
+	return nil
+}
+
+// Between returns the turns recorded from from up to but not including to,
+// oldest first.
+func (s *UsageStore) Between(from, to time.Time) ([]*UsageRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := []*UsageRecord{}
+	for month := MonthStart(from); month.Before(to); month = month.AddDate(0, 1, 0) {
+		recs, err := readUsage(s.monthPath(month))
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range recs {
+			if !r.At.Before(from) && r.At.Before(to) {
+				records = append(records, r)
+			}
+		}
+	}
+	return records, nil
+}
+
+// readUsage reads a month of the ledger. A missing file is an empty month;
+// a torn last line (from a crash mid-append) is skipped.
+func readUsage(path string) ([]*UsageRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	defer f.Close()
+
+	var records []*UsageRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var rec UsageRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			continue
+		}
+		records = append(records, &rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsageStore_Between(t *testing.T) {
+	dir := t.TempDir()
+	store := NewUsageStore(dir)
+
+	lastMonth := time.Date(2026, 9, 30, 23, 0, 0, 0, time.UTC)
+	today := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	for _, rec := range []*UsageRecord{
+		{At: lastMonth, Source: UsageChat, InputTokens: 10, Cost: 1},
+		{At: today.Add(-24 * time.Hour), Source: UsageChat, InputTokens: 20, Cost: 2},
+		{At: today, Source: UsageSchedule, InputTokens: 30, OutputTokens: 5, ReasoningTokens: 5, CacheReadTokens: 100, Cost: 3},
+	} {
+		if err := store.Append(rec); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	// Each month has its own file
+	for _, name := range []string{"2026-09.jsonl", "2026-10.jsonl"} {
+		if _, err := os.Stat(filepath.Join(dir, "usage", name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+
+	month, err := store.Between(MonthStart(today), today.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Between: %v", err)
+	}
+	if len(month) != 2 {
+		t.Fatalf("expected 2 turns this month, got %d", len(month))
+	}
+
+	day, _ := store.Between(DayStart(today), today.Add(time.Hour))
+	var totals UsageTotals
+	for _, rec := range day {
+		totals.Add(rec)
+	}
+	if totals.Turns != 1 || totals.Tokens != 40 || totals.CacheReadTokens != 100 || totals.Cost != 3 {
+		t.Errorf("unexpected totals for today %+v", totals)
+	}
+
+	all, _ := store.Between(lastMonth, today.Add(time.Hour))
+	if len(all) != 3 {
+		t.Errorf("expected turns across months, got %d", len(all))
+	}
+}
@@ -20,6 +20,8 @@ func CheckAuth(engineType string) AuthStatus {
 	switch engineType {
 	case "opencode":
 		return CheckOpenCodeAuth()
+	case "openai":
+		return CheckOpenAIAuth()
 	default:
 		return AuthStatus{
 			EngineType: engineType,
@@ -81,3 +83,15 @@ func CheckOpenCodeAuth() AuthStatus {
 	return status
 }
 
+// CheckOpenAIAuth checks credentials for the OpenAI-compatible engine. Local
+// servers (llama.cpp, vLLM, Ollama) usually need no key, so a missing
+// OPENAI_API_KEY is not an error; engine.api_key in the config also works.
+func CheckOpenAIAuth() AuthStatus {
+	status := AuthStatus{EngineType: "openai", Authenticated: true}
+	if os.Getenv("OPENAI_API_KEY") != "" {
+		status.Method = "env"
+	} else {
+		status.Method = "none"
+	}
+	return status
+}
@@ -40,3 +40,17 @@ func TestCheckOpenCodeAuth_OpenAIKey(t *testing.T) {
 		t.Errorf("expected method 'env', got %q", status.Method)
 	}
 }
+
+func TestCheckAuth_OpenAI(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	status := CheckAuth("openai")
+	if !status.Authenticated || status.Method != "none" {
+		t.Errorf("without key: got %+v, want authenticated with method none", status)
+	}
+
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	status = CheckAuth("openai")
+	if !status.Authenticated || status.Method != "env" {
+		t.Errorf("with key: got %+v, want authenticated with method env", status)
+	}
+}
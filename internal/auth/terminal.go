@@ -40,26 +40,13 @@ func StartTerminalSession(ctx context.Context, engineType string) (*TerminalSess
 		old.Close()
 	}
 
-	var cmdName string
-	var cmdArgs []string
-
-	switch engineType {
-	case "opencode":
-		cmdName = "opencode"
-		cmdArgs = []string{"auth", "login"}
-	default:
-		return nil, fmt.Errorf("unsupported engine type: %s", engineType)
-	}
-
 	ctx, cancel := context.WithCancel(ctx)
-	cmd := exec.CommandContext(ctx, cmdName, cmdArgs...)
-	// Ensure HOME is set correctly — Bun/OpenCode reads the passwd entry which
-	// may be /nonexistent for system users, even if the HOME env var is set.
-	home := os.Getenv("HOME")
-	if home == "" || home == "/nonexistent" {
-		home = "/home/openpact-system"
+	cmd, err := LoginCommand(ctx, engineType)
+	if err != nil {
+		cancel()
+		return nil, err
 	}
-	cmd.Env = append(os.Environ(), "TERM=xterm-256color", "HOME="+home)
+	cmd.Env = append(cmd.Env, "TERM=xterm-256color")
 
 	// Start command in a PTY
 	ptmx, err := pty.Start(cmd)
@@ -145,3 +132,29 @@ func (s *TerminalSession) Wait() error {
 
 	return err
 }
+
+// LoginCommand builds the interactive login command for the given engine type.
+// The returned command is not started; callers attach it to a PTY (admin UI)
+// or directly to the terminal (openpact auth).
+func LoginCommand(ctx context.Context, engineType string) (*exec.Cmd, error) {
+	var cmdName string
+	var cmdArgs []string
+
+	switch engineType {
+	case "opencode":
+		cmdName = "opencode"
+		cmdArgs = []string{"auth", "login"}
+	default:
+		return nil, fmt.Errorf("unsupported engine type: %s", engineType)
+	}
+
+	cmd := exec.CommandContext(ctx, cmdName, cmdArgs...)
+	// Ensure HOME is set correctly — Bun/OpenCode reads the passwd entry which
+	// may be /nonexistent for system users, even if the HOME env var is set.
+	home := os.Getenv("HOME")
+	if home == "" || home == "/nonexistent" {
+		home = "/home/openpact-system"
+	}
+	cmd.Env = append(os.Environ(), "HOME="+home)
+	return cmd, nil
+}
@@ -0,0 +1,52 @@
+package chat
+
+import "strings"
+
+// Action ID prefixes of the buttons on an approval prompt.
+const (
+	approveActionPrefix = "openpact_approve:"
+	denyActionPrefix    = "openpact_deny:"
+)
+
+// ApprovalPrompt asks a tool approver whether a tool call may run. Providers
+// show Text with Approve and Deny buttons.
+type ApprovalPrompt struct {
+	ID   string // Approval ID, echoed back to the ApprovalHandler
+	Text string // Description of the tool call
+}
+
+// ApprovalHandler is called when a user presses a button on an approval
+// prompt. It returns the text to replace the prompt with, or an error to
+// show the user (who may not be allowed to answer, or too late).
+type ApprovalHandler func(provider, userID, approvalID string, approved bool) (result string, err error)
+
+// ApprovalPrompter is implemented by providers that can post approval
+// prompts with buttons: Discord buttons, Slack interactive blocks and
+// Telegram inline keyboards.
+type ApprovalPrompter interface {
+	// SendApprovalPrompt posts prompt to target. Target format is the same
+	// as for Provider.SendMessage.
+	SendApprovalPrompt(target string, prompt ApprovalPrompt) error
+
+	// SetApprovalHandler registers the callback for button presses.
+	SetApprovalHandler(h ApprovalHandler)
+}
+
+// ApprovalActionID returns the button action ID for answering an approval.
+func ApprovalActionID(approvalID string, approved bool) string {
+	if approved {
+		return approveActionPrefix + approvalID
+	}
+	return denyActionPrefix + approvalID
+}
+
+// ParseApprovalAction parses a button action ID made by ApprovalActionID.
+func ParseApprovalAction(actionID string) (approvalID string, approved, ok bool) {
+	if id, found := strings.CutPrefix(actionID, approveActionPrefix); found {
+		return id, true, true
+	}
+	if id, found := strings.CutPrefix(actionID, denyActionPrefix); found {
+		return id, false, true
+	}
+	return "", false, false
+}
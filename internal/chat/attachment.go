@@ -0,0 +1,59 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Attachment is a file sent with a chat message. Providers fill in what the
+// platform reports and a Fetch function; nothing is downloaded until the
+// orchestrator has checked the attachment against the inbox limits.
+type Attachment struct {
+	Name        string // File name as sent (untrusted)
+	ContentType string // MIME type reported by the platform (untrusted; may be empty)
+	Size        int64  // Size reported by the platform (0 if unknown)
+
+	// Fetch writes the file contents to w. It must stop when ctx is done.
+	Fetch func(ctx context.Context, w io.Writer) error
+}
+
+// FileSender is implemented by providers that can upload files, used by the
+// chat_send tool to send workspace files back to users.
+type FileSender interface {
+	// SendFile uploads the file at path to target, with an optional caption.
+	// Target format is the same as for Provider.SendMessage.
+	SendFile(target, path, caption string) error
+}
+
+// downloadClient bounds attachment downloads; Fetch callers also pass a
+// context with the turn's deadline.
+var downloadClient = &http.Client{Timeout: 2 * time.Minute}
+
+// FetchURL returns a Fetch function that downloads url with an HTTP GET,
+// sending the given headers (e.g. a bot token for Slack's private URLs).
+func FetchURL(url string, header http.Header) func(ctx context.Context, w io.Writer) error {
+	return func(ctx context.Context, w io.Writer) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		resp, err := downloadClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("download failed: %s", resp.Status)
+		}
+		_, err = io.Copy(w, resp.Body)
+		return err
+	}
+}
@@ -0,0 +1,282 @@
+package chat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultAllowedTypes are the attachment types accepted when none are
+// configured: what current models can read, plus voice notes.
+var DefaultAllowedTypes = []string{"image/*", "audio/*", "text/*", "application/pdf"}
+
+// maxNameLen caps the length of stored file names.
+const maxNameLen = 80
+
+// sniffedTypes are types that http.DetectContentType always recognises. An
+// attachment claiming one of them must also sniff as one, so an executable
+// cannot be passed off as an image by its name alone.
+var sniffedTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/bmp":       true,
+	"application/pdf": true,
+}
+
+var errTooLarge = errors.New("file too large")
+
+// InboxConfig limits the attachments accepted into the inbox.
+type InboxConfig struct {
+	MaxSize      int64    // Max bytes per file (0 = unlimited)
+	MaxFiles     int      // Max attachments per message (0 = unlimited)
+	AllowedTypes []string // MIME types; "image/*" matches a whole family
+}
+
+// SavedFile is an attachment stored in the inbox.
+type SavedFile struct {
+	Name string // Sanitised original file name
+	Path string // Absolute path of the stored copy
+	MIME string // Type detected from the content
+	Size int64
+}
+
+// Inbox stores chat attachments in a quarantine directory under ai-data.
+// Downloads are capped at the size limit, the type is taken from the content
+// rather than the sender's claim, and files get a generated name and mode
+// 0640, so nothing saved there is executable or can overwrite other files.
+type Inbox struct {
+	dir string
+	cfg InboxConfig
+	now func() time.Time
+}
+
+// NewInbox creates an inbox rooted at dir.
+func NewInbox(dir string, cfg InboxConfig) *Inbox {
+	if len(cfg.AllowedTypes) == 0 {
+		cfg.AllowedTypes = DefaultAllowedTypes
+	}
+	return &Inbox{dir: dir, cfg: cfg, now: time.Now}
+}
+
+// Dir returns the inbox root directory.
+func (in *Inbox) Dir() string {
+	return in.dir
+}
+
+// Save downloads attachments into <dir>/<provider>/<date>/. Attachments that
+// break a limit or fail to download are skipped and described in rejected,
+// so the AI can tell the user what happened. A nil *Inbox (attachments
+// disabled) rejects everything.
+func (in *Inbox) Save(ctx context.Context, provider string, atts []Attachment) (saved []SavedFile, rejected []string) {
+	if in == nil {
+		for _, a := range atts {
+			rejected = append(rejected, sanitizeFileName(a.Name)+": attachments are disabled")
+		}
+		return nil, rejected
+	}
+
+	now := in.now()
+	dir := filepath.Join(in.dir, sanitizeFileName(provider), now.Format("2006-01-02"))
+
+	for i, a := range atts {
+		name := sanitizeFileName(a.Name)
+		if in.cfg.MaxFiles > 0 && i >= in.cfg.MaxFiles {
+			rejected = append(rejected, fmt.Sprintf("%s: only %d attachments per message are accepted", name, in.cfg.MaxFiles))
+			continue
+		}
+		f, err := in.save(ctx, dir, name, now, a)
+		if err != nil {
+			rejected = append(rejected, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		saved = append(saved, *f)
+	}
+	return saved, rejected
+}
+
+func (in *Inbox) save(ctx context.Context, dir, name string, now time.Time, a Attachment) (*SavedFile, error) {
+	if in.cfg.MaxSize > 0 && a.Size > in.cfg.MaxSize {
+		return nil, fmt.Errorf("too large (%s, limit %s)", FormatSize(a.Size), FormatSize(in.cfg.MaxSize))
+	}
+	if a.Fetch == nil {
+		return nil, errors.New("cannot be downloaded")
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create inbox directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".incoming-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	w := &limitedWriter{w: tmp, remaining: in.cfg.MaxSize}
+	fetchErr := a.Fetch(ctx, w)
+	closeErr := tmp.Close()
+	if errors.Is(fetchErr, errTooLarge) {
+		return nil, fmt.Errorf("too large (limit %s)", FormatSize(in.cfg.MaxSize))
+	}
+	if fetchErr != nil {
+		return nil, fmt.Errorf("download failed: %w", fetchErr)
+	}
+	if closeErr != nil {
+		return nil, fmt.Errorf("failed to write file: %w", closeErr)
+	}
+
+	head, err := readHead(tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+	mimeType := detectType(head, a.ContentType, name)
+	if !typeAllowed(mimeType, in.cfg.AllowedTypes) {
+		return nil, fmt.Errorf("type %s is not accepted", mimeType)
+	}
+
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	path := filepath.Join(dir, now.Format("150405")+"-"+hex.EncodeToString(suffix)+"-"+name)
+	if err := os.Chmod(tmp.Name(), 0640); err != nil {
+		return nil, fmt.Errorf("failed to set file mode: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+
+	return &SavedFile{Name: name, Path: path, MIME: mimeType, Size: w.written}, nil
+}
+
+// limitedWriter fails with errTooLarge once more than remaining bytes have
+// been written, so an oversized download is cut off rather than stored.
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64 // 0 = unlimited
+	written   int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.remaining > 0 && l.written+int64(len(p)) > l.remaining {
+		return 0, errTooLarge
+	}
+	n, err := l.w.Write(p)
+	l.written += int64(n)
+	return n, err
+}
+
+// readHead returns up to the first 512 bytes of a file, enough for
+// http.DetectContentType.
+func readHead(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// detectType determines an attachment's MIME type from its first bytes. The
+// claimed type (reported by the platform, or guessed from the file name) is
+// used only where it refines the sniffed type, or where the content is not
+// recognised and the claim is not one the sniffer would have confirmed.
+func detectType(head []byte, declared, name string) string {
+	claimed := baseType(declared)
+	if claimed == "" {
+		claimed = baseType(mime.TypeByExtension(strings.ToLower(filepath.Ext(name))))
+	}
+	sniffed := baseType(http.DetectContentType(head))
+
+	switch {
+	case sniffed == "application/octet-stream":
+		if claimed == "" || sniffedTypes[claimed] || strings.HasPrefix(claimed, "text/") {
+			return sniffed
+		}
+		return claimed
+	case sniffed == "text/plain" && strings.HasPrefix(claimed, "text/"):
+		return claimed // e.g. text/markdown, text/csv
+	case claimed != "" && subType(sniffed) == subType(claimed):
+		return claimed // e.g. audio/ogg sniffed as application/ogg
+	}
+	return sniffed
+}
+
+// typeAllowed reports whether mimeType matches one of the allowed patterns.
+func typeAllowed(mimeType string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if pattern == mimeType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasSuffix(prefix, "/") && strings.HasPrefix(mimeType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// baseType strips parameters such as "; charset=utf-8" from a MIME type.
+func baseType(t string) string {
+	if i := strings.IndexByte(t, ';'); i >= 0 {
+		t = t[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(t))
+}
+
+func subType(t string) string {
+	if i := strings.IndexByte(t, '/'); i >= 0 {
+		return t[i+1:]
+	}
+	return t
+}
+
+// sanitizeFileName reduces a sender-supplied name to a safe base name of
+// letters, digits, dots, dashes and underscores.
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	name = strings.TrimLeft(b.String(), ".")
+	if name == "" || name == "_" {
+		name = "file"
+	}
+	if len(name) > maxNameLen {
+		ext := filepath.Ext(name)
+		if len(ext) > 10 {
+			ext = ""
+		}
+		name = name[:maxNameLen-len(ext)] + ext
+	}
+	return name
+}
+
+// FormatSize formats a byte count for messages, e.g. "2.5 MB".
+func FormatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
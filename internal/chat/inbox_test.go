@@ -0,0 +1,156 @@
+package chat
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pngHeader is the start of a PNG file, enough for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func bytesAttachment(name, contentType string, data []byte) Attachment {
+	return Attachment{
+		Name:        name,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Fetch: func(ctx context.Context, w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		},
+	}
+}
+
+func newTestInbox(t *testing.T, cfg InboxConfig) *Inbox {
+	t.Helper()
+	in := NewInbox(t.TempDir(), cfg)
+	in.now = func() time.Time { return time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC) }
+	return in
+}
+
+func TestInboxSave(t *testing.T) {
+	in := newTestInbox(t, InboxConfig{MaxSize: 1024})
+
+	saved, rejected := in.Save(context.Background(), "discord", []Attachment{
+		bytesAttachment("../../My Photo.png", "image/png", pngHeader),
+		bytesAttachment("notes.md", "text/markdown; charset=utf-8", []byte("# Notes\n")),
+	})
+	if len(rejected) != 0 {
+		t.Fatalf("rejected = %v", rejected)
+	}
+	if len(saved) != 2 {
+		t.Fatalf("saved = %d files, want 2", len(saved))
+	}
+
+	photo := saved[0]
+	if photo.Name != "My_Photo.png" || photo.MIME != "image/png" || photo.Size != int64(len(pngHeader)) {
+		t.Errorf("photo = %+v", photo)
+	}
+	wantDir := filepath.Join(in.Dir(), "discord", "2026-03-14")
+	if filepath.Dir(photo.Path) != wantDir || !strings.HasSuffix(photo.Path, "-My_Photo.png") {
+		t.Errorf("photo stored at %s, want under %s", photo.Path, wantDir)
+	}
+	info, err := os.Stat(photo.Path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+
+	if saved[1].MIME != "text/markdown" {
+		t.Errorf("notes.md type = %s, want text/markdown", saved[1].MIME)
+	}
+
+	// No temp files are left behind
+	entries, _ := os.ReadDir(wantDir)
+	if len(entries) != 2 {
+		t.Errorf("inbox contains %d entries, want 2", len(entries))
+	}
+}
+
+func TestInboxSaveRejects(t *testing.T) {
+	in := newTestInbox(t, InboxConfig{MaxSize: 64, MaxFiles: 3})
+
+	streamedTooLarge := bytesAttachment("big.txt", "text/plain", []byte(strings.Repeat("a", 100)))
+	streamedTooLarge.Size = 0 // size not reported; cut off while downloading
+
+	saved, rejected := in.Save(context.Background(), "telegram", []Attachment{
+		bytesAttachment("setup.png", "image/png", []byte("MZ\x90\x00\x03\x00\x00\x00")), // executable posing as an image
+		bytesAttachment("huge.png", "image/png", make([]byte, 65)),
+		streamedTooLarge,
+		bytesAttachment("extra.png", "image/png", pngHeader),
+	})
+	if len(saved) != 0 {
+		t.Errorf("saved = %+v, want none", saved)
+	}
+
+	want := []string{
+		"setup.png: type application/octet-stream is not accepted",
+		"huge.png: too large",
+		"big.txt: too large",
+		"extra.png: only 3 attachments per message are accepted",
+	}
+	if len(rejected) != len(want) {
+		t.Fatalf("rejected = %v", rejected)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(rejected[i], prefix) {
+			t.Errorf("rejected[%d] = %q, want prefix %q", i, rejected[i], prefix)
+		}
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(in.Dir(), "telegram", "2026-03-14"))
+	if len(entries) != 0 {
+		t.Errorf("rejected files left in inbox: %v", entries)
+	}
+}
+
+func TestInboxNilRejectsAll(t *testing.T) {
+	var in *Inbox
+	saved, rejected := in.Save(context.Background(), "slack", []Attachment{bytesAttachment("a.png", "image/png", pngHeader)})
+	if len(saved) != 0 || len(rejected) != 1 || !strings.Contains(rejected[0], "disabled") {
+		t.Errorf("saved = %v, rejected = %v", saved, rejected)
+	}
+}
+
+func TestDetectType(t *testing.T) {
+	tests := []struct {
+		name     string
+		declared string
+		head     []byte
+		want     string
+	}{
+		{"photo.jpg", "image/jpeg", pngHeader, "image/png"}, // content wins
+		{"voice.ogg", "audio/ogg", []byte("OggS\x00\x02"), "audio/ogg"},
+		{"clip.m4a", "audio/mp4", []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"), "audio/mp4"},
+		{"data.csv", "text/csv", []byte("a,b\n1,2\n"), "text/csv"},
+		{"readme", "", []byte("hello"), "text/plain"},
+		{"fake.pdf", "application/pdf", []byte{0x7f, 'E', 'L', 'F', 0x02}, "application/octet-stream"},
+		{"fake.txt", "text/plain", []byte{0x00, 0x01, 0x02, 0x03}, "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		if got := detectType(tt.head, tt.declared, tt.name); got != tt.want {
+			t.Errorf("detectType(%s, %q) = %s, want %s", tt.name, tt.declared, got, tt.want)
+		}
+	}
+}
+
+func TestTypeAllowed(t *testing.T) {
+	allowed := []string{"image/*", "application/pdf"}
+	for mime, want := range map[string]bool{
+		"image/png":                true,
+		"application/pdf":          true,
+		"application/pdf-evil":     false,
+		"imagex/png":               false,
+		"application/octet-stream": false,
+	} {
+		if got := typeAllowed(mime, allowed); got != want {
+			t.Errorf("typeAllowed(%s) = %v, want %v", mime, got, want)
+		}
+	}
+}
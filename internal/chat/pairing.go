@@ -0,0 +1,17 @@
+package chat
+
+// PairingHandler is called when a user who isn't on a provider's allowlist
+// messages the bot directly. It returns the reply to send them, or "" to
+// ignore the message.
+type PairingHandler func(provider, channelID, userID, userName string) string
+
+// PairingProvider is implemented by providers that let unknown users ask for
+// access, and whose allowlist can be changed while they run.
+type PairingProvider interface {
+	// SetPairingHandler registers the callback for direct messages from
+	// users who aren't allowed.
+	SetPairingHandler(h PairingHandler)
+
+	// SetAllowedUsers replaces the allowlist.
+	SetAllowedUsers(users []string)
+}
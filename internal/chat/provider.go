@@ -1,6 +1,8 @@
 // Package chat defines the generic chat provider interface for multi-platform messaging.
 package chat
 
+import "github.com/open-pact/openpact/internal/logging"
+
 // Detail mode constants control what gets included in chat responses.
 const (
 	ModeSimple   = "simple"   // Text only (default)
@@ -31,11 +33,24 @@ type ChatResponse struct {
 	Text      string         // Plain text response (always present)
 	Thinking  string         // Thinking/reasoning content (if collected)
 	ToolCalls []ToolCallInfo // Tool calls made during the response
+
+	CorrelationID string // Correlation ID of the turn, for provider-side logging
+}
+
+// ResponseLogger returns l annotated with the response's correlation ID, so a
+// provider's delivery errors can be matched with the orchestrator's turn logs.
+func ResponseLogger(l *logging.Logger, resp *ChatResponse) *logging.Logger {
+	if resp == nil || resp.CorrelationID == "" {
+		return l
+	}
+	return l.WithField(logging.CorrelationField, resp.CorrelationID)
 }
 
 // MessageHandler is called when a chat message is received from a user.
 // The provider name is included so the orchestrator knows the source.
-type MessageHandler func(provider, channelID, userID, content string) (response *ChatResponse, err error)
+// Attachments holds any files sent with the message; content may be empty
+// when a file is sent without a caption.
+type MessageHandler func(provider, channelID, userID, content string, attachments []Attachment) (response *ChatResponse, err error)
 
 // CommandHandler is called when a slash/bot command is received.
 type CommandHandler func(provider, channelID, userID, command, args string) (response string, err error)
@@ -62,3 +77,24 @@ type Provider interface {
 	// Target format is provider-specific but should support "user:<id>" prefix for DMs.
 	SendMessage(target, content string) error
 }
+
+// ResponseSender is implemented by providers that can deliver a full
+// ChatResponse outside a conversation turn, split to the platform's message
+// limit and with thinking and tool blocks rendered as in replies. Scheduled
+// agent jobs use it; other providers get the text via SendMessage.
+type ResponseSender interface {
+	// SendResponse delivers resp to target. Target format is the same as
+	// for Provider.SendMessage.
+	SendResponse(target string, resp *ChatResponse) error
+}
+
+// PrivateReplier is implemented by providers that can tell whether the reply
+// to a command is seen only by the user who sent it: the command was sent in
+// a direct message, or the provider answers it ephemerally. Commands that
+// hand out or take secrets, like /link, are refused where others could see
+// them.
+type PrivateReplier interface {
+	// RepliesPrivately reports whether the reply to command, sent in
+	// channelID, is shown only to its sender.
+	RepliesPrivately(channelID, command string) bool
+}
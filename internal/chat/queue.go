@@ -0,0 +1,45 @@
+package chat
+
+import "sync"
+
+// Queue runs functions one at a time per key, in the order they were added,
+// and different keys concurrently. Providers queue messages by chat so that
+// each chat's turns run in order, while the event loop stays free to receive
+// approval button presses. The zero value is ready to use.
+type Queue struct {
+	mu      sync.Mutex
+	pending map[string][]func() // a key has a running worker while present
+}
+
+// Run queues fn behind the functions already queued for key.
+func (q *Queue) Run(key string, fn func()) {
+	q.mu.Lock()
+	if q.pending == nil {
+		q.pending = make(map[string][]func())
+	}
+	queued, running := q.pending[key]
+	q.pending[key] = append(queued, fn)
+	q.mu.Unlock()
+
+	if !running {
+		go q.drain(key)
+	}
+}
+
+// drain runs the functions queued for key until there are none left.
+func (q *Queue) drain(key string) {
+	for {
+		q.mu.Lock()
+		queued := q.pending[key]
+		if len(queued) == 0 {
+			delete(q.pending, key)
+			q.mu.Unlock()
+			return
+		}
+		fn := queued[0]
+		q.pending[key] = queued[1:]
+		q.mu.Unlock()
+
+		fn()
+	}
+}
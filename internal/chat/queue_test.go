@@ -0,0 +1,52 @@
+package chat
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	var q Queue
+	var mu sync.Mutex
+	var order []int
+
+	// The first function of chat A blocks; the rest of A waits behind it
+	release := make(chan struct{})
+	done := make(chan struct{}, 4)
+	q.Run("a", func() {
+		<-release
+		mu.Lock()
+		order = append(order, 1)
+		mu.Unlock()
+		done <- struct{}{}
+	})
+	for _, n := range []int{2, 3} {
+		n := n
+		q.Run("a", func() {
+			mu.Lock()
+			order = append(order, n)
+			mu.Unlock()
+			done <- struct{}{}
+		})
+	}
+
+	// Chat B runs meanwhile
+	ranB := make(chan struct{})
+	q.Run("b", func() { close(ranB) })
+	select {
+	case <-ranB:
+	case <-time.After(time.Second):
+		t.Fatal("expected another chat to run while the first is blocked")
+	}
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		<-done
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != 3 || order[0] != 1 || order[1] != 2 || order[2] != 3 {
+		t.Errorf("expected chat A to run in order, got %v", order)
+	}
+}
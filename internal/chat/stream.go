@@ -0,0 +1,199 @@
+package chat
+
+import (
+	"sync"
+	"time"
+
+	"github.com/open-pact/openpact/internal/logging"
+)
+
+// Placeholder is the text of the message posted before any reply text arrives.
+const Placeholder = "Thinking…"
+
+// DefaultEditInterval is the minimum time between edits of a live message.
+// Discord allows about 5 edits per 5 seconds per channel; Slack's chat.update
+// about 50 per minute.
+const DefaultEditInterval = 1500 * time.Millisecond
+
+// StreamUpdate is a snapshot of a reply while it is being generated.
+type StreamUpdate struct {
+	Text string // Full reply text so far (not a delta)
+	Tool string // Name of the tool currently running, if any
+}
+
+// Render formats the update for display in a live message.
+func (u StreamUpdate) Render() string {
+	text := u.Text
+	if u.Tool != "" {
+		if text != "" {
+			text += "\n\n"
+		}
+		text += "⏳ Running " + u.Tool + "…"
+	}
+	return text
+}
+
+// StreamFunc receives progress updates while a reply is generated.
+type StreamFunc func(update StreamUpdate)
+
+// StreamingMessageHandler is like MessageHandler, but reports progress through
+// onUpdate (which may be nil) as the reply is generated. The returned response
+// is the final reply.
+type StreamingMessageHandler func(provider, channelID, userID, content string, attachments []Attachment, onUpdate StreamFunc) (response *ChatResponse, err error)
+
+// StreamingProvider is implemented by providers that can edit a message they
+// posted, so replies are shown as they are written. Providers that cannot
+// edit only implement Provider and receive the final reply via MessageHandler.
+type StreamingProvider interface {
+	Provider
+
+	// SetStreamingMessageHandler registers the callback for incoming user
+	// messages. When set, it is used instead of the MessageHandler.
+	SetStreamingMessageHandler(h StreamingMessageHandler)
+}
+
+// LiveMessage edits a posted placeholder message as stream updates arrive.
+// Updates are coalesced and applied at most once per interval, so a fast
+// stream never exceeds the platform's edit rate limit. A nil *LiveMessage
+// ignores all calls, for providers that failed to post the placeholder.
+type LiveMessage struct {
+	edit     func(text string) error
+	interval time.Duration
+	maxLen   int
+	log      *logging.Logger
+
+	mu      sync.Mutex
+	pending string
+	shown   string
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewLiveMessage starts editing a message through edit. Text longer than
+// maxLen is truncated in the live view; the provider sends the complete reply
+// once the turn finishes.
+func NewLiveMessage(edit func(text string) error, interval time.Duration, maxLen int, logger *logging.Logger) *LiveMessage {
+	m := &LiveMessage{
+		edit:     edit,
+		interval: interval,
+		maxLen:   maxLen,
+		log:      logger,
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+// Update records the latest state of the reply. It never blocks on the
+// platform API. Its signature matches StreamFunc.
+func (m *LiveMessage) Update(u StreamUpdate) {
+	if m == nil {
+		return
+	}
+	text := truncateRunes(u.Render(), m.maxLen)
+	if text == "" {
+		return
+	}
+
+	m.mu.Lock()
+	m.pending = text
+	m.mu.Unlock()
+
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Stop stops editing and waits for an edit in flight to finish, so the
+// provider can safely replace the message with the final reply. Pending
+// updates are dropped.
+func (m *LiveMessage) Stop() {
+	if m == nil {
+		return
+	}
+	close(m.stop)
+	<-m.done
+}
+
+func (m *LiveMessage) run() {
+	defer close(m.done)
+
+	var last time.Time
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-m.wake:
+		}
+
+		if wait := m.interval - time.Since(last); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-m.stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		m.mu.Lock()
+		text := m.pending
+		changed := text != m.shown
+		m.mu.Unlock()
+		if !changed {
+			continue
+		}
+
+		if err := m.edit(text); err != nil {
+			m.log.Debug("Failed to edit live message: %v", err)
+		}
+		m.mu.Lock()
+		m.shown = text
+		m.mu.Unlock()
+		last = time.Now()
+	}
+}
+
+// KeepTyping calls send now and then every interval until the returned stop
+// function is called. Platforms expire typing indicators after a few seconds,
+// so they must be refreshed for the duration of a turn.
+func KeepTyping(send func() error, interval time.Duration, logger *logging.Logger) (stop func()) {
+	stopCh := make(chan struct{})
+	go func() {
+		if err := send(); err != nil {
+			logger.Debug("Error sending typing indicator: %v", err)
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				if err := send(); err != nil {
+					logger.Debug("Error sending typing indicator: %v", err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(stopCh) }) }
+}
+
+// truncateRunes shortens s to at most max runes, ending with an ellipsis if cut.
+func truncateRunes(s string, max int) string {
+	if max <= 0 {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
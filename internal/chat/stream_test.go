@@ -0,0 +1,71 @@
+package chat
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/open-pact/openpact/internal/logging"
+)
+
+func TestLiveMessageCoalescesEdits(t *testing.T) {
+	var mu sync.Mutex
+	var edits []string
+	live := NewLiveMessage(func(text string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		edits = append(edits, text)
+		return nil
+	}, 50*time.Millisecond, 0, logging.Standard())
+
+	// A burst of updates within one interval produces a single edit with the latest text
+	live.Update(StreamUpdate{Text: "H"})
+	time.Sleep(10 * time.Millisecond)
+	live.Update(StreamUpdate{Text: "He"})
+	live.Update(StreamUpdate{Text: "Hello"})
+	time.Sleep(120 * time.Millisecond)
+
+	// An unchanged update does not trigger an edit
+	live.Update(StreamUpdate{Text: "Hello"})
+	time.Sleep(80 * time.Millisecond)
+	live.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(edits) < 1 || len(edits) > 2 {
+		t.Fatalf("edits = %q, want 1-2 coalesced edits", edits)
+	}
+	if edits[len(edits)-1] != "Hello" {
+		t.Errorf("last edit = %q, want %q", edits[len(edits)-1], "Hello")
+	}
+	for i := 1; i < len(edits); i++ {
+		if edits[i] == edits[i-1] {
+			t.Errorf("duplicate edit %q", edits[i])
+		}
+	}
+}
+
+func TestLiveMessageTruncatesAndRendersTool(t *testing.T) {
+	edited := make(chan string, 1)
+	live := NewLiveMessage(func(text string) error {
+		edited <- text
+		return nil
+	}, time.Millisecond, 12, logging.Standard())
+	defer live.Stop()
+
+	live.Update(StreamUpdate{Text: "Checking", Tool: "web_fetch"})
+	select {
+	case got := <-edited:
+		if got != "Checking\n\n⏳…" {
+			t.Errorf("edit = %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no edit")
+	}
+}
+
+func TestNilLiveMessage(t *testing.T) {
+	var live *LiveMessage
+	live.Update(StreamUpdate{Text: "x"})
+	live.Stop()
+}
@@ -10,18 +10,24 @@ import (
 
 // Config holds all OpenPact configuration
 type Config struct {
-	Engine    EngineConfig     `yaml:"engine"`
-	Workspace WorkspaceConfig  `yaml:"workspace"`
-	Discord   DiscordConfig    `yaml:"discord"`
-	Telegram  TelegramConfig   `yaml:"telegram"`
-	Slack     SlackConfig      `yaml:"slack"`
-	GitHub    GitHubConfig     `yaml:"github"`
-	Calendars []CalendarConfig `yaml:"calendars"`
-	Vault     VaultConfig      `yaml:"vault"`
-	Starlark  StarlarkConfig   `yaml:"starlark"`
-	Logging   LoggingConfig    `yaml:"logging"`
-	Server    ServerConfig     `yaml:"server"`
-	Admin     AdminConfig      `yaml:"admin"`
+	Engine      EngineConfig      `yaml:"engine"`
+	Workspace   WorkspaceConfig   `yaml:"workspace"`
+	Discord     DiscordConfig     `yaml:"discord"`
+	Telegram    TelegramConfig    `yaml:"telegram"`
+	Slack       SlackConfig       `yaml:"slack"`
+	Attachments AttachmentsConfig `yaml:"attachments"`
+	GitHub      GitHubConfig      `yaml:"github"`
+	Calendars   []CalendarConfig  `yaml:"calendars"`
+	Vault       VaultConfig       `yaml:"vault"`
+	Starlark    StarlarkConfig    `yaml:"starlark"`
+	Scheduler   SchedulerConfig   `yaml:"scheduler"`
+	ToolPolicy  ToolPolicyConfig  `yaml:"tool_policy"`
+	Permissions PermissionsConfig `yaml:"permissions"`
+	Pairing     PairingConfig     `yaml:"pairing"`
+	Usage       UsageConfig       `yaml:"usage"`
+	Logging     LoggingConfig     `yaml:"logging"`
+	Server      ServerConfig      `yaml:"server"`
+	Admin       AdminConfig       `yaml:"admin"`
 }
 
 // AdminConfig configures the admin web UI
@@ -43,8 +49,18 @@ type ServerConfig struct {
 	RateLimit  RateLimitConfig `yaml:"rate_limit"`
 }
 
-// RateLimitConfig configures rate limiting
+// RateLimitConfig configures rate limiting. Rate/Burst apply to MCP tool
+// calls (one bucket per tool); User and Channel apply to incoming chat
+// messages. A rate of 0 disables that limit.
 type RateLimitConfig struct {
+	Rate    float64       `yaml:"rate"`    // Requests per second
+	Burst   int           `yaml:"burst"`   // Max burst size
+	User    RateLimitRule `yaml:"user"`    // Per provider user
+	Channel RateLimitRule `yaml:"channel"` // Per chat channel
+}
+
+// RateLimitRule is a token bucket rate/burst pair
+type RateLimitRule struct {
 	Rate  float64 `yaml:"rate"`  // Requests per second
 	Burst int     `yaml:"burst"` // Max burst size
 }
@@ -69,12 +85,14 @@ type CalendarConfig struct {
 
 // EngineConfig configures the AI engine
 type EngineConfig struct {
-	Type     string `yaml:"type"`     // "opencode"
+	Type     string `yaml:"type"`     // "opencode" or "openai"
 	Provider string `yaml:"provider"` // For OpenCode: "anthropic", "openai", "ollama", etc.
 	Model    string `yaml:"model"`    // Model name
 	Port     int    `yaml:"port"`     // Port for opencode serve (default: 4098)
 	Hostname string `yaml:"hostname"` // Hostname for opencode serve (default: 127.0.0.1)
 	Password string `yaml:"password"` // Optional OPENCODE_SERVER_PASSWORD
+	BaseURL  string `yaml:"base_url"` // For openai: API base URL (default: http://127.0.0.1:8080/v1)
+	APIKey   string `yaml:"api_key"`  // For openai: optional API key (default: $OPENAI_API_KEY)
 }
 
 // WorkspaceConfig configures workspace paths
@@ -102,6 +120,16 @@ func (w WorkspaceConfig) ScriptsDir() string {
 	return filepath.Join(w.Path, "ai-data", "scripts")
 }
 
+// InboxDir returns the path to the quarantine directory for chat attachments.
+func (w WorkspaceConfig) InboxDir() string {
+	return filepath.Join(w.Path, "ai-data", "inbox")
+}
+
+// PromptsDir returns the path to the MCP prompt templates directory.
+func (w WorkspaceConfig) PromptsDir() string {
+	return filepath.Join(w.Path, "ai-data", "prompts")
+}
+
 // EnsureDirs creates all required workspace directories if they don't exist.
 func (w WorkspaceConfig) EnsureDirs() error {
 	dirs := []string{
@@ -110,6 +138,8 @@ func (w WorkspaceConfig) EnsureDirs() error {
 		w.DataDir(),
 		w.AIDataDir(),
 		w.ScriptsDir(),
+		w.InboxDir(),
+		w.PromptsDir(),
 		filepath.Join(w.AIDataDir(), "memory"),
 		filepath.Join(w.AIDataDir(), "skills"),
 	}
@@ -141,11 +171,104 @@ type SlackConfig struct {
 	AllowedChans []string `yaml:"allowed_chans"` // Slack channel IDs allowed
 }
 
+// AttachmentsConfig limits the files accepted from chat messages. Accepted
+// files are saved to ai-data/inbox and passed to the engine.
+type AttachmentsConfig struct {
+	Enabled      bool     `yaml:"enabled"`       // Accept attachments
+	MaxSizeMB    int      `yaml:"max_size_mb"`   // Max size per file
+	MaxFiles     int      `yaml:"max_files"`     // Max attachments per message
+	AllowedTypes []string `yaml:"allowed_types"` // MIME types; "image/*" matches a family
+}
+
 // StarlarkConfig configures Starlark script limits
 type StarlarkConfig struct {
-	Enabled        bool  `yaml:"enabled"`          // Enable Starlark scripts
-	MaxExecutionMs int64 `yaml:"max_execution_ms"` // Max script runtime
-	MaxMemoryMB    int   `yaml:"max_memory_mb"`    // Max memory usage
+	Enabled        bool   `yaml:"enabled"`          // Enable Starlark scripts
+	MaxExecutionMs int64  `yaml:"max_execution_ms"` // Max script runtime
+	MaxMemoryMB    int    `yaml:"max_memory_mb"`    // Max memory a run may allocate for the values it builds
+	MaxSteps       uint64 `yaml:"max_steps"`        // Max Starlark computation steps per run
+
+	Exec StarlarkExecConfig `yaml:"exec"` // Capabilities for inline script_exec code
+}
+
+// StarlarkExecConfig grants capabilities to inline code run through the
+// script_exec tool, which has no manifest of its own. The zero value allows
+// no hosts and no secrets.
+type StarlarkExecConfig struct {
+	Hosts            []string `yaml:"hosts"`              // Hosts http.get/http.post may reach
+	Secrets          []string `yaml:"secrets"`            // Secrets secrets.get may read
+	MaxRuntimeMs     int64    `yaml:"max_runtime_ms"`     // Lowers max_execution_ms; 0 keeps it
+	MaxResponseBytes int64    `yaml:"max_response_bytes"` // Largest HTTP response body
+}
+
+// SchedulerConfig configures scheduled jobs
+type SchedulerConfig struct {
+	History ScheduleHistoryConfig `yaml:"history"`
+}
+
+// ScheduleHistoryConfig limits the run history kept per schedule in
+// secure/data/schedule_runs. A limit of 0 disables it.
+type ScheduleHistoryConfig struct {
+	MaxRuns    int `yaml:"max_runs"`     // Runs kept per schedule
+	MaxAgeDays int `yaml:"max_age_days"` // Days a run is kept
+}
+
+// ToolPolicyConfig sets which MCP tools run without asking. A policy is
+// "auto" (run), "confirm" (ask the owner in chat or the admin UI first) or
+// "deny" (never run).
+type ToolPolicyConfig struct {
+	Default        string            `yaml:"default"`         // Policy of tools not listed in Tools
+	Tools          map[string]string `yaml:"tools"`           // Policy by tool name
+	ConfirmTimeout int               `yaml:"confirm_timeout"` // Seconds to wait for an answer
+	Approvers      []string          `yaml:"approvers"`       // "provider:userID"s asked to answer confirm calls in chat
+}
+
+// PermissionsConfig gives chat users roles that limit the MCP tools used on
+// their behalf. Users without a role are not limited.
+type PermissionsConfig struct {
+	DefaultRole string                `yaml:"default_role"` // Role of users not listed in Users
+	Users       map[string]string     `yaml:"users"`        // Role by "provider:userID"
+	Roles       map[string]RoleConfig `yaml:"roles"`        // Roles by name
+}
+
+// RoleConfig lists the tools a role may use. Tool names and argument values
+// are matched as glob patterns, e.g. "vault_*".
+type RoleConfig struct {
+	Tools     []string                       `yaml:"tools"`      // Tools the role may call; empty allows all
+	DenyTools []string                       `yaml:"deny_tools"` // Tools the role may not call
+	Args      map[string]map[string][]string `yaml:"args"`       // Allowed argument values, by tool and argument
+}
+
+// PairingConfig lets users who aren't on a provider's allowlist ask for
+// access by messaging the bot directly. They get a one-time pairing code,
+// and the request waits for an approver or an admin.
+type PairingConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	Approvers  []string `yaml:"approvers"`   // "provider:userID"s notified of requests, who may answer them with /pair
+	CodeTTL    int      `yaml:"code_ttl"`    // Minutes a request waits for an answer
+	MaxPending int      `yaml:"max_pending"` // Requests waiting at once; further strangers are ignored
+}
+
+// UsageConfig sets budgets on the tokens and cost of engine turns, counted
+// per UTC day and month in the usage ledger. Budgets left at zero have no
+// limit.
+type UsageConfig struct {
+	Action          string                  `yaml:"action"`           // "block" or "downgrade": what happens to turns once a budget is used up
+	DowngradeModel  string                  `yaml:"downgrade_model"`  // "provider/model" that answers instead, for the downgrade action
+	Global          BudgetConfig            `yaml:"global"`           // All turns together
+	DefaultUser     BudgetConfig            `yaml:"default_user"`     // Each chat user or person not listed in Users
+	Users           map[string]BudgetConfig `yaml:"users"`            // By "provider:userID" or "person:<name>"
+	Channels        map[string]BudgetConfig `yaml:"channels"`         // By "provider:channelID"
+	DefaultSchedule BudgetConfig            `yaml:"default_schedule"` // Each schedule not listed in Schedules
+	Schedules       map[string]BudgetConfig `yaml:"schedules"`        // By schedule name
+}
+
+// BudgetConfig limits usage over a day and over a month.
+type BudgetConfig struct {
+	Action        string  `yaml:"action"`         // Overrides UsageConfig.Action for this budget
+	DailyCost     float64 `yaml:"daily_cost"`     // USD, as reported by the engine
+	MonthlyCost   float64 `yaml:"monthly_cost"`   // USD
+	DailyTokens   int     `yaml:"daily_tokens"`   // Input, output and reasoning tokens
+	MonthlyTokens int     `yaml:"monthly_tokens"` // Input, output and reasoning tokens
 }
 
 // Default returns a config with sensible defaults
@@ -166,10 +289,34 @@ func Default() *Config {
 		},
 		Telegram: TelegramConfig{Enabled: false},
 		Slack:    SlackConfig{Enabled: false},
+		Attachments: AttachmentsConfig{
+			Enabled:      true,
+			MaxSizeMB:    20,
+			MaxFiles:     10,
+			AllowedTypes: []string{"image/*", "audio/*", "text/*", "application/pdf"},
+		},
 		Starlark: StarlarkConfig{
 			Enabled:        true,
 			MaxExecutionMs: 30000, // 30 seconds
 			MaxMemoryMB:    128,
+			MaxSteps:       10_000_000,
+		},
+		Scheduler: SchedulerConfig{
+			History: ScheduleHistoryConfig{
+				MaxRuns:    100,
+				MaxAgeDays: 90,
+			},
+		},
+		ToolPolicy: ToolPolicyConfig{
+			Default:        "auto",
+			ConfirmTimeout: 300, // 5 minutes
+		},
+		Pairing: PairingConfig{
+			CodeTTL:    1440, // 1 day
+			MaxPending: 10,
+		},
+		Usage: UsageConfig{
+			Action: "block",
 		},
 		Logging: LoggingConfig{
 			Level: "info",
@@ -178,8 +325,10 @@ func Default() *Config {
 		Server: ServerConfig{
 			HealthAddr: ":8081",
 			RateLimit: RateLimitConfig{
-				Rate:  10,
-				Burst: 20,
+				Rate:    10,
+				Burst:   20,
+				User:    RateLimitRule{Rate: 0.2, Burst: 5}, // ~12 messages/minute
+				Channel: RateLimitRule{Rate: 0.5, Burst: 10},
 			},
 		},
 		Admin: AdminConfig{
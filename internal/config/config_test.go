@@ -32,6 +32,34 @@ func TestDefault(t *testing.T) {
 	if cfg.Starlark.MaxMemoryMB != 128 {
 		t.Errorf("expected Starlark max memory 128MB, got %d", cfg.Starlark.MaxMemoryMB)
 	}
+
+	if cfg.Starlark.MaxSteps != 10_000_000 {
+		t.Errorf("expected Starlark max steps 10000000, got %d", cfg.Starlark.MaxSteps)
+	}
+
+	if cfg.Scheduler.History.MaxRuns != 100 || cfg.Scheduler.History.MaxAgeDays != 90 {
+		t.Errorf("expected schedule history limits 100 runs / 90 days, got %+v", cfg.Scheduler.History)
+	}
+
+	if cfg.ToolPolicy.Default != "auto" || cfg.ToolPolicy.ConfirmTimeout != 300 {
+		t.Errorf("expected tools to run without approval by default, got %+v", cfg.ToolPolicy)
+	}
+
+	if cfg.Usage.Action != "block" || cfg.Usage.Global != (BudgetConfig{}) {
+		t.Errorf("expected no usage budgets, blocking turns once one is set and used up, got %+v", cfg.Usage)
+	}
+
+	if cfg.Pairing.Enabled || cfg.Pairing.CodeTTL != 1440 || cfg.Pairing.MaxPending != 10 {
+		t.Errorf("expected pairing to be off with a 1 day code TTL and 10 pending requests, got %+v", cfg.Pairing)
+	}
+
+	if cfg.Server.RateLimit.User.Rate <= 0 || cfg.Server.RateLimit.Channel.Rate <= 0 {
+		t.Error("expected per-user and per-channel chat rate limits to be enabled by default")
+	}
+
+	if !cfg.Attachments.Enabled || cfg.Attachments.MaxSizeMB <= 0 || len(cfg.Attachments.AllowedTypes) == 0 {
+		t.Errorf("expected attachments enabled with limits by default, got %+v", cfg.Attachments)
+	}
 }
 
 func TestLoadFromFile(t *testing.T) {
@@ -54,6 +82,30 @@ discord:
 starlark:
   max_execution_ms: 60000
   max_memory_mb: 256
+tool_policy:
+  tools:
+    chat_send: confirm
+    script_exec: deny
+usage:
+  action: downgrade
+  downgrade_model: anthropic/claude-3-5-haiku-latest
+  global:
+    monthly_cost: 50
+  schedules:
+    nightly:
+      action: block
+      daily_tokens: 200000
+permissions:
+  default_role: guest
+  users:
+    "discord:123456": owner
+  roles:
+    owner: {}
+    guest:
+      deny_tools: ["vault_*", "chat_send"]
+      args:
+        workspace_write:
+          path: ["guest/*"]
 `
 	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
 		t.Fatalf("failed to write test config: %v", err)
@@ -95,6 +147,23 @@ starlark:
 	if cfg.Starlark.MaxMemoryMB != 256 {
 		t.Errorf("expected max_memory_mb 256, got %d", cfg.Starlark.MaxMemoryMB)
 	}
+
+	if cfg.ToolPolicy.Default != "auto" || cfg.ToolPolicy.Tools["chat_send"] != "confirm" || cfg.ToolPolicy.Tools["script_exec"] != "deny" {
+		t.Errorf("expected tool policies to be loaded, got %+v", cfg.ToolPolicy)
+	}
+
+	usage := cfg.Usage
+	if usage.Action != "downgrade" || usage.Global.MonthlyCost != 50 || usage.Schedules["nightly"].DailyTokens != 200000 || usage.Schedules["nightly"].Action != "block" {
+		t.Errorf("expected usage budgets to be loaded, got %+v", usage)
+	}
+
+	perms := cfg.Permissions
+	if perms.DefaultRole != "guest" || perms.Users["discord:123456"] != "owner" || len(perms.Roles) != 2 {
+		t.Errorf("expected permissions to be loaded, got %+v", perms)
+	}
+	if guest := perms.Roles["guest"]; len(guest.DenyTools) != 2 || guest.Args["workspace_write"]["path"][0] != "guest/*" {
+		t.Errorf("unexpected guest role %+v", guest)
+	}
 }
 
 func TestLoadEnvOverride(t *testing.T) {
@@ -145,6 +214,8 @@ func TestEnsureDirs(t *testing.T) {
 		w.DataDir(),
 		w.AIDataDir(),
 		w.ScriptsDir(),
+		w.InboxDir(),
+		w.PromptsDir(),
 		filepath.Join(w.AIDataDir(), "memory"),
 		filepath.Join(w.AIDataDir(), "skills"),
 	}
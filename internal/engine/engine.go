@@ -1,16 +1,27 @@
 // Package engine provides an abstraction layer for AI coding agents.
-// Supports OpenCode as the backend.
+// Supports OpenCode and any OpenAI-compatible chat completions API as backends.
 package engine
 
 import (
 	"context"
 	"encoding/json"
+
+	"github.com/open-pact/openpact/internal/logging"
 )
 
 // Message represents a conversation message
 type Message struct {
-	Role    string `json:"role"`    // "user", "assistant", "system"
-	Content string `json:"content"` // Message text
+	Role    string `json:"role"`            // "user", "assistant", "system"
+	Content string `json:"content"`         // Message text
+	Files   []File `json:"files,omitempty"` // Attached files (user messages only)
+}
+
+// File is a file attached to a message. The file must stay on disk for the
+// life of the session: engines may read it again when replaying history.
+type File struct {
+	Path string `json:"path"` // Absolute path
+	Name string `json:"name"` // Original file name
+	MIME string `json:"mime"` // Verified MIME type
 }
 
 // ToolCall represents a tool/function call from the AI
@@ -65,6 +76,7 @@ type ContextUsage struct {
 	Model          string  `json:"model"`           // Model identifier (e.g. "claude-sonnet-4-20250514")
 	MessageCount   int     `json:"message_count"`   // Number of assistant messages
 	CurrentContext int     `json:"current_context"`  // Current context size (input tokens from last assistant message)
+	TotalInput     int     `json:"total_input"`     // Sum of input tokens across all assistant messages
 	TotalOutput    int     `json:"total_output"`    // Sum of output tokens across all assistant messages
 	TotalReasoning int     `json:"total_reasoning"` // Sum of reasoning tokens across all assistant messages
 	CacheRead      int     `json:"cache_read"`      // Sum of cache read tokens
@@ -111,18 +123,58 @@ type Engine interface {
 	SetDefaultModel(provider, model string)
 }
 
+// HealthReporter is optionally implemented by engines that can report backend
+// reachability for the health endpoints.
+type HealthReporter interface {
+	// Ping checks that the backend answers its health endpoint.
+	Ping(ctx context.Context) error
+	// StreamConnected reports whether the real-time event stream is up.
+	StreamConnected() bool
+}
+
+// ToolSpec describes a tool offered to the model by engines that run tool
+// calls themselves.
+type ToolSpec struct {
+	Name        string
+	Description string
+	InputSchema map[string]interface{}
+}
+
+// ToolRunner executes tools in-process. OpenCode reaches the MCP server over
+// HTTP on its own; the OpenAI engine has no such client and calls tools
+// through this interface instead.
+type ToolRunner interface {
+	// Tools returns the tools to offer to the model during the turn that
+	// ctx belongs to.
+	Tools(ctx context.Context) []ToolSpec
+	// CallTool runs a tool and returns its text output.
+	CallTool(ctx context.Context, name string, args map[string]interface{}) (string, error)
+}
+
 // Config holds engine configuration
 type Config struct {
-	Type     string // "opencode"
+	Type     string // "opencode" or "openai"
 	Provider string // For OpenCode: provider name
 	Model    string // Model to use
 	WorkDir  string // Working directory (workspace path, used for MCP config)
 	Port     int    // Port for opencode serve (0 = use DefaultPort)
 	Hostname string // Hostname for opencode serve (default: 127.0.0.1)
 	Password string // Optional OPENCODE_SERVER_PASSWORD
+
+	BaseURL string     // For openai: API base URL (default: DefaultOpenAIBaseURL)
+	APIKey  string     // For openai: optional bearer token (default: $OPENAI_API_KEY)
+	DataDir string     // For openai: directory for the session store (default: <WorkDir>/secure/data)
+	Tools   ToolRunner // For openai: in-process tool execution
+
+	Logger *logging.Logger // Optional; defaults to logging.Standard()
 }
 
 // New creates a new engine based on config
 func New(cfg Config) (Engine, error) {
-	return NewOpenCode(cfg)
+	switch cfg.Type {
+	case "openai":
+		return NewOpenAI(cfg)
+	default:
+		return NewOpenCode(cfg)
+	}
 }
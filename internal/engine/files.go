@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// visionTypes are the image formats accepted by vision models across
+// providers. Other images (e.g. HEIC) are only referenced by path.
+var visionTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// isImage reports whether mime is an image type models can view.
+func isImage(mime string) bool {
+	return visionTypes[mime]
+}
+
+// isText reports whether mime is a text type.
+func isText(mime string) bool {
+	return strings.HasPrefix(mime, "text/")
+}
+
+// fileDataURL reads a file and returns it as a base64 data: URL. Files are
+// inlined rather than passed by path so the engine process does not need
+// read access to the inbox.
+func fileDataURL(f File) (string, error) {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read attachment %s: %w", f.Name, err)
+	}
+	return "data:" + f.MIME + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,25 @@
+package engine
+
+import "context"
+
+type modelKey struct{}
+
+type modelOverride struct {
+	provider string
+	model    string
+}
+
+// WithModel returns a context that makes Send answer that turn with the given
+// model instead of the default. An empty provider keeps the default provider.
+func WithModel(ctx context.Context, provider, model string) context.Context {
+	return context.WithValue(ctx, modelKey{}, modelOverride{provider: provider, model: model})
+}
+
+// ModelFromContext returns the model override carried by ctx, if any.
+func ModelFromContext(ctx context.Context) (provider, model string, ok bool) {
+	if ctx == nil {
+		return "", "", false
+	}
+	m, ok := ctx.Value(modelKey{}).(modelOverride)
+	return m.provider, m.model, ok
+}
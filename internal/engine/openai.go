@@ -0,0 +1,919 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-pact/openpact/internal/logging"
+)
+
+// DefaultOpenAIBaseURL is used when no base URL is configured. It points at
+// llama.cpp's llama-server default; vLLM, Ollama and hosted APIs expose the
+// same /chat/completions endpoint under their own base URLs.
+const DefaultOpenAIBaseURL = "http://127.0.0.1:8080/v1"
+
+// openAIProviderID is the provider reported for models served by the OpenAI engine.
+const openAIProviderID = "openai"
+
+// maxToolRounds bounds the completion/tool-call rounds in one turn so a model
+// that keeps calling tools cannot loop forever.
+const maxToolRounds = 20
+
+// OpenAI implements the Engine interface directly against an OpenAI-compatible
+// /chat/completions endpoint, without the OpenCode sidecar. It keeps its own
+// session history under secure/data/sessions, streams responses via SSE deltas
+// and runs tool calls in-process through Config.Tools.
+type OpenAI struct {
+	cfg          Config
+	systemPrompt string
+	baseURL      string // e.g. "http://127.0.0.1:8080/v1"
+	apiKey       string
+	client       *http.Client
+	store        *sessionStore
+	tools        ToolRunner
+	mu           sync.Mutex
+	running      map[string]*activeTurn // sessionID → in-flight turn
+	log          *logging.Logger
+}
+
+// activeTurn lets AbortSession cancel a turn in flight.
+type activeTurn struct {
+	cancel context.CancelFunc
+}
+
+// NewOpenAI creates a new OpenAI-compatible engine
+func NewOpenAI(cfg Config) (*OpenAI, error) {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logging.Standard()
+	}
+
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+
+	apiKey := cfg.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+
+	dataDir := cfg.DataDir
+	if dataDir == "" {
+		dataDir = filepath.Join(cfg.WorkDir, "secure", "data")
+	}
+
+	return &OpenAI{
+		cfg:     cfg,
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		client: &http.Client{
+			Timeout: 30 * time.Minute, // Long timeout for AI responses
+		},
+		store:   newSessionStore(filepath.Join(dataDir, "sessions")),
+		tools:   cfg.Tools,
+		running: make(map[string]*activeTurn),
+		log:     logger.WithField("component", "engine"),
+	}, nil
+}
+
+// Start checks that the API answers. An unreachable endpoint is logged rather
+// than fatal: local model servers are often started alongside OpenPact, and
+// each request fails on its own until the server is up.
+func (e *OpenAI) Start(ctx context.Context) error {
+	e.log.Info("Using OpenAI-compatible API at %s", e.baseURL)
+
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := e.Ping(pingCtx); err != nil {
+		e.log.Warn("OpenAI-compatible API not reachable yet: %v", err)
+	}
+	return nil
+}
+
+// Stop cancels any turns in flight.
+func (e *OpenAI) Stop() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, turn := range e.running {
+		turn.cancel()
+	}
+	return nil
+}
+
+// Send appends the last user message to the session and runs a turn: the
+// model is called with the full history, tool calls are executed and fed back,
+// and this repeats until the model answers without calling tools. Text and
+// reasoning are streamed as they arrive; each event carries the part's full
+// text so far, like OpenCode's SSE updates.
+func (e *OpenAI) Send(ctx context.Context, sessionID string, messages []Message) (<-chan Response, error) {
+	// Extract the last user message
+	var userMsg string
+	var files []File
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			userMsg = messages[i].Content
+			files = messages[i].Files
+			break
+		}
+	}
+	if userMsg == "" {
+		return nil, fmt.Errorf("no user message found")
+	}
+
+	msg := newStoredMessage("user", "")
+	msg.Parts = []chatPart{{
+		ID:        newID("prt"),
+		MessageID: msg.ID,
+		SessionID: sessionID,
+		Type:      "text",
+		Text:      userMsg,
+	}}
+	// Attachments are stored by reference and read again whenever the
+	// history is sent, so session files stay small.
+	for _, f := range files {
+		msg.Parts = append(msg.Parts, chatPart{
+			ID:        newID("prt"),
+			MessageID: msg.ID,
+			SessionID: sessionID,
+			Type:      "file",
+			Mime:      f.MIME,
+			Filename:  f.Name,
+			URL:       "file://" + f.Path,
+		})
+	}
+	err := e.store.update(sessionID, func(sess *storedSession) error {
+		if sess.Session.Title == "" {
+			sess.Session.Title = sessionTitle(userMsg)
+		}
+		appendMessage(sess, msg)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store message: %w", err)
+	}
+
+	turnCtx, cancel := context.WithCancel(ctx)
+	turn := &activeTurn{cancel: cancel}
+	e.mu.Lock()
+	e.running[sessionID] = turn
+	e.mu.Unlock()
+
+	logger := e.log.WithContext(ctx).WithField("session", sessionID)
+	responseChan := make(chan Response, 32)
+
+	go func() {
+		defer close(responseChan)
+		defer func() {
+			cancel()
+			e.mu.Lock()
+			if e.running[sessionID] == turn {
+				delete(e.running, sessionID)
+			}
+			e.mu.Unlock()
+		}()
+
+		if err := e.runTurn(turnCtx, logger, sessionID, responseChan); err != nil {
+			if errors.Is(err, context.Canceled) {
+				logger.Info("Turn aborted")
+			} else {
+				logger.Error("Turn failed: %v", err)
+			}
+		}
+
+		select {
+		case responseChan <- Response{Done: true, SessionID: sessionID}:
+		case <-ctx.Done():
+		}
+	}()
+
+	return responseChan, nil
+}
+
+// runTurn calls the model until it stops requesting tools, storing one
+// assistant message per round.
+func (e *OpenAI) runTurn(ctx context.Context, logger *logging.Logger, sessionID string, ch chan<- Response) error {
+	e.mu.Lock()
+	systemPrompt := e.systemPrompt
+	model := e.cfg.Model
+	e.mu.Unlock()
+	if _, m, ok := ModelFromContext(ctx); ok {
+		model = m
+	}
+
+	tools := e.toolDefinitions(ctx)
+
+	for round := 0; round < maxToolRounds; round++ {
+		sess, err := e.store.get(sessionID)
+		if err != nil {
+			return err
+		}
+
+		req := chatRequest{
+			Model:         model,
+			Messages:      buildChatHistory(systemPrompt, sess.Messages),
+			Tools:         tools,
+			Stream:        true,
+			StreamOptions: &streamOptions{IncludeUsage: true},
+		}
+
+		msg := newStoredMessage("assistant", model)
+		result, err := e.streamCompletion(ctx, req, sessionID, msg.ID, ch)
+		msg.Parts = result.parts
+		msg.Usage = result.usage
+		if err != nil {
+			// Keep whatever was streamed before the failure
+			if len(msg.Parts) > 0 {
+				e.saveMessage(logger, sessionID, msg)
+			}
+			return err
+		}
+
+		if len(result.toolCalls) == 0 {
+			return e.saveMessage(logger, sessionID, msg)
+		}
+
+		for _, call := range result.toolCalls {
+			msg.Parts = append(msg.Parts, e.runTool(ctx, sessionID, msg.ID, call, ch))
+		}
+		if err := e.saveMessage(logger, sessionID, msg); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("stopped after %d tool rounds", maxToolRounds)
+}
+
+// saveMessage appends an assistant message to the session.
+func (e *OpenAI) saveMessage(logger *logging.Logger, sessionID string, msg storedMessage) error {
+	msg.Time.Updated = time.Now().UnixMilli()
+	err := e.store.update(sessionID, func(sess *storedSession) error {
+		appendMessage(sess, msg)
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to store assistant message: %v", err)
+	}
+	return err
+}
+
+// completionResult is what one streamed chat completion produced.
+type completionResult struct {
+	parts     []chatPart // reasoning and text parts
+	toolCalls []chatToolCall
+	usage     *tokenUsage
+}
+
+// streamCompletion posts a streaming chat completion request and forwards
+// reasoning and text deltas to ch as they arrive. Tool call deltas are
+// assembled and returned for the caller to execute.
+func (e *OpenAI) streamCompletion(ctx context.Context, req chatRequest, sessionID, messageID string, ch chan<- Response) (completionResult, error) {
+	var result completionResult
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return result, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return result, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	e.setAuth(httpReq)
+
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return result, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return result, fmt.Errorf("chat completion failed (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	reasoning := chatPart{ID: newID("prt"), MessageID: messageID, SessionID: sessionID, Type: "reasoning"}
+	text := chatPart{ID: newID("prt"), MessageID: messageID, SessionID: sessionID, Type: "text"}
+	var reasoningSent, textSent bool
+
+	calls := make(map[int]*chatToolCall)
+	var callOrder []int
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+stream:
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue
+		}
+		if chunk.Error != nil {
+			err = fmt.Errorf("chat completion error: %s", chunk.Error.Message)
+			break stream
+		}
+		if chunk.Usage != nil {
+			result.usage = chunk.Usage
+		}
+
+		for _, choice := range chunk.Choices {
+			delta := choice.Delta
+
+			if r := delta.ReasoningContent + delta.Reasoning; r != "" {
+				reasoning.Text += r
+				send(ctx, ch, Response{
+					Thinking:  reasoning.Text,
+					SessionID: sessionID,
+					PartID:    reasoning.ID,
+					PartType:  reasoning.Type,
+					IsUpdate:  reasoningSent,
+				})
+				reasoningSent = true
+			}
+
+			if delta.Content != "" {
+				text.Text += delta.Content
+				send(ctx, ch, Response{
+					Content:   text.Text,
+					SessionID: sessionID,
+					PartID:    text.ID,
+					PartType:  text.Type,
+					IsUpdate:  textSent,
+				})
+				textSent = true
+			}
+
+			for _, tc := range delta.ToolCalls {
+				call, ok := calls[tc.Index]
+				if !ok {
+					call = &chatToolCall{Type: "function"}
+					calls[tc.Index] = call
+					callOrder = append(callOrder, tc.Index)
+				}
+				if tc.ID != "" {
+					call.ID = tc.ID
+				}
+				if call.Function.Name == "" {
+					call.Function.Name = tc.Function.Name
+				}
+				call.Function.Arguments += tc.Function.Arguments
+			}
+		}
+	}
+	if err == nil {
+		if scanErr := scanner.Err(); scanErr != nil {
+			err = fmt.Errorf("failed to read stream: %w", scanErr)
+		}
+	}
+
+	if reasoning.Text != "" {
+		result.parts = append(result.parts, reasoning)
+	}
+	if text.Text != "" {
+		result.parts = append(result.parts, text)
+	}
+	for _, idx := range callOrder {
+		call := *calls[idx]
+		if call.ID == "" {
+			call.ID = newID("call")
+		}
+		result.toolCalls = append(result.toolCalls, call)
+	}
+
+	return result, err
+}
+
+// runTool executes one tool call and returns the resolved tool part. The part
+// is streamed once when the call starts and again when it finishes.
+func (e *OpenAI) runTool(ctx context.Context, sessionID, messageID string, call chatToolCall, ch chan<- Response) chatPart {
+	part := chatPart{
+		ID:        newID("prt"),
+		MessageID: messageID,
+		SessionID: sessionID,
+		Type:      "tool",
+		CallID:    call.ID,
+		Tool:      call.Function.Name,
+		State: &toolState{
+			Status: "running",
+			Input:  toolInput(call.Function.Arguments),
+		},
+	}
+	e.sendPart(ctx, ch, part, false)
+
+	var output string
+	var args map[string]interface{}
+	err := json.Unmarshal(part.State.Input, &args)
+	switch {
+	case err != nil:
+		err = fmt.Errorf("invalid arguments: %w", err)
+	case e.tools == nil:
+		err = fmt.Errorf("no tools are available")
+	default:
+		output, err = e.tools.CallTool(ctx, call.Function.Name, args)
+	}
+
+	if err != nil {
+		part.State.Status = "error"
+		part.State.Error = err.Error()
+	} else {
+		part.State.Status = "completed"
+		part.State.Output = output
+	}
+	e.sendPart(ctx, ch, part, true)
+	return part
+}
+
+// sendPart forwards a tool part as raw JSON, like OpenCode's non-text parts.
+func (e *OpenAI) sendPart(ctx context.Context, ch chan<- Response, part chatPart, isUpdate bool) {
+	raw, err := json.Marshal(part)
+	if err != nil {
+		return
+	}
+	send(ctx, ch, Response{
+		Parts:     []json.RawMessage{raw},
+		SessionID: part.SessionID,
+		PartID:    part.ID,
+		PartType:  part.Type,
+		IsUpdate:  isUpdate,
+	})
+}
+
+// toolDefinitions converts the tool runner's tools for the turn to chat
+// completion tools.
+func (e *OpenAI) toolDefinitions(ctx context.Context) []chatTool {
+	if e.tools == nil {
+		return nil
+	}
+	specs := e.tools.Tools(ctx)
+	defs := make([]chatTool, 0, len(specs))
+	for _, spec := range specs {
+		params := spec.InputSchema
+		if params == nil {
+			params = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		defs = append(defs, chatTool{
+			Type: "function",
+			Function: chatFunction{
+				Name:        spec.Name,
+				Description: spec.Description,
+				Parameters:  params,
+			},
+		})
+	}
+	return defs
+}
+
+// SetSystemPrompt sets the system prompt for context injection.
+func (e *OpenAI) SetSystemPrompt(prompt string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.systemPrompt = prompt
+}
+
+// CreateSession creates a new stored session.
+func (e *OpenAI) CreateSession() (*Session, error) {
+	now := time.Now().UnixMilli()
+	sess := Session{
+		ID:        newID("ses"),
+		Directory: e.cfg.WorkDir,
+	}
+	sess.Time.Created = now
+	sess.Time.Updated = now
+
+	if err := e.store.create(sess); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return &sess, nil
+}
+
+// ListSessions returns all stored sessions, most recently updated first.
+func (e *OpenAI) ListSessions() ([]Session, error) {
+	sessions, err := e.store.list()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// GetSession returns a specific session by ID.
+func (e *OpenAI) GetSession(id string) (*Session, error) {
+	sess, err := e.store.get(id)
+	if err != nil {
+		return nil, fmt.Errorf("get session failed: %w", err)
+	}
+	return &sess.Session, nil
+}
+
+// DeleteSession aborts any turn in flight and removes the session.
+func (e *OpenAI) DeleteSession(id string) error {
+	e.AbortSession(id)
+	if err := e.store.delete(id); err != nil {
+		return fmt.Errorf("delete session failed: %w", err)
+	}
+	return nil
+}
+
+// AbortSession cancels the session's turn in flight, if any.
+func (e *OpenAI) AbortSession(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if turn, ok := e.running[id]; ok {
+		turn.cancel()
+	}
+	return nil
+}
+
+// GetMessages returns the last limit messages of a session (all if limit <= 0).
+func (e *OpenAI) GetMessages(sessionID string, limit int) ([]MessageInfo, error) {
+	sess, err := e.store.get(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	stored := sess.Messages
+	if limit > 0 && len(stored) > limit {
+		stored = stored[len(stored)-limit:]
+	}
+
+	messages := make([]MessageInfo, len(stored))
+	for i, m := range stored {
+		messages[i] = m.info(sessionID)
+	}
+	return messages, nil
+}
+
+// GetContextUsage sums the token usage reported for the session's assistant
+// messages. Costs and model limits are not known to this engine.
+func (e *OpenAI) GetContextUsage(sessionID string) (*ContextUsage, error) {
+	sess, err := e.store.get(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	e.mu.Lock()
+	usage := &ContextUsage{Model: e.cfg.Model}
+	e.mu.Unlock()
+
+	for _, m := range sess.Messages {
+		if m.Role != "assistant" {
+			continue
+		}
+		usage.MessageCount++
+		if m.Model != "" {
+			usage.Model = m.Model
+		}
+		if m.Usage != nil {
+			usage.CurrentContext = m.Usage.PromptTokens
+			usage.TotalInput += m.Usage.PromptTokens
+			usage.TotalOutput += m.Usage.CompletionTokens
+		}
+	}
+
+	return usage, nil
+}
+
+// ListModels fetches the models served by the API.
+func (e *OpenAI) ListModels() ([]ModelInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, e.baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	e.setAuth(req)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("list models failed (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode models: %w", err)
+	}
+
+	models := make([]ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, ModelInfo{ProviderID: openAIProviderID, ModelID: m.ID})
+	}
+	return models, nil
+}
+
+// GetDefaultModel returns the model used for new requests. The provider is
+// always "openai" since the engine talks to a single endpoint.
+func (e *OpenAI) GetDefaultModel() (provider, model string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return openAIProviderID, e.cfg.Model
+}
+
+// SetDefaultModel updates the model used for new requests. The provider is ignored.
+func (e *OpenAI) SetDefaultModel(provider, model string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cfg.Model = model
+}
+
+// Ping checks that the API answers its models endpoint (implements HealthReporter).
+func (e *OpenAI) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.baseURL+"/models", nil)
+	if err != nil {
+		return err
+	}
+	e.setAuth(req)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("models endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// StreamConnected always reports true (implements HealthReporter): each
+// request streams its own response, so there is no persistent connection.
+func (e *OpenAI) StreamConnected() bool {
+	return true
+}
+
+// setAuth adds the bearer token to a request if an API key is configured.
+func (e *OpenAI) setAuth(req *http.Request) {
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+}
+
+// send delivers a response unless the turn has been cancelled.
+func send(ctx context.Context, ch chan<- Response, r Response) {
+	select {
+	case ch <- r:
+	case <-ctx.Done():
+	}
+}
+
+// newStoredMessage creates an empty message stamped with the current time.
+func newStoredMessage(role, model string) storedMessage {
+	now := time.Now().UnixMilli()
+	msg := storedMessage{ID: newID("msg"), Role: role, Model: model}
+	msg.Time.Created = now
+	msg.Time.Updated = now
+	return msg
+}
+
+// appendMessage adds a message to a session and bumps its updated time.
+func appendMessage(sess *storedSession, msg storedMessage) {
+	sess.Messages = append(sess.Messages, msg)
+	sess.Session.Time.Updated = msg.Time.Updated
+}
+
+// sessionTitle derives a title from the first user message, skipping the
+// "[via provider, ...]" context line the orchestrator prepends.
+func sessionTitle(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || (strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]")) {
+			continue
+		}
+		runes := []rune(line)
+		if len(runes) > 60 {
+			return string(runes[:60]) + "..."
+		}
+		return line
+	}
+	return ""
+}
+
+// toolInput normalises streamed tool call arguments into valid JSON.
+func toolInput(args string) json.RawMessage {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		return json.RawMessage("{}")
+	}
+	if json.Valid([]byte(args)) {
+		return json.RawMessage(args)
+	}
+	raw, _ := json.Marshal(args)
+	return raw
+}
+
+// compactJSON renders stored JSON without the indentation added when the
+// session file is written.
+func compactJSON(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
+// inlineFilePart prepares a stored file part for a chat completions request.
+// Images are returned as a data: URL; text files are inlined into the note.
+// Other types, which chat completions servers cannot take, are left to the
+// path reference in the message text.
+func inlineFilePart(p chatPart) (image, note string) {
+	f := File{Path: strings.TrimPrefix(p.URL, "file://"), Name: p.Filename, MIME: p.Mime}
+	switch {
+	case isImage(f.MIME):
+		url, err := fileDataURL(f)
+		if err != nil {
+			return "", fmt.Sprintf("\n\n[attachment %s is no longer available]", f.Name)
+		}
+		return url, ""
+	case isText(f.MIME):
+		data, err := os.ReadFile(f.Path)
+		if err != nil {
+			return "", fmt.Sprintf("\n\n[attachment %s is no longer available]", f.Name)
+		}
+		return "", fmt.Sprintf("\n\n--- %s ---\n%s", f.Name, data)
+	}
+	return "", ""
+}
+
+// buildChatHistory converts stored messages to chat completion messages.
+// Assistant tool parts become tool_calls followed by one "tool" message per
+// result; reasoning parts are not sent back to the model.
+func buildChatHistory(systemPrompt string, stored []storedMessage) []chatMessage {
+	var history []chatMessage
+	if systemPrompt != "" {
+		history = append(history, chatMessage{Role: "system", Content: systemPrompt})
+	}
+
+	for _, m := range stored {
+		var text strings.Builder
+		var calls []chatToolCall
+		var results []chatMessage
+
+		var images []string
+		for _, p := range m.Parts {
+			switch p.Type {
+			case "text":
+				text.WriteString(p.Text)
+			case "file":
+				image, note := inlineFilePart(p)
+				if image != "" {
+					images = append(images, image)
+				}
+				text.WriteString(note)
+			case "tool":
+				if p.State == nil {
+					continue
+				}
+				call := chatToolCall{ID: p.CallID, Type: "function"}
+				call.Function.Name = p.Tool
+				call.Function.Arguments = compactJSON(p.State.Input)
+				calls = append(calls, call)
+
+				content := p.State.Output
+				if p.State.Status == "error" {
+					content = "Error: " + p.State.Error
+				}
+				results = append(results, chatMessage{Role: "tool", ToolCallID: p.CallID, Content: content})
+			}
+		}
+
+		if m.Role == "user" {
+			history = append(history, chatMessage{Role: "user", Content: text.String(), Images: images})
+			continue
+		}
+		if text.Len() == 0 && len(calls) == 0 {
+			continue
+		}
+		history = append(history, chatMessage{Role: "assistant", Content: text.String(), ToolCalls: calls})
+		history = append(history, results...)
+	}
+
+	return history
+}
+
+// Chat completions wire types.
+
+type chatRequest struct {
+	Model         string         `json:"model"`
+	Messages      []chatMessage  `json:"messages"`
+	Tools         []chatTool     `json:"tools,omitempty"`
+	Stream        bool           `json:"stream"`
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type chatMessage struct {
+	Role       string         `json:"role"`
+	Content    string         `json:"content"`
+	ToolCalls  []chatToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+	Images     []string       `json:"-"` // data: URLs, sent as image_url content parts
+}
+
+// contentPart is one element of a multi-part message content array.
+type contentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+}
+
+// MarshalJSON sends content as an array of parts when the message carries
+// images, and as a plain string otherwise, which every server accepts.
+func (m chatMessage) MarshalJSON() ([]byte, error) {
+	type plain chatMessage
+	if len(m.Images) == 0 {
+		return json.Marshal(plain(m))
+	}
+
+	parts := []contentPart{{Type: "text", Text: m.Content}}
+	for _, url := range m.Images {
+		part := contentPart{Type: "image_url"}
+		part.ImageURL = &struct {
+			URL string `json:"url"`
+		}{URL: url}
+		parts = append(parts, part)
+	}
+	return json.Marshal(struct {
+		plain
+		Content []contentPart `json:"content"`
+	}{plain(m), parts})
+}
+
+type chatTool struct {
+	Type     string       `json:"type"`
+	Function chatFunction `json:"function"`
+}
+
+type chatFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+type chatToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"` // llama.cpp, vLLM, DeepSeek
+			Reasoning        string `json:"reasoning"`         // Ollama, OpenRouter
+			ToolCalls        []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *tokenUsage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
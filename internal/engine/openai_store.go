@@ -0,0 +1,235 @@
+package engine
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// errSessionNotFound is returned by the session store for unknown IDs.
+var errSessionNotFound = errors.New("session not found")
+
+// validSessionID guards against path traversal via IDs from the admin API.
+var validSessionID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// chatPart is a stored message part. The JSON shape mirrors OpenCode's parts
+// (text, reasoning, tool with state, file) so the orchestrator and admin UI handle
+// both engines' messages the same way.
+type chatPart struct {
+	ID        string     `json:"id"`
+	MessageID string     `json:"messageID"`
+	SessionID string     `json:"sessionID"`
+	Type      string     `json:"type"`
+	Text      string     `json:"text,omitempty"`
+	CallID    string     `json:"callID,omitempty"`
+	Tool      string     `json:"tool,omitempty"`
+	State     *toolState `json:"state,omitempty"`
+	Mime      string     `json:"mime,omitempty"`     // file parts
+	Filename  string     `json:"filename,omitempty"` // file parts
+	URL       string     `json:"url,omitempty"`      // file parts: file:// URL of the inbox copy
+}
+
+// toolState is the state of a tool part.
+type toolState struct {
+	Status string          `json:"status"` // "running", "completed", "error"
+	Input  json.RawMessage `json:"input"`
+	Output string          `json:"output,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// tokenUsage is the usage block reported by chat completions responses.
+type tokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// storedMessage is one message in a stored session.
+type storedMessage struct {
+	ID    string      `json:"id"`
+	Role  string      `json:"role"`
+	Parts []chatPart  `json:"parts"`
+	Model string      `json:"model,omitempty"`
+	Usage *tokenUsage `json:"usage,omitempty"`
+	Time  struct {
+		Created int64 `json:"created"`
+		Updated int64 `json:"updated"`
+	} `json:"time"`
+}
+
+// storedSession is the on-disk form of one session.
+type storedSession struct {
+	Session  Session         `json:"session"`
+	Messages []storedMessage `json:"messages"`
+}
+
+// info converts a stored message to the MessageInfo returned by the Engine API.
+func (m storedMessage) info(sessionID string) MessageInfo {
+	info := MessageInfo{
+		ID:        m.ID,
+		SessionID: sessionID,
+		Role:      m.Role,
+		Parts:     make([]json.RawMessage, 0, len(m.Parts)),
+	}
+	info.Time.Created = m.Time.Created
+	info.Time.Updated = m.Time.Updated
+	for _, p := range m.Parts {
+		raw, err := json.Marshal(p)
+		if err != nil {
+			continue
+		}
+		info.Parts = append(info.Parts, raw)
+	}
+	return info
+}
+
+// sessionStore keeps one JSON file per session in a directory under
+// secure/data, out of reach of the AI.
+type sessionStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func newSessionStore(dir string) *sessionStore {
+	return &sessionStore{dir: dir}
+}
+
+func (s *sessionStore) path(id string) (string, error) {
+	if !validSessionID.MatchString(id) {
+		return "", fmt.Errorf("invalid session ID: %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+// get returns a session by ID.
+func (s *sessionStore) get(id string) (*storedSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(id)
+}
+
+// create stores a new session.
+func (s *sessionStore) create(sess Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save(&storedSession{Session: sess, Messages: []storedMessage{}})
+}
+
+// update loads a session, applies fn and saves the result, holding the lock
+// throughout so concurrent updates are not lost.
+func (s *sessionStore) update(id string, fn func(*storedSession) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, err := s.load(id)
+	if err != nil {
+		return err
+	}
+	if err := fn(sess); err != nil {
+		return err
+	}
+	return s.save(sess)
+}
+
+// list returns all sessions, most recently updated first.
+func (s *sessionStore) list() ([]Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Session{}, nil
+		}
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		sess, err := s.load(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, sess.Session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Time.Updated > sessions[j].Time.Updated
+	})
+	return sessions, nil
+}
+
+// delete removes a session.
+func (s *sessionStore) delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return errSessionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *sessionStore) load(id string) (*storedSession, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errSessionNotFound
+		}
+		return nil, err
+	}
+	var sess storedSession
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", id, err)
+	}
+	return &sess, nil
+}
+
+func (s *sessionStore) save(sess *storedSession) error {
+	path, err := s.path(sess.Session.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0750); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	data, err := json.MarshalIndent(sess, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// newID returns a random ID with the given prefix, e.g. "ses_3f9a...".
+func newID(prefix string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return prefix + "_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,365 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTools is a ToolRunner that records calls.
+type fakeTools struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (f *fakeTools) Tools(ctx context.Context) []ToolSpec {
+	return []ToolSpec{{
+		Name:        "workspace_read",
+		Description: "Read a file",
+		InputSchema: map[string]interface{}{"type": "object"},
+	}}
+}
+
+func (f *fakeTools) CallTool(ctx context.Context, name string, args map[string]interface{}) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fmt.Sprintf("%s(%v)", name, args["path"]))
+	return "file contents", nil
+}
+
+// newFakeOpenAIServer serves /models and a streaming /chat/completions that
+// asks for a tool call when the last message is from the user and answers
+// with text once the tool result is in.
+func newFakeOpenAIServer(t *testing.T, requests *[]chatRequest) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/models", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"qwen2.5-7b"},{"id":"llama-3.1-8b"}]}`)
+	})
+	mux.HandleFunc("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		*requests = append(*requests, req)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		last := req.Messages[len(req.Messages)-1]
+		var chunks []string
+		if last.Role == "user" {
+			chunks = []string{
+				`{"choices":[{"delta":{"reasoning_content":"Need the file."}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"workspace_read","arguments":""}}]}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"path\":"}}]}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"notes.md\"}"}}]},"finish_reason":"tool_calls"}]}`,
+				`{"choices":[],"usage":{"prompt_tokens":100,"completion_tokens":10,"total_tokens":110}}`,
+			}
+		} else {
+			chunks = []string{
+				`{"choices":[{"delta":{"content":"The file says "}}]}`,
+				`{"choices":[{"delta":{"content":"hello."},"finish_reason":"stop"}]}`,
+				`{"choices":[],"usage":{"prompt_tokens":150,"completion_tokens":5,"total_tokens":155}}`,
+			}
+		}
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestOpenAI(t *testing.T, baseURL string, tools ToolRunner) *OpenAI {
+	t.Helper()
+	eng, err := NewOpenAI(Config{
+		Type:    "openai",
+		Model:   "qwen2.5-7b",
+		BaseURL: baseURL,
+		APIKey:  "test-key",
+		DataDir: t.TempDir(),
+		Tools:   tools,
+	})
+	if err != nil {
+		t.Fatalf("NewOpenAI failed: %v", err)
+	}
+	return eng
+}
+
+func TestNewEngine_OpenAI(t *testing.T) {
+	eng, err := New(Config{Type: "openai", DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, ok := eng.(*OpenAI); !ok {
+		t.Errorf("New() returned %T, want *engine.OpenAI", eng)
+	}
+}
+
+func TestOpenAI_SendRunsToolLoop(t *testing.T) {
+	var requests []chatRequest
+	srv := newFakeOpenAIServer(t, &requests)
+	tools := &fakeTools{}
+	eng := newTestOpenAI(t, srv.URL+"/v1", tools)
+	eng.SetSystemPrompt("You are helpful.")
+
+	sess, err := eng.CreateSession()
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	ch, err := eng.Send(context.Background(), sess.ID, []Message{{Role: "user", Content: "[via discord, channel:1, user:2]\nWhat is in notes.md?"}})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	var text, thinking string
+	var toolParts, doneCount int
+	for resp := range ch {
+		if resp.Content != "" {
+			text = resp.Content // each event carries the full text so far
+		}
+		if resp.Thinking != "" {
+			thinking = resp.Thinking
+		}
+		if resp.PartType == "tool" {
+			toolParts++
+		}
+		if resp.Done {
+			doneCount++
+		}
+	}
+
+	if text != "The file says hello." {
+		t.Errorf("text = %q", text)
+	}
+	if thinking != "Need the file." {
+		t.Errorf("thinking = %q", thinking)
+	}
+	if toolParts != 2 {
+		t.Errorf("tool part events = %d, want 2 (running + completed)", toolParts)
+	}
+	if doneCount != 1 {
+		t.Errorf("done events = %d, want 1", doneCount)
+	}
+	if len(tools.calls) != 1 || tools.calls[0] != "workspace_read(notes.md)" {
+		t.Errorf("tool calls = %v", tools.calls)
+	}
+
+	// Second request must carry the system prompt, the assistant tool call and its result
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+	if len(requests[0].Tools) != 1 || requests[0].Tools[0].Function.Name != "workspace_read" {
+		t.Errorf("tools offered = %+v", requests[0].Tools)
+	}
+	msgs := requests[1].Messages
+	if len(msgs) != 4 || msgs[0].Role != "system" || msgs[2].Role != "assistant" || msgs[3].Role != "tool" {
+		t.Fatalf("second request messages = %+v", msgs)
+	}
+	if len(msgs[2].ToolCalls) != 1 || msgs[2].ToolCalls[0].Function.Arguments != `{"path":"notes.md"}` {
+		t.Errorf("assistant tool calls = %+v", msgs[2].ToolCalls)
+	}
+	if msgs[3].ToolCallID != "call_1" || msgs[3].Content != "file contents" {
+		t.Errorf("tool result = %+v", msgs[3])
+	}
+
+	// History is stored with OpenCode-shaped tool parts
+	messages, err := eng.GetMessages(sess.ID, 0)
+	if err != nil {
+		t.Fatalf("GetMessages failed: %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("stored messages = %d, want 3 (user, tool round, answer)", len(messages))
+	}
+	var found bool
+	for _, raw := range messages[1].Parts {
+		var part struct {
+			Type  string `json:"type"`
+			Tool  string `json:"tool"`
+			State struct {
+				Status string `json:"status"`
+				Output string `json:"output"`
+			} `json:"state"`
+		}
+		json.Unmarshal(raw, &part)
+		if part.Type == "tool" && part.Tool == "workspace_read" && part.State.Status == "completed" && part.State.Output == "file contents" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("resolved tool part not stored: %s", messages[1].Parts)
+	}
+
+	usage, err := eng.GetContextUsage(sess.ID)
+	if err != nil {
+		t.Fatalf("GetContextUsage failed: %v", err)
+	}
+	if usage.MessageCount != 2 || usage.CurrentContext != 150 || usage.TotalInput != 250 || usage.TotalOutput != 15 {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	got, err := eng.GetSession(sess.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if got.Title != "What is in notes.md?" {
+		t.Errorf("title = %q", got.Title)
+	}
+}
+
+func TestOpenAI_SendWithModel(t *testing.T) {
+	var requests []chatRequest
+	srv := newFakeOpenAIServer(t, &requests)
+	eng := newTestOpenAI(t, srv.URL+"/v1", &fakeTools{})
+
+	sess, err := eng.CreateSession()
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	ctx := WithModel(context.Background(), "", "llama-3.1-8b")
+	ch, err := eng.Send(ctx, sess.ID, []Message{{Role: "user", Content: "What is in notes.md?"}})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	for range ch {
+	}
+
+	for i, req := range requests {
+		if req.Model != "llama-3.1-8b" {
+			t.Errorf("request %d model = %q, want the override", i, req.Model)
+		}
+	}
+	if provider, model := eng.GetDefaultModel(); model != "qwen2.5-7b" {
+		t.Errorf("default model changed to %s/%s", provider, model)
+	}
+}
+
+func TestOpenAI_Sessions(t *testing.T) {
+	eng := newTestOpenAI(t, "http://127.0.0.1:1/v1", nil)
+
+	a, err := eng.CreateSession()
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if _, err := eng.CreateSession(); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	sessions, err := eng.ListSessions()
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("ListSessions = %d, %v; want 2", len(sessions), err)
+	}
+
+	if err := eng.DeleteSession(a.ID); err != nil {
+		t.Fatalf("DeleteSession failed: %v", err)
+	}
+	if _, err := eng.GetSession(a.ID); err == nil {
+		t.Error("expected error for deleted session")
+	}
+	if _, err := eng.GetSession("../../config"); err == nil || !strings.Contains(err.Error(), "invalid session ID") {
+		t.Errorf("expected invalid session ID error, got %v", err)
+	}
+	if _, err := eng.Send(context.Background(), "ses_missing", []Message{{Role: "user", Content: "hi"}}); err == nil {
+		t.Error("expected error sending to unknown session")
+	}
+}
+
+func TestOpenAI_ListModels(t *testing.T) {
+	var requests []chatRequest
+	srv := newFakeOpenAIServer(t, &requests)
+	eng := newTestOpenAI(t, srv.URL+"/v1/", nil)
+
+	models, err := eng.ListModels()
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(models) != 2 || models[0].ProviderID != "openai" || models[0].ModelID != "qwen2.5-7b" {
+		t.Errorf("models = %+v", models)
+	}
+	if err := eng.Ping(context.Background()); err != nil {
+		t.Errorf("Ping failed: %v", err)
+	}
+
+	eng.SetDefaultModel("anthropic", "llama-3.1-8b")
+	if provider, model := eng.GetDefaultModel(); provider != "openai" || model != "llama-3.1-8b" {
+		t.Errorf("default model = %s/%s", provider, model)
+	}
+}
+
+func TestBuildChatHistory_Files(t *testing.T) {
+	dir := t.TempDir()
+	png := filepath.Join(dir, "photo.png")
+	os.WriteFile(png, []byte("\x89PNG\r\n\x1a\n"), 0640)
+	txt := filepath.Join(dir, "notes.txt")
+	os.WriteFile(txt, []byte("hello"), 0640)
+
+	stored := []storedMessage{{
+		Role: "user",
+		Parts: []chatPart{
+			{Type: "text", Text: "Look at these"},
+			{Type: "file", Mime: "image/png", Filename: "photo.png", URL: "file://" + png},
+			{Type: "file", Mime: "text/plain", Filename: "notes.txt", URL: "file://" + txt},
+			{Type: "file", Mime: "audio/ogg", Filename: "voice.ogg", URL: "file://" + filepath.Join(dir, "voice.ogg")},
+			{Type: "file", Mime: "image/jpeg", Filename: "gone.jpg", URL: "file://" + filepath.Join(dir, "gone.jpg")},
+		},
+	}}
+
+	history := buildChatHistory("", stored)
+	if len(history) != 1 {
+		t.Fatalf("history = %+v", history)
+	}
+	msg := history[0]
+	if len(msg.Images) != 1 || !strings.HasPrefix(msg.Images[0], "data:image/png;base64,") {
+		t.Errorf("images = %v", msg.Images)
+	}
+	want := "Look at these\n\n--- notes.txt ---\nhello\n\n[attachment gone.jpg is no longer available]"
+	if msg.Content != want {
+		t.Errorf("content = %q, want %q", msg.Content, want)
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var wire struct {
+		Role    string `json:"role"`
+		Content []struct {
+			Type     string `json:"type"`
+			Text     string `json:"text"`
+			ImageURL struct {
+				URL string `json:"url"`
+			} `json:"image_url"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		t.Fatalf("content is not a parts array: %s", data)
+	}
+	if wire.Role != "user" || len(wire.Content) != 2 || wire.Content[0].Text != want || wire.Content[1].Type != "image_url" || wire.Content[1].ImageURL.URL != msg.Images[0] {
+		t.Errorf("wire message = %s", data)
+	}
+
+	// Messages without images keep plain string content
+	data, _ = json.Marshal(chatMessage{Role: "user", Content: "hi"})
+	if string(data) != `{"role":"user","content":"hi"}` {
+		t.Errorf("plain message = %s", data)
+	}
+}
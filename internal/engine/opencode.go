@@ -6,13 +6,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/open-pact/openpact/internal/logging"
 )
 
 // OpenCode implements the Engine interface using `opencode serve` HTTP API.
@@ -25,6 +26,7 @@ type OpenCode struct {
 	client       *http.Client
 	mu           sync.Mutex
 	sse          *sseClient   // Persistent SSE connection for real-time streaming
+	log          *logging.Logger
 }
 
 // DefaultPort is the fixed port used by both the entrypoint (which launches
@@ -33,11 +35,16 @@ const DefaultPort = 4098
 
 // NewOpenCode creates a new OpenCode engine
 func NewOpenCode(cfg Config) (*OpenCode, error) {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logging.Standard()
+	}
 	return &OpenCode{
 		cfg: cfg,
 		client: &http.Client{
 			Timeout: 30 * time.Minute, // Long timeout for AI responses
 		},
+		log: logger.WithField("component", "engine"),
 	}, nil
 }
 
@@ -57,17 +64,18 @@ func (o *OpenCode) Start(ctx context.Context) error {
 
 	o.baseURL = fmt.Sprintf("http://%s:%d", hostname, port)
 
-	log.Printf("Connecting to opencode serve at %s", o.baseURL)
+	o.log.Info("Connecting to opencode serve at %s", o.baseURL)
 
 	// Wait for server to be ready
 	if err := o.waitForReady(ctx); err != nil {
 		return fmt.Errorf("opencode serve failed to become ready: %w", err)
 	}
 
-	log.Printf("opencode serve is ready at %s", o.baseURL)
+	o.log.Info("opencode serve is ready at %s", o.baseURL)
 
 	// Start persistent SSE connection for real-time streaming
 	o.sse = newSSEClient(o.baseURL, o.cfg.Password)
+	o.sse.log = o.log.WithField("stream", "sse")
 	o.sse.Start(ctx)
 
 	return nil
@@ -81,6 +89,31 @@ func (o *OpenCode) Stop() error {
 	return nil
 }
 
+// promptParts builds the parts of a prompt request. Attachments the model can
+// read natively (images, PDFs, text) become file parts with data: URLs; others,
+// such as voice notes, are only referenced by path in the message text.
+func promptParts(text string, files []File) ([]map[string]string, error) {
+	parts := []map[string]string{
+		{"type": "text", "text": text},
+	}
+	for _, f := range files {
+		if !isImage(f.MIME) && !isText(f.MIME) && f.MIME != "application/pdf" {
+			continue
+		}
+		url, err := fileDataURL(f)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, map[string]string{
+			"type":     "file",
+			"mime":     f.MIME,
+			"filename": f.Name,
+			"url":      url,
+		})
+	}
+	return parts, nil
+}
+
 // Send posts a message to a session and streams the response.
 // If the SSE client is connected, events are streamed in real-time as parts
 // are created/updated. After completion, a GET reconciliation ensures no parts
@@ -92,11 +125,22 @@ func (o *OpenCode) Send(ctx context.Context, sessionID string, messages []Messag
 	model := o.cfg.Model
 	o.mu.Unlock()
 
+	// A turn may use another model, such as a cheaper one once a usage
+	// budget is used up
+	if p, m, ok := ModelFromContext(ctx); ok {
+		if p != "" {
+			provider = p
+		}
+		model = m
+	}
+
 	// Extract the last user message
 	var userMsg string
+	var files []File
 	for i := len(messages) - 1; i >= 0; i-- {
 		if messages[i].Role == "user" {
 			userMsg = messages[i].Content
+			files = messages[i].Files
 			break
 		}
 	}
@@ -105,10 +149,12 @@ func (o *OpenCode) Send(ctx context.Context, sessionID string, messages []Messag
 	}
 
 	// Build request body
+	parts, err := promptParts(userMsg, files)
+	if err != nil {
+		return nil, err
+	}
 	body := map[string]interface{}{
-		"parts": []map[string]string{
-			{"type": "text", "text": userMsg},
-		},
+		"parts": parts,
 	}
 
 	if systemPrompt != "" {
@@ -142,6 +188,8 @@ func (o *OpenCode) Send(ctx context.Context, sessionID string, messages []Messag
 
 // sendStreaming uses the SSE event stream for real-time part delivery.
 func (o *OpenCode) sendStreaming(ctx context.Context, sessionID string, jsonBody []byte) (<-chan Response, error) {
+	logger := o.log.WithContext(ctx).WithField("session", sessionID)
+
 	// Subscribe to SSE events BEFORE sending the POST (so we don't miss early events)
 	sub := o.sse.Subscribe(sessionID)
 
@@ -175,7 +223,7 @@ func (o *OpenCode) sendStreaming(ctx context.Context, sessionID string, jsonBody
 
 			case result := <-postDone:
 				if result.err != nil {
-					log.Printf("[sse] POST failed for session %s: %v", sessionID, result.err)
+					logger.Error("POST failed: %v", result.err)
 					responseChan <- Response{
 						Done:      true,
 						SessionID: sessionID,
@@ -205,7 +253,7 @@ func (o *OpenCode) sendStreaming(ctx context.Context, sessionID string, jsonBody
 		}
 
 		// Reconciliation: GET resolved messages to catch anything missed by SSE
-		o.reconcile(sessionID, anchorID, seenParts, responseChan)
+		o.reconcile(logger, sessionID, anchorID, seenParts, responseChan)
 
 		responseChan <- Response{
 			Done:      true,
@@ -341,7 +389,7 @@ func (o *OpenCode) postMessage(ctx context.Context, sessionID string, jsonBody [
 // reconcile fetches resolved messages via GET and forwards any parts not already
 // sent via SSE. This catches tool output, file parts, and anything else that
 // may have been missed during streaming.
-func (o *OpenCode) reconcile(sessionID, anchorID string, seenParts map[string]bool, ch chan<- Response) {
+func (o *OpenCode) reconcile(logger *logging.Logger, sessionID, anchorID string, seenParts map[string]bool, ch chan<- Response) {
 	if anchorID == "" {
 		return
 	}
@@ -350,7 +398,7 @@ func (o *OpenCode) reconcile(sessionID, anchorID string, seenParts map[string]bo
 	for _, limit := range []int{10, 50, 200} {
 		messages, err := o.GetMessages(sessionID, limit)
 		if err != nil {
-			log.Printf("[reconcile] Error fetching messages (limit %d): %v", limit, err)
+			logger.Error("reconcile: error fetching messages (limit %d): %v", limit, err)
 			break
 		}
 
@@ -413,6 +461,8 @@ func (o *OpenCode) forwardUnseenParts(parts []json.RawMessage, sessionID string,
 
 // sendBlocking is the original POST+GET fallback when SSE is unavailable.
 func (o *OpenCode) sendBlocking(ctx context.Context, sessionID string, jsonBody []byte) (<-chan Response, error) {
+	logger := o.log.WithContext(ctx).WithField("session", sessionID)
+
 	url := fmt.Sprintf("%s/session/%s/message", o.baseURL, sessionID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonBody))
 	if err != nil {
@@ -440,7 +490,7 @@ func (o *OpenCode) sendBlocking(ctx context.Context, sessionID string, jsonBody
 
 		respBody, err := io.ReadAll(resp.Body)
 		if err != nil {
-			log.Printf("Error reading response: %v", err)
+			logger.Error("Error reading response: %v", err)
 			return
 		}
 
@@ -455,7 +505,7 @@ func (o *OpenCode) sendBlocking(ctx context.Context, sessionID string, jsonBody
 		for _, limit := range []int{10, 50, 200} {
 			messages, err := o.GetMessages(sessionID, limit)
 			if err != nil {
-				log.Printf("Error fetching messages (limit %d): %v", limit, err)
+				logger.Error("Error fetching messages (limit %d): %v", limit, err)
 				break
 			}
 
@@ -784,6 +834,7 @@ func (o *OpenCode) GetContextUsage(sessionID string) (*ContextUsage, error) {
 		}
 		usage.MessageCount++
 		usage.CurrentContext = msg.Info.Tokens.Input // overwrite each time; last one is current
+		usage.TotalInput += msg.Info.Tokens.Input
 		usage.TotalOutput += msg.Info.Tokens.Output
 		usage.TotalReasoning += msg.Info.Tokens.Reasoning
 		usage.CacheRead += msg.Info.Tokens.Cache.Read
@@ -950,6 +1001,35 @@ func (o *OpenCode) waitForReady(ctx context.Context) error {
 	return fmt.Errorf("opencode serve did not become ready within 15 seconds")
 }
 
+// Ping checks that opencode serve answers its health endpoint (implements HealthReporter).
+func (o *OpenCode) Ping(ctx context.Context) error {
+	if o.baseURL == "" {
+		return fmt.Errorf("engine not started")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.baseURL+"/global/health", nil)
+	if err != nil {
+		return err
+	}
+	o.setAuth(req)
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// StreamConnected reports whether the SSE event stream is connected (implements HealthReporter).
+func (o *OpenCode) StreamConnected() bool {
+	return o.sse != nil && o.sse.IsConnected()
+}
+
 // setAuth adds authentication to a request if a password is configured.
 func (o *OpenCode) setAuth(req *http.Request) {
 	if o.cfg.Password != "" {
@@ -1016,7 +1096,7 @@ func BuildOpenCodeConfig(cfg Config, mcpToken string) map[string]interface{} {
 	}
 
 	if mcpToken == "" {
-		log.Printf("WARNING: no MCP token provided — AI will have no tools available")
+		logging.Standard().WithField("component", "engine").Warn("no MCP token provided — AI will have no tools available")
 		return config
 	}
 
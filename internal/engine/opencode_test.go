@@ -260,3 +260,31 @@ func TestBuildOpenCodeConfig_ValidJSON(t *testing.T) {
 		t.Fatalf("config JSON must be parseable: %v", err)
 	}
 }
+
+func TestPromptParts_Files(t *testing.T) {
+	dir := t.TempDir()
+	pdf := dir + "/report.pdf"
+	os.WriteFile(pdf, []byte("%PDF-1.4"), 0640)
+
+	parts, err := promptParts("see attached", []File{
+		{Path: pdf, Name: "report.pdf", MIME: "application/pdf"},
+		{Path: dir + "/voice.ogg", Name: "voice.ogg", MIME: "audio/ogg"}, // not sent as a part
+	})
+	if err != nil {
+		t.Fatalf("promptParts failed: %v", err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("parts = %v, want text + one file", parts)
+	}
+	if parts[0]["type"] != "text" || parts[0]["text"] != "see attached" {
+		t.Errorf("text part = %v", parts[0])
+	}
+	file := parts[1]
+	if file["type"] != "file" || file["mime"] != "application/pdf" || file["filename"] != "report.pdf" || file["url"] != "data:application/pdf;base64,JVBERi0xLjQ=" {
+		t.Errorf("file part = %v", file)
+	}
+
+	if _, err := promptParts("x", []File{{Path: dir + "/missing.png", Name: "missing.png", MIME: "image/png"}}); err == nil {
+		t.Error("expected error for a missing attachment")
+	}
+}
@@ -4,11 +4,12 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/open-pact/openpact/internal/logging"
 )
 
 // sseEvent represents a parsed SSE event from the OpenCode /event stream.
@@ -29,6 +30,7 @@ type sseClient struct {
 	baseURL  string
 	password string
 	client   *http.Client // No timeout — long-lived SSE connection
+	log      *logging.Logger
 
 	mu          sync.RWMutex
 	subscribers map[string][]*sseSubscription // sessionID → subs
@@ -48,6 +50,7 @@ func newSSEClient(baseURL, password string) *sseClient {
 		password:    password,
 		client:      &http.Client{}, // No timeout for long-lived SSE
 		subscribers: make(map[string][]*sseSubscription),
+		log:         logging.Standard().WithFields(map[string]any{"component": "engine", "stream": "sse"}),
 	}
 }
 
@@ -133,7 +136,7 @@ func (s *sseClient) run() {
 		}
 
 		if err != nil {
-			log.Printf("[sse] connection lost: %v — reconnecting in %s", err, backoff)
+			s.log.Warn("connection lost: %v — reconnecting in %s", err, backoff)
 		}
 
 		select {
@@ -174,7 +177,7 @@ func (s *sseClient) connect() error {
 
 	// Reset backoff on successful connect
 	s.setConnected(true)
-	log.Printf("[sse] connected to %s", url)
+	s.log.Info("connected to %s", url)
 
 	scanner := bufio.NewScanner(resp.Body)
 	// Allow up to 1MB per line (SSE events can be large with tool output)
@@ -262,7 +265,7 @@ func (s *sseClient) dispatch(sessionID string, evt sseEvent) {
 		case sub.ch <- evt:
 		default:
 			// Channel full — drop event to avoid blocking
-			log.Printf("[sse] dropping event for session %s (channel full)", sessionID)
+			s.log.Warn("dropping event for session %s (channel full)", sessionID)
 		}
 	}
 }
@@ -73,6 +73,16 @@ type Server struct {
 	toolCallsTotal   uint64
 	toolCallsSuccess uint64
 	toolCallsError   uint64
+
+	// Labeled metrics (Prometheus format only)
+	toolCalls        *counterVec
+	toolDuration     *histogramVec
+	providerMessages *counterVec
+	chatTurns        *counterVec
+	chatDuration     *histogramVec
+	scheduleRuns     *counterVec
+	scheduleDuration *histogramVec
+	rateLimited      *counterVec
 }
 
 // NewServer creates a new health/metrics server
@@ -81,6 +91,23 @@ func NewServer(addr string) *Server {
 		checks:    make(map[string]Check),
 		startTime: time.Now(),
 		addr:      addr,
+
+		toolCalls: newCounterVec("openpact_tool_calls_by_tool_total",
+			"MCP tool calls by tool and status", "tool", "status"),
+		toolDuration: newHistogramVec("openpact_tool_call_duration_seconds",
+			"MCP tool call latency", DefaultBuckets, "tool"),
+		providerMessages: newCounterVec("openpact_provider_messages_total",
+			"Chat messages by provider and direction", "provider", "direction"),
+		chatTurns: newCounterVec("openpact_chat_turns_total",
+			"Chat turns handled by provider and status", "provider", "status"),
+		chatDuration: newHistogramVec("openpact_chat_turn_duration_seconds",
+			"Time from receiving a chat message to having the reply ready", DefaultBuckets, "provider"),
+		scheduleRuns: newCounterVec("openpact_schedule_runs_total",
+			"Scheduled job runs by schedule and status", "schedule_id", "schedule", "type", "status"),
+		scheduleDuration: newHistogramVec("openpact_schedule_run_duration_seconds",
+			"Scheduled job run duration", DefaultBuckets, "schedule_id", "schedule"),
+		rateLimited: newCounterVec("openpact_rate_limited_total",
+			"Requests rejected by the rate limiter, by scope", "scope"),
 	}
 
 	mux := http.NewServeMux()
@@ -230,7 +257,16 @@ func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 
 	fmt.Fprintf(w, "# HELP openpact_tool_calls_error Failed tool calls\n")
 	fmt.Fprintf(w, "# TYPE openpact_tool_calls_error counter\n")
-	fmt.Fprintf(w, "openpact_tool_calls_error %d\n", metrics.ToolCallsError)
+	fmt.Fprintf(w, "openpact_tool_calls_error %d\n\n", metrics.ToolCallsError)
+
+	s.toolCalls.write(w)
+	s.toolDuration.write(w)
+	s.providerMessages.write(w)
+	s.chatTurns.write(w)
+	s.chatDuration.write(w)
+	s.scheduleRuns.write(w)
+	s.scheduleDuration.write(w)
+	s.rateLimited.write(w)
 }
 
 // Metric recording methods
@@ -264,6 +300,48 @@ func (s *Server) RecordToolCall(success bool) {
 	}
 }
 
+// ObserveToolCall records a tool call with its name and latency.
+func (s *Server) ObserveToolCall(tool string, success bool, d time.Duration) {
+	s.RecordToolCall(success)
+	s.toolCalls.Inc(tool, statusLabel(success))
+	s.toolDuration.Observe(d, tool)
+}
+
+// RecordProviderMessage records a message received from or sent to a chat provider.
+func (s *Server) RecordProviderMessage(provider string, sent bool) {
+	s.RecordMessage(sent)
+	direction := "received"
+	if sent {
+		direction = "sent"
+	}
+	s.providerMessages.Inc(provider, direction)
+}
+
+// ObserveChatTurn records a completed chat turn (message in, reply ready).
+func (s *Server) ObserveChatTurn(provider string, success bool, d time.Duration) {
+	s.RecordRequest(success)
+	s.chatTurns.Inc(provider, statusLabel(success))
+	s.chatDuration.Observe(d, provider)
+}
+
+// ObserveScheduleRun records a scheduled job run.
+func (s *Server) ObserveScheduleRun(id, name, jobType string, success bool, d time.Duration) {
+	s.scheduleRuns.Inc(id, name, jobType, statusLabel(success))
+	s.scheduleDuration.Observe(d, id, name)
+}
+
+// RecordRateLimited records a request rejected by the rate limiter.
+func (s *Server) RecordRateLimited(scope string) {
+	s.rateLimited.Inc(scope)
+}
+
+func statusLabel(success bool) string {
+	if success {
+		return "success"
+	}
+	return "error"
+}
+
 // GetMetrics returns current metrics snapshot
 func (s *Server) GetMetrics() Metrics {
 	return Metrics{
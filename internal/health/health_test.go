@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewServer(t *testing.T) {
@@ -261,3 +262,53 @@ func TestMetricsConcurrency(t *testing.T) {
 		t.Errorf("ToolCallsTotal = %d, want 100", metrics.ToolCallsTotal)
 	}
 }
+
+func TestMetricsEndpointLabeled(t *testing.T) {
+	s := NewServer(":8080")
+
+	s.ObserveToolCall("workspace_read", true, 20*time.Millisecond)
+	s.ObserveToolCall("workspace_read", false, 3*time.Second)
+	s.RecordProviderMessage("discord", false)
+	s.ObserveChatTurn("discord", true, 4*time.Second)
+	s.ObserveScheduleRun("abc123", "nightly \"digest\"", "script", true, time.Second)
+	s.RecordRateLimited("user")
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	s.handleMetrics(w, req)
+
+	body := w.Body.String()
+
+	wants := []string{
+		"# TYPE openpact_tool_calls_by_tool_total counter",
+		`openpact_tool_calls_by_tool_total{tool="workspace_read",status="success"} 1`,
+		`openpact_tool_calls_by_tool_total{tool="workspace_read",status="error"} 1`,
+		"# TYPE openpact_tool_call_duration_seconds histogram",
+		`openpact_tool_call_duration_seconds_bucket{tool="workspace_read",le="0.05"} 1`,
+		`openpact_tool_call_duration_seconds_bucket{tool="workspace_read",le="5"} 2`,
+		`openpact_tool_call_duration_seconds_bucket{tool="workspace_read",le="+Inf"} 2`,
+		`openpact_tool_call_duration_seconds_count{tool="workspace_read"} 2`,
+		`openpact_provider_messages_total{provider="discord",direction="received"} 1`,
+		`openpact_chat_turns_total{provider="discord",status="success"} 1`,
+		`openpact_schedule_runs_total{schedule_id="abc123",schedule="nightly \"digest\"",type="script",status="success"} 1`,
+		`openpact_rate_limited_total{scope="user"} 1`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in metrics output", want)
+		}
+	}
+
+	// Labeled recorders also feed the aggregate counters
+	metrics := s.GetMetrics()
+	if metrics.ToolCallsTotal != 2 || metrics.ToolCallsError != 1 {
+		t.Errorf("tool call totals = %d/%d, want 2/1", metrics.ToolCallsTotal, metrics.ToolCallsError)
+	}
+	if metrics.MessagesReceived != 1 {
+		t.Errorf("MessagesReceived = %d, want 1", metrics.MessagesReceived)
+	}
+	if metrics.RequestsSuccess != 1 {
+		t.Errorf("RequestsSuccess = %d, want 1", metrics.RequestsSuccess)
+	}
+}
@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// CorrelationField is the field name used for correlation IDs in log entries.
+const CorrelationField = "correlation_id"
+
+type correlationKey struct{}
+
+// NewCorrelationID returns a random ID for tying together the log lines of one
+// request as it moves from a chat provider through the engine and MCP tools.
+func NewCorrelationID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithCorrelationID returns a context carrying the given correlation ID.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the correlation ID carried by ctx, or "" if none.
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// EnsureCorrelationID returns ctx unchanged if it already carries a correlation
+// ID, otherwise a derived context with a new one.
+func EnsureCorrelationID(ctx context.Context) (context.Context, string) {
+	if id := CorrelationID(ctx); id != "" {
+		return ctx, id
+	}
+	id := NewCorrelationID()
+	return WithCorrelationID(ctx, id), id
+}
+
+// WithContext returns a logger with the context's correlation ID attached, or
+// l itself if the context carries none.
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	id := CorrelationID(ctx)
+	if id == "" {
+		return l
+	}
+	return l.WithField(CorrelationField, id)
+}
@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCorrelationIDContext(t *testing.T) {
+	ctx := context.Background()
+	if id := CorrelationID(ctx); id != "" {
+		t.Errorf("expected empty correlation ID, got %q", id)
+	}
+
+	ctx, id := EnsureCorrelationID(ctx)
+	if len(id) != 16 {
+		t.Errorf("expected 16 hex chars, got %q", id)
+	}
+	if got := CorrelationID(ctx); got != id {
+		t.Errorf("CorrelationID() = %q, want %q", got, id)
+	}
+
+	// An existing ID is preserved
+	ctx2, id2 := EnsureCorrelationID(ctx)
+	if id2 != id || CorrelationID(ctx2) != id {
+		t.Errorf("EnsureCorrelationID replaced existing ID %q with %q", id, id2)
+	}
+}
+
+func TestLoggerWithContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(Config{Level: LevelInfo, Output: &buf})
+
+	ctx := WithCorrelationID(context.Background(), "abc123")
+	logger.WithContext(ctx).Info("handled")
+
+	if !strings.Contains(buf.String(), "correlation_id=abc123") {
+		t.Errorf("expected correlation ID in output, got: %s", buf.String())
+	}
+
+	if logger.WithContext(context.Background()) != logger {
+		t.Error("WithContext without an ID should return the same logger")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"sync"
 	"time"
 )
@@ -60,13 +61,20 @@ type Entry struct {
 	Fields  map[string]any    `json:"fields,omitempty"`
 }
 
-// Logger is a structured logger
+// Logger is a structured logger. Loggers derived with WithField/WithFields
+// share their parent's level, output and format, so reconfiguring the root
+// logger affects every component logger derived from it.
 type Logger struct {
+	core   *core
+	fields map[string]any
+}
+
+// core holds the state shared by a logger and all loggers derived from it.
+type core struct {
 	mu     sync.Mutex
 	level  Level
 	output io.Writer
 	json   bool
-	fields map[string]any
 }
 
 // Config configures a Logger
@@ -82,9 +90,11 @@ func New(cfg Config) *Logger {
 		cfg.Output = os.Stdout
 	}
 	return &Logger{
-		level:  cfg.Level,
-		output: cfg.Output,
-		json:   cfg.JSONFormat,
+		core: &core{
+			level:  cfg.Level,
+			output: cfg.Output,
+			json:   cfg.JSONFormat,
+		},
 		fields: make(map[string]any),
 	}
 }
@@ -97,12 +107,20 @@ func Default() *Logger {
 	})
 }
 
+// standard is the process-wide logger used by components that have not been
+// given one explicitly. It writes to stderr so it never interferes with
+// JSON-RPC on stdout (see cmd/mcp-server).
+var standard = New(Config{Level: LevelInfo, Output: os.Stderr})
+
+// Standard returns the process-wide fallback logger.
+func Standard() *Logger {
+	return standard
+}
+
 // WithField returns a new logger with the given field added
 func (l *Logger) WithField(key string, value any) *Logger {
 	newLogger := &Logger{
-		level:  l.level,
-		output: l.output,
-		json:   l.json,
+		core:   l.core,
 		fields: make(map[string]any),
 	}
 	for k, v := range l.fields {
@@ -115,9 +133,7 @@ func (l *Logger) WithField(key string, value any) *Logger {
 // WithFields returns a new logger with the given fields added
 func (l *Logger) WithFields(fields map[string]any) *Logger {
 	newLogger := &Logger{
-		level:  l.level,
-		output: l.output,
-		json:   l.json,
+		core:   l.core,
 		fields: make(map[string]any),
 	}
 	for k, v := range l.fields {
@@ -131,35 +147,46 @@ func (l *Logger) WithFields(fields map[string]any) *Logger {
 
 // log writes a log entry
 func (l *Logger) log(level Level, msg string, args ...any) {
-	if level < l.level {
+	c := l.core
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if level < c.level {
 		return
 	}
 
+	message := msg
+	if len(args) > 0 {
+		message = fmt.Sprintf(msg, args...)
+	}
+
 	entry := Entry{
 		Time:    time.Now().UTC(),
 		Level:   level.String(),
-		Message: fmt.Sprintf(msg, args...),
+		Message: message,
 	}
 
 	if len(l.fields) > 0 {
 		entry.Fields = l.fields
 	}
 
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	if l.json {
+	if c.json {
 		data, _ := json.Marshal(entry)
-		fmt.Fprintln(l.output, string(data))
+		fmt.Fprintln(c.output, string(data))
 	} else {
-		// Human-readable format
+		// Human-readable format, fields sorted for stable output
 		fieldsStr := ""
 		if len(entry.Fields) > 0 {
-			for k, v := range entry.Fields {
-				fieldsStr += fmt.Sprintf(" %s=%v", k, v)
+			keys := make([]string, 0, len(entry.Fields))
+			for k := range entry.Fields {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fieldsStr += fmt.Sprintf(" %s=%v", k, entry.Fields[k])
 			}
 		}
-		fmt.Fprintf(l.output, "%s [%s] %s%s\n",
+		fmt.Fprintf(c.output, "%s [%s] %s%s\n",
 			entry.Time.Format("2006-01-02T15:04:05Z"),
 			entry.Level,
 			entry.Message,
@@ -190,21 +217,37 @@ func (l *Logger) Error(msg string, args ...any) {
 
 // SetLevel changes the logging level
 func (l *Logger) SetLevel(level Level) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.level = level
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	l.core.level = level
 }
 
 // SetOutput changes the output writer
 func (l *Logger) SetOutput(w io.Writer) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.output = w
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	l.core.output = w
 }
 
 // SetJSONFormat enables or disables JSON output
 func (l *Logger) SetJSONFormat(enabled bool) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.json = enabled
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	l.core.json = enabled
+}
+
+// DebugEnabled reports whether debug-level entries are being written.
+func (l *Logger) DebugEnabled() bool {
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	return l.core.level <= LevelDebug
+}
+
+// Redact returns s unchanged when debug logging is enabled, and a length-only
+// placeholder otherwise. Use it for user message bodies and tool arguments.
+func (l *Logger) Redact(s string) string {
+	if l.DebugEnabled() {
+		return s
+	}
+	return fmt.Sprintf("[redacted %d chars]", len(s))
 }
@@ -209,3 +209,31 @@ func TestSetLevel(t *testing.T) {
 		t.Error("info should be logged after SetLevel(LevelInfo)")
 	}
 }
+
+func TestDerivedLoggerSharesLevel(t *testing.T) {
+	var buf bytes.Buffer
+	root := New(Config{
+		Level:  LevelInfo,
+		Output: &buf,
+	})
+	child := root.WithField("component", "scheduler")
+
+	root.SetLevel(LevelError)
+	child.Info("should not appear")
+	if buf.Len() > 0 {
+		t.Error("derived logger should follow the root logger's level")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	logger := New(Config{Level: LevelInfo, Output: &bytes.Buffer{}})
+
+	if got := logger.Redact("my secret message"); got != "[redacted 17 chars]" {
+		t.Errorf("Redact at INFO = %q", got)
+	}
+
+	logger.SetLevel(LevelDebug)
+	if got := logger.Redact("my secret message"); got != "my secret message" {
+		t.Errorf("Redact at DEBUG = %q, want original text", got)
+	}
+}
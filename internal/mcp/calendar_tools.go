@@ -96,38 +96,48 @@ func calendarReadTool(calendars []CalendarConfig) *Tool {
 				return allEvents[i].Start.Before(allEvents[j].Start)
 			})
 
-			// Format output
-			if len(allEvents) == 0 {
-				return fmt.Sprintf("No events in the next %d days", days), nil
-			}
+			return formatEvents(allEvents, days), nil
+		},
+	}
+}
 
-			var sb strings.Builder
-			sb.WriteString(fmt.Sprintf("Events in the next %d days:\n\n", days))
+// formatEvents renders events, already sorted by start time, grouped by day.
+func formatEvents(events []Event, days int) string {
+	if len(events) == 0 {
+		return fmt.Sprintf("No events in the next %d days", days)
+	}
 
-			currentDate := ""
-			for _, e := range allEvents {
-				dateStr := e.Start.Format("Monday, January 2")
-				if dateStr != currentDate {
-					currentDate = dateStr
-					sb.WriteString(fmt.Sprintf("## %s\n", dateStr))
-				}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Events in the next %d days:\n\n", days))
 
-				if e.AllDay {
-					sb.WriteString(fmt.Sprintf("- [All day] %s\n", e.Summary))
-				} else {
-					timeStr := e.Start.Format("15:04")
-					endStr := e.End.Format("15:04")
-					sb.WriteString(fmt.Sprintf("- [%s-%s] %s\n", timeStr, endStr, e.Summary))
-				}
+	currentDate := ""
+	for _, e := range events {
+		dateStr := e.Start.Format("Monday, January 2")
+		if dateStr != currentDate {
+			currentDate = dateStr
+			sb.WriteString(fmt.Sprintf("## %s\n", dateStr))
+		}
 
-				if e.Location != "" {
-					sb.WriteString(fmt.Sprintf("  Location: %s\n", e.Location))
-				}
-			}
+		if e.AllDay {
+			sb.WriteString(fmt.Sprintf("- [All day] %s\n", e.Summary))
+		} else {
+			timeStr := e.Start.Format("15:04")
+			endStr := e.End.Format("15:04")
+			sb.WriteString(fmt.Sprintf("- [%s-%s] %s\n", timeStr, endStr, e.Summary))
+		}
 
-			return sb.String(), nil
-		},
+		if e.Location != "" {
+			sb.WriteString(fmt.Sprintf("  Location: %s\n", e.Location))
+		}
 	}
+
+	return sb.String()
+}
+
+// FetchCalendarEvents reads the events of an iCal feed that overlap the
+// given range. The scheduler uses it for calendar triggers.
+func FetchCalendarEvents(ctx context.Context, url string, start, end time.Time) ([]Event, error) {
+	return fetchCalendarEvents(ctx, url, start, end)
 }
 
 // fetchCalendarEvents fetches and parses an iCal feed
@@ -3,6 +3,8 @@ package mcp
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -10,18 +12,20 @@ import (
 type ChatProviderLookup interface {
 	GetActiveProviderNames() []string
 	SendViaProvider(provider, target, content string) error
+	SendFileViaProvider(provider, target, path, caption string) error
 }
 
 // RegisterChatTools adds the unified chat_send tool to the MCP server.
 // The tool is always registered; providers are resolved dynamically at call time.
-func RegisterChatTools(s *Server, lookup ChatProviderLookup) {
-	s.RegisterTool(chatSendTool(lookup))
+// Files sent with the tool must be inside aiDataPath.
+func RegisterChatTools(s *Server, lookup ChatProviderLookup, aiDataPath string) {
+	s.RegisterTool(chatSendTool(lookup, aiDataPath))
 }
 
-func chatSendTool(lookup ChatProviderLookup) *Tool {
+func chatSendTool(lookup ChatProviderLookup, basePath string) *Tool {
 	return &Tool{
 		Name: "chat_send",
-		Description: "Send a message via a chat provider. " +
+		Description: "Send a message via a chat provider, optionally with a file from the workspace. " +
 			"Use 'user:<id>' for DMs or 'channel:<id>' for channels. " +
 			"Target ID format depends on the provider.",
 		InputSchema: map[string]interface{}{
@@ -37,18 +41,23 @@ func chatSendTool(lookup ChatProviderLookup) *Tool {
 				},
 				"message": map[string]interface{}{
 					"type":        "string",
-					"description": "Message content to send",
+					"description": "Message content to send (the caption when a file is sent)",
+				},
+				"file": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional file to send, as a path relative to workspace root (e.g. 'reports/summary.pdf')",
 				},
 			},
-			"required": []string{"provider", "target", "message"},
+			"required": []string{"provider", "target"},
 		},
 		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 			provider, _ := args["provider"].(string)
 			target, _ := args["target"].(string)
 			message, _ := args["message"].(string)
+			file, _ := args["file"].(string)
 
-			if provider == "" || target == "" || message == "" {
-				return nil, fmt.Errorf("provider, target, and message are all required")
+			if provider == "" || target == "" || (message == "" && file == "") {
+				return nil, fmt.Errorf("provider, target, and message or file are required")
 			}
 
 			// Validate provider is active
@@ -64,6 +73,17 @@ func chatSendTool(lookup ChatProviderLookup) *Tool {
 				return nil, fmt.Errorf("provider %q is not active (available: %s)", provider, strings.Join(active, ", "))
 			}
 
+			if file != "" {
+				fullPath, err := workspaceFile(basePath, file)
+				if err != nil {
+					return nil, err
+				}
+				if err := lookup.SendFileViaProvider(provider, target, fullPath, message); err != nil {
+					return nil, fmt.Errorf("failed to send file: %w", err)
+				}
+				return fmt.Sprintf("File %s sent via %s to %s", file, provider, target), nil
+			}
+
 			if err := lookup.SendViaProvider(provider, target, message); err != nil {
 				return nil, fmt.Errorf("failed to send message: %w", err)
 			}
@@ -72,3 +92,21 @@ func chatSendTool(lookup ChatProviderLookup) *Tool {
 		},
 	}
 }
+
+// workspaceFile resolves a path relative to the workspace root and checks
+// that it names a regular file inside it.
+func workspaceFile(basePath, path string) (string, error) {
+	fullPath := filepath.Join(basePath, path)
+	rel, err := filepath.Rel(basePath, fullPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path escapes workspace")
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a file", path)
+	}
+	return fullPath, nil
+}
@@ -3,6 +3,8 @@ package mcp
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -13,6 +15,7 @@ type mockChatLookup struct {
 	sentProvider    string
 	sentTarget      string
 	sentMessage     string
+	sentFile        string
 	sendErr         error
 }
 
@@ -27,12 +30,20 @@ func (m *mockChatLookup) SendViaProvider(provider, target, content string) error
 	return m.sendErr
 }
 
+func (m *mockChatLookup) SendFileViaProvider(provider, target, path, caption string) error {
+	m.sentProvider = provider
+	m.sentTarget = target
+	m.sentFile = path
+	m.sentMessage = caption
+	return m.sendErr
+}
+
 func TestChatSendTool(t *testing.T) {
 	lookup := &mockChatLookup{
 		activeProviders: []string{"discord", "telegram"},
 	}
 
-	tool := chatSendTool(lookup)
+	tool := chatSendTool(lookup, t.TempDir())
 
 	if tool.Name != "chat_send" {
 		t.Errorf("expected name 'chat_send', got '%s'", tool.Name)
@@ -68,7 +79,7 @@ func TestChatSendToolMissingFields(t *testing.T) {
 	lookup := &mockChatLookup{
 		activeProviders: []string{"discord"},
 	}
-	tool := chatSendTool(lookup)
+	tool := chatSendTool(lookup, t.TempDir())
 
 	tests := []struct {
 		name string
@@ -103,7 +114,7 @@ func TestChatSendToolSenderError(t *testing.T) {
 		activeProviders: []string{"discord"},
 		sendErr:         fmt.Errorf("send failed"),
 	}
-	tool := chatSendTool(lookup)
+	tool := chatSendTool(lookup, t.TempDir())
 
 	args := map[string]interface{}{
 		"provider": "discord",
@@ -124,7 +135,7 @@ func TestChatSendToolInactiveProvider(t *testing.T) {
 	lookup := &mockChatLookup{
 		activeProviders: []string{"discord"},
 	}
-	tool := chatSendTool(lookup)
+	tool := chatSendTool(lookup, t.TempDir())
 
 	args := map[string]interface{}{
 		"provider": "telegram",
@@ -141,13 +152,52 @@ func TestChatSendToolInactiveProvider(t *testing.T) {
 	}
 }
 
+func TestChatSendToolFile(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "reports"), 0755)
+	os.WriteFile(filepath.Join(dir, "reports", "summary.pdf"), []byte("%PDF-1.4"), 0644)
+
+	lookup := &mockChatLookup{activeProviders: []string{"discord"}}
+	tool := chatSendTool(lookup, dir)
+
+	result, err := tool.Handler(context.Background(), map[string]interface{}{
+		"provider": "discord",
+		"target":   "channel:42",
+		"file":     "reports/summary.pdf",
+		"message":  "Here is the report",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lookup.sentFile != filepath.Join(dir, "reports", "summary.pdf") {
+		t.Errorf("sent file = %q", lookup.sentFile)
+	}
+	if lookup.sentMessage != "Here is the report" {
+		t.Errorf("caption = %q", lookup.sentMessage)
+	}
+	if !strings.Contains(result.(string), "File reports/summary.pdf sent") {
+		t.Errorf("unexpected result: %v", result)
+	}
+
+	for _, path := range []string{"../secure/config.yaml", "reports", "missing.txt"} {
+		_, err := tool.Handler(context.Background(), map[string]interface{}{
+			"provider": "discord",
+			"target":   "channel:42",
+			"file":     path,
+		})
+		if err == nil {
+			t.Errorf("expected error sending %q", path)
+		}
+	}
+}
+
 func TestRegisterChatTools(t *testing.T) {
 	s := NewServer(nil, nil)
 	lookup := &mockChatLookup{
 		activeProviders: []string{"discord", "telegram"},
 	}
 
-	RegisterChatTools(s, lookup)
+	RegisterChatTools(s, lookup, t.TempDir())
 
 	tools := s.ListTools()
 	if len(tools) != 1 {
@@ -1,63 +1,306 @@
 package mcp
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-pact/openpact/internal/logging"
 )
 
 // MCPPort is the fixed port for the in-process MCP HTTP server.
 const MCPPort = 3100
 
-// HTTPHandler returns an http.Handler implementing Streamable HTTP transport
-// for the MCP server. Only POST is supported (JSON-RPC request → JSON response).
+// CorrelationHeader carries the correlation ID of the request that triggered
+// an MCP call. OpenCode does not forward it, so the server falls back to its
+// CorrelationResolver when it is absent.
+const CorrelationHeader = "X-Correlation-ID"
+
+// streamKeepAlive is how often an idle SSE stream gets a comment line, so
+// proxies and clients do not time it out.
+var streamKeepAlive = 30 * time.Second
+
+// HTTPHandler returns an http.Handler implementing the Streamable HTTP
+// transport for the MCP server:
+//
+//   - POST carries a JSON-RPC message or batch. Responses are returned as
+//     JSON, or as an SSE stream (with progress notifications) when the client
+//     accepts text/event-stream and sent a progress token.
+//   - GET opens an SSE stream of server-initiated notifications, such as
+//     resource updates, for a session.
+//   - DELETE ends a session.
+//
+// initialize assigns a session ID, returned in the Mcp-Session-Id header.
+// Requests without the header are handled statelessly.
 func (s *Server) HTTPHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("MCP HTTP: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+		ctx := logging.WithCorrelationID(r.Context(), s.correlationID(r))
+		logger := s.logger(ctx).WithField("transport", "http")
+
+		logger.Debug("%s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
 
-		if r.Method != http.MethodPost {
-			log.Printf("MCP HTTP: rejecting %s (only POST allowed)", r.Method)
+		switch r.Method {
+		case http.MethodPost:
+			s.servePost(ctx, logger, w, r)
+		case http.MethodGet:
+			s.serveStream(ctx, logger, w, r)
+		case http.MethodDelete:
+			s.serveDelete(logger, w, r)
+		default:
+			logger.Warn("rejecting %s (only GET, POST and DELETE allowed)", r.Method)
+			w.Header().Set("Allow", "GET, POST, DELETE")
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
 		}
+	})
+}
+
+// servePost handles a POSTed JSON-RPC message or batch.
+func (s *Server) servePost(ctx context.Context, logger *logging.Logger, w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.requestSession(logger, w, r)
+	if !ok {
+		return
+	}
+
+	// Read and parse JSON-RPC message(s)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		logger.Error("failed to read body: %v", err)
+		writeJSONRPCError(w, nil, -32700, "Failed to read request body")
+		return
+	}
+
+	msgs, batch, err := parseMessages(body)
+	if err != nil {
+		logger.Warn("parse error: %v (body: %s)", err, logger.Redact(truncate(string(body), 200)))
+		writeJSONRPCError(w, nil, -32700, "Parse error")
+		return
+	}
+
+	var reqs []Request
+	for _, msg := range msgs {
+		logger.Debug("message method=%s id=%v", msg.Method, msg.ID)
+		if isRequest(msg) {
+			reqs = append(reqs, msg)
+		}
+		if msg.Method == "initialize" && sess == nil {
+			if sess, err = s.createSession(); err != nil {
+				logger.Error("%v", err)
+				writeJSONRPCError(w, msg.ID, -32603, "Failed to create session")
+				return
+			}
+			w.Header().Set(SessionHeader, sess.id)
+			logger.Info("Started session %s", sess.id)
+		}
+	}
+	if sess != nil {
+		ctx = withSession(ctx, sess)
+	}
+
+	// Notifications and responses only: nothing to answer
+	if len(reqs) == 0 {
+		s.processMessages(ctx, msgs)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if flusher, ok := w.(http.Flusher); ok && acceptsEventStream(r) && wantsProgress(reqs) {
+		s.streamResponses(ctx, logger, w, flusher, msgs, reqs)
+		return
+	}
+
+	// Process the messages using the shared logic
+	responses := s.processMessages(ctx, msgs)
+	logResponses(logger, reqs, responses)
+
+	// Write JSON-RPC response(s)
+	var out interface{} = responses[0]
+	if batch {
+		out = responses
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		logger.Error("failed to write response: %v", err)
+	}
+}
 
-		// Read and parse JSON-RPC request
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			log.Printf("MCP HTTP: failed to read body: %v", err)
-			writeJSONRPCError(w, nil, -32700, "Failed to read request body")
+// streamResponses answers a POST with an SSE stream: progress notifications
+// are sent as the requests run, followed by one event per response.
+func (s *Server) streamResponses(ctx context.Context, logger *logging.Logger, w http.ResponseWriter, flusher http.Flusher, msgs, reqs []Request) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var mu sync.Mutex
+	closed := false
+	write := func(v interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return // a late progress update after the responses were sent
+		}
+		if err := writeEvent(w, v); err != nil {
+			logger.Warn("failed to write event: %v", err)
 			return
 		}
+		flusher.Flush()
+	}
 
-		var req Request
-		if err := json.Unmarshal(body, &req); err != nil {
-			log.Printf("MCP HTTP: parse error: %v (body: %s)", err, truncate(string(body), 200))
-			writeJSONRPCError(w, nil, -32700, "Parse error")
+	ctx = withNotify(ctx, func(n Notification) { write(n) })
+	responses := s.processMessages(ctx, msgs)
+	logResponses(logger, reqs, responses)
+	for _, resp := range responses {
+		write(resp)
+	}
+
+	mu.Lock()
+	closed = true
+	mu.Unlock()
+}
+
+// serveStream handles GET: an SSE stream delivering the session's
+// server-initiated notifications until the client disconnects or the
+// session ends.
+func (s *Server) serveStream(ctx context.Context, logger *logging.Logger, w http.ResponseWriter, r *http.Request) {
+	if !acceptsEventStream(r) {
+		logger.Warn("rejecting GET without Accept: text/event-stream")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, ok := s.requestSession(logger, w, r)
+	if !ok {
+		return
+	}
+	if sess == nil {
+		http.Error(w, "Missing "+SessionHeader+" header", http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	sess.streams++
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		sess.streams--
+		sess.lastSeen = time.Now()
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	logger.Debug("opened stream for session %s", sess.id)
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
 			return
+		case <-sess.done:
+			return
+		case n := <-sess.out:
+			if err := writeEvent(w, n); err != nil {
+				logger.Warn("failed to write event: %v", err)
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
 		}
+		flusher.Flush()
+	}
+}
 
-		log.Printf("MCP HTTP: request method=%s id=%v", req.Method, req.ID)
+// serveDelete handles DELETE: the client ending its session.
+func (s *Server) serveDelete(logger *logging.Logger, w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.requestSession(logger, w, r)
+	if !ok {
+		return
+	}
+	if sess == nil {
+		http.Error(w, "Missing "+SessionHeader+" header", http.StatusBadRequest)
+		return
+	}
+	s.closeSession(sess)
+	logger.Info("Ended session %s", sess.id)
+	w.WriteHeader(http.StatusNoContent)
+}
 
-		// Process the request using the shared logic
-		resp := s.processRequest(r.Context(), req)
+// requestSession resolves the request's Mcp-Session-Id header. It returns a
+// nil session if there is no header, and false (after writing a 404) if the
+// header names a session that does not exist or has ended.
+func (s *Server) requestSession(logger *logging.Logger, w http.ResponseWriter, r *http.Request) (*session, bool) {
+	id := r.Header.Get(SessionHeader)
+	if id == "" {
+		return nil, true
+	}
+	sess := s.lookupSession(id)
+	if sess == nil {
+		logger.Warn("unknown session %s", truncate(id, 16))
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return nil, false
+	}
+	return sess, true
+}
+
+// acceptsEventStream reports whether the client accepts SSE responses.
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(strings.Join(r.Header.Values("Accept"), ","), "text/event-stream")
+}
+
+// writeEvent writes a JSON-RPC message as an SSE "message" event.
+func writeEvent(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+	return err
+}
 
+// logResponses logs the outcome of each request.
+func logResponses(logger *logging.Logger, reqs []Request, responses []Response) {
+	for i, resp := range responses {
 		if resp.Error != nil {
-			log.Printf("MCP HTTP: response error code=%d msg=%s", resp.Error.Code, resp.Error.Message)
+			logger.Warn("response error method=%s code=%d msg=%s", reqs[i].Method, resp.Error.Code, resp.Error.Message)
 		} else {
-			log.Printf("MCP HTTP: response OK for method=%s", req.Method)
+			logger.Debug("response OK for method=%s", reqs[i].Method)
 		}
+	}
+}
+
+// correlationID picks the correlation ID for an HTTP request: the header if
+// present, then the resolver, then a fresh ID.
+func (s *Server) correlationID(r *http.Request) string {
+	if id := r.Header.Get(CorrelationHeader); id != "" {
+		return id
+	}
 
-		// Write JSON-RPC response
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(resp); err != nil {
-			log.Printf("MCP HTTP: failed to write response: %v", err)
+	s.mu.RLock()
+	resolve := s.correlation
+	s.mu.RUnlock()
+
+	if resolve != nil {
+		if id := resolve(); id != "" {
+			return id
 		}
-	})
+	}
+	return logging.NewCorrelationID()
 }
 
 // truncate truncates a string to maxLen, appending "..." if truncated.
@@ -74,7 +317,7 @@ func BearerTokenMiddleware(token string, next http.Handler) http.Handler {
 		auth := r.Header.Get("Authorization")
 		expected := "Bearer " + token
 		if !strings.EqualFold(auth, expected) {
-			log.Printf("MCP HTTP: auth rejected from %s (got %q)", r.RemoteAddr, truncate(auth, 20))
+			logging.Standard().WithField("component", "mcp").Warn("HTTP auth rejected from %s (got %q)", r.RemoteAddr, truncate(auth, 20))
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
@@ -100,6 +343,6 @@ func writeJSONRPCError(w http.ResponseWriter, id interface{}, code int, message
 		Error:   &Error{Code: code, Message: message},
 	}
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.Printf("MCP HTTP: failed to write error response: %v", err)
+		logging.Standard().WithField("component", "mcp").Error("HTTP: failed to write error response: %v", err)
 	}
 }
@@ -1,12 +1,17 @@
 package mcp
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/open-pact/openpact/internal/logging"
 )
 
 func newTestServerWithTool() *Server {
@@ -205,16 +210,29 @@ func TestHTTPHandler_MethodNotAllowed_GET(t *testing.T) {
 	}
 }
 
-func TestHTTPHandler_MethodNotAllowed_DELETE(t *testing.T) {
+func TestHTTPHandler_MethodNotAllowed_PUT(t *testing.T) {
 	s := newTestServerWithTool()
 	handler := s.HTTPHandler()
 
 	w := httptest.NewRecorder()
-	r := httptest.NewRequest(http.MethodDelete, "/mcp", nil)
+	r := httptest.NewRequest(http.MethodPut, "/mcp", nil)
 	handler.ServeHTTP(w, r)
 
 	if w.Code != http.StatusMethodNotAllowed {
-		t.Errorf("expected 405 for DELETE, got %d", w.Code)
+		t.Errorf("expected 405 for PUT, got %d", w.Code)
+	}
+}
+
+func TestHTTPHandler_DeleteWithoutSession(t *testing.T) {
+	s := newTestServerWithTool()
+	handler := s.HTTPHandler()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodDelete, "/mcp", nil)
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for DELETE without a session, got %d", w.Code)
 	}
 }
 
@@ -313,3 +331,233 @@ func TestHTTPHandler_InvalidJSON(t *testing.T) {
 		t.Errorf("expected error code -32700 (parse error), got %d", resp.Error.Code)
 	}
 }
+
+func TestHTTPHandler_CorrelationID(t *testing.T) {
+	s := NewServer(nil, nil)
+	var seen string
+	s.RegisterTool(&Tool{
+		Name: "whoami",
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			seen = logging.CorrelationID(ctx)
+			return "ok", nil
+		},
+	})
+	s.SetCorrelationResolver(func() string { return "from-resolver" })
+	handler := s.HTTPHandler()
+
+	call := Request{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "tools/call",
+		Params:  map[string]interface{}{"name": "whoami"},
+	}
+
+	postJSONRPC(t, handler, call)
+	if seen != "from-resolver" {
+		t.Errorf("without header: correlation ID = %q, want %q", seen, "from-resolver")
+	}
+
+	body, _ := json.Marshal(call)
+	r := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewReader(body))
+	r.Header.Set(CorrelationHeader, "from-header")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if seen != "from-header" {
+		t.Errorf("with header: correlation ID = %q, want %q", seen, "from-header")
+	}
+}
+
+// postRaw posts a raw body with optional session ID and Accept header.
+func postRaw(handler http.Handler, body, sessionID, accept string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", accept)
+	if sessionID != "" {
+		r.Header.Set(SessionHeader, sessionID)
+	}
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestHTTPHandler_Sessions(t *testing.T) {
+	s := newTestServerWithTool()
+	handler := s.HTTPHandler()
+
+	w := postRaw(handler, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`, "", "application/json")
+	id := w.Header().Get(SessionHeader)
+	if w.Code != http.StatusOK || id == "" {
+		t.Fatalf("initialize: code %d, session %q", w.Code, id)
+	}
+	if !strings.Contains(w.Body.String(), `"protocolVersion":"2025-03-26"`) {
+		t.Errorf("expected negotiated protocol version, got %s", w.Body.String())
+	}
+
+	if w := postRaw(handler, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`, id, "application/json"); w.Code != http.StatusOK {
+		t.Errorf("tools/list with session: code %d", w.Code)
+	}
+	if w := postRaw(handler, `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`, "nope", "application/json"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown session: expected 404, got %d", w.Code)
+	}
+
+	r := httptest.NewRequest(http.MethodDelete, "/mcp", nil)
+	r.Header.Set(SessionHeader, id)
+	del := httptest.NewRecorder()
+	handler.ServeHTTP(del, r)
+	if del.Code != http.StatusNoContent {
+		t.Errorf("DELETE: expected 204, got %d", del.Code)
+	}
+	if w := postRaw(handler, `{"jsonrpc":"2.0","id":4,"method":"tools/list"}`, id, "application/json"); w.Code != http.StatusNotFound {
+		t.Errorf("ended session: expected 404, got %d", w.Code)
+	}
+}
+
+func TestHTTPHandler_Batch(t *testing.T) {
+	s := newTestServerWithTool()
+	handler := s.HTTPHandler()
+
+	w := postRaw(handler, `[
+		{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"message":"one"}}},
+		{"jsonrpc":"2.0","method":"notifications/initialized"},
+		{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"message":"two"}}}
+	]`, "", "application/json")
+
+	var responses []Response
+	if err := json.NewDecoder(w.Body).Decode(&responses); err != nil {
+		t.Fatalf("failed to decode batch response: %v", err)
+	}
+	if len(responses) != 2 || responses[0].ID != float64(1) || responses[1].ID != float64(2) {
+		t.Fatalf("responses = %+v", responses)
+	}
+
+	w = postRaw(handler, `{"jsonrpc":"2.0","method":"notifications/initialized"}`, "", "application/json")
+	if w.Code != http.StatusAccepted || w.Body.Len() != 0 {
+		t.Errorf("notification only: expected empty 202, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = postRaw(handler, `[]`, "", "application/json")
+	if !strings.Contains(w.Body.String(), "-32700") {
+		t.Errorf("empty batch: expected parse error, got %s", w.Body.String())
+	}
+}
+
+func TestHTTPHandler_ProgressStream(t *testing.T) {
+	s := NewServer(nil, nil)
+	s.RegisterTool(&Tool{
+		Name: "slow",
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			ReportProgress(ctx, "step one")
+			ReportProgress(ctx, "step two")
+			return "done", nil
+		},
+	})
+	handler := s.HTTPHandler()
+	call := `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"slow","_meta":{"progressToken":"p1"}}}`
+
+	w := postRaw(handler, call, "", "application/json, text/event-stream")
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected SSE response, got %s", ct)
+	}
+	events := strings.Split(strings.TrimSpace(w.Body.String()), "\n\n")
+	if len(events) != 3 {
+		t.Fatalf("expected 2 progress events and a response, got %q", w.Body.String())
+	}
+	if !strings.Contains(events[0], `"method":"notifications/progress"`) || !strings.Contains(events[0], `"progressToken":"p1"`) || !strings.Contains(events[0], `"progress":1`) {
+		t.Errorf("first event = %s", events[0])
+	}
+	if !strings.Contains(events[1], `"progress":2`) || !strings.Contains(events[1], "step two") {
+		t.Errorf("second event = %s", events[1])
+	}
+	if !strings.HasPrefix(events[2], "event: message\ndata: ") || !strings.Contains(events[2], `"id":7`) || !strings.Contains(events[2], "done") {
+		t.Errorf("response event = %s", events[2])
+	}
+
+	// Clients that only accept JSON get a plain response
+	w = postRaw(handler, call, "", "application/json")
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON response, got %s", ct)
+	}
+}
+
+func TestHTTPHandler_Cancel(t *testing.T) {
+	s := NewServer(nil, nil)
+	started := make(chan struct{})
+	s.RegisterTool(&Tool{
+		Name: "wait",
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	handler := s.HTTPHandler()
+
+	id := postRaw(handler, `{"jsonrpc":"2.0","id":1,"method":"initialize"}`, "", "application/json").Header().Get(SessionHeader)
+
+	result := make(chan *httptest.ResponseRecorder)
+	go func() {
+		result <- postRaw(handler, `{"jsonrpc":"2.0","id":"call-1","method":"tools/call","params":{"name":"wait"}}`, id, "application/json")
+	}()
+	<-started
+
+	w := postRaw(handler, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"call-1","reason":"user stopped"}}`, id, "application/json")
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("cancel: expected 202, got %d", w.Code)
+	}
+
+	select {
+	case w := <-result:
+		if !strings.Contains(w.Body.String(), "context canceled") {
+			t.Errorf("expected cancelled tool call, got %s", w.Body.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("tool call was not cancelled")
+	}
+}
+
+func TestHTTPHandler_NotificationStream(t *testing.T) {
+	s := newTestServerWithTool()
+	srv := httptest.NewServer(s.HTTPHandler())
+	defer srv.Close()
+
+	id := postRaw(s.HTTPHandler(), `{"jsonrpc":"2.0","id":1,"method":"initialize"}`, "", "application/json").Header().Get(SessionHeader)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(SessionHeader, id)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("GET: status %d, type %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	s.deliver(s.lookupSession(id), Notification{JSONRPC: "2.0", Method: "notifications/resources/updated", Params: map[string]interface{}{"uri": "openpact://memory/MEMORY.md"}})
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream: %v", err)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			if !strings.Contains(line, "notifications/resources/updated") {
+				t.Errorf("unexpected event: %s", line)
+			}
+			break
+		}
+	}
+
+	// Without a session there is nothing to stream
+	req, _ = http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp2, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusBadRequest {
+		t.Errorf("GET without session: expected 400, got %d", resp2.StatusCode)
+	}
+}
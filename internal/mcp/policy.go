@@ -0,0 +1,144 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Policy decides whether a tool call runs without asking.
+type Policy string
+
+const (
+	PolicyAuto    Policy = "auto"    // Run the call
+	PolicyConfirm Policy = "confirm" // Ask the owner first
+	PolicyDeny    Policy = "deny"    // Never run the call; the tool is not listed
+)
+
+// DefaultConfirmTimeout is how long a confirm call waits for an answer when
+// the policy does not set a timeout.
+const DefaultConfirmTimeout = 5 * time.Minute
+
+// ErrApprovalTimeout is returned by a ToolApprover when nobody answered
+// before the deadline of its context.
+var ErrApprovalTimeout = errors.New("approval timed out")
+
+// ParsePolicy parses a policy name. The empty string is PolicyAuto.
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case "":
+		return PolicyAuto, nil
+	case PolicyAuto, PolicyConfirm, PolicyDeny:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown tool policy %q (want auto, confirm or deny)", s)
+	}
+}
+
+// ToolPolicy sets the policy of each tool.
+type ToolPolicy struct {
+	Default Policy            // Policy of tools not in Tools; "" is PolicyAuto
+	Tools   map[string]Policy // Policy by tool name
+	Timeout time.Duration     // How long confirm calls wait; 0 is DefaultConfirmTimeout
+}
+
+// For returns the policy of the named tool.
+func (p *ToolPolicy) For(tool string) Policy {
+	if p == nil {
+		return PolicyAuto
+	}
+	if policy, ok := p.Tools[tool]; ok && policy != "" {
+		return policy
+	}
+	if p.Default != "" {
+		return p.Default
+	}
+	return PolicyAuto
+}
+
+// confirmTimeout returns how long confirm calls wait for an answer.
+func (p *ToolPolicy) confirmTimeout() time.Duration {
+	if p == nil || p.Timeout <= 0 {
+		return DefaultConfirmTimeout
+	}
+	return p.Timeout
+}
+
+// ToolApprover asks the owner whether a tool call may run (implemented by
+// the orchestrator, which posts the question to the chat the call came from
+// and to the admin UI).
+type ToolApprover interface {
+	// ApproveToolCall blocks until the call is approved or denied, or ctx is
+	// done. It returns ErrApprovalTimeout if ctx's deadline passed first.
+	ApproveToolCall(ctx context.Context, tool string, args map[string]interface{}) (bool, error)
+}
+
+// SetToolPolicy sets the per-tool policy. Without one, every tool runs.
+func (s *Server) SetToolPolicy(p *ToolPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = p
+}
+
+// SetToolApprover sets the approver asked about confirm calls. Without one,
+// confirm calls fail.
+func (s *Server) SetToolApprover(a ToolApprover) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.approver = a
+}
+
+// checkDenied returns an error if the tool's policy is deny. It runs before
+// the rate limit, so denied calls do not use up the tool's tokens.
+func (s *Server) checkDenied(ctx context.Context, name string) error {
+	s.mu.RLock()
+	policy := s.policy
+	s.mu.RUnlock()
+
+	if policy.For(name) == PolicyDeny {
+		s.logger(ctx).WithField("tool", name).Warn("Denied by tool policy")
+		return fmt.Errorf("tool %s is disabled by policy", name)
+	}
+	return nil
+}
+
+// checkPolicy asks the approver about calls to confirm tools and returns an
+// error unless they are approved. Deny tools are refused earlier by
+// checkDenied.
+func (s *Server) checkPolicy(ctx context.Context, name string, args map[string]interface{}) error {
+	s.mu.RLock()
+	policy := s.policy
+	approver := s.approver
+	s.mu.RUnlock()
+
+	logger := s.logger(ctx).WithField("tool", name)
+
+	switch policy.For(name) {
+	case PolicyConfirm:
+		if approver == nil {
+			return fmt.Errorf("tool %s requires approval, but no approver is configured", name)
+		}
+	default:
+		return nil
+	}
+
+	logger.Info("Waiting for approval")
+	ReportProgress(ctx, fmt.Sprintf("Waiting for approval to run %s", name))
+
+	ctx, cancel := context.WithTimeout(ctx, policy.confirmTimeout())
+	defer cancel()
+	approved, err := approver.ApproveToolCall(ctx, name, args)
+	switch {
+	case errors.Is(err, ErrApprovalTimeout):
+		logger.Warn("Approval timed out")
+		return fmt.Errorf("tool %s was not approved in time", name)
+	case err != nil:
+		return fmt.Errorf("approval failed: %w", err)
+	case !approved:
+		logger.Info("Call denied by owner")
+		return fmt.Errorf("tool %s was denied by the owner", name)
+	}
+	logger.Info("Call approved")
+	return nil
+}
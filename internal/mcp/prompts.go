@@ -0,0 +1,230 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/open-pact/openpact/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// Prompt is a reusable prompt template (MCP prompts/list).
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+
+	body string
+}
+
+// PromptArgument is a value substituted into a prompt template.
+type PromptArgument struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description"`
+	Required    bool   `json:"required,omitempty" yaml:"required"`
+}
+
+// promptNamePattern limits template names to safe file names.
+var promptNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// placeholderPattern matches {{name}} placeholders in a template body.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_-]+)\s*\}\}`)
+
+// PromptLibrary serves prompt templates from a directory of Markdown files.
+// Each <name>.md file is one prompt; optional YAML frontmatter sets its
+// description and arguments, and {{argument}} placeholders in the body are
+// filled in by prompts/get. Files are read on every request, so templates
+// the AI writes with workspace_write are available immediately.
+type PromptLibrary struct {
+	dir string
+}
+
+// NewPromptLibrary creates a library reading templates from dir.
+func NewPromptLibrary(dir string) *PromptLibrary {
+	return &PromptLibrary{dir: dir}
+}
+
+// List returns all templates, sorted by name. Templates with invalid
+// frontmatter are logged and skipped.
+func (l *PromptLibrary) List() ([]Prompt, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Prompt{}, nil
+		}
+		return nil, fmt.Errorf("failed to list prompts: %w", err)
+	}
+
+	prompts := []Prompt{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".md")
+		if !ok || entry.IsDir() || !promptNamePattern.MatchString(name) {
+			continue
+		}
+		p, err := l.load(name)
+		if err != nil {
+			logging.Standard().WithField("component", "mcp").Warn("Skipping prompt %s: %v", entry.Name(), err)
+			continue
+		}
+		prompts = append(prompts, *p)
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	return prompts, nil
+}
+
+// Get renders the named template with args. Required arguments must be
+// given; optional ones that are missing are replaced with an empty string.
+func (l *PromptLibrary) Get(name string, args map[string]string) (*Prompt, string, error) {
+	if !promptNamePattern.MatchString(name) {
+		return nil, "", fmt.Errorf("prompt '%s' not found", name)
+	}
+	p, err := l.load(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", fmt.Errorf("prompt '%s' not found", name)
+		}
+		return nil, "", err
+	}
+
+	declared := make(map[string]bool, len(p.Arguments))
+	for _, arg := range p.Arguments {
+		declared[arg.Name] = true
+		if arg.Required && args[arg.Name] == "" {
+			return nil, "", fmt.Errorf("missing required argument '%s'", arg.Name)
+		}
+	}
+
+	text := placeholderPattern.ReplaceAllStringFunc(p.body, func(m string) string {
+		key := placeholderPattern.FindStringSubmatch(m)[1]
+		if !declared[key] {
+			return m // not an argument; leave literal braces alone
+		}
+		return args[key]
+	})
+	return p, strings.TrimSpace(text), nil
+}
+
+// load reads and parses a template file.
+func (l *PromptLibrary) load(name string) (*Prompt, error) {
+	data, err := os.ReadFile(filepath.Join(l.dir, name+".md"))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Prompt{Name: name, body: string(data)}
+	front, body, ok := splitFrontmatter(data)
+	if !ok {
+		return p, nil
+	}
+
+	var meta struct {
+		Description string           `yaml:"description"`
+		Arguments   []PromptArgument `yaml:"arguments"`
+	}
+	if err := yaml.Unmarshal(front, &meta); err != nil {
+		return nil, fmt.Errorf("invalid frontmatter: %w", err)
+	}
+	for _, arg := range meta.Arguments {
+		if !promptNamePattern.MatchString(arg.Name) {
+			return nil, fmt.Errorf("invalid argument name '%s'", arg.Name)
+		}
+	}
+	p.Description = meta.Description
+	p.Arguments = meta.Arguments
+	p.body = string(body)
+	return p, nil
+}
+
+// splitFrontmatter separates a leading "---" delimited YAML block from the
+// rest of a Markdown file.
+func splitFrontmatter(data []byte) (front, body []byte, ok bool) {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	rest, found := bytes.CutPrefix(data, []byte("---\n"))
+	if !found {
+		return nil, data, false
+	}
+	if after, found := bytes.CutPrefix(rest, []byte("---\n")); found {
+		return nil, after, true // empty frontmatter
+	}
+	end := bytes.Index(rest, []byte("\n---\n"))
+	if end < 0 {
+		if !bytes.HasSuffix(rest, []byte("\n---")) {
+			return nil, data, false
+		}
+		return rest[:len(rest)-len("\n---")], nil, true
+	}
+	return rest[:end], rest[end+len("\n---\n"):], true
+}
+
+// SetPromptLibrary sets the templates served by prompts/list and prompts/get.
+func (s *Server) SetPromptLibrary(l *PromptLibrary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prompts = l
+}
+
+// handlePromptsList handles prompts/list
+func (s *Server) handlePromptsList(ctx context.Context) (interface{}, *Error) {
+	s.mu.RLock()
+	library := s.prompts
+	s.mu.RUnlock()
+
+	prompts := []Prompt{}
+	if library != nil {
+		var err error
+		if prompts, err = library.List(); err != nil {
+			return nil, &Error{Code: -32000, Message: err.Error()}
+		}
+	}
+	return map[string]interface{}{
+		"prompts": prompts,
+	}, nil
+}
+
+// handlePromptsGet handles prompts/get, returning the rendered template as a
+// single user message.
+func (s *Server) handlePromptsGet(ctx context.Context, req Request) (interface{}, *Error) {
+	s.mu.RLock()
+	library := s.prompts
+	s.mu.RUnlock()
+
+	params, _ := req.Params.(map[string]interface{})
+	name, _ := params["name"].(string)
+	if name == "" {
+		return nil, &Error{Code: -32602, Message: "name is required"}
+	}
+	if library == nil {
+		return nil, &Error{Code: -32602, Message: fmt.Sprintf("prompt '%s' not found", name)}
+	}
+
+	args := map[string]string{}
+	rawArgs, _ := params["arguments"].(map[string]interface{})
+	for k, v := range rawArgs {
+		args[k] = fmt.Sprintf("%v", v)
+	}
+
+	p, text, err := library.Get(name, args)
+	if err != nil {
+		return nil, &Error{Code: -32602, Message: err.Error()}
+	}
+	s.logger(ctx).Info("Rendered prompt '%s'", name)
+
+	return map[string]interface{}{
+		"description": p.Description,
+		"messages": []map[string]interface{}{
+			{
+				"role": "user",
+				"content": map[string]interface{}{
+					"type": "text",
+					"text": text,
+				},
+			},
+		},
+	}, nil
+}
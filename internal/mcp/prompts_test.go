@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const summarisePrompt = `---
+description: Summarise a vault note
+arguments:
+  - name: note
+    description: Path of the note
+    required: true
+  - name: style
+    description: Optional tone
+---
+Summarise {{ note }} in a {{style}} tone. Keep {{literal}} braces.
+`
+
+func newPromptLibrary(t *testing.T) *PromptLibrary {
+	t.Helper()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "summarise.md"), []byte(summarisePrompt), 0644)
+	os.WriteFile(filepath.Join(dir, "plain.md"), []byte("Just say hi."), 0644)
+	os.WriteFile(filepath.Join(dir, "broken.md"), []byte("---\narguments: [\n---\nbody"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a prompt"), 0644)
+	return NewPromptLibrary(dir)
+}
+
+func TestPromptLibraryList(t *testing.T) {
+	prompts, err := newPromptLibrary(t).List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(prompts) != 2 || prompts[0].Name != "plain" || prompts[1].Name != "summarise" {
+		t.Fatalf("prompts = %+v", prompts)
+	}
+	s := prompts[1]
+	if s.Description != "Summarise a vault note" || len(s.Arguments) != 2 || !s.Arguments[0].Required || s.Arguments[1].Required {
+		t.Errorf("summarise = %+v", s)
+	}
+
+	missing, err := NewPromptLibrary(filepath.Join(t.TempDir(), "none")).List()
+	if err != nil || len(missing) != 0 {
+		t.Errorf("missing dir: %v, %v", missing, err)
+	}
+}
+
+func TestPromptLibraryGet(t *testing.T) {
+	l := newPromptLibrary(t)
+
+	_, text, err := l.Get("summarise", map[string]string{"note": "Projects/OpenPact.md"})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if text != "Summarise Projects/OpenPact.md in a  tone. Keep {{literal}} braces." {
+		t.Errorf("text = %q", text)
+	}
+
+	if _, _, err := l.Get("summarise", nil); err == nil || !strings.Contains(err.Error(), "note") {
+		t.Errorf("expected missing argument error, got %v", err)
+	}
+	for _, name := range []string{"unknown", "../plain", "broken"} {
+		if _, _, err := l.Get(name, nil); err == nil {
+			t.Errorf("Get(%s) should fail", name)
+		}
+	}
+}
+
+func TestPromptsRequests(t *testing.T) {
+	s := NewServer(nil, nil)
+	s.SetPromptLibrary(newPromptLibrary(t))
+	ctx := context.Background()
+
+	resp := s.processRequest(ctx, Request{JSONRPC: "2.0", ID: 1, Method: "prompts/list"})
+	if resp.Error != nil {
+		t.Fatalf("prompts/list: %v", resp.Error)
+	}
+	if prompts := resp.Result.(map[string]interface{})["prompts"].([]Prompt); len(prompts) != 2 {
+		t.Errorf("prompts = %+v", prompts)
+	}
+
+	resp = s.processRequest(ctx, Request{JSONRPC: "2.0", ID: 2, Method: "prompts/get", Params: map[string]interface{}{
+		"name":      "plain",
+		"arguments": map[string]interface{}{},
+	}})
+	if resp.Error != nil {
+		t.Fatalf("prompts/get: %v", resp.Error)
+	}
+	messages := resp.Result.(map[string]interface{})["messages"].([]map[string]interface{})
+	content := messages[0]["content"].(map[string]interface{})
+	if messages[0]["role"] != "user" || content["text"] != "Just say hi." {
+		t.Errorf("messages = %+v", messages)
+	}
+
+	resp = s.processRequest(ctx, Request{JSONRPC: "2.0", ID: 3, Method: "prompts/get", Params: map[string]interface{}{"name": "summarise"}})
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("expected invalid params error, got %+v", resp.Error)
+	}
+}
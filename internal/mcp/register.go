@@ -1,7 +1,7 @@
 package mcp
 
 import (
-	"log"
+	"path/filepath"
 
 	"github.com/open-pact/openpact/internal/admin"
 	"github.com/open-pact/openpact/internal/starlark"
@@ -26,15 +26,24 @@ type RegistrationConfig struct {
 type ScriptRegistrationConfig struct {
 	ScriptsDir     string
 	MaxExecutionMs int64
+	MaxSteps       uint64
+	MaxMemoryMB    int
 	Secrets        map[string]string
 	ScriptStore    *admin.ScriptStore // nil if approvals not enabled
+
+	ExecCapabilities starlark.Capabilities // Limits for script_exec code
 }
 
-// RegisterAllTools registers all MCP tools on the given server using the provided config.
+// RegisterAllTools registers all MCP tools, resources and prompts on the given server
+// using the provided config.
 // This is used by both the orchestrator (in-process) and the standalone MCP server binary.
 func RegisterAllTools(srv *Server, cfg RegistrationConfig) {
 	// Workspace + memory tools (always registered, scoped to AI data dir)
 	RegisterDefaultTools(srv, cfg.AIDataDir, cfg.ReloadContext)
+	RegisterWorkspaceResources(srv, cfg.AIDataDir)
+
+	// Prompt templates from ai-data/prompts/
+	srv.SetPromptLibrary(NewPromptLibrary(filepath.Join(cfg.AIDataDir, "prompts")))
 
 	// Derive system data dir from workspace path for secrets/approvals
 	dataDir := cfg.WorkspacePath + "/secure/data"
@@ -42,11 +51,13 @@ func RegisterAllTools(srv *Server, cfg RegistrationConfig) {
 	// Calendar tools
 	if len(cfg.Calendars) > 0 {
 		RegisterCalendarTools(srv, cfg.Calendars)
+		RegisterCalendarResources(srv, cfg.Calendars)
 	}
 
 	// Vault tools
 	if cfg.Vault != nil && cfg.Vault.Path != "" {
 		RegisterVaultTools(srv, *cfg.Vault)
+		RegisterVaultResources(srv, *cfg.Vault)
 	}
 
 	// Web tools (always available)
@@ -62,8 +73,12 @@ func RegisterAllTools(srv *Server, cfg RegistrationConfig) {
 		scriptCfg := ScriptConfig{
 			ScriptsDir:     cfg.Script.ScriptsDir,
 			MaxExecutionMs: cfg.Script.MaxExecutionMs,
+			MaxSteps:       cfg.Script.MaxSteps,
+			MaxMemoryMB:    cfg.Script.MaxMemoryMB,
 			Secrets:        cfg.Script.Secrets,
 			ScriptStore:    cfg.Script.ScriptStore,
+
+			ExecCapabilities: cfg.Script.ExecCapabilities,
 		}
 
 		// Load secrets from store if secrets not provided
@@ -71,7 +86,7 @@ func RegisterAllTools(srv *Server, cfg RegistrationConfig) {
 			secretStore := admin.NewSecretStore(dataDir)
 			secrets, err := secretStore.All()
 			if err != nil {
-				log.Printf("Warning: failed to load secrets: %v", err)
+				srv.log.Warn("failed to load secrets: %v", err)
 				secrets = map[string]string{}
 			}
 			scriptCfg.Secrets = secrets
@@ -81,10 +96,10 @@ func RegisterAllTools(srv *Server, cfg RegistrationConfig) {
 		if cfg.Script.ScriptStore == nil {
 			scriptStore, err := admin.NewScriptStore(cfg.Script.ScriptsDir, dataDir, cfg.Allowlist)
 			if err != nil {
-				log.Printf("Warning: failed to create script store: %v", err)
+				srv.log.Warn("failed to create script store: %v", err)
 			} else {
 				scriptCfg.ScriptStore = scriptStore
-				log.Println("Script approval checking enabled")
+				srv.log.Info("Script approval checking enabled")
 			}
 		}
 
@@ -93,7 +108,7 @@ func RegisterAllTools(srv *Server, cfg RegistrationConfig) {
 
 	// Chat tools
 	if cfg.Chat != nil {
-		RegisterChatTools(srv, cfg.Chat)
+		RegisterChatTools(srv, cfg.Chat, cfg.AIDataDir)
 	}
 
 	// Model tools
@@ -0,0 +1,248 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// URI prefixes of the built-in resource families.
+const (
+	WorkspaceResourcePrefix = "openpact://workspace/"
+	MemoryResourcePrefix    = "openpact://memory/"
+	VaultResourcePrefix     = "openpact://vault/"
+	CalendarResourcePrefix  = "openpact://calendar/"
+)
+
+// maxListedResources caps the resources listed per source, so a large vault
+// does not produce an unusable list. Unlisted files can still be read.
+const maxListedResources = 1000
+
+// maxResourceSize caps the size of a file read as a resource.
+const maxResourceSize = 10 << 20
+
+// calendarResourceDays is how far ahead a calendar resource looks.
+const calendarResourceDays = 7
+
+// errListFull stops a directory walk once maxListedResources is reached.
+var errListFull = errors.New("resource list full")
+
+// RegisterWorkspaceResources exposes the AI data directory: memory files
+// (MEMORY.md and memory/*.md) under openpact://memory/ and every other file
+// under openpact://workspace/.
+func RegisterWorkspaceResources(s *Server, aiDataPath string) {
+	isMemory := func(rel string, dir bool) bool {
+		return rel == "memory" || (!dir && rel == "MEMORY.md") || strings.HasPrefix(rel, "memory/")
+	}
+
+	s.RegisterResourceSource(fileResourceSource(MemoryResourcePrefix, aiDataPath, "Memory file", "workspace_read", func(rel string, dir bool) bool {
+		return isMemory(rel, dir) && (dir || strings.HasSuffix(rel, ".md"))
+	}))
+	s.RegisterResourceSource(fileResourceSource(WorkspaceResourcePrefix, aiDataPath, "Workspace file", "workspace_read", func(rel string, dir bool) bool {
+		return !isMemory(rel, dir)
+	}))
+}
+
+// RegisterVaultResources exposes the Markdown notes of the Obsidian vault
+// under openpact://vault/, limited like vault_read.
+func RegisterVaultResources(s *Server, cfg VaultConfig) {
+	s.RegisterResourceSource(fileResourceSource(VaultResourcePrefix, cfg.Path, "Vault note", "vault_read", func(rel string, dir bool) bool {
+		return dir || strings.HasSuffix(rel, ".md")
+	}))
+}
+
+// RegisterCalendarResources exposes each calendar's upcoming events under
+// openpact://calendar/<name>, limited like calendar_read. Feeds are fetched
+// on every read, so calendar resources cannot be subscribed to.
+func RegisterCalendarResources(s *Server, calendars []CalendarConfig) {
+	s.RegisterResourceSource(&ResourceSource{
+		Prefix: CalendarResourcePrefix,
+		Tool:   "calendar_read",
+		ToolArgs: func(uri string) map[string]interface{} {
+			name, _ := url.PathUnescape(strings.TrimPrefix(uri, CalendarResourcePrefix))
+			return map[string]interface{}{"calendar": name}
+		},
+		List: func(ctx context.Context) ([]Resource, error) {
+			resources := make([]Resource, 0, len(calendars))
+			for _, cal := range calendars {
+				resources = append(resources, Resource{
+					URI:         CalendarResourcePrefix + url.PathEscape(cal.Name),
+					Name:        cal.Name,
+					Description: fmt.Sprintf("Events in the next %d days from the %s calendar", calendarResourceDays, cal.Name),
+					MimeType:    "text/markdown",
+				})
+			}
+			return resources, nil
+		},
+		Read: func(ctx context.Context, uri string) (*ResourceContents, error) {
+			name, err := url.PathUnescape(strings.TrimPrefix(uri, CalendarResourcePrefix))
+			if err != nil {
+				return nil, errResourceNotFound
+			}
+			for _, cal := range calendars {
+				if !strings.EqualFold(cal.Name, name) {
+					continue
+				}
+				now := time.Now()
+				events, err := fetchCalendarEvents(ctx, cal.URL, now, now.AddDate(0, 0, calendarResourceDays))
+				if err != nil {
+					return nil, fmt.Errorf("failed to fetch '%s': %w", cal.Name, err)
+				}
+				return &ResourceContents{
+					URI:      uri,
+					MimeType: "text/markdown",
+					Text:     formatEvents(events, calendarResourceDays),
+				}, nil
+			}
+			return nil, errResourceNotFound
+		},
+	})
+}
+
+// fileResourceSource serves the files under root that include accepts,
+// addressed by their slash-separated path relative to root. Hidden files and
+// directories (.git, .obsidian) are never served. include is called with
+// directories too, so whole subtrees can be excluded. Roles see a read as a
+// call to tool with the file's relative path.
+func fileResourceSource(prefix, root, description, tool string, include func(rel string, dir bool) bool) *ResourceSource {
+	// resolve maps a URI to the file it names, or fails with
+	// errResourceNotFound if it is outside the source.
+	resolve := func(uri string) (string, string, error) {
+		rel, err := url.PathUnescape(strings.TrimPrefix(uri, prefix))
+		if err != nil || rel == "" || path.Clean(rel) != rel || strings.HasPrefix(rel, "../") || rel == ".." {
+			return "", "", errResourceNotFound
+		}
+		for _, part := range strings.Split(rel, "/") {
+			if strings.HasPrefix(part, ".") {
+				return "", "", errResourceNotFound
+			}
+		}
+		for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+			if !include(dir, true) {
+				return "", "", errResourceNotFound
+			}
+		}
+		if !include(rel, false) {
+			return "", "", errResourceNotFound
+		}
+		return filepath.Join(root, filepath.FromSlash(rel)), rel, nil
+	}
+
+	return &ResourceSource{
+		Prefix: prefix,
+		Tool:   tool,
+		ToolArgs: func(uri string) map[string]interface{} {
+			rel, _ := url.PathUnescape(strings.TrimPrefix(uri, prefix))
+			return map[string]interface{}{"path": rel}
+		},
+		List: func(ctx context.Context) ([]Resource, error) {
+			resources := []Resource{}
+			err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					if p == root && os.IsNotExist(err) {
+						return fs.SkipDir
+					}
+					return err
+				}
+				if p == root {
+					return nil
+				}
+				rel, _ := filepath.Rel(root, p)
+				rel = filepath.ToSlash(rel)
+				if strings.HasPrefix(d.Name(), ".") || !include(rel, d.IsDir()) {
+					if d.IsDir() {
+						return fs.SkipDir
+					}
+					return nil
+				}
+				if d.IsDir() || !d.Type().IsRegular() {
+					return nil
+				}
+				if len(resources) >= maxListedResources {
+					return errListFull
+				}
+				resources = append(resources, Resource{
+					URI:         prefix + (&url.URL{Path: rel}).EscapedPath(),
+					Name:        rel,
+					Description: description,
+					MimeType:    resourceMimeType(rel),
+				})
+				return ctx.Err()
+			})
+			if err != nil && !errors.Is(err, errListFull) {
+				return nil, err
+			}
+			return resources, nil
+		},
+		Read: func(ctx context.Context, uri string) (*ResourceContents, error) {
+			fullPath, rel, err := resolve(uri)
+			if err != nil {
+				return nil, err
+			}
+			info, err := os.Stat(fullPath)
+			if err != nil || !info.Mode().IsRegular() {
+				return nil, errResourceNotFound
+			}
+			if info.Size() > maxResourceSize {
+				return nil, fmt.Errorf("%s is too large to read as a resource", rel)
+			}
+			data, err := os.ReadFile(fullPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file: %w", err)
+			}
+
+			contents := &ResourceContents{URI: uri, MimeType: resourceMimeType(rel)}
+			if utf8.Valid(data) {
+				contents.Text = string(data)
+				if contents.MimeType == "" {
+					contents.MimeType = "text/plain"
+				}
+			} else {
+				contents.Blob = base64.StdEncoding.EncodeToString(data)
+				if contents.MimeType == "" {
+					contents.MimeType = http.DetectContentType(data)
+				}
+			}
+			return contents, nil
+		},
+		Version: func(uri string) (string, error) {
+			fullPath, _, err := resolve(uri)
+			if err != nil {
+				return "", err
+			}
+			info, err := os.Stat(fullPath)
+			if err != nil {
+				return "", errResourceNotFound
+			}
+			return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()), nil
+		},
+	}
+}
+
+// resourceMimeType guesses a file's type from its extension. Markdown is
+// special-cased because it is missing from many systems' MIME tables.
+func resourceMimeType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	switch ext {
+	case ".md":
+		return "text/markdown"
+	case ".star":
+		return "text/x-starlark"
+	}
+	t := mime.TypeByExtension(ext)
+	if i := strings.IndexByte(t, ';'); i >= 0 {
+		t = t[:i]
+	}
+	return t
+}
@@ -0,0 +1,350 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Resource describes a piece of context an MCP client can read without a
+// tool call (MCP resources/list).
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceContents is the result of reading a resource. Text resources set
+// Text; binary ones set Blob to the base64-encoded content.
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// ResourceSource serves every resource whose URI starts with Prefix, such as
+// the workspace files or the configured calendars.
+type ResourceSource struct {
+	Prefix string // e.g. "openpact://vault/"
+	List   func(ctx context.Context) ([]Resource, error)
+	Read   func(ctx context.Context, uri string) (*ResourceContents, error)
+
+	// Version returns a value that changes whenever the resource changes,
+	// such as its modification time. Sources without it (e.g. remote
+	// calendar feeds) cannot be subscribed to.
+	Version func(uri string) (string, error)
+
+	// Tool names the tool that reads these resources, such as vault_read.
+	// Roles and the tool policy apply to the resources as they would to
+	// calling Tool with ToolArgs(uri), so a role that may not read the vault
+	// does not see vault resources either. Empty means no limits.
+	Tool     string
+	ToolArgs func(uri string) map[string]interface{}
+}
+
+// errResourceNotFound is returned by ResourceSource.Read for unknown URIs.
+var errResourceNotFound = errors.New("resource not found")
+
+// subscriptionPollInterval is how often subscribed resources are checked
+// for changes.
+var subscriptionPollInterval = 5 * time.Second
+
+// RegisterResourceSource adds a family of resources to the server.
+func (s *Server) RegisterResourceSource(src *ResourceSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resources = append(s.resources, src)
+	s.log.Debug("Registered resources '%s'", src.Prefix)
+}
+
+// resourceSource returns the source serving uri, or nil.
+func (s *Server) resourceSource(uri string) *ResourceSource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, src := range s.resources {
+		if strings.HasPrefix(uri, src.Prefix) {
+			return src
+		}
+	}
+	return nil
+}
+
+// checkResource returns an error unless the caller may read uri from src:
+// every role must allow calling src.Tool, and the tool policy must let it
+// run without asking, since there is nobody to ask about a resource read.
+func (s *Server) checkResource(ctx context.Context, src *ResourceSource, uri string) error {
+	if src.Tool == "" {
+		return nil
+	}
+	var args map[string]interface{}
+	if src.ToolArgs != nil {
+		args = src.ToolArgs(uri)
+	}
+
+	s.mu.RLock()
+	policy := s.policy
+	resolve := s.roles
+	s.mu.RUnlock()
+
+	if p := policy.For(src.Tool); p != PolicyAuto {
+		return fmt.Errorf("resource %s is not available: tool %s has the %s policy", uri, src.Tool, p)
+	}
+	if resolve == nil {
+		return nil
+	}
+	for _, role := range resolve(ctx) {
+		if err := role.Check(src.Tool, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListResources returns the resources of every registered source that the
+// caller may read. A source that fails to list is logged and skipped so one
+// unreachable calendar does not hide the workspace.
+func (s *Server) ListResources(ctx context.Context) []Resource {
+	s.mu.RLock()
+	sources := append([]*ResourceSource(nil), s.resources...)
+	s.mu.RUnlock()
+
+	resources := []Resource{}
+	for _, src := range sources {
+		list, err := src.List(ctx)
+		if err != nil {
+			s.logger(ctx).Warn("Failed to list resources '%s': %v", src.Prefix, err)
+			continue
+		}
+		for _, r := range list {
+			if s.checkResource(ctx, src, r.URI) == nil {
+				resources = append(resources, r)
+			}
+		}
+	}
+	return resources
+}
+
+// ReadResource returns the contents of the resource at uri.
+func (s *Server) ReadResource(ctx context.Context, uri string) (*ResourceContents, error) {
+	src := s.resourceSource(uri)
+	if src == nil {
+		return nil, errResourceNotFound
+	}
+	if err := s.checkResource(ctx, src, uri); err != nil {
+		s.logger(ctx).Warn("Refused resource %s: %v", uri, err)
+		return nil, err
+	}
+	s.logger(ctx).Info("Reading resource %s", uri)
+	return src.Read(ctx, uri)
+}
+
+// subscription tracks the sessions watching one resource.
+type subscription struct {
+	version  string // last seen version
+	sessions map[*session]bool
+}
+
+// subscribeResource starts watching uri for sess; a
+// notifications/resources/updated message is sent to it whenever the
+// resource's version changes.
+func (s *Server) subscribeResource(ctx context.Context, sess *session, uri string) error {
+	src := s.resourceSource(uri)
+	if src == nil {
+		return errResourceNotFound
+	}
+	if err := s.checkResource(ctx, src, uri); err != nil {
+		s.logger(ctx).Warn("Refused subscription to %s: %v", uri, err)
+		return err
+	}
+	if src.Version == nil {
+		return fmt.Errorf("resource %s does not support subscriptions", uri)
+	}
+	version, err := src.Version(uri)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub := s.subscriptions[uri]
+	if sub == nil {
+		sub = &subscription{version: version, sessions: make(map[*session]bool)}
+		s.subscriptions[uri] = sub
+	}
+	sub.sessions[sess] = true
+	if s.watchStop == nil {
+		s.watchStop = make(chan struct{})
+		go s.watchSubscriptions(s.watchStop)
+	}
+	return nil
+}
+
+// unsubscribeResource stops watching uri for sess.
+func (s *Server) unsubscribeResource(sess *session, uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sub := s.subscriptions[uri]; sub != nil {
+		delete(sub.sessions, sess)
+		if len(sub.sessions) == 0 {
+			delete(s.subscriptions, uri)
+		}
+	}
+}
+
+// watchSubscriptions polls subscribed resources until stop is closed.
+func (s *Server) watchSubscriptions(stop chan struct{}) {
+	ticker := time.NewTicker(subscriptionPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.checkSubscriptions()
+		}
+	}
+}
+
+// checkSubscriptions notifies the subscribers of every resource whose
+// version has changed since it was last checked. A resource that has been
+// deleted reports an empty version, which counts as a change.
+func (s *Server) checkSubscriptions() {
+	s.mu.RLock()
+	uris := make([]string, 0, len(s.subscriptions))
+	for uri := range s.subscriptions {
+		uris = append(uris, uri)
+	}
+	s.mu.RUnlock()
+	sort.Strings(uris)
+
+	for _, uri := range uris {
+		src := s.resourceSource(uri)
+		if src == nil || src.Version == nil {
+			continue
+		}
+		version, err := src.Version(uri)
+		if err != nil {
+			version = ""
+		}
+
+		var subscribers []*session
+		s.mu.Lock()
+		if sub := s.subscriptions[uri]; sub != nil && sub.version != version {
+			sub.version = version
+			for sess := range sub.sessions {
+				subscribers = append(subscribers, sess)
+			}
+		}
+		s.mu.Unlock()
+
+		for _, sess := range subscribers {
+			s.deliver(sess, Notification{
+				JSONRPC: "2.0",
+				Method:  "notifications/resources/updated",
+				Params:  map[string]interface{}{"uri": uri},
+			})
+		}
+	}
+}
+
+// handleResourcesList handles resources/list
+func (s *Server) handleResourcesList(ctx context.Context) interface{} {
+	return map[string]interface{}{
+		"resources": s.ListResources(ctx),
+	}
+}
+
+// handleResourcesRead handles resources/read
+func (s *Server) handleResourcesRead(ctx context.Context, req Request) (interface{}, *Error) {
+	uri, rpcErr := resourceURIParam(req)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	contents, err := s.ReadResource(ctx, uri)
+	if err != nil {
+		return nil, resourceError(uri, err)
+	}
+	return map[string]interface{}{
+		"contents": []*ResourceContents{contents},
+	}, nil
+}
+
+// handleResourcesSubscribe handles resources/subscribe. Subscriptions
+// belong to a session, so stateless HTTP requests cannot subscribe.
+func (s *Server) handleResourcesSubscribe(ctx context.Context, req Request) (interface{}, *Error) {
+	uri, rpcErr := resourceURIParam(req)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	sess := sessionFrom(ctx)
+	if sess == nil {
+		return nil, &Error{Code: -32600, Message: "resources/subscribe requires a session (send the " + SessionHeader + " header returned by initialize)"}
+	}
+	if err := s.subscribeResource(ctx, sess, uri); err != nil {
+		return nil, resourceError(uri, err)
+	}
+	return map[string]interface{}{}, nil
+}
+
+// handleResourcesUnsubscribe handles resources/unsubscribe
+func (s *Server) handleResourcesUnsubscribe(ctx context.Context, req Request) (interface{}, *Error) {
+	uri, rpcErr := resourceURIParam(req)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	if sess := sessionFrom(ctx); sess != nil {
+		s.unsubscribeResource(sess, uri)
+	}
+	return map[string]interface{}{}, nil
+}
+
+// resourceURIParam extracts the "uri" parameter of a resources/* request.
+func resourceURIParam(req Request) (string, *Error) {
+	params, _ := req.Params.(map[string]interface{})
+	uri, _ := params["uri"].(string)
+	if uri == "" {
+		return "", &Error{Code: -32602, Message: "uri is required"}
+	}
+	return uri, nil
+}
+
+// resourceError maps a resource error to a JSON-RPC error, using the MCP
+// "resource not found" code where it applies.
+func resourceError(uri string, err error) *Error {
+	if errors.Is(err, errResourceNotFound) {
+		return &Error{Code: -32002, Message: fmt.Sprintf("Resource not found: %s", uri)}
+	}
+	return &Error{Code: -32000, Message: err.Error()}
+}
+
+// Notification is a JSON-RPC notification sent from the server to the client.
+type Notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// sendNotification writes a notification to the stdio client. Servers
+// without a writer (the HTTP endpoint) drop it.
+func (s *Server) sendNotification(n Notification) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.writer == nil {
+		return
+	}
+
+	data, err := json.Marshal(n)
+	if err != nil {
+		s.log.Error("Error marshaling notification: %v", err)
+		return
+	}
+	if _, err := s.writer.Write(append(data, '\n')); err != nil {
+		s.log.Error("Error writing notification: %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newResourceTestServer(t *testing.T) (*Server, string, *bytes.Buffer) {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"MEMORY.md":             "# Memory",
+		"memory/2026-03-14.md":  "Met Sam for lunch",
+		"SOUL.md":               "# Soul",
+		"notes/todo list.md":    "- buy milk",
+		".secret/ignored.md":    "hidden",
+		"inbox/discord/pic.png": "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	s := NewServer(nil, &buf)
+	RegisterWorkspaceResources(s, dir)
+	t.Cleanup(s.Stop)
+	return s, dir, &buf
+}
+
+func TestListResources(t *testing.T) {
+	s, _, _ := newResourceTestServer(t)
+
+	got := map[string]string{}
+	for _, r := range s.ListResources(context.Background()) {
+		got[r.URI] = r.MimeType
+	}
+
+	want := map[string]string{
+		"openpact://memory/MEMORY.md":                "text/markdown",
+		"openpact://memory/memory/2026-03-14.md":     "text/markdown",
+		"openpact://workspace/SOUL.md":               "text/markdown",
+		"openpact://workspace/notes/todo%20list.md":  "text/markdown",
+		"openpact://workspace/inbox/discord/pic.png": "image/png",
+	}
+	if len(got) != len(want) {
+		t.Errorf("resources = %v", got)
+	}
+	for uri, mimeType := range want {
+		if got[uri] != mimeType {
+			t.Errorf("%s: mime = %q, want %q (listed: %v)", uri, got[uri], mimeType, got)
+		}
+	}
+}
+
+func TestReadResource(t *testing.T) {
+	s, _, _ := newResourceTestServer(t)
+	ctx := context.Background()
+
+	c, err := s.ReadResource(ctx, "openpact://workspace/notes/todo%20list.md")
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if c.Text != "- buy milk" || c.MimeType != "text/markdown" {
+		t.Errorf("contents = %+v", c)
+	}
+
+	c, err = s.ReadResource(ctx, "openpact://workspace/inbox/discord/pic.png")
+	if err != nil {
+		t.Fatalf("read image: %v", err)
+	}
+	if c.Blob == "" || c.Text != "" {
+		t.Errorf("binary file should be returned as a blob: %+v", c)
+	}
+
+	for _, uri := range []string{
+		"openpact://workspace/../etc/passwd",
+		"openpact://workspace/%2e%2e/etc/passwd",
+		"openpact://workspace/.secret/ignored.md",
+		"openpact://workspace/MEMORY.md", // served under memory
+		"openpact://memory/SOUL.md",
+		"openpact://workspace/missing.md",
+		"openpact://other/file",
+	} {
+		if _, err := s.ReadResource(ctx, uri); err != errResourceNotFound {
+			t.Errorf("ReadResource(%s) error = %v, want not found", uri, err)
+		}
+	}
+}
+
+func TestResourcesRequests(t *testing.T) {
+	s, _, _ := newResourceTestServer(t)
+	ctx := context.Background()
+
+	resp := s.processRequest(ctx, Request{JSONRPC: "2.0", ID: 1, Method: "resources/read", Params: map[string]interface{}{
+		"uri": "openpact://memory/MEMORY.md",
+	}})
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error)
+	}
+	contents := resp.Result.(map[string]interface{})["contents"].([]*ResourceContents)
+	if len(contents) != 1 || contents[0].Text != "# Memory" {
+		t.Errorf("contents = %+v", contents)
+	}
+
+	resp = s.processRequest(ctx, Request{JSONRPC: "2.0", ID: 2, Method: "resources/read", Params: map[string]interface{}{
+		"uri": "openpact://memory/nope.md",
+	}})
+	if resp.Error == nil || resp.Error.Code != -32002 {
+		t.Errorf("expected resource not found error, got %+v", resp.Error)
+	}
+
+	resp = s.processRequest(ctx, Request{JSONRPC: "2.0", ID: 3, Method: "resources/read"})
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("expected invalid params error, got %+v", resp.Error)
+	}
+}
+
+func TestResourcesFollowRoles(t *testing.T) {
+	s, _, _ := newResourceTestServer(t)
+	ctx := context.Background()
+
+	reader := &Role{
+		Name: "reader",
+		Args: map[string]map[string][]string{"workspace_read": {"path": {"MEMORY.md", "memory/*"}}},
+	}
+	s.SetRoleResolver(func(ctx context.Context) []*Role { return []*Role{reader} })
+
+	var listed []string
+	for _, r := range s.ListResources(ctx) {
+		listed = append(listed, r.URI)
+	}
+	if len(listed) != 2 || !strings.HasPrefix(listed[0], MemoryResourcePrefix) || !strings.HasPrefix(listed[1], MemoryResourcePrefix) {
+		t.Errorf("expected only memory files to be listed, got %v", listed)
+	}
+
+	if _, err := s.ReadResource(ctx, "openpact://memory/MEMORY.md"); err != nil {
+		t.Errorf("allowed read failed: %v", err)
+	}
+	if _, err := s.ReadResource(ctx, "openpact://workspace/SOUL.md"); err == nil || !strings.Contains(err.Error(), `path "SOUL.md"`) {
+		t.Errorf("expected the read to be refused, got %v", err)
+	}
+	if err := s.subscribeResource(ctx, s.stdio, "openpact://workspace/SOUL.md"); err == nil {
+		t.Error("expected the subscription to be refused")
+	}
+
+	// Resources of a tool that needs approval are not served
+	s.SetRoleResolver(nil)
+	s.SetToolPolicy(&ToolPolicy{Tools: map[string]Policy{"workspace_read": PolicyConfirm}})
+	if list := s.ListResources(ctx); len(list) != 0 {
+		t.Errorf("expected no resources, got %+v", list)
+	}
+	if _, err := s.ReadResource(ctx, "openpact://memory/MEMORY.md"); err == nil || !strings.Contains(err.Error(), "confirm policy") {
+		t.Errorf("expected the read to be refused, got %v", err)
+	}
+}
+
+func TestSubscribeResource(t *testing.T) {
+	s, dir, buf := newResourceTestServer(t)
+	uri := "openpact://memory/MEMORY.md"
+
+	if err := s.subscribeResource(context.Background(), s.stdio, uri); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	// Unchanged: no notification
+	s.checkSubscriptions()
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected notification: %s", buf.String())
+	}
+
+	path := filepath.Join(dir, "MEMORY.md")
+	os.WriteFile(path, []byte("# Memory\n- likes tea"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+
+	s.checkSubscriptions()
+	if !strings.Contains(buf.String(), `"method":"notifications/resources/updated"`) || !strings.Contains(buf.String(), uri) {
+		t.Errorf("expected update notification, got %s", buf.String())
+	}
+
+	// Reported once per change
+	buf.Reset()
+	s.checkSubscriptions()
+	if buf.Len() != 0 {
+		t.Errorf("duplicate notification: %s", buf.String())
+	}
+
+	s.unsubscribeResource(s.stdio, uri)
+	os.WriteFile(path, []byte("changed again"), 0644)
+	s.checkSubscriptions()
+	if buf.Len() != 0 {
+		t.Errorf("notification after unsubscribe: %s", buf.String())
+	}
+}
+
+func TestSubscribeCalendarUnsupported(t *testing.T) {
+	s := NewServer(nil, nil)
+	RegisterCalendarResources(s, []CalendarConfig{{Name: "Work", URL: "http://localhost/cal.ics"}})
+
+	list := s.ListResources(context.Background())
+	if len(list) != 1 || list[0].URI != "openpact://calendar/Work" {
+		t.Fatalf("resources = %+v", list)
+	}
+	if err := s.subscribeResource(context.Background(), s.stdio, "openpact://calendar/Work"); err == nil {
+		t.Error("expected calendar subscription to be refused")
+	}
+}
@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+)
+
+// Role limits the tools a chat user may call and the arguments they may
+// pass. Patterns use path.Match syntax, so "vault_*" matches every vault
+// tool. The zero Role allows everything.
+type Role struct {
+	Name  string
+	Tools []string                       // Tools the role may call; empty allows every tool
+	Deny  []string                       // Tools the role may not call, even if listed in Tools
+	Args  map[string]map[string][]string // Allowed values of arguments, by tool and argument name
+}
+
+// RoleResolver returns the roles whose limits apply to a tool call made with
+// ctx. A call must be allowed by every returned role; nil means no limits.
+type RoleResolver func(ctx context.Context) []*Role
+
+// SetRoleResolver sets the function used to find the roles that limit each
+// tool call. Without one, calls are not limited by role.
+func (s *Server) SetRoleResolver(fn RoleResolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roles = fn
+}
+
+// Validate reports the first malformed pattern in r.
+func (r *Role) Validate() error {
+	for _, patterns := range [][]string{r.Tools, r.Deny} {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("role %s: bad tool pattern %q", r.Name, p)
+			}
+		}
+	}
+	for tool, args := range r.Args {
+		for arg, patterns := range args {
+			for _, p := range patterns {
+				if _, err := path.Match(p, ""); err != nil {
+					return fmt.Errorf("role %s: bad pattern %q for %s.%s", r.Name, p, tool, arg)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Check returns an error unless r allows calling tool with args. Each
+// constrained argument must match one of its patterns; a missing argument
+// counts as the empty string, and non-string values are compared in their
+// fmt %v form.
+func (r *Role) Check(tool string, args map[string]interface{}) error {
+	if !r.AllowsTool(tool) {
+		return fmt.Errorf("role %s may not use tool %s", r.Name, tool)
+	}
+
+	constraints := r.Args[tool]
+	names := make([]string, 0, len(constraints))
+	for name := range constraints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := ""
+		if v, ok := args[name]; ok && v != nil {
+			value = fmt.Sprintf("%v", v)
+		}
+		if !matchAny(constraints[name], value) {
+			return fmt.Errorf("role %s may not call %s with %s %q", r.Name, tool, name, value)
+		}
+	}
+	return nil
+}
+
+// AllowsTool reports whether r may call tool with some arguments.
+func (r *Role) AllowsTool(tool string) bool {
+	return !matchAny(r.Deny, tool) && (len(r.Tools) == 0 || matchAny(r.Tools, tool))
+}
+
+// checkRoles returns an error unless every role that applies to the call
+// allows it.
+func (s *Server) checkRoles(ctx context.Context, name string, args map[string]interface{}) error {
+	s.mu.RLock()
+	resolve := s.roles
+	s.mu.RUnlock()
+	if resolve == nil {
+		return nil
+	}
+
+	for _, role := range resolve(ctx) {
+		if err := role.Check(name, args); err != nil {
+			s.logger(ctx).WithField("tool", name).Warn("Refused by role: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -17,6 +17,7 @@ type SchedulerLookup interface {
 	Update(id string, updates *admin.Schedule) (*admin.Schedule, error)
 	Delete(id string) error
 	SetEnabled(id string, enabled bool) error
+	ListRuns(id string, limit int) ([]*admin.ScheduleRun, error)
 }
 
 // RegisterScheduleTools adds schedule management tools to the MCP server.
@@ -27,6 +28,7 @@ func RegisterScheduleTools(s *Server, lookup SchedulerLookup) {
 	s.RegisterTool(scheduleDeleteTool(lookup))
 	s.RegisterTool(scheduleEnableTool(lookup))
 	s.RegisterTool(scheduleDisableTool(lookup))
+	s.RegisterTool(scheduleHistoryTool(lookup))
 }
 
 func scheduleListTool(lookup SchedulerLookup) *Tool {
@@ -67,6 +69,53 @@ func scheduleListTool(lookup SchedulerLookup) *Tool {
 				if s.RunOnce {
 					entry["run_once"] = true
 				}
+				if s.Timezone != "" {
+					entry["timezone"] = s.Timezone
+				}
+				if s.Overlap != "" {
+					entry["overlap"] = s.Overlap
+				}
+				if s.CatchUp != "" {
+					entry["catch_up"] = s.CatchUp
+				}
+				if s.TimeoutSeconds > 0 {
+					entry["timeout_seconds"] = s.TimeoutSeconds
+				}
+				if s.JitterSeconds > 0 {
+					entry["jitter_seconds"] = s.JitterSeconds
+				}
+				if s.Retry != nil && s.Retry.MaxAttempts > 1 {
+					entry["retry_attempts"] = s.Retry.MaxAttempts
+				}
+				if s.OutputMode != "" {
+					entry["output_mode"] = s.OutputMode
+				}
+				if s.FailureTarget != nil {
+					entry["failure_target"] = map[string]string{
+						"provider":   s.FailureTarget.Provider,
+						"channel_id": s.FailureTarget.ChannelID,
+					}
+				}
+				if s.ConsecutiveFailures > 0 {
+					entry["consecutive_failures"] = s.ConsecutiveFailures
+				}
+				if !s.IsCron() {
+					entry["trigger"] = s.Trigger
+				}
+				switch s.Trigger {
+				case admin.TriggerWebhook:
+					entry["webhook_path"] = "/hooks/" + s.ID
+				case admin.TriggerFileChange:
+					entry["watch_paths"] = s.WatchPaths
+				case admin.TriggerCalendar:
+					if s.Calendar != "" {
+						entry["calendar"] = s.Calendar
+					}
+					entry["lead_minutes"] = s.LeadMinutes
+				}
+				if s.Session != "" {
+					entry["session"] = s.Session
+				}
 				if s.LastRunAt != nil {
 					entry["last_run_at"] = s.LastRunAt.Format("2006-01-02T15:04:05Z")
 					entry["last_run_status"] = s.LastRunStatus
@@ -85,7 +134,7 @@ func scheduleListTool(lookup SchedulerLookup) *Tool {
 func scheduleCreateTool(lookup SchedulerLookup) *Tool {
 	return &Tool{
 		Name:        "schedule_create",
-		Description: "Create a new scheduled job. Type must be 'script' (requires script_name) or 'agent' (requires prompt). Cron expression uses standard 5-field format (minute hour day-of-month month day-of-week).",
+		Description: "Create a new scheduled job. Type must be 'script' (requires script_name) or 'agent' (requires prompt). Cron expression uses standard 5-field format (minute hour day-of-month month day-of-week). Event-triggered jobs (webhook, file_change, calendar) need no cron expression and receive the event data: scripts as the 'args' dict, agents appended to the prompt.",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
@@ -108,7 +157,7 @@ func scheduleCreateTool(lookup SchedulerLookup) *Tool {
 				},
 				"prompt": map[string]interface{}{
 					"type":        "string",
-					"description": "Prompt to send to a new AI session (for type=agent)",
+					"description": "Prompt to send to the AI session (for type=agent)",
 				},
 				"enabled": map[string]interface{}{
 					"type":        "boolean",
@@ -126,8 +175,77 @@ func scheduleCreateTool(lookup SchedulerLookup) *Tool {
 					"type":        "boolean",
 					"description": "If true, the schedule auto-disables after one execution. Useful for deferred one-off tasks.",
 				},
+				"timezone": map[string]interface{}{
+					"type":        "string",
+					"description": "IANA timezone the cron expression is read in, e.g. 'Europe/London' (default: server time)",
+				},
+				"overlap": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"skip", "queue", "allow"},
+					"description": "What to do when the job fires while it is still running: 'skip' the new run (default), 'queue' it, or 'allow' both",
+				},
+				"catch_up": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"none", "once", "all"},
+					"description": "Runs missed while OpenPact was down: 'none' (default), run 'once', or replay 'all'",
+				},
+				"timeout_seconds": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum run time in seconds (default: 300 for scripts, 600 for agents)",
+				},
+				"jitter_seconds": map[string]interface{}{
+					"type":        "integer",
+					"description": "Delay each scheduled run by a random 0 to N seconds",
+				},
+				"retry_attempts": map[string]interface{}{
+					"type":        "integer",
+					"description": "Total attempts for a failing run, including the first (default: 1, no retries)",
+				},
+				"retry_backoff_seconds": map[string]interface{}{
+					"type":        "integer",
+					"description": "Wait before the first retry, doubled for each further one (default: 30)",
+				},
+				"output_mode": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"always", "on_change", "on_error"},
+					"description": "Which runs are sent to the output channel: 'always' (default), 'on_change' (status or output differs from the previous run) or 'on_error'",
+				},
+				"failure_provider": map[string]interface{}{
+					"type":        "string",
+					"description": "Chat provider to alert when the job fails after all retries (e.g. 'discord')",
+				},
+				"failure_channel": map[string]interface{}{
+					"type":        "string",
+					"description": "Channel ID to alert when the job fails after all retries",
+				},
+				"failure_threshold": map[string]interface{}{
+					"type":        "integer",
+					"description": "Consecutive failed runs before alerting (default: 1)",
+				},
+				"trigger": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"cron", "webhook", "file_change", "calendar"},
+					"description": "What starts the job: 'cron' (default), 'webhook' (a signed POST to /hooks/<id>), 'file_change' (files matching watch_paths change) or 'calendar' (lead_minutes before each calendar event)",
+				},
+				"watch_paths": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "For trigger=file_change: globs under ai-data/ or vault/, e.g. 'ai-data/inbox/*.md' or 'vault/Projects/**'",
+				},
+				"calendar": map[string]interface{}{
+					"type":        "string",
+					"description": "For trigger=calendar: name of the calendar to watch (default: all calendars)",
+				},
+				"lead_minutes": map[string]interface{}{
+					"type":        "integer",
+					"description": "For trigger=calendar: minutes before each event starts to run the job",
+				},
+				"session": map[string]interface{}{
+					"type":        "string",
+					"description": "For type=agent: session to continue so the agent remembers earlier runs. 'channel' uses the output channel's chat session; any other name keeps a session shared by schedules using that name (default: a new session each run)",
+				},
 			},
-			"required": []string{"name", "cron_expr", "type"},
+			"required": []string{"name", "type"},
 		},
 		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 			name, _ := args["name"].(string)
@@ -138,10 +256,13 @@ func scheduleCreateTool(lookup SchedulerLookup) *Tool {
 			outputProvider, _ := args["output_provider"].(string)
 			outputChannel, _ := args["output_channel"].(string)
 
-			// Validate cron expression
-			parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-			if _, err := parser.Parse(cronExpr); err != nil {
-				return nil, fmt.Errorf("invalid cron expression %q: %w", cronExpr, err)
+			// Validate cron expression (optional for event triggers)
+			trigger := strArg(args, "trigger")
+			if cronExpr != "" || trigger == "" || trigger == admin.TriggerCron {
+				parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+				if _, err := parser.Parse(cronExpr); err != nil {
+					return nil, fmt.Errorf("invalid cron expression %q: %w", cronExpr, err)
+				}
 			}
 
 			enabled := true
@@ -160,6 +281,7 @@ func scheduleCreateTool(lookup SchedulerLookup) *Tool {
 				ScriptName: scriptName,
 				Prompt:     prompt,
 			}
+			applyScheduleOptions(sched, args)
 
 			if outputProvider != "" && outputChannel != "" {
 				sched.OutputTarget = &admin.OutputTarget{
@@ -173,11 +295,17 @@ func scheduleCreateTool(lookup SchedulerLookup) *Tool {
 				return nil, fmt.Errorf("failed to create schedule: %w", err)
 			}
 
-			return map[string]interface{}{
+			result := map[string]interface{}{
 				"status": "created",
 				"id":     created.ID,
 				"name":   created.Name,
-			}, nil
+			}
+			if created.Trigger == admin.TriggerWebhook {
+				// The signing secret is only shown to admins
+				result["webhook_path"] = "/hooks/" + created.ID
+				result["note"] = "Sign webhook bodies with the secret shown on the schedule in the admin UI"
+			}
+			return result, nil
 		},
 	}
 }
@@ -226,6 +354,75 @@ func scheduleUpdateTool(lookup SchedulerLookup) *Tool {
 					"type":        "boolean",
 					"description": "If true, the schedule auto-disables after one execution",
 				},
+				"timezone": map[string]interface{}{
+					"type":        "string",
+					"description": "IANA timezone the cron expression is read in, e.g. 'Europe/London' (default: server time)",
+				},
+				"overlap": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"skip", "queue", "allow"},
+					"description": "What to do when the job fires while it is still running: 'skip' the new run (default), 'queue' it, or 'allow' both",
+				},
+				"catch_up": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"none", "once", "all"},
+					"description": "Runs missed while OpenPact was down: 'none' (default), run 'once', or replay 'all'",
+				},
+				"timeout_seconds": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum run time in seconds (default: 300 for scripts, 600 for agents)",
+				},
+				"jitter_seconds": map[string]interface{}{
+					"type":        "integer",
+					"description": "Delay each scheduled run by a random 0 to N seconds",
+				},
+				"retry_attempts": map[string]interface{}{
+					"type":        "integer",
+					"description": "Total attempts for a failing run, including the first (default: 1, no retries)",
+				},
+				"retry_backoff_seconds": map[string]interface{}{
+					"type":        "integer",
+					"description": "Wait before the first retry, doubled for each further one (default: 30)",
+				},
+				"output_mode": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"always", "on_change", "on_error"},
+					"description": "Which runs are sent to the output channel: 'always' (default), 'on_change' (status or output differs from the previous run) or 'on_error'",
+				},
+				"failure_provider": map[string]interface{}{
+					"type":        "string",
+					"description": "Chat provider to alert when the job fails after all retries (e.g. 'discord')",
+				},
+				"failure_channel": map[string]interface{}{
+					"type":        "string",
+					"description": "Channel ID to alert when the job fails after all retries",
+				},
+				"failure_threshold": map[string]interface{}{
+					"type":        "integer",
+					"description": "Consecutive failed runs before alerting (default: 1)",
+				},
+				"trigger": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"cron", "webhook", "file_change", "calendar"},
+					"description": "What starts the job: 'cron' (default), 'webhook' (a signed POST to /hooks/<id>), 'file_change' (files matching watch_paths change) or 'calendar' (lead_minutes before each calendar event)",
+				},
+				"watch_paths": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "For trigger=file_change: globs under ai-data/ or vault/, e.g. 'ai-data/inbox/*.md' or 'vault/Projects/**'",
+				},
+				"calendar": map[string]interface{}{
+					"type":        "string",
+					"description": "For trigger=calendar: name of the calendar to watch (default: all calendars)",
+				},
+				"lead_minutes": map[string]interface{}{
+					"type":        "integer",
+					"description": "For trigger=calendar: minutes before each event starts to run the job",
+				},
+				"session": map[string]interface{}{
+					"type":        "string",
+					"description": "For type=agent: session to continue so the agent remembers earlier runs. 'channel' uses the output channel's chat session; any other name keeps a session shared by schedules using that name (default: a new session each run)",
+				},
 			},
 			"required": []string{"id"},
 		},
@@ -245,14 +442,36 @@ func scheduleUpdateTool(lookup SchedulerLookup) *Tool {
 
 			runOnce, _ := args["run_once"].(bool)
 
+			// The store replaces the timing options on every update, so
+			// carry over the ones not being changed.
+			existing, err := lookup.Get(id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to update schedule: %w", err)
+			}
+
 			updates := &admin.Schedule{
-				Name:       strArg(args, "name"),
-				CronExpr:   strArg(args, "cron_expr"),
-				Type:       strArg(args, "type"),
-				ScriptName: strArg(args, "script_name"),
-				Prompt:     strArg(args, "prompt"),
-				RunOnce:    runOnce,
+				Name:             strArg(args, "name"),
+				CronExpr:         strArg(args, "cron_expr"),
+				Type:             strArg(args, "type"),
+				ScriptName:       strArg(args, "script_name"),
+				Prompt:           strArg(args, "prompt"),
+				RunOnce:          runOnce,
+				Timezone:         existing.Timezone,
+				Overlap:          existing.Overlap,
+				CatchUp:          existing.CatchUp,
+				TimeoutSeconds:   existing.TimeoutSeconds,
+				JitterSeconds:    existing.JitterSeconds,
+				Retry:            existing.Retry,
+				OutputMode:       existing.OutputMode,
+				FailureTarget:    existing.FailureTarget,
+				FailureThreshold: existing.FailureThreshold,
+				Trigger:          existing.Trigger,
+				WatchPaths:       existing.WatchPaths,
+				Calendar:         existing.Calendar,
+				LeadMinutes:      existing.LeadMinutes,
+				Session:          existing.Session,
 			}
+			applyScheduleOptions(updates, args)
 
 			outputProvider := strArg(args, "output_provider")
 			outputChannel := strArg(args, "output_channel")
@@ -373,6 +592,165 @@ func scheduleDisableTool(lookup SchedulerLookup) *Tool {
 	}
 }
 
+// scheduleHistoryOutputLen caps the output of each run listed by
+// schedule_history unless a single run is requested.
+const scheduleHistoryOutputLen = 2000
+
+func scheduleHistoryTool(lookup SchedulerLookup) *Tool {
+	return &Tool{
+		Name:        "schedule_history",
+		Description: "Show the run history of a scheduled job, newest first: when each run started, how long it took, what triggered it, its status, error and output, and the session an agent job created. Pass run_id to get the full output and script logs of one run.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"id": map[string]interface{}{
+					"type":        "string",
+					"description": "Schedule ID",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of runs to return (default: 10)",
+				},
+				"run_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Return only this run, with its full output and logs",
+				},
+			},
+			"required": []string{"id"},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			id, _ := args["id"].(string)
+			if id == "" {
+				return nil, fmt.Errorf("id is required")
+			}
+			runID := strArg(args, "run_id")
+
+			limit := 10
+			if l, ok := args["limit"].(float64); ok && l > 0 {
+				limit = int(l)
+			}
+			if runID != "" {
+				limit = 0
+			}
+
+			if _, err := lookup.Get(id); err != nil {
+				return nil, fmt.Errorf("failed to get schedule: %w", err)
+			}
+			runs, err := lookup.ListRuns(id, limit)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list runs: %w", err)
+			}
+
+			result := []map[string]interface{}{}
+			for _, run := range runs {
+				if runID != "" && run.ID != runID {
+					continue
+				}
+				entry := map[string]interface{}{
+					"run_id":      run.ID,
+					"started_at":  run.StartedAt.Format("2006-01-02T15:04:05Z"),
+					"duration_ms": run.DurationMs,
+					"trigger":     run.Trigger,
+					"status":      run.Status,
+				}
+				if run.Error != "" {
+					entry["error"] = run.Error
+				}
+				if run.Output != "" {
+					output := run.Output
+					if runID == "" && len(output) > scheduleHistoryOutputLen {
+						output = output[:scheduleHistoryOutputLen] + "... (truncated, pass run_id for the full output)"
+					}
+					entry["output"] = output
+				}
+				if runID != "" && len(run.Logs) > 0 {
+					entry["logs"] = run.Logs
+				}
+				if run.SessionID != "" {
+					entry["session_id"] = run.SessionID
+				}
+				result = append(result, entry)
+			}
+			if runID != "" && len(result) == 0 {
+				return nil, fmt.Errorf("run %s not found", runID)
+			}
+
+			return map[string]interface{}{
+				"id":    id,
+				"runs":  result,
+				"count": len(result),
+			}, nil
+		},
+	}
+}
+
+// applyScheduleOptions sets the trigger, timing, delivery and session options given
+// in args on sched.
+func applyScheduleOptions(sched *admin.Schedule, args map[string]interface{}) {
+	if v, ok := args["trigger"].(string); ok {
+		sched.Trigger = v
+	}
+	if v, ok := args["watch_paths"].([]interface{}); ok {
+		sched.WatchPaths = nil
+		for _, p := range v {
+			if p, ok := p.(string); ok && p != "" {
+				sched.WatchPaths = append(sched.WatchPaths, p)
+			}
+		}
+	}
+	if v, ok := args["calendar"].(string); ok {
+		sched.Calendar = v
+	}
+	if v, ok := args["lead_minutes"].(float64); ok {
+		sched.LeadMinutes = int(v)
+	}
+	if v, ok := args["session"].(string); ok {
+		sched.Session = v
+	}
+	if v, ok := args["timezone"].(string); ok {
+		sched.Timezone = v
+	}
+	if v, ok := args["overlap"].(string); ok {
+		sched.Overlap = v
+	}
+	if v, ok := args["catch_up"].(string); ok {
+		sched.CatchUp = v
+	}
+	if v, ok := args["timeout_seconds"].(float64); ok {
+		sched.TimeoutSeconds = int(v)
+	}
+	if v, ok := args["jitter_seconds"].(float64); ok {
+		sched.JitterSeconds = int(v)
+	}
+
+	attempts, hasAttempts := args["retry_attempts"].(float64)
+	backoff, hasBackoff := args["retry_backoff_seconds"].(float64)
+	if hasAttempts || hasBackoff {
+		retry := admin.RetryPolicy{}
+		if sched.Retry != nil {
+			retry = *sched.Retry
+		}
+		if hasAttempts {
+			retry.MaxAttempts = int(attempts)
+		}
+		if hasBackoff {
+			retry.BackoffSeconds = int(backoff)
+		}
+		sched.Retry = &retry
+	}
+
+	if v, ok := args["output_mode"].(string); ok {
+		sched.OutputMode = v
+	}
+	provider, channel := strArg(args, "failure_provider"), strArg(args, "failure_channel")
+	if provider != "" && channel != "" {
+		sched.FailureTarget = &admin.OutputTarget{Provider: provider, ChannelID: channel}
+	}
+	if v, ok := args["failure_threshold"].(float64); ok {
+		sched.FailureThreshold = int(v)
+	}
+}
+
 func strArg(args map[string]interface{}, key string) string {
 	v, _ := args[key].(string)
 	return v
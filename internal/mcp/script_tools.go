@@ -3,6 +3,7 @@ package mcp
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 
 	"github.com/open-pact/openpact/internal/admin"
 	"github.com/open-pact/openpact/internal/starlark"
@@ -12,14 +13,22 @@ import (
 type ScriptConfig struct {
 	ScriptsDir     string             // Directory containing .star scripts
 	MaxExecutionMs int64              // Max execution time
+	MaxSteps       uint64             // Max computation steps per run
+	MaxMemoryMB    int                // Max memory built-ins may allocate per run
 	Secrets        map[string]string  // Secrets to inject into scripts (name -> value)
 	ScriptStore    *admin.ScriptStore // Optional: script store for approval checking
+
+	// Capabilities for script_exec code; the zero value allows no hosts and no secrets
+	ExecCapabilities starlark.Capabilities
 }
 
 // RegisterScriptTools registers Starlark script execution tools
 func RegisterScriptTools(srv *Server, cfg ScriptConfig) {
 	sandbox := starlark.New(starlark.Config{
 		MaxExecutionMs: cfg.MaxExecutionMs,
+		MaxSteps:       cfg.MaxSteps,
+		MaxMemoryMB:    cfg.MaxMemoryMB,
+		LibDir:         filepath.Join(cfg.ScriptsDir, starlark.LibDirName),
 	})
 	loader := starlark.NewLoader(cfg.ScriptsDir, sandbox)
 
@@ -34,7 +43,7 @@ func RegisterScriptTools(srv *Server, cfg ScriptConfig) {
 
 	srv.RegisterTool(scriptListTool(loader, cfg.ScriptStore))
 	srv.RegisterTool(scriptRunTool(sandbox, loader, secretProvider, cfg.ScriptStore))
-	srv.RegisterTool(scriptExecTool(sandbox, secretProvider))
+	srv.RegisterTool(scriptExecTool(sandbox, secretProvider, cfg.ExecCapabilities))
 	srv.RegisterTool(scriptReloadTool(loader))
 }
 
@@ -63,6 +72,9 @@ func scriptListTool(loader *starlark.Loader, scriptStore *admin.ScriptStore) *To
 					"metadata":         s.Metadata,
 					"required_secrets": requiredSecrets,
 				}
+				if caps, err := starlark.ParseCapabilities(s.Source); err == nil {
+					scriptResult["capabilities"] = caps
+				}
 
 				// Add approval status if script store is available
 				if scriptStore != nil {
@@ -92,7 +104,7 @@ func scriptListTool(loader *starlark.Loader, scriptStore *admin.ScriptStore) *To
 func scriptRunTool(sandbox *starlark.Sandbox, loader *starlark.Loader, secretProvider *starlark.SecretProvider, scriptStore *admin.ScriptStore) *Tool {
 	return &Tool{
 		Name:        "script_run",
-		Description: "Execute a Starlark script by name. Scripts must be approved before execution. Scripts can access secrets via secrets.get('NAME'). Results are sanitized to prevent secret leakage.",
+		Description: "Execute a Starlark script by name. Scripts must be approved before execution. Scripts can access secrets via secrets.get('NAME'). Output of print() and log.info/warn/error is returned in 'logs'. Results are sanitized to prevent secret leakage.",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
@@ -136,7 +148,7 @@ func scriptRunTool(sandbox *starlark.Sandbox, loader *starlark.Loader, secretPro
 					if err == admin.ErrScriptModified {
 						return map[string]interface{}{
 							"error":   "script_modified",
-							"message": fmt.Sprintf("Script '%s' has been modified since approval. Re-approval required.", scriptName),
+							"message": fmt.Sprintf("Script '%s' or a library it loads has been modified since approval. Re-approval required.", scriptName),
 							"script":  scriptName,
 							"status":  "modified",
 						}, nil
@@ -152,7 +164,15 @@ func scriptRunTool(sandbox *starlark.Sandbox, loader *starlark.Loader, secretPro
 				return nil, fmt.Errorf("script not found: %s", name)
 			}
 
+			// Enforce the script's capability manifest
+			caps, err := starlark.ParseCapabilities(script.Source)
+			if err != nil {
+				return nil, fmt.Errorf("script %s has an invalid capability manifest: %w", name, err)
+			}
+			ctx = starlark.WithCapabilities(ctx, caps)
+
 			var result starlark.Result
+			ReportProgress(ctx, fmt.Sprintf("Running %s", script.Name))
 
 			if function != "" {
 				// Convert args to []any
@@ -173,7 +193,9 @@ func scriptRunTool(sandbox *starlark.Sandbox, loader *starlark.Loader, secretPro
 			return map[string]interface{}{
 				"value":       result.Value,
 				"error":       result.Error,
+				"error_kind":  result.ErrorKind,
 				"duration_ms": result.Duration.Milliseconds(),
+				"logs":        result.Logs,
 			}, nil
 		},
 	}
@@ -184,10 +206,10 @@ func hasStarExtension(name string) bool {
 }
 
 // scriptExecTool creates the script_exec tool
-func scriptExecTool(sandbox *starlark.Sandbox, secretProvider *starlark.SecretProvider) *Tool {
+func scriptExecTool(sandbox *starlark.Sandbox, secretProvider *starlark.SecretProvider, caps starlark.Capabilities) *Tool {
 	return &Tool{
 		Name:        "script_exec",
-		Description: "Execute arbitrary Starlark code. Scripts can access secrets via secrets.get('NAME'). Results are sanitized to prevent secret leakage.",
+		Description: "Execute arbitrary Starlark code. Inline code can only reach the hosts and secrets allowed by the admin's starlark.exec config (none by default). Results are sanitized to prevent secret leakage.",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
@@ -201,7 +223,9 @@ func scriptExecTool(sandbox *starlark.Sandbox, secretProvider *starlark.SecretPr
 		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 			code, _ := args["code"].(string)
 
-			result := sandbox.Execute(ctx, "exec", code)
+			ReportProgress(ctx, "Running code")
+			execCaps := caps
+			result := sandbox.Execute(starlark.WithCapabilities(ctx, &execCaps), "exec", code)
 
 			// CRITICAL: Sanitize result to prevent secret leakage
 			result = starlark.SanitizeResult(result, secretProvider)
@@ -209,7 +233,9 @@ func scriptExecTool(sandbox *starlark.Sandbox, secretProvider *starlark.SecretPr
 			return map[string]interface{}{
 				"value":       result.Value,
 				"error":       result.Error,
+				"error_kind":  result.ErrorKind,
 				"duration_ms": result.Duration.Milliseconds(),
+				"logs":        result.Logs,
 			}, nil
 		},
 	}
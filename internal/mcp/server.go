@@ -7,8 +7,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"math"
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/open-pact/openpact/internal/logging"
+	"github.com/open-pact/openpact/internal/ratelimit"
 )
 
 // Tool represents an MCP tool that can be called by the AI
@@ -22,44 +27,139 @@ type Tool struct {
 // ToolHandler is the function signature for tool implementations
 type ToolHandler func(ctx context.Context, args map[string]interface{}) (interface{}, error)
 
+// MetricsRecorder receives per-tool call metrics (implemented by health.Server).
+type MetricsRecorder interface {
+	ObserveToolCall(tool string, success bool, d time.Duration)
+}
+
+// CorrelationResolver returns the correlation ID of the chat turn that an
+// incoming MCP request most likely belongs to, or "" if it cannot tell.
+type CorrelationResolver func() string
+
 // Server is the MCP server that exposes tools to the AI
 type Server struct {
-	tools   map[string]*Tool
-	reader  io.Reader
-	writer  io.Writer
-	mu      sync.RWMutex
-	running bool
+	tools       map[string]*Tool
+	reader      io.Reader
+	writer      io.Writer
+	mu          sync.RWMutex
+	running     bool
+	metrics     MetricsRecorder
+	limiter     *ratelimit.Registry
+	log         *logging.Logger
+	correlation CorrelationResolver
+	policy      *ToolPolicy
+	approver    ToolApprover
+	roles       RoleResolver
+
+	resources     []*ResourceSource
+	subscriptions map[string]*subscription // by resource URI
+	watchStop     chan struct{}            // closes the subscription watcher
+	prompts       *PromptLibrary
+
+	stdio    *session                      // the implicit session of the stdio client
+	sessions map[string]*session           // HTTP sessions by ID
+	inflight map[string]context.CancelFunc // running requests, by inflightKey
 }
 
 // NewServer creates a new MCP server
 func NewServer(r io.Reader, w io.Writer) *Server {
 	return &Server{
-		tools:  make(map[string]*Tool),
-		reader: r,
-		writer: w,
+		tools:         make(map[string]*Tool),
+		reader:        r,
+		writer:        w,
+		log:           logging.Standard().WithField("component", "mcp"),
+		subscriptions: make(map[string]*subscription),
+		stdio:         &session{id: "stdio", done: make(chan struct{})},
+		sessions:      make(map[string]*session),
+		inflight:      make(map[string]context.CancelFunc),
 	}
 }
 
+// SetLogger sets the logger used for request and tool call logging.
+func (s *Server) SetLogger(l *logging.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log = l.WithField("component", "mcp")
+}
+
+// SetCorrelationResolver sets the fallback used to attach a correlation ID to
+// HTTP requests that do not carry an X-Correlation-ID header.
+func (s *Server) SetCorrelationResolver(fn CorrelationResolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.correlation = fn
+}
+
+// logger returns the server's logger with ctx's correlation ID attached.
+func (s *Server) logger(ctx context.Context) *logging.Logger {
+	s.mu.RLock()
+	l := s.log
+	s.mu.RUnlock()
+	return l.WithContext(ctx)
+}
+
 // RegisterTool adds a tool to the server
 func (s *Server) RegisterTool(tool *Tool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.tools[tool.Name] = tool
-	log.Printf("MCP: Registered tool '%s'", tool.Name)
+	s.log.Debug("Registered tool '%s'", tool.Name)
+}
+
+// SetMetrics sets the recorder that receives tool call metrics.
+func (s *Server) SetMetrics(m MetricsRecorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics = m
+}
+
+// SetRateLimiter sets the limiter consulted (per tool) before each tool call.
+func (s *Server) SetRateLimiter(rl *ratelimit.Registry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limiter = rl
 }
 
-// ListTools returns all registered tools
+// ListTools returns all registered tools, except those denied by policy
 func (s *Server) ListTools() []*Tool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	tools := make([]*Tool, 0, len(s.tools))
 	for _, t := range s.tools {
+		if s.policy.For(t.Name) == PolicyDeny {
+			continue
+		}
 		tools = append(tools, t)
 	}
 	return tools
 }
 
+// ListToolsFor returns the tools a call made with ctx could use: those of
+// ListTools that every role applying to ctx allows.
+func (s *Server) ListToolsFor(ctx context.Context) []*Tool {
+	s.mu.RLock()
+	resolve := s.roles
+	s.mu.RUnlock()
+
+	tools := s.ListTools()
+	if resolve == nil {
+		return tools
+	}
+	roles := resolve(ctx)
+	allowed := tools[:0]
+	for _, t := range tools {
+		ok := true
+		for _, role := range roles {
+			ok = ok && role.AllowsTool(t.Name)
+		}
+		if ok {
+			allowed = append(allowed, t)
+		}
+	}
+	return allowed
+}
+
 // Start begins processing MCP requests
 func (s *Server) Start(ctx context.Context) error {
 	s.mu.Lock()
@@ -75,16 +175,16 @@ func (s *Server) Start(ctx context.Context) error {
 		default:
 		}
 
-		var req Request
-		if err := decoder.Decode(&req); err != nil {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
 			if err == io.EOF {
 				return nil
 			}
-			log.Printf("MCP: Error decoding request: %v", err)
+			s.logger(ctx).Error("Error decoding request: %v", err)
 			continue
 		}
 
-		go s.handleRequest(ctx, req)
+		go s.handleMessage(ctx, raw)
 	}
 }
 
@@ -93,10 +193,17 @@ func (s *Server) Stop() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.running = false
+	if s.watchStop != nil {
+		close(s.watchStop)
+		s.watchStop = nil
+	}
+	for _, sess := range s.sessions {
+		s.closeSessionLocked(sess)
+	}
 }
 
 // processRequest processes a single MCP request and returns the response.
-// Used by both the stdio path (handleRequest) and the HTTP handler.
+// Used by both the stdio path (handleMessage) and the HTTP handler.
 func (s *Server) processRequest(ctx context.Context, req Request) Response {
 	var resp Response
 	resp.ID = req.ID
@@ -117,6 +224,18 @@ func (s *Server) processRequest(ctx context.Context, req Request) Response {
 		} else {
 			resp.Result = result
 		}
+	case "resources/list":
+		resp.Result = s.handleResourcesList(ctx)
+	case "resources/read":
+		resp.Result, resp.Error = s.handleResourcesRead(ctx, req)
+	case "resources/subscribe":
+		resp.Result, resp.Error = s.handleResourcesSubscribe(ctx, req)
+	case "resources/unsubscribe":
+		resp.Result, resp.Error = s.handleResourcesUnsubscribe(ctx, req)
+	case "prompts/list":
+		resp.Result, resp.Error = s.handlePromptsList(ctx)
+	case "prompts/get":
+		resp.Result, resp.Error = s.handlePromptsGet(ctx, req)
 	default:
 		resp.Error = &Error{
 			Code:    -32601,
@@ -127,18 +246,52 @@ func (s *Server) processRequest(ctx context.Context, req Request) Response {
 	return resp
 }
 
-// handleRequest processes a single MCP request (stdio path).
-func (s *Server) handleRequest(ctx context.Context, req Request) {
-	resp := s.processRequest(ctx, req)
-	s.sendResponse(resp)
+// handleMessage processes a message or batch from the stdio client. Progress
+// and other notifications for its requests are written to stdout.
+func (s *Server) handleMessage(ctx context.Context, raw []byte) {
+	msgs, batch, err := parseMessages(raw)
+	if err != nil {
+		s.sendResponse(Response{JSONRPC: "2.0", Error: &Error{Code: -32600, Message: "Invalid request"}})
+		return
+	}
+
+	ctx = withNotify(withSession(ctx, s.stdio), s.sendNotification)
+	responses := s.processMessages(ctx, msgs)
+	switch {
+	case len(responses) == 0:
+	case batch:
+		s.sendResponse(responses)
+	default:
+		s.sendResponse(responses[0])
+	}
 }
 
+// supportedProtocolVersions are the MCP revisions this server implements,
+// newest first. Clients that do not ask for one get the oldest.
+var supportedProtocolVersions = []string{"2025-03-26", "2024-11-05"}
+
 // handleInitialize handles the initialize request
 func (s *Server) handleInitialize(req Request) interface{} {
+	params, _ := req.Params.(map[string]interface{})
+	requested, _ := params["protocolVersion"].(string)
+	version := supportedProtocolVersions[len(supportedProtocolVersions)-1]
+	if requested != "" {
+		version = supportedProtocolVersions[0]
+		for _, v := range supportedProtocolVersions {
+			if v == requested {
+				version = v
+			}
+		}
+	}
+
 	return map[string]interface{}{
-		"protocolVersion": "2024-11-05",
+		"protocolVersion": version,
 		"capabilities": map[string]interface{}{
 			"tools": map[string]interface{}{},
+			"resources": map[string]interface{}{
+				"subscribe": true,
+			},
+			"prompts": map[string]interface{}{},
 		},
 		"serverInfo": map[string]interface{}{
 			"name":    "openpact-mcp",
@@ -147,13 +300,18 @@ func (s *Server) handleInitialize(req Request) interface{} {
 	}
 }
 
-// handleToolsList returns the list of available tools
+// handleToolsList returns the list of available tools. It is not limited by
+// role: clients such as OpenCode list tools once rather than per turn, so
+// the roles are checked when a tool is called.
 func (s *Server) handleToolsList() interface{} {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	tools := make([]map[string]interface{}, 0, len(s.tools))
 	for _, t := range s.tools {
+		if s.policy.For(t.Name) == PolicyDeny {
+			continue
+		}
 		tools = append(tools, map[string]interface{}{
 			"name":        t.Name,
 			"description": t.Description,
@@ -176,44 +334,126 @@ func (s *Server) handleToolCall(ctx context.Context, req Request) (interface{},
 	name, _ := params["name"].(string)
 	args, _ := params["arguments"].(map[string]interface{})
 
+	text, err := s.CallTool(withProgress(ctx, req), name, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": text,
+			},
+		},
+	}, nil
+}
+
+// CallTool runs a registered tool and returns its result as text. It applies
+// the same rate limits, roles, tool policy, metrics and logging as MCP
+// requests, so engines that execute tool calls in-process are treated like
+// any other MCP client.
+func (s *Server) CallTool(ctx context.Context, name string, args map[string]interface{}) (string, error) {
 	s.mu.RLock()
 	tool, exists := s.tools[name]
+	metrics := s.metrics
+	limiter := s.limiter
 	s.mu.RUnlock()
 
 	if !exists {
-		return nil, fmt.Errorf("unknown tool: %s", name)
+		return "", fmt.Errorf("unknown tool: %s", name)
+	}
+
+	logger := s.logger(ctx).WithField("tool", name)
+
+	// Refused calls must not use up the tool's shared rate limit
+	if err := s.checkRoles(ctx, name, args); err != nil {
+		return "", err
+	}
+	if err := s.checkDenied(ctx, name); err != nil {
+		return "", err
+	}
+
+	if ok, wait := limiter.Check(ratelimit.ScopeTool, name); !ok {
+		logger.Warn("Rate limited")
+		return "", fmt.Errorf("rate limit exceeded for tool %s, retry in %ds", name, int(math.Ceil(wait.Seconds())))
 	}
 
-	log.Printf("MCP: Calling tool '%s' with args: %v", name, args)
+	if err := s.checkPolicy(ctx, name, args); err != nil {
+		return "", err
+	}
+
+	logger.Info("Calling tool with args: %s", describeArgs(logger, args))
 
+	start := time.Now()
+	stopHeartbeat := progressHeartbeatLoop(ctx, name, start)
 	result, err := tool.Handler(ctx, args)
+	stopHeartbeat()
+	elapsed := time.Since(start)
+	if metrics != nil {
+		metrics.ObserveToolCall(name, err == nil, elapsed)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("tool error: %w", err)
+		logger.Warn("Tool failed after %s: %v", elapsed.Round(time.Millisecond), err)
+		return "", fmt.Errorf("tool error: %w", err)
 	}
 
-	return map[string]interface{}{
-		"content": []map[string]interface{}{
-			{
-				"type": "text",
-				"text": fmt.Sprintf("%v", result),
-			},
-		},
-	}, nil
+	return fmt.Sprintf("%v", result), nil
+}
+
+// progressHeartbeatLoop reports progress every progressHeartbeat while a
+// tool runs, so clients that asked for progress can tell a long script from
+// a hung one. The returned function stops it.
+func progressHeartbeatLoop(ctx context.Context, name string, start time.Time) func() {
+	if ctx.Value(progressContextKey) == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(progressHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ReportProgress(ctx, fmt.Sprintf("%s still running (%s)", name, time.Since(start).Round(time.Second)))
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// describeArgs renders tool arguments for logging. Argument values can contain
+// message text or file contents, so only the keys are shown unless debug
+// logging is enabled.
+func describeArgs(logger *logging.Logger, args map[string]interface{}) string {
+	if logger.DebugEnabled() {
+		return fmt.Sprintf("%v", args)
+	}
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return fmt.Sprintf("%v", keys)
 }
 
-// sendResponse sends a JSON-RPC response
-func (s *Server) sendResponse(resp Response) {
+// sendResponse sends a JSON-RPC response (or a batch of them) to stdout
+func (s *Server) sendResponse(resp interface{}) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	data, err := json.Marshal(resp)
 	if err != nil {
-		log.Printf("MCP: Error marshaling response: %v", err)
+		s.log.Error("Error marshaling response: %v", err)
 		return
 	}
 
 	if _, err := s.writer.Write(append(data, '\n')); err != nil {
-		log.Printf("MCP: Error writing response: %v", err)
+		s.log.Error("Error writing response: %v", err)
 	}
 }
 
@@ -4,7 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/open-pact/openpact/internal/ratelimit"
 )
 
 func TestRegisterTool(t *testing.T) {
@@ -60,6 +66,13 @@ func TestHandleInitialize(t *testing.T) {
 	if serverInfo["name"] != "openpact-mcp" {
 		t.Errorf("expected server name 'openpact-mcp', got '%v'", serverInfo["name"])
 	}
+
+	capabilities, _ := resultMap["capabilities"].(map[string]interface{})
+	for _, name := range []string{"tools", "resources", "prompts"} {
+		if _, ok := capabilities[name]; !ok {
+			t.Errorf("expected %s capability, got %v", name, capabilities)
+		}
+	}
 }
 
 func TestHandleToolsList(t *testing.T) {
@@ -160,6 +173,234 @@ func TestHandleToolCallUnknownTool(t *testing.T) {
 	}
 }
 
+// recordingMetrics captures ObserveToolCall invocations.
+type recordingMetrics struct {
+	calls []string
+}
+
+func (m *recordingMetrics) ObserveToolCall(tool string, success bool, d time.Duration) {
+	status := "success"
+	if !success {
+		status = "error"
+	}
+	m.calls = append(m.calls, tool+":"+status)
+}
+
+func TestHandleToolCallRecordsMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer(&buf, &buf)
+	metrics := &recordingMetrics{}
+	s.SetMetrics(metrics)
+
+	s.RegisterTool(&Tool{
+		Name: "ok_tool",
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return "ok", nil
+		},
+	})
+	s.RegisterTool(&Tool{
+		Name: "bad_tool",
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return nil, errors.New("boom")
+		},
+	})
+
+	for _, name := range []string{"ok_tool", "bad_tool"} {
+		s.handleToolCall(context.Background(), Request{
+			JSONRPC: "2.0",
+			ID:      1,
+			Method:  "tools/call",
+			Params:  map[string]interface{}{"name": name},
+		})
+	}
+
+	want := []string{"ok_tool:success", "bad_tool:error"}
+	if len(metrics.calls) != len(want) {
+		t.Fatalf("expected %d recorded calls, got %v", len(want), metrics.calls)
+	}
+	for i := range want {
+		if metrics.calls[i] != want[i] {
+			t.Errorf("call %d = %q, want %q", i, metrics.calls[i], want[i])
+		}
+	}
+}
+
+func TestHandleToolCallRateLimited(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer(&buf, &buf)
+	s.SetRateLimiter(ratelimit.NewRegistry(map[ratelimit.Scope]ratelimit.Config{
+		ratelimit.ScopeTool: {Rate: 0.001, Burst: 1},
+	}))
+
+	calls := 0
+	s.RegisterTool(&Tool{
+		Name: "counter",
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			calls++
+			return calls, nil
+		},
+	})
+
+	req := Request{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "tools/call",
+		Params:  map[string]interface{}{"name": "counter"},
+	}
+
+	if _, err := s.handleToolCall(context.Background(), req); err != nil {
+		t.Fatalf("first call should succeed: %v", err)
+	}
+	_, err := s.handleToolCall(context.Background(), req)
+	if err == nil || !strings.Contains(err.Error(), "rate limit exceeded") {
+		t.Errorf("expected rate limit error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+}
+
+func TestRefusedCallsNotRateLimited(t *testing.T) {
+	s := NewServer(nil, nil)
+	s.SetRateLimiter(ratelimit.NewRegistry(map[ratelimit.Scope]ratelimit.Config{
+		ratelimit.ScopeTool: {Rate: 0.001, Burst: 1},
+	}))
+	for _, name := range []string{"vault_write", "script_exec"} {
+		s.RegisterTool(&Tool{
+			Name: name,
+			Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				return "ok", nil
+			},
+		})
+	}
+
+	guest := &Role{Name: "guest", Deny: []string{"vault_*"}}
+	var roles []*Role
+	s.SetRoleResolver(func(ctx context.Context) []*Role { return roles })
+
+	roles = []*Role{guest}
+	for i := 0; i < 3; i++ {
+		if _, err := s.CallTool(context.Background(), "vault_write", nil); err == nil || !strings.Contains(err.Error(), "role guest") {
+			t.Fatalf("expected a role error, got %v", err)
+		}
+	}
+
+	roles = nil
+	if _, err := s.CallTool(context.Background(), "vault_write", nil); err != nil {
+		t.Errorf("refused calls used up the rate limit: %v", err)
+	}
+
+	// Nor do calls to tools denied by policy
+	s.SetToolPolicy(&ToolPolicy{Tools: map[string]Policy{"script_exec": PolicyDeny}})
+	for i := 0; i < 3; i++ {
+		if _, err := s.CallTool(context.Background(), "script_exec", nil); err == nil || !strings.Contains(err.Error(), "disabled by policy") {
+			t.Fatalf("expected a policy error, got %v", err)
+		}
+	}
+	s.SetToolPolicy(nil)
+	if _, err := s.CallTool(context.Background(), "script_exec", nil); err != nil {
+		t.Errorf("denied calls used up the rate limit: %v", err)
+	}
+}
+
+// fakeApprover answers approval requests with a fixed answer, or waits for
+// the deadline when block is set.
+type fakeApprover struct {
+	approve bool
+	block   bool
+	asked   []string
+}
+
+func (a *fakeApprover) ApproveToolCall(ctx context.Context, tool string, args map[string]interface{}) (bool, error) {
+	a.asked = append(a.asked, tool)
+	if a.block {
+		<-ctx.Done()
+		return false, ErrApprovalTimeout
+	}
+	return a.approve, nil
+}
+
+func TestHandleToolCallPolicy(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer(&buf, &buf)
+	s.SetToolPolicy(&ToolPolicy{
+		Default: PolicyConfirm,
+		Tools:   map[string]Policy{"safe": PolicyAuto, "forbidden": PolicyDeny},
+		Timeout: 10 * time.Millisecond,
+	})
+
+	calls := 0
+	for _, name := range []string{"safe", "risky", "forbidden"} {
+		s.RegisterTool(&Tool{
+			Name: name,
+			Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				calls++
+				return "ran", nil
+			},
+		})
+	}
+
+	call := func(name string) error {
+		_, err := s.handleToolCall(context.Background(), Request{
+			JSONRPC: "2.0",
+			ID:      1,
+			Method:  "tools/call",
+			Params:  map[string]interface{}{"name": name},
+		})
+		return err
+	}
+
+	// Denied tools are hidden and never run
+	list := s.handleToolsList().(map[string]interface{})["tools"].([]map[string]interface{})
+	if len(list) != 2 {
+		t.Errorf("expected the denied tool to be hidden, got %v", list)
+	}
+	if err := call("forbidden"); err == nil || !strings.Contains(err.Error(), "disabled by policy") {
+		t.Errorf("expected a policy error, got %v", err)
+	}
+
+	// Confirm calls fail without an approver
+	if err := call("risky"); err == nil || !strings.Contains(err.Error(), "no approver") {
+		t.Errorf("expected a missing approver error, got %v", err)
+	}
+
+	approver := &fakeApprover{approve: true}
+	s.SetToolApprover(approver)
+	if err := call("safe"); err != nil {
+		t.Errorf("auto call failed: %v", err)
+	}
+	if err := call("risky"); err != nil {
+		t.Errorf("approved call failed: %v", err)
+	}
+	if len(approver.asked) != 1 || approver.asked[0] != "risky" {
+		t.Errorf("expected one approval request for risky, got %v", approver.asked)
+	}
+
+	approver.approve = false
+	if err := call("risky"); err == nil || !strings.Contains(err.Error(), "denied by the owner") {
+		t.Errorf("expected a denial, got %v", err)
+	}
+	approver.block = true
+	if err := call("risky"); err == nil || !strings.Contains(err.Error(), "not approved in time") {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("handlers ran %d times, want 2", calls)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for in, want := range map[string]Policy{"": PolicyAuto, "auto": PolicyAuto, "confirm": PolicyConfirm, "deny": PolicyDeny} {
+		if got, err := ParsePolicy(in); err != nil || got != want {
+			t.Errorf("ParsePolicy(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParsePolicy("ask"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
+
 func TestRequestResponse(t *testing.T) {
 	req := Request{
 		JSONRPC: "2.0",
@@ -214,3 +455,146 @@ func TestErrorResponse(t *testing.T) {
 		t.Errorf("expected error code -32601, got %d", decoded.Error.Code)
 	}
 }
+
+func TestHandleMessage_StdioBatchAndProgress(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer(nil, &buf)
+	s.RegisterTool(&Tool{
+		Name: "work",
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			ReportProgress(ctx, "halfway")
+			return "ok", nil
+		},
+	})
+
+	s.handleMessage(context.Background(), []byte(`[
+		{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"work","_meta":{"progressToken":5}}},
+		{"jsonrpc":"2.0","method":"notifications/initialized"},
+		{"jsonrpc":"2.0","id":2,"method":"tools/list"}
+	]`))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a progress notification and a batch response, got %q", buf.String())
+	}
+	if !strings.Contains(lines[0], `"notifications/progress"`) || !strings.Contains(lines[0], `"progressToken":5`) {
+		t.Errorf("progress = %s", lines[0])
+	}
+	var responses []Response
+	if err := json.Unmarshal([]byte(lines[1]), &responses); err != nil || len(responses) != 2 {
+		t.Errorf("batch response = %s (%v)", lines[1], err)
+	}
+
+	// A lone notification gets no response
+	buf.Reset()
+	s.handleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+	if buf.Len() != 0 {
+		t.Errorf("unexpected output for notification: %s", buf.String())
+	}
+}
+
+func TestHandleToolCallRoles(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer(&buf, &buf)
+	for _, name := range []string{"memory_read", "vault_write", "chat_send"} {
+		s.RegisterTool(&Tool{
+			Name: name,
+			Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				return "ok", nil
+			},
+		})
+	}
+
+	guest := &Role{
+		Name: "guest",
+		Deny: []string{"vault_*"},
+		Args: map[string]map[string][]string{"chat_send": {"target": {"channel:family"}}},
+	}
+	var roles []*Role
+	s.SetRoleResolver(func(ctx context.Context) []*Role { return roles })
+
+	call := func(name string, args map[string]interface{}) error {
+		_, err := s.CallTool(context.Background(), name, args)
+		return err
+	}
+
+	// No roles apply
+	if err := call("vault_write", nil); err != nil {
+		t.Errorf("unlimited call failed: %v", err)
+	}
+
+	roles = []*Role{guest}
+	if err := call("memory_read", nil); err != nil {
+		t.Errorf("allowed call failed: %v", err)
+	}
+	if err := call("vault_write", nil); err == nil || !strings.Contains(err.Error(), "role guest may not use tool vault_write") {
+		t.Errorf("expected a denied tool, got %v", err)
+	}
+	if err := call("chat_send", map[string]interface{}{"target": "channel:family"}); err != nil {
+		t.Errorf("allowed argument failed: %v", err)
+	}
+	if err := call("chat_send", map[string]interface{}{"target": "user:someone"}); err == nil || !strings.Contains(err.Error(), `target "user:someone"`) {
+		t.Errorf("expected a refused argument, got %v", err)
+	}
+	if err := call("chat_send", nil); err == nil {
+		t.Error("expected a missing constrained argument to be refused")
+	}
+
+	// Every role must allow the call
+	roles = []*Role{{Name: "owner"}, {Name: "reader", Tools: []string{"memory_*"}}}
+	if err := call("memory_read", nil); err != nil {
+		t.Errorf("allowed call failed: %v", err)
+	}
+	if err := call("chat_send", map[string]interface{}{"target": "channel:family"}); err == nil {
+		t.Error("expected a tool outside the allow list to be refused")
+	}
+}
+
+func TestListToolsFor(t *testing.T) {
+	s := NewServer(nil, nil)
+	for _, name := range []string{"memory_read", "vault_write", "chat_send"} {
+		s.RegisterTool(&Tool{Name: name})
+	}
+	names := func(tools []*Tool) string {
+		var list []string
+		for _, t := range tools {
+			list = append(list, t.Name)
+		}
+		sort.Strings(list)
+		return strings.Join(list, ",")
+	}
+
+	if got := names(s.ListToolsFor(context.Background())); got != "chat_send,memory_read,vault_write" {
+		t.Errorf("without roles: %s", got)
+	}
+
+	// Tools with constrained arguments are still offered
+	guest := &Role{
+		Name: "guest",
+		Deny: []string{"vault_*"},
+		Args: map[string]map[string][]string{"chat_send": {"target": {"channel:family"}}},
+	}
+	reader := &Role{Name: "reader", Tools: []string{"memory_*", "chat_*"}}
+	var roles []*Role
+	s.SetRoleResolver(func(ctx context.Context) []*Role { return roles })
+
+	roles = []*Role{guest}
+	if got := names(s.ListToolsFor(context.Background())); got != "chat_send,memory_read" {
+		t.Errorf("guest: %s", got)
+	}
+	roles = []*Role{guest, reader}
+	s.SetToolPolicy(&ToolPolicy{Tools: map[string]Policy{"chat_send": PolicyDeny}})
+	if got := names(s.ListToolsFor(context.Background())); got != "memory_read" {
+		t.Errorf("guest and reader: %s", got)
+	}
+}
+
+func TestRoleValidate(t *testing.T) {
+	if err := (&Role{Name: "ok", Tools: []string{"vault_*"}}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	bad := &Role{Name: "bad", Args: map[string]map[string][]string{"chat_send": {"target": {"channel:["}}}}
+	if err := bad.Validate(); err == nil || !strings.Contains(err.Error(), "chat_send.target") {
+		t.Errorf("expected a bad pattern error, got %v", err)
+	}
+}
@@ -0,0 +1,332 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SessionHeader carries the session ID assigned by initialize on the
+// Streamable HTTP transport.
+const SessionHeader = "Mcp-Session-Id"
+
+// sessionIdleTimeout is how long a session without requests or an open
+// stream is kept before it is discarded.
+const sessionIdleTimeout = time.Hour
+
+// maxSessions caps the number of HTTP sessions kept at once; the least
+// recently used one is evicted to make room.
+const maxSessions = 64
+
+// sessionQueueSize is the number of server-initiated messages buffered for
+// a session while no GET stream is reading them.
+const sessionQueueSize = 64
+
+// progressHeartbeat is how often a running tool call that asked for progress
+// reports that it is still running.
+var progressHeartbeat = 10 * time.Second
+
+// session is one connected MCP client. HTTP clients get a session from
+// initialize and send its ID with every request; the stdio client is a
+// single implicit session. Sessions scope request cancellation and resource
+// subscriptions, and queue server-initiated notifications until a GET
+// stream picks them up.
+type session struct {
+	id       string
+	out      chan Notification // nil for the stdio session, which writes directly
+	done     chan struct{}     // closed when the session is terminated
+	lastSeen time.Time         // guarded by Server.mu
+	streams  int               // open GET streams, guarded by Server.mu
+}
+
+func newSession(id string) *session {
+	return &session{
+		id:       id,
+		out:      make(chan Notification, sessionQueueSize),
+		done:     make(chan struct{}),
+		lastSeen: time.Now(),
+	}
+}
+
+type contextKey int
+
+const (
+	sessionContextKey contextKey = iota
+	notifyContextKey
+	progressContextKey
+)
+
+// withSession attaches the client session to a request context.
+func withSession(ctx context.Context, sess *session) context.Context {
+	return context.WithValue(ctx, sessionContextKey, sess)
+}
+
+// sessionFrom returns the client session of a request, or nil for
+// stateless HTTP requests.
+func sessionFrom(ctx context.Context) *session {
+	sess, _ := ctx.Value(sessionContextKey).(*session)
+	return sess
+}
+
+// notifyFunc delivers a notification on the stream answering the current
+// request (stdio, or an SSE response to a POST).
+type notifyFunc func(n Notification)
+
+// withNotify attaches the request's notification channel to ctx.
+func withNotify(ctx context.Context, notify notifyFunc) context.Context {
+	return context.WithValue(ctx, notifyContextKey, notify)
+}
+
+// progressReporter sends notifications/progress for one request.
+type progressReporter struct {
+	mu     sync.Mutex
+	token  interface{}
+	count  float64
+	notify notifyFunc
+}
+
+// ReportProgress tells the client that the current tool call is still
+// making progress. It is a no-op unless the client asked for progress
+// (params._meta.progressToken) on a transport that can deliver it.
+func ReportProgress(ctx context.Context, message string) {
+	p, _ := ctx.Value(progressContextKey).(*progressReporter)
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.count++
+	progress := p.count
+	p.mu.Unlock()
+
+	p.notify(Notification{
+		JSONRPC: "2.0",
+		Method:  "notifications/progress",
+		Params: map[string]interface{}{
+			"progressToken": p.token,
+			"progress":      progress,
+			"message":       message,
+		},
+	})
+}
+
+// withProgress installs a progress reporter for req if it carries a
+// progress token and ctx has a channel to deliver notifications on.
+func withProgress(ctx context.Context, req Request) context.Context {
+	notify, _ := ctx.Value(notifyContextKey).(notifyFunc)
+	if notify == nil {
+		return ctx
+	}
+	params, _ := req.Params.(map[string]interface{})
+	meta, _ := params["_meta"].(map[string]interface{})
+	token, ok := meta["progressToken"]
+	if !ok || token == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressContextKey, &progressReporter{token: token, notify: notify})
+}
+
+// wantsProgress reports whether any request asks for progress notifications.
+func wantsProgress(reqs []Request) bool {
+	for _, req := range reqs {
+		params, _ := req.Params.(map[string]interface{})
+		meta, _ := params["_meta"].(map[string]interface{})
+		if meta["progressToken"] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMessages decodes a JSON-RPC message or batch.
+func parseMessages(body []byte) (msgs []Request, batch bool, err error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &msgs); err != nil {
+			return nil, true, err
+		}
+		if len(msgs) == 0 {
+			return nil, true, fmt.Errorf("empty batch")
+		}
+		return msgs, true, nil
+	}
+	var msg Request
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, false, err
+	}
+	return []Request{msg}, false, nil
+}
+
+// isRequest reports whether msg expects a response. Messages without an ID
+// are notifications, and messages without a method are responses to
+// server requests (which this server does not send).
+func isRequest(msg Request) bool {
+	return msg.ID != nil && msg.Method != ""
+}
+
+// processMessages handles a batch of incoming messages: notifications are
+// applied first, then requests run concurrently. It returns one response
+// per request, in order.
+func (s *Server) processMessages(ctx context.Context, msgs []Request) []Response {
+	var reqs []Request
+	for _, msg := range msgs {
+		if isRequest(msg) {
+			reqs = append(reqs, msg)
+		} else if msg.Method != "" {
+			s.handleNotification(ctx, msg)
+		}
+	}
+
+	responses := make([]Response, len(reqs))
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = s.runRequest(ctx, req)
+		}()
+	}
+	wg.Wait()
+	return responses
+}
+
+// runRequest processes a request under a context that notifications/cancelled
+// can cancel, when the request belongs to a session.
+func (s *Server) runRequest(ctx context.Context, req Request) Response {
+	sess := sessionFrom(ctx)
+	if sess == nil {
+		return s.processRequest(ctx, req)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	key := inflightKey(sess, req.ID)
+	s.mu.Lock()
+	s.inflight[key] = cancel
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.inflight, key)
+		s.mu.Unlock()
+		cancel()
+	}()
+
+	return s.processRequest(ctx, req)
+}
+
+// inflightKey identifies a running request; IDs are only unique per session.
+func inflightKey(sess *session, id interface{}) string {
+	return sess.id + "\x00" + fmt.Sprint(id)
+}
+
+// handleNotification applies a notification from the client.
+func (s *Server) handleNotification(ctx context.Context, msg Request) {
+	logger := s.logger(ctx)
+	switch msg.Method {
+	case "notifications/cancelled":
+		params, _ := msg.Params.(map[string]interface{})
+		reason, _ := params["reason"].(string)
+		sess := sessionFrom(ctx)
+		if sess == nil {
+			logger.Debug("Ignoring cancellation without a session")
+			return
+		}
+		s.mu.Lock()
+		cancel := s.inflight[inflightKey(sess, params["requestId"])]
+		s.mu.Unlock()
+		if cancel != nil {
+			logger.Info("Request %v cancelled by client: %s", params["requestId"], reason)
+			cancel()
+		}
+	case "notifications/initialized":
+	default:
+		logger.Debug("Ignoring notification %s", msg.Method)
+	}
+}
+
+// createSession starts a new HTTP session, discarding idle ones first.
+func (s *Server) createSession() (*session, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	sess := newSession(hex.EncodeToString(b))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var oldest *session
+	for _, other := range s.sessions {
+		if other.streams == 0 && time.Since(other.lastSeen) > sessionIdleTimeout {
+			s.closeSessionLocked(other)
+			continue
+		}
+		if oldest == nil || other.lastSeen.Before(oldest.lastSeen) {
+			oldest = other
+		}
+	}
+	if len(s.sessions) >= maxSessions && oldest != nil {
+		s.closeSessionLocked(oldest)
+	}
+	s.sessions[sess.id] = sess
+	return sess, nil
+}
+
+// lookupSession returns the session with the given ID, marking it as used.
+func (s *Server) lookupSession(id string) *session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.sessions[id]
+	if sess != nil {
+		sess.lastSeen = time.Now()
+	}
+	return sess
+}
+
+// closeSession terminates a session: its streams end, its running requests
+// are cancelled and its subscriptions are dropped.
+func (s *Server) closeSession(sess *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeSessionLocked(sess)
+}
+
+func (s *Server) closeSessionLocked(sess *session) {
+	if _, ok := s.sessions[sess.id]; !ok {
+		return
+	}
+	delete(s.sessions, sess.id)
+	close(sess.done)
+
+	prefix := sess.id + "\x00"
+	for key, cancel := range s.inflight {
+		if len(key) >= len(prefix) && key[:len(prefix)] == prefix {
+			cancel()
+		}
+	}
+	for uri, sub := range s.subscriptions {
+		delete(sub.sessions, sess)
+		if len(sub.sessions) == 0 {
+			delete(s.subscriptions, uri)
+		}
+	}
+}
+
+// deliver sends a server-initiated notification to a session: straight to
+// stdout for the stdio client, or to the queue read by the session's GET
+// stream. If the queue is full the notification is dropped.
+func (s *Server) deliver(sess *session, n Notification) {
+	if sess.out == nil {
+		s.sendNotification(n)
+		return
+	}
+	select {
+	case sess.out <- n:
+	default:
+		s.log.Warn("Dropping %s for session %s: queue full", n.Method, sess.id)
+	}
+}
@@ -3,10 +3,11 @@ package mcp
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/open-pact/openpact/internal/logging"
 )
 
 // ContextReloader is a callback to reload context files after memory writes.
@@ -119,7 +120,7 @@ func workspaceWriteTool(basePath string, reloadContext ContextReloader) *Tool {
 				strings.HasPrefix(fullPath, filepath.Join(basePath, "memory")+string(filepath.Separator))
 			if reloadContext != nil && shouldReload {
 				if err := reloadContext(); err != nil {
-					log.Printf("Warning: failed to reload context after writing %s: %v", path, err)
+					logging.Standard().WithContext(ctx).WithField("component", "mcp").Warn("failed to reload context after writing %s: %v", path, err)
 				}
 			}
 
@@ -64,6 +64,7 @@ func webFetchTool() *Tool {
 			// Set a reasonable user agent
 			req.Header.Set("User-Agent", "OpenPact/0.1 (AI Assistant)")
 
+			ReportProgress(ctx, "Fetching "+url)
 			resp, err := client.Do(req)
 			if err != nil {
 				return nil, fmt.Errorf("fetch failed: %w", err)
@@ -73,6 +74,7 @@ func webFetchTool() *Tool {
 			if resp.StatusCode != http.StatusOK {
 				return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
 			}
+			ReportProgress(ctx, "Reading response")
 
 			// Read body with limit
 			limitedReader := io.LimitReader(resp.Body, int64(maxLen*2)) // Allow for HTML overhead
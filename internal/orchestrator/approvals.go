@@ -0,0 +1,275 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/open-pact/openpact/internal/admin"
+	"github.com/open-pact/openpact/internal/chat"
+	"github.com/open-pact/openpact/internal/config"
+	"github.com/open-pact/openpact/internal/logging"
+	"github.com/open-pact/openpact/internal/mcp"
+)
+
+// maxPromptArgsLen caps the tool arguments shown in a chat approval prompt.
+const maxPromptArgsLen = 1000
+
+// turnOrigin is the chat message that started a turn.
+type turnOrigin struct {
+	provider  string
+	channelID string
+	userID    string
+	role      *mcp.Role // nil if the sender is not limited
+}
+
+// pendingApproval is a tool call waiting for an answer. answer is buffered
+// so the first answer never blocks.
+type pendingApproval struct {
+	admin.ToolApproval
+	answer chan bool
+}
+
+// toolPolicy converts the tool_policy config section.
+func toolPolicy(cfg config.ToolPolicyConfig) (*mcp.ToolPolicy, error) {
+	def, err := mcp.ParsePolicy(cfg.Default)
+	if err != nil {
+		return nil, fmt.Errorf("tool_policy.default: %w", err)
+	}
+	policy := &mcp.ToolPolicy{
+		Default: def,
+		Tools:   make(map[string]mcp.Policy, len(cfg.Tools)),
+		Timeout: time.Duration(cfg.ConfirmTimeout) * time.Second,
+	}
+	for tool, name := range cfg.Tools {
+		p, err := mcp.ParsePolicy(name)
+		if err != nil {
+			return nil, fmt.Errorf("tool_policy.tools.%s: %w", tool, err)
+		}
+		policy.Tools[tool] = p
+	}
+	return policy, nil
+}
+
+// setTurnOrigin records the chat message that started the turn with the
+// given correlation ID. endTurn forgets it.
+func (o *Orchestrator) setTurnOrigin(correlationID string, origin turnOrigin) {
+	o.inflightMu.Lock()
+	defer o.inflightMu.Unlock()
+	o.origins[correlationID] = origin
+}
+
+// turnOriginOf returns the chat message that started the turn with the given
+// correlation ID, if it came from chat and is still running.
+func (o *Orchestrator) turnOriginOf(correlationID string) (turnOrigin, bool) {
+	o.inflightMu.Lock()
+	defer o.inflightMu.Unlock()
+	origin, ok := o.origins[correlationID]
+	return origin, ok
+}
+
+// ApproveToolCall asks the owner whether a confirm tool call may run
+// (implements mcp.ToolApprover). The tool approvers are asked by direct
+// message, and the call is listed in the admin UI, where any admin may
+// answer it. The user whose message led to the call is only told it waits.
+func (o *Orchestrator) ApproveToolCall(ctx context.Context, tool string, args map[string]interface{}) (bool, error) {
+	id, err := newApprovalID()
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	pending := &pendingApproval{
+		ToolApproval: admin.ToolApproval{
+			ID:          id,
+			Tool:        tool,
+			Args:        args,
+			RequestedAt: now,
+			ExpiresAt:   now.Add(mcp.DefaultConfirmTimeout),
+		},
+		answer: make(chan bool, 1),
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		pending.ExpiresAt = deadline
+	}
+	origin, fromChat := o.turnOriginOf(logging.CorrelationID(ctx))
+	if fromChat {
+		pending.Provider = origin.provider
+		pending.ChannelID = origin.channelID
+		pending.UserID = origin.userID
+	}
+
+	o.approvalMu.Lock()
+	o.approvals[id] = pending
+	o.approvalMu.Unlock()
+	defer func() {
+		o.approvalMu.Lock()
+		delete(o.approvals, id)
+		o.approvalMu.Unlock()
+	}()
+
+	logger := o.log.WithContext(ctx).WithFields(map[string]any{"tool": tool, "approval": id})
+	o.sendApprovalPrompts(logger, pending)
+
+	select {
+	case approved := <-pending.answer:
+		return approved, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return false, mcp.ErrApprovalTimeout
+		}
+		return false, ctx.Err()
+	}
+}
+
+// isToolApprover reports whether a chat user may answer tool approvals.
+func (o *Orchestrator) isToolApprover(provider, userID string) bool {
+	account := admin.AccountKey(provider, userID)
+	for _, approver := range o.cfg.ToolPolicy.Approvers {
+		if approver == account {
+			return true
+		}
+	}
+	return false
+}
+
+// sendApprovalPrompts sends an approval prompt to each tool approver by
+// direct message. If the call came from a chat turn, that channel is told the
+// call is waiting, unless its sender is an approver and got the prompt.
+func (o *Orchestrator) sendApprovalPrompts(logger *logging.Logger, p *pendingApproval) {
+	argsJSON, _ := json.Marshal(p.Args)
+	argsText := string(argsJSON)
+	if len(argsText) > maxPromptArgsLen {
+		argsText = argsText[:maxPromptArgsLen] + "…"
+	}
+	text := fmt.Sprintf("Approval needed: the assistant wants to call %s with %s", p.Tool, argsText)
+	if p.Provider != "" {
+		text += fmt.Sprintf(" for %s in channel %s", admin.AccountKey(p.Provider, p.UserID), p.ChannelID)
+	}
+	text += fmt.Sprintf("\nAnswer within %s.", time.Until(p.ExpiresAt).Round(time.Second))
+
+	for _, approver := range o.cfg.ToolPolicy.Approvers {
+		provider, userID, ok := strings.Cut(approver, ":")
+		if !ok {
+			logger.Warn("Ignoring tool approver %q: expected provider:userID", approver)
+			continue
+		}
+		if err := o.sendApprovalPrompt(provider, "user:"+userID, p.ID, text); err != nil {
+			logger.Warn("Failed to send approval prompt to %s: %v", approver, err)
+		}
+	}
+	if len(o.cfg.ToolPolicy.Approvers) == 0 {
+		logger.Info("Tool call waiting for approval in the admin UI")
+	}
+
+	if p.Provider != "" && !o.isToolApprover(p.Provider, p.UserID) {
+		notice := fmt.Sprintf("Waiting for the owner to approve %s.", p.Tool)
+		if err := o.SendViaProvider(p.Provider, "channel:"+p.ChannelID, notice); err != nil {
+			logger.Warn("Failed to tell %s the call is waiting: %v", p.Provider, err)
+		}
+	}
+}
+
+// sendApprovalPrompt posts an approval prompt to target. Providers without
+// buttons get a plain message pointing at the admin UI.
+func (o *Orchestrator) sendApprovalPrompt(providerName, target, approvalID, text string) error {
+	o.providerMu.RLock()
+	provider, ok := o.providers[providerName]
+	o.providerMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("provider %s is not running", providerName)
+	}
+
+	if prompter, ok := provider.(chat.ApprovalPrompter); ok {
+		return prompter.SendApprovalPrompt(target, chat.ApprovalPrompt{ID: approvalID, Text: text})
+	}
+	return provider.SendMessage(target, text+" Approve or deny it in the admin UI.")
+}
+
+// handleApprovalAnswer is called when a chat user presses an approval button
+// (implements chat.ApprovalHandler). Only tool approvers may answer, so users
+// can't approve the calls made for them.
+func (o *Orchestrator) handleApprovalAnswer(provider, userID, approvalID string, approved bool) (string, error) {
+	if !o.isToolApprover(provider, userID) {
+		return "", errors.New("only tool approvers can answer this approval")
+	}
+
+	o.approvalMu.Lock()
+	defer o.approvalMu.Unlock()
+
+	p, ok := o.approvals[approvalID]
+	if !ok {
+		return "", errors.New("this approval has expired or was already answered")
+	}
+	if !o.answerLocked(p, approved) {
+		return "", errors.New("this approval was already answered")
+	}
+
+	o.log.Info("Tool call %s %s by %s user %s", p.Tool, approvalVerb(approved), provider, userID)
+	if approved {
+		return fmt.Sprintf("Approved: %s", p.Tool), nil
+	}
+	return fmt.Sprintf("Denied: %s", p.Tool), nil
+}
+
+// PendingToolApprovals returns the tool calls waiting for approval, oldest
+// first (implements admin.ToolApprovalAPI).
+func (o *Orchestrator) PendingToolApprovals() []admin.ToolApproval {
+	o.approvalMu.Lock()
+	defer o.approvalMu.Unlock()
+
+	approvals := make([]admin.ToolApproval, 0, len(o.approvals))
+	for _, p := range o.approvals {
+		approvals = append(approvals, p.ToolApproval)
+	}
+	sort.Slice(approvals, func(i, j int) bool { return approvals[i].RequestedAt.Before(approvals[j].RequestedAt) })
+	return approvals
+}
+
+// ResolveToolApproval answers a tool approval from the admin UI (implements
+// admin.ToolApprovalAPI).
+func (o *Orchestrator) ResolveToolApproval(id string, approved bool, by string) error {
+	o.approvalMu.Lock()
+	defer o.approvalMu.Unlock()
+
+	p, ok := o.approvals[id]
+	if !ok || !o.answerLocked(p, approved) {
+		return admin.ErrToolApprovalNotFound
+	}
+	o.log.Info("Tool call %s %s by admin %s", p.Tool, approvalVerb(approved), by)
+	return nil
+}
+
+// answerLocked delivers the answer to a pending approval. It reports false
+// if the approval was already answered. The caller must hold approvalMu.
+func (o *Orchestrator) answerLocked(p *pendingApproval, approved bool) bool {
+	select {
+	case p.answer <- approved:
+		return true
+	default:
+		return false
+	}
+}
+
+func approvalVerb(approved bool) string {
+	if approved {
+		return "approved"
+	}
+	return "denied"
+}
+
+// newApprovalID returns a short random approval ID. It is kept short because
+// Telegram limits button data to 64 bytes.
+func newApprovalID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate approval ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
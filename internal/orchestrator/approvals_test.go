@@ -0,0 +1,199 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-pact/openpact/internal/admin"
+	"github.com/open-pact/openpact/internal/chat"
+	"github.com/open-pact/openpact/internal/config"
+	"github.com/open-pact/openpact/internal/logging"
+	"github.com/open-pact/openpact/internal/mcp"
+)
+
+// promptingProvider records approval prompts and messages instead of
+// posting them.
+type promptingProvider struct {
+	chat.Provider
+	prompts  chan chat.ApprovalPrompt
+	targets  chan string
+	messages chan string
+}
+
+func (p *promptingProvider) SendMessage(target, content string) error {
+	p.messages <- target + " " + content
+	return nil
+}
+
+func (p *promptingProvider) SendApprovalPrompt(target string, prompt chat.ApprovalPrompt) error {
+	p.targets <- target
+	p.prompts <- prompt
+	return nil
+}
+
+func (p *promptingProvider) SetApprovalHandler(h chat.ApprovalHandler) {}
+
+func TestApproveToolCallFromChat(t *testing.T) {
+	o := newTestOrchestrator(t)
+	o.cfg.ToolPolicy.Approvers = []string{"discord:owner"}
+	provider := &promptingProvider{
+		prompts:  make(chan chat.ApprovalPrompt, 1),
+		targets:  make(chan string, 1),
+		messages: make(chan string, 1),
+	}
+	o.providers["discord"] = provider
+
+	o.beginTurn("cid-1", "sess-1")
+	o.setTurnOrigin("cid-1", turnOrigin{provider: "discord", channelID: "chan1", userID: "member"})
+	defer o.endTurn("cid-1")
+
+	ctx, cancel := context.WithTimeout(logging.WithCorrelationID(context.Background(), "cid-1"), time.Minute)
+	defer cancel()
+
+	type answer struct {
+		approved bool
+		err      error
+	}
+	done := make(chan answer, 1)
+	go func() {
+		approved, err := o.ApproveToolCall(ctx, "chat_send", map[string]interface{}{"message": "hi"})
+		done <- answer{approved, err}
+	}()
+
+	prompt := <-provider.prompts
+	if target := <-provider.targets; target != "user:owner" {
+		t.Errorf("prompt sent to %q, want the approver user:owner", target)
+	}
+	if !strings.Contains(prompt.Text, "chat_send") || !strings.Contains(prompt.Text, `"message":"hi"`) || !strings.Contains(prompt.Text, "discord:member") {
+		t.Errorf("unexpected prompt text %q", prompt.Text)
+	}
+	if notice := <-provider.messages; !strings.HasPrefix(notice, "channel:chan1 Waiting for the owner") {
+		t.Errorf("unexpected notice %q", notice)
+	}
+
+	pending := o.PendingToolApprovals()
+	if len(pending) != 1 || pending[0].ID != prompt.ID || pending[0].UserID != "member" {
+		t.Fatalf("unexpected pending approvals %+v", pending)
+	}
+
+	// Only approvers may answer in chat, not the sender the call is for
+	if _, err := o.handleApprovalAnswer("discord", "member", prompt.ID, true); err == nil {
+		t.Error("expected the sender's own answer to be refused")
+	}
+	if _, err := o.handleApprovalAnswer("telegram", "owner", prompt.ID, true); err == nil {
+		t.Error("expected an answer from another provider's account to be refused")
+	}
+	result, err := o.handleApprovalAnswer("discord", "owner", prompt.ID, true)
+	if err != nil || result != "Approved: chat_send" {
+		t.Errorf("handleApprovalAnswer = %q, %v", result, err)
+	}
+
+	if got := <-done; !got.approved || got.err != nil {
+		t.Errorf("ApproveToolCall = %v, %v; want approved", got.approved, got.err)
+	}
+	if _, err := o.handleApprovalAnswer("discord", "owner", prompt.ID, false); err == nil {
+		t.Error("expected a late answer to be refused")
+	}
+	if pending := o.PendingToolApprovals(); len(pending) != 0 {
+		t.Errorf("expected no pending approvals, got %+v", pending)
+	}
+}
+
+func TestApproveToolCallFromAdmin(t *testing.T) {
+	o := newTestOrchestrator(t)
+
+	// Calls outside a chat turn wait for the admin UI
+	done := make(chan bool, 1)
+	go func() {
+		approved, _ := o.ApproveToolCall(context.Background(), "vault_write", nil)
+		done <- approved
+	}()
+
+	var pending []admin.ToolApproval
+	for i := 0; i < 100 && len(pending) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		pending = o.PendingToolApprovals()
+	}
+	if len(pending) != 1 || pending[0].Provider != "" {
+		t.Fatalf("unexpected pending approvals %+v", pending)
+	}
+	if err := o.ResolveToolApproval(pending[0].ID, false, "admin"); err != nil {
+		t.Fatalf("ResolveToolApproval: %v", err)
+	}
+	if approved := <-done; approved {
+		t.Error("expected the call to be denied")
+	}
+	if err := o.ResolveToolApproval(pending[0].ID, true, "admin"); !errors.Is(err, admin.ErrToolApprovalNotFound) {
+		t.Errorf("expected ErrToolApprovalNotFound, got %v", err)
+	}
+}
+
+func TestApproveToolCallWithoutApprovers(t *testing.T) {
+	o := newTestOrchestrator(t)
+	provider := &promptingProvider{messages: make(chan string, 1)}
+	o.providers["telegram"] = provider
+
+	o.beginTurn("cid-1", "sess-1")
+	o.setTurnOrigin("cid-1", turnOrigin{provider: "telegram", channelID: "-100", userID: "42"})
+	defer o.endTurn("cid-1")
+
+	ctx, cancel := context.WithCancel(logging.WithCorrelationID(context.Background(), "cid-1"))
+	done := make(chan error, 1)
+	go func() {
+		_, err := o.ApproveToolCall(ctx, "vault_write", nil)
+		done <- err
+	}()
+
+	if notice := <-provider.messages; !strings.Contains(notice, "Waiting for the owner") {
+		t.Errorf("unexpected notice %q", notice)
+	}
+	pending := o.PendingToolApprovals()
+	if len(pending) != 1 {
+		t.Fatalf("unexpected pending approvals %+v", pending)
+	}
+	// Without approvers, nobody can answer in chat
+	if _, err := o.handleApprovalAnswer("telegram", "42", pending[0].ID, true); err == nil {
+		t.Error("expected the sender's own answer to be refused")
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the call to be cancelled, got %v", err)
+	}
+}
+
+func TestApproveToolCallTimeout(t *testing.T) {
+	o := newTestOrchestrator(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := o.ApproveToolCall(ctx, "script_exec", nil); !errors.Is(err, mcp.ErrApprovalTimeout) {
+		t.Errorf("expected ErrApprovalTimeout, got %v", err)
+	}
+	if pending := o.PendingToolApprovals(); len(pending) != 0 {
+		t.Errorf("expected the expired approval to be removed, got %+v", pending)
+	}
+}
+
+func TestToolPolicyConfig(t *testing.T) {
+	policy, err := toolPolicy(config.ToolPolicyConfig{
+		Default:        "confirm",
+		Tools:          map[string]string{"memory_read": "auto", "script_exec": "deny"},
+		ConfirmTimeout: 60,
+	})
+	if err != nil {
+		t.Fatalf("toolPolicy: %v", err)
+	}
+	if policy.For("chat_send") != mcp.PolicyConfirm || policy.For("memory_read") != mcp.PolicyAuto || policy.For("script_exec") != mcp.PolicyDeny {
+		t.Errorf("unexpected policy %+v", policy)
+	}
+	if policy.Timeout != time.Minute {
+		t.Errorf("expected a 1m timeout, got %s", policy.Timeout)
+	}
+
+	if _, err := toolPolicy(config.ToolPolicyConfig{Tools: map[string]string{"chat_send": "ask"}}); err == nil || !strings.Contains(err.Error(), "chat_send") {
+		t.Errorf("expected an error naming the tool, got %v", err)
+	}
+}
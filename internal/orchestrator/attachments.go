@@ -0,0 +1,48 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-pact/openpact/internal/chat"
+	"github.com/open-pact/openpact/internal/engine"
+	"github.com/open-pact/openpact/internal/logging"
+)
+
+// receiveAttachments saves a message's attachments to the inbox. It returns
+// the saved files for the engine and a note for the message text listing
+// their workspace paths and any attachments that were refused, so the AI can
+// read them with its tools and tell the user about the rest.
+func (o *Orchestrator) receiveAttachments(ctx context.Context, logger *logging.Logger, provider string, attachments []chat.Attachment) ([]engine.File, string) {
+	if len(attachments) == 0 {
+		return nil, ""
+	}
+
+	saved, rejected := o.inbox.Save(ctx, provider, attachments)
+
+	aiData := o.cfg.Workspace.AIDataDir()
+	var files []engine.File
+	var note strings.Builder
+	if len(saved) > 0 {
+		note.WriteString("\n\n[attachments saved to the workspace]")
+		for _, f := range saved {
+			rel, err := filepath.Rel(aiData, f.Path)
+			if err != nil {
+				rel = f.Path
+			}
+			fmt.Fprintf(&note, "\n- %s (%s, %s)", filepath.ToSlash(rel), f.MIME, chat.FormatSize(f.Size))
+			files = append(files, engine.File{Path: f.Path, Name: f.Name, MIME: f.MIME})
+			logger.Info("Attachment saved: %s (%s, %s)", rel, f.MIME, chat.FormatSize(f.Size))
+		}
+	}
+	if len(rejected) > 0 {
+		note.WriteString("\n\n[attachments not accepted]")
+		for _, r := range rejected {
+			note.WriteString("\n- " + r)
+			logger.Warn("Attachment rejected: %s", r)
+		}
+	}
+	return files, note.String()
+}
@@ -0,0 +1,67 @@
+package orchestrator
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/open-pact/openpact/internal/chat"
+	"github.com/open-pact/openpact/internal/engine"
+)
+
+func fakeAttachment(name, contentType string, data []byte) chat.Attachment {
+	return chat.Attachment{
+		Name:        name,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Fetch: func(ctx context.Context, w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		},
+	}
+}
+
+func TestChatMessageAttachments(t *testing.T) {
+	o := newTestOrchestrator(t)
+	o.inbox = chat.NewInbox(o.cfg.Workspace.InboxDir(), chat.InboxConfig{MaxSize: 1024})
+
+	var sent []engine.Message
+	o.engine = &stubEngine{
+		send: func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+			sent = messages
+			ch := make(chan engine.Response, 1)
+			ch <- engine.Response{Content: "Nice photo.", PartID: "p1"}
+			close(ch)
+			return ch, nil
+		},
+	}
+
+	_, err := o.handleChatMessage("telegram", "42", "7", "", []chat.Attachment{
+		fakeAttachment("photo.jpg", "image/jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")),
+		fakeAttachment("tool.exe", "application/x-msdownload", []byte("MZ\x90\x00")),
+	})
+	if err != nil {
+		t.Fatalf("handleChatMessage: %v", err)
+	}
+
+	if len(sent) != 1 || len(sent[0].Files) != 1 {
+		t.Fatalf("engine messages = %+v, want one message with one file", sent)
+	}
+	file := sent[0].Files[0]
+	if file.MIME != "image/jpeg" || file.Name != "photo.jpg" || !strings.HasPrefix(file.Path, o.cfg.Workspace.InboxDir()) {
+		t.Errorf("file = %+v", file)
+	}
+
+	content := sent[0].Content
+	for _, want := range []string{
+		"[via telegram, channel:42, user:7]",
+		"[attachments saved to the workspace]\n- inbox/telegram/",
+		"-photo.jpg (image/jpeg, 11 bytes)",
+		"[attachments not accepted]\n- tool.exe: type application/x-msdownload is not accepted",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("message content missing %q:\n%s", want, content)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package orchestrator
+
+// beginTurn records that an engine turn with the given correlation ID is in
+// progress for sessionID.
+func (o *Orchestrator) beginTurn(correlationID, sessionID string) {
+	o.inflightMu.Lock()
+	defer o.inflightMu.Unlock()
+	o.inflight[correlationID] = sessionID
+}
+
+// endTurn removes a turn recorded by beginTurn, and its chat origin.
+func (o *Orchestrator) endTurn(correlationID string) {
+	o.inflightMu.Lock()
+	defer o.inflightMu.Unlock()
+	delete(o.inflight, correlationID)
+	delete(o.origins, correlationID)
+}
+
+// activeCorrelationID returns the correlation ID of the only in-flight turn,
+// or "" if there are none or several. OpenCode's MCP requests carry no
+// reference to the turn that caused them, so tool calls can only be attributed
+// unambiguously when a single turn is running; otherwise the MCP server gives
+// the request its own ID.
+func (o *Orchestrator) activeCorrelationID() string {
+	o.inflightMu.Lock()
+	defer o.inflightMu.Unlock()
+
+	if len(o.inflight) != 1 {
+		return ""
+	}
+	for id := range o.inflight {
+		return id
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-pact/openpact/internal/config"
+	"github.com/open-pact/openpact/internal/engine"
+	"github.com/open-pact/openpact/internal/logging"
+)
+
+// stubEngine overrides the engine calls used by handleChatMessage. Methods not
+// overridden panic via the nil embedded interface.
+type stubEngine struct {
+	engine.Engine
+	send  func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error)
+	usage func(sessionID string) (*engine.ContextUsage, error) // nil: no usage
+}
+
+func (s *stubEngine) CreateSession() (*engine.Session, error) {
+	return &engine.Session{ID: "sess-1"}, nil
+}
+
+func (s *stubEngine) GetContextUsage(sessionID string) (*engine.ContextUsage, error) {
+	if s.usage == nil {
+		return &engine.ContextUsage{}, nil
+	}
+	return s.usage(sessionID)
+}
+
+func (s *stubEngine) Send(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+	return s.send(ctx, sessionID, messages)
+}
+
+func newTestOrchestrator(t *testing.T) *Orchestrator {
+	t.Helper()
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Engine:    config.EngineConfig{Type: "opencode"},
+		Workspace: config.WorkspaceConfig{Path: tmpDir},
+	}
+	cfg.Workspace.EnsureDirs()
+
+	o, err := New(cfg, nil)
+	if err != nil {
+		t.Fatalf("failed to create orchestrator: %v", err)
+	}
+	t.Cleanup(o.closeMCPHTTPServer)
+	return o
+}
+
+func TestChatMessageCorrelationID(t *testing.T) {
+	o := newTestOrchestrator(t)
+
+	var engineCID, activeCID string
+	o.engine = &stubEngine{
+		send: func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+			engineCID = logging.CorrelationID(ctx)
+			activeCID = o.activeCorrelationID()
+			ch := make(chan engine.Response, 1)
+			ch <- engine.Response{Content: "hi", PartID: "p1"}
+			close(ch)
+			return ch, nil
+		},
+	}
+
+	resp, err := o.handleChatMessage("discord", "chan1", "user1", "hello", nil)
+	if err != nil {
+		t.Fatalf("handleChatMessage: %v", err)
+	}
+	if resp.CorrelationID == "" {
+		t.Fatal("expected response to carry a correlation ID")
+	}
+	if engineCID != resp.CorrelationID {
+		t.Errorf("engine saw correlation ID %q, response has %q", engineCID, resp.CorrelationID)
+	}
+	if activeCID != resp.CorrelationID {
+		t.Errorf("active correlation ID during turn = %q, want %q", activeCID, resp.CorrelationID)
+	}
+	if got := o.activeCorrelationID(); got != "" {
+		t.Errorf("expected no active turn after completion, got %q", got)
+	}
+}
+
+func TestActiveCorrelationIDAmbiguous(t *testing.T) {
+	o := newTestOrchestrator(t)
+
+	o.beginTurn("a", "s1")
+	o.beginTurn("b", "s2")
+	if got := o.activeCorrelationID(); got != "" {
+		t.Errorf("expected no ID with two turns in flight, got %q", got)
+	}
+
+	o.endTurn("a")
+	if got := o.activeCorrelationID(); got != "b" {
+		t.Errorf("activeCorrelationID() = %q, want %q", got, "b")
+	}
+}
@@ -0,0 +1,97 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/open-pact/openpact/internal/engine"
+	"github.com/open-pact/openpact/internal/health"
+	"github.com/open-pact/openpact/internal/mcp"
+)
+
+// registerHealthChecks registers the engine, SSE stream and MCP HTTP checks.
+// Provider checks are registered as providers are started.
+func (o *Orchestrator) registerHealthChecks() {
+	o.health.RegisterCheck("engine", func(ctx context.Context) health.CheckResult {
+		reporter, ok := o.engine.(engine.HealthReporter)
+		if !ok {
+			return health.CheckResult{Status: health.StatusHealthy, Message: "no health reporting"}
+		}
+		if err := reporter.Ping(ctx); err != nil {
+			return health.CheckResult{Status: health.StatusUnhealthy, Message: err.Error()}
+		}
+		return health.CheckResult{Status: health.StatusHealthy}
+	})
+
+	o.health.RegisterCheck("engine_stream", func(ctx context.Context) health.CheckResult {
+		reporter, ok := o.engine.(engine.HealthReporter)
+		if !ok {
+			return health.CheckResult{Status: health.StatusHealthy, Message: "no health reporting"}
+		}
+		if !reporter.StreamConnected() {
+			// Send falls back to blocking requests, so this is degraded rather than down
+			return health.CheckResult{Status: health.StatusDegraded, Message: "SSE stream disconnected"}
+		}
+		return health.CheckResult{Status: health.StatusHealthy}
+	})
+
+	o.health.RegisterCheck("mcp_http", func(ctx context.Context) health.CheckResult {
+		addr := fmt.Sprintf("127.0.0.1:%d", mcp.MCPPort)
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return health.CheckResult{Status: health.StatusUnhealthy, Message: err.Error()}
+		}
+		conn.Close()
+		return health.CheckResult{Status: health.StatusHealthy}
+	})
+}
+
+// registerProviderCheck registers a health check reporting a chat provider's state.
+// Registering the same provider twice replaces the previous check.
+func (o *Orchestrator) registerProviderCheck(name string) {
+	o.health.RegisterCheck("provider:"+name, func(ctx context.Context) health.CheckResult {
+		status, _ := o.GetProviderStatus(name)
+		switch status.State {
+		case "connected", "stopped":
+			return health.CheckResult{Status: health.StatusHealthy, Message: status.State}
+		case "error":
+			return health.CheckResult{Status: health.StatusDegraded, Message: status.Error}
+		default:
+			return health.CheckResult{Status: health.StatusDegraded, Message: status.State}
+		}
+	})
+}
+
+// startHealthServer starts the health/metrics HTTP server if an address is configured.
+func (o *Orchestrator) startHealthServer() {
+	if o.cfg.Server.HealthAddr == "" {
+		return
+	}
+
+	go func() {
+		o.log.Info("Health server listening on %s", o.cfg.Server.HealthAddr)
+		if err := o.health.Start(); err != nil && err != http.ErrServerClosed {
+			o.log.Error("Health server error: %v", err)
+		}
+	}()
+}
+
+// stopHealthServer shuts down the health server if it was started.
+func (o *Orchestrator) stopHealthServer() error {
+	if o.cfg.Server.HealthAddr == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return o.health.Stop(ctx)
+}
+
+// Health returns the health/metrics server.
+func (o *Orchestrator) Health() *health.Server {
+	return o.health
+}
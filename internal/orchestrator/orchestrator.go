@@ -6,13 +6,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"math"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/open-pact/openpact/internal/admin"
 	"github.com/open-pact/openpact/internal/auth"
@@ -20,8 +21,12 @@ import (
 	"github.com/open-pact/openpact/internal/config"
 	opcontext "github.com/open-pact/openpact/internal/context"
 	"github.com/open-pact/openpact/internal/engine"
+	"github.com/open-pact/openpact/internal/health"
+	"github.com/open-pact/openpact/internal/logging"
 	"github.com/open-pact/openpact/internal/mcp"
+	"github.com/open-pact/openpact/internal/ratelimit"
 	"github.com/open-pact/openpact/internal/scheduler"
+	"github.com/open-pact/openpact/internal/starlark"
 	"github.com/open-pact/openpact/internal/providers/discord"
 	"github.com/open-pact/openpact/internal/providers/slack"
 	"github.com/open-pact/openpact/internal/providers/telegram"
@@ -39,9 +44,14 @@ type Orchestrator struct {
 	providerStore *admin.ProviderStore
 	modelStore    *admin.ModelPreferenceStore
 	scheduler     *scheduler.Scheduler
+	health        *health.Server
+	limits        *ratelimit.Registry
+	inbox         *chat.Inbox // nil if attachments are disabled
+	log           *logging.Logger
 
 	// MCP HTTP server (in-process, remote transport for OpenCode)
 	mcpHTTPServer *http.Server
+	mcpListener   net.Listener
 	mcpToken      string
 
 	// Dynamic provider management
@@ -57,6 +67,29 @@ type Orchestrator struct {
 	channelModes map[string]string
 	modeMu       sync.RWMutex
 
+	// In-flight engine turns: correlation ID -> session ID, and the chat
+	// message that started each chat turn
+	inflight   map[string]string
+	origins    map[string]turnOrigin
+	inflightMu sync.Mutex
+
+	// Roles of chat users, limiting the tools used on their behalf
+	perms *permissions
+
+	// People registry, linking the chat accounts of one person
+	people *admin.PeopleStore
+
+	// Usage ledger, its running totals and the budgets checked against
+	// them. Metered turns run one at a time per session (see lockSession).
+	usage        *admin.UsageStore
+	totals       usageTotals
+	budgets      *budgets
+	sessionTurns sessionLocks
+
+	// Tool calls waiting for the owner's approval, by approval ID
+	approvals  map[string]*pendingApproval
+	approvalMu sync.Mutex
+
 	// State
 	mu      sync.RWMutex
 	running bool
@@ -87,13 +120,23 @@ func New(cfg *config.Config, providerStore *admin.ProviderStore) (*Orchestrator,
 		channelModes:    make(map[string]string),
 		providers:       make(map[string]chat.Provider),
 		providerStatus:  make(map[string]admin.ProviderStatusInfo),
+		inflight:        make(map[string]string),
+		origins:         make(map[string]turnOrigin),
+		approvals:       make(map[string]*pendingApproval),
 	}
 
+	// Configure the process-wide logger so components that fall back to it
+	// share the configured level and format.
+	logger := logging.Standard()
+	logger.SetLevel(logging.ParseLevel(cfg.Logging.Level))
+	logger.SetJSONFormat(cfg.Logging.JSON)
+	o.log = logger.WithField("component", "orchestrator")
+
 	// Initialize context loader (reads from AI-accessible data dir)
 	o.contextLoader = opcontext.NewLoader(cfg.Workspace.AIDataDir())
 
 	// Seed workspace with template context files if they don't exist
-	seedContextTemplates(cfg.Workspace.AIDataDir())
+	seedContextTemplates(o.log, cfg.Workspace.AIDataDir())
 
 	// Seed provider store from YAML config (one-time migration)
 	if providerStore != nil {
@@ -120,13 +163,63 @@ func New(cfg *config.Config, providerStore *admin.ProviderStore) (*Orchestrator,
 		}
 		if len(seedProviders) > 0 {
 			if err := providerStore.SeedFromConfig(seedProviders); err != nil {
-				log.Printf("Warning: failed to seed provider store: %v", err)
+				o.log.Warn("failed to seed provider store: %v", err)
 			}
 		}
 	}
 
+	// Health/metrics server (only listens if HealthAddr is set, but always records)
+	o.health = health.NewServer(cfg.Server.HealthAddr)
+
 	// Initialize MCP server (in-process, for admin API tool introspection)
 	o.mcpServer = mcp.NewServer(nil, nil)
+	o.mcpServer.SetLogger(logger)
+	o.mcpServer.SetMetrics(o.health)
+	o.mcpServer.SetCorrelationResolver(o.activeCorrelationID)
+
+	// Rate limiting: per user and per channel for chat, per tool for MCP
+	rl := cfg.Server.RateLimit
+	o.limits = ratelimit.NewRegistry(map[ratelimit.Scope]ratelimit.Config{
+		ratelimit.ScopeUser:    {Rate: rl.User.Rate, Burst: rl.User.Burst},
+		ratelimit.ScopeChannel: {Rate: rl.Channel.Rate, Burst: rl.Channel.Burst},
+		ratelimit.ScopeTool:    {Rate: rl.Rate, Burst: rl.Burst},
+	})
+	o.limits.SetRejectHook(func(scope ratelimit.Scope, key string) {
+		o.log.Warn("Rate limited %s %s", scope, key)
+		o.health.RecordRateLimited(string(scope))
+	})
+	o.mcpServer.SetRateLimiter(o.limits)
+
+	// Tool policy: confirm calls are approved in chat or the admin UI
+	policy, err := toolPolicy(cfg.ToolPolicy)
+	if err != nil {
+		return nil, err
+	}
+	o.mcpServer.SetToolPolicy(policy)
+	o.mcpServer.SetToolApprover(o)
+
+	// Roles: tool calls are limited by the role of the user whose message
+	// started the turn
+	if o.perms, err = newPermissions(cfg.Permissions); err != nil {
+		return nil, err
+	}
+	o.mcpServer.SetRoleResolver(o.toolCallRoles)
+	o.people = admin.NewPeopleStore(cfg.Workspace.DataDir())
+
+	// Usage budgets: turns are blocked or downgraded once one is used up
+	if o.budgets, err = newBudgets(cfg.Usage); err != nil {
+		return nil, err
+	}
+	o.usage = admin.NewUsageStore(cfg.Workspace.DataDir())
+
+	// Chat attachments are quarantined in ai-data/inbox
+	if ac := cfg.Attachments; ac.Enabled {
+		o.inbox = chat.NewInbox(cfg.Workspace.InboxDir(), chat.InboxConfig{
+			MaxSize:      int64(ac.MaxSizeMB) << 20,
+			MaxFiles:     ac.MaxFiles,
+			AllowedTypes: ac.AllowedTypes,
+		})
+	}
 
 	// Build registration config for MCP tools
 	regCfg := mcp.RegistrationConfig{
@@ -161,7 +254,7 @@ func New(cfg *config.Config, providerStore *admin.ProviderStore) (*Orchestrator,
 		if token != "" {
 			regCfg.GitHub = &mcp.GitHubConfig{Token: token}
 		} else {
-			log.Println("GitHub enabled but GITHUB_TOKEN not set, skipping")
+			o.log.Warn("GitHub enabled but GITHUB_TOKEN not set, skipping")
 		}
 	}
 
@@ -170,14 +263,30 @@ func New(cfg *config.Config, providerStore *admin.ProviderStore) (*Orchestrator,
 		regCfg.Script = &mcp.ScriptRegistrationConfig{
 			ScriptsDir:     cfg.Workspace.ScriptsDir(),
 			MaxExecutionMs: cfg.Starlark.MaxExecutionMs,
+			MaxSteps:       cfg.Starlark.MaxSteps,
+			MaxMemoryMB:    cfg.Starlark.MaxMemoryMB,
+			ExecCapabilities: starlark.Capabilities{
+				Hosts:            cfg.Starlark.Exec.Hosts,
+				Secrets:          cfg.Starlark.Exec.Secrets,
+				MaxRuntimeMs:     cfg.Starlark.Exec.MaxRuntimeMs,
+				MaxResponseBytes: cfg.Starlark.Exec.MaxResponseBytes,
+			},
 		}
 	}
 
 	// Initialize scheduler
 	scheduleStore := admin.NewScheduleStore(cfg.Workspace.DataDir())
+	scheduleStore.SetRunRetention(cfg.Scheduler.History.MaxRuns, time.Duration(cfg.Scheduler.History.MaxAgeDays)*24*time.Hour)
 	schedCfg := scheduler.Config{
 		ScriptsDir:     cfg.Workspace.ScriptsDir(),
 		MaxExecutionMs: cfg.Starlark.MaxExecutionMs,
+		MaxSteps:       cfg.Starlark.MaxSteps,
+		MaxMemoryMB:    cfg.Starlark.MaxMemoryMB,
+		WatchRoots:     map[string]string{"ai-data": cfg.Workspace.AIDataDir()},
+		Logger:         logger,
+	}
+	if cfg.Vault.Path != "" {
+		schedCfg.WatchRoots["vault"] = cfg.Vault.Path
 	}
 	// Load secrets for scheduler's script execution
 	secretStore := admin.NewSecretStore(cfg.Workspace.DataDir())
@@ -192,6 +301,10 @@ func New(cfg *config.Config, providerStore *admin.ProviderStore) (*Orchestrator,
 		}
 	}
 	o.scheduler = scheduler.New(scheduleStore, schedCfg)
+	o.scheduler.SetMetricsAPI(o.health)
+	if len(cfg.Calendars) > 0 {
+		o.scheduler.SetCalendarAPI(o)
+	}
 	regCfg.Scheduler = o
 
 	// Register all tools
@@ -201,7 +314,7 @@ func New(cfg *config.Config, providerStore *admin.ProviderStore) (*Orchestrator,
 	if regCfg.Script != nil && cfg.Admin.Enabled {
 		scriptStore, err := admin.NewScriptStore(cfg.Workspace.ScriptsDir(), cfg.Workspace.DataDir(), cfg.Admin.Allowlist)
 		if err != nil {
-			log.Printf("Warning: failed to create script store for admin: %v", err)
+			o.log.Warn("failed to create script store for admin: %v", err)
 		} else {
 			o.scriptStore = scriptStore
 		}
@@ -210,11 +323,12 @@ func New(cfg *config.Config, providerStore *admin.ProviderStore) (*Orchestrator,
 	// Check engine authentication
 	authStatus := auth.CheckAuth(cfg.Engine.Type)
 	if !authStatus.Authenticated {
-		log.Printf("WARNING: Engine authentication not configured for %s.", cfg.Engine.Type)
-		log.Printf("Visit the admin UI to sign in, or run: openpact auth %s", cfg.Engine.Type)
+		o.log.Warn("Engine authentication not configured for %s.", cfg.Engine.Type)
+		o.log.Warn("Visit the admin UI to sign in, or run: openpact auth %s", cfg.Engine.Type)
 	}
 
-	// Initialize engine (connect-only — OpenCode is managed by the entrypoint)
+	// Initialize engine (connect-only — OpenCode is managed by the entrypoint;
+	// the OpenAI engine calls MCP tools in-process instead)
 	engineCfg := engine.Config{
 		Type:     cfg.Engine.Type,
 		Provider: cfg.Engine.Provider,
@@ -223,6 +337,11 @@ func New(cfg *config.Config, providerStore *admin.ProviderStore) (*Orchestrator,
 		Port:     cfg.Engine.Port,
 		Hostname: cfg.Engine.Hostname,
 		Password: cfg.Engine.Password,
+		BaseURL:  cfg.Engine.BaseURL,
+		APIKey:   cfg.Engine.APIKey,
+		DataDir:  cfg.Workspace.DataDir(),
+		Tools:    mcpToolRunner{o.mcpServer},
+		Logger:   logger,
 	}
 	eng, err := engine.New(engineCfg)
 	if err != nil {
@@ -232,7 +351,7 @@ func New(cfg *config.Config, providerStore *admin.ProviderStore) (*Orchestrator,
 	// Load and set system prompt
 	systemPrompt, err := o.contextLoader.Load()
 	if err != nil {
-		log.Printf("Warning: failed to load context: %v", err)
+		o.log.Warn("failed to load context: %v", err)
 	}
 	if systemPrompt != "" {
 		eng.SetSystemPrompt(systemPrompt)
@@ -243,10 +362,10 @@ func New(cfg *config.Config, providerStore *admin.ProviderStore) (*Orchestrator,
 	// Initialize model preference store and apply saved preference
 	o.modelStore = admin.NewModelPreferenceStore(cfg.Workspace.DataDir())
 	if pref, err := o.modelStore.Get(); err != nil {
-		log.Printf("Warning: failed to load model preference: %v", err)
+		o.log.Warn("failed to load model preference: %v", err)
 	} else if pref != nil {
 		eng.SetDefaultModel(pref.Provider, pref.Model)
-		log.Printf("Restored default model: %s/%s", pref.Provider, pref.Model)
+		o.log.Info("Restored default model: %s/%s", pref.Provider, pref.Model)
 	}
 
 	// Start MCP HTTP server immediately so it's ready before OpenCode connects.
@@ -255,6 +374,8 @@ func New(cfg *config.Config, providerStore *admin.ProviderStore) (*Orchestrator,
 		return nil, fmt.Errorf("failed to start MCP HTTP server: %w", err)
 	}
 
+	o.registerHealthChecks()
+
 	return o, nil
 }
 
@@ -267,6 +388,7 @@ func (o *Orchestrator) StartProvider(name string) error {
 	}
 	o.providerStatus[name] = admin.ProviderStatusInfo{State: "starting"}
 	o.providerMu.Unlock()
+	o.registerProviderCheck(name)
 
 	cfg, err := o.providerStore.Get(name)
 	if err != nil {
@@ -280,8 +402,18 @@ func (o *Orchestrator) StartProvider(name string) error {
 		return err
 	}
 
-	provider.SetMessageHandler(o.handleChatMessage)
+	if sp, ok := provider.(chat.StreamingProvider); ok {
+		sp.SetStreamingMessageHandler(o.handleChatMessageStream)
+	} else {
+		provider.SetMessageHandler(o.handleChatMessage)
+	}
 	provider.SetCommandHandler(o.handleChatCommand)
+	if ap, ok := provider.(chat.ApprovalPrompter); ok {
+		ap.SetApprovalHandler(o.handleApprovalAnswer)
+	}
+	if pp, ok := provider.(chat.PairingProvider); ok {
+		pp.SetPairingHandler(o.handlePairingRequest)
+	}
 
 	if err := provider.Start(); err != nil {
 		o.setProviderError(name, err.Error())
@@ -293,7 +425,7 @@ func (o *Orchestrator) StartProvider(name string) error {
 	o.providerStatus[name] = admin.ProviderStatusInfo{State: "connected"}
 	o.providerMu.Unlock()
 
-	log.Printf("Chat provider started: %s", name)
+	o.log.Info("Chat provider started: %s", name)
 	return nil
 }
 
@@ -318,7 +450,7 @@ func (o *Orchestrator) StopProvider(name string) error {
 	}
 	o.providerMu.Unlock()
 
-	log.Printf("Chat provider stopped: %s", name)
+	o.log.Info("Chat provider stopped: %s", name)
 	return err
 }
 
@@ -331,7 +463,7 @@ func (o *Orchestrator) RestartProvider(name string) error {
 
 	if isRunning {
 		if err := o.StopProvider(name); err != nil {
-			log.Printf("Warning: error stopping %s during restart: %v", name, err)
+			o.log.Warn("error stopping %s during restart: %v", name, err)
 		}
 	}
 
@@ -374,6 +506,26 @@ func (o *Orchestrator) GetActiveProviderNames() []string {
 	return names
 }
 
+// SendFileViaProvider uploads a file through a specific provider (implements mcp.ChatProviderLookup).
+func (o *Orchestrator) SendFileViaProvider(provider, target, path, caption string) error {
+	o.providerMu.RLock()
+	p, ok := o.providers[provider]
+	o.providerMu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("provider %s is not running", provider)
+	}
+	fs, ok := p.(chat.FileSender)
+	if !ok {
+		return fmt.Errorf("provider %s cannot send files", provider)
+	}
+	if err := fs.SendFile(target, path, caption); err != nil {
+		return err
+	}
+	o.health.RecordProviderMessage(provider, true)
+	return nil
+}
+
 // SendViaProvider sends a message through a specific provider (implements mcp.ChatProviderLookup).
 func (o *Orchestrator) SendViaProvider(provider, target, content string) error {
 	o.providerMu.RLock()
@@ -383,7 +535,35 @@ func (o *Orchestrator) SendViaProvider(provider, target, content string) error {
 	if !ok {
 		return fmt.Errorf("provider %s is not running", provider)
 	}
-	return p.SendMessage(target, content)
+	if err := p.SendMessage(target, content); err != nil {
+		return err
+	}
+	o.health.RecordProviderMessage(provider, true)
+	return nil
+}
+
+// SendResponseViaProvider delivers a full response through a provider's
+// reply path (implements scheduler.ChatAPI). Providers that cannot render
+// rich responses are sent the text as a plain message.
+func (o *Orchestrator) SendResponseViaProvider(provider, target string, resp *chat.ChatResponse) error {
+	o.providerMu.RLock()
+	p, ok := o.providers[provider]
+	o.providerMu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("provider %s is not running", provider)
+	}
+	var err error
+	if rs, ok := p.(chat.ResponseSender); ok {
+		err = rs.SendResponse(target, resp)
+	} else {
+		err = p.SendMessage(target, resp.Text)
+	}
+	if err != nil {
+		return err
+	}
+	o.health.RecordProviderMessage(provider, true)
+	return nil
 }
 
 func (o *Orchestrator) setProviderError(name, errMsg string) {
@@ -403,6 +583,7 @@ func (o *Orchestrator) createProvider(name string, cfg admin.ProviderConfig) (ch
 			Token:        token,
 			AllowedUsers: cfg.AllowedUsers,
 			AllowedChans: cfg.AllowedChans,
+			Logger:       logging.Standard(),
 		})
 	case "telegram":
 		token := o.providerStore.ResolveToken("telegram", "token")
@@ -412,6 +593,7 @@ func (o *Orchestrator) createProvider(name string, cfg admin.ProviderConfig) (ch
 		return telegram.New(telegram.Config{
 			Token:        token,
 			AllowedUsers: cfg.AllowedUsers,
+			Logger:       logging.Standard(),
 		})
 	case "slack":
 		botToken := o.providerStore.ResolveToken("slack", "bot_token")
@@ -424,6 +606,7 @@ func (o *Orchestrator) createProvider(name string, cfg admin.ProviderConfig) (ch
 			AppToken:     appToken,
 			AllowedUsers: cfg.AllowedUsers,
 			AllowedChans: cfg.AllowedChans,
+			Logger:       logging.Standard(),
 		})
 	default:
 		return nil, fmt.Errorf("unknown provider: %s", name)
@@ -442,13 +625,15 @@ func (o *Orchestrator) Start(ctx context.Context) error {
 	ctx, o.cancel = context.WithCancel(ctx)
 	o.mu.Unlock()
 
-	log.Println("OpenPact orchestrator starting...")
+	o.log.Info("OpenPact orchestrator starting...")
+
+	o.startHealthServer()
 
 	// Start engine (launches opencode serve)
 	if err := o.engine.Start(ctx); err != nil {
 		return fmt.Errorf("failed to start engine: %w", err)
 	}
-	log.Printf("Engine started: %s", o.cfg.Engine.Type)
+	o.log.Info("Engine started: %s", o.cfg.Engine.Type)
 
 	// Load persisted sessions and modes from disk
 	o.loadChannelSessions()
@@ -457,16 +642,22 @@ func (o *Orchestrator) Start(ctx context.Context) error {
 	// Wire scheduler APIs now that engine is ready
 	o.scheduler.SetEngineAPI(o)
 	o.scheduler.SetChatAPI(o)
+	o.scheduler.SetSessionAPI(o)
 
 	// Start scheduler
 	if err := o.scheduler.Start(ctx); err != nil {
-		log.Printf("Warning: failed to start scheduler: %v", err)
+		o.log.Warn("failed to start scheduler: %v", err)
 	}
 
 	// Start enabled providers from store (failures are non-fatal)
 	o.startEnabledProviders()
 
-	log.Println("OpenPact orchestrator started successfully")
+	// Take away pairing access once it expires
+	if o.providerStore != nil {
+		go o.sweepPairings(ctx)
+	}
+
+	o.log.Info("OpenPact orchestrator started successfully")
 
 	// Wait for context cancellation
 	<-ctx.Done()
@@ -482,7 +673,7 @@ func (o *Orchestrator) startEnabledProviders() {
 
 	configs, err := o.providerStore.List()
 	if err != nil {
-		log.Printf("Warning: failed to list providers: %v", err)
+		o.log.Warn("failed to list providers: %v", err)
 		return
 	}
 
@@ -495,7 +686,7 @@ func (o *Orchestrator) startEnabledProviders() {
 		}
 
 		if err := o.StartProvider(cfg.Name); err != nil {
-			log.Printf("Warning: failed to start provider %s: %v", cfg.Name, err)
+			o.log.Warn("failed to start provider %s: %v", cfg.Name, err)
 			// Status is already set to error by StartProvider
 		}
 	}
@@ -513,7 +704,7 @@ func (o *Orchestrator) Stop() {
 
 // shutdown cleans up all components
 func (o *Orchestrator) shutdown() error {
-	log.Println("OpenPact orchestrator shutting down...")
+	o.log.Info("OpenPact orchestrator shutting down...")
 
 	var errs []error
 
@@ -551,11 +742,16 @@ func (o *Orchestrator) shutdown() error {
 		o.mcpServer.Stop()
 	}
 
+	// Stop health server
+	if err := o.stopHealthServer(); err != nil {
+		errs = append(errs, fmt.Errorf("health stop: %w", err))
+	}
+
 	o.mu.Lock()
 	o.running = false
 	o.mu.Unlock()
 
-	log.Println("OpenPact orchestrator stopped")
+	o.log.Info("OpenPact orchestrator stopped")
 
 	if len(errs) > 0 {
 		return fmt.Errorf("shutdown errors: %v", errs)
@@ -563,35 +759,97 @@ func (o *Orchestrator) shutdown() error {
 	return nil
 }
 
-// handleChatMessage processes incoming chat messages from any provider.
-func (o *Orchestrator) handleChatMessage(provider, channelID, userID, content string) (*chat.ChatResponse, error) {
-	log.Printf("[%s] Message from %s in %s: %s", provider, userID, channelID, content)
+// handleChatMessage processes incoming chat messages from providers that
+// cannot edit messages, returning only the final reply.
+func (o *Orchestrator) handleChatMessage(provider, channelID, userID, content string, attachments []chat.Attachment) (*chat.ChatResponse, error) {
+	return o.handleChatMessageStream(provider, channelID, userID, content, attachments, nil)
+}
+
+// handleChatMessageStream processes incoming chat messages from any provider.
+// If onUpdate is non-nil it is called with the reply so far as text parts
+// arrive and tools run, so the provider can edit a placeholder in place.
+// Each message gets a correlation ID that is carried through the engine call
+// and returned to the provider on the response.
+func (o *Orchestrator) handleChatMessageStream(provider, channelID, userID, content string, attachments []chat.Attachment, onUpdate chat.StreamFunc) (result *chat.ChatResponse, err error) {
+	ctx, cid := logging.EnsureCorrelationID(context.Background())
+	logger := o.log.WithContext(ctx).WithFields(map[string]any{
+		"provider": provider,
+		"channel":  channelID,
+		"user":     userID,
+	})
+
+	// Message bodies are only logged in full at debug level
+	logger.Info("Message received: %s", logger.Redact(content))
+
+	o.health.RecordProviderMessage(provider, false)
 
-	// Get or create per-channel session
-	sessionID := o.GetChannelSession(provider, channelID)
+	if reply := o.slowDownReply(provider, channelID, userID); reply != "" {
+		o.health.RecordProviderMessage(provider, true)
+		return &chat.ChatResponse{Text: reply, CorrelationID: cid}, nil
+	}
+
+	start := time.Now()
+	defer func() {
+		o.health.ObserveChatTurn(provider, err == nil, time.Since(start))
+		if err != nil {
+			logger.Error("Turn failed after %s: %v", time.Since(start).Round(time.Millisecond), err)
+			return
+		}
+		if result != nil {
+			result.CorrelationID = cid
+			logger.Info("Turn completed in %s (%d chars)", time.Since(start).Round(time.Millisecond), len(result.Text))
+			// The provider delivers the reply after we return
+			o.health.RecordProviderMessage(provider, true)
+		}
+	}()
+
+	// Budgets are checked before the session is touched
+	person := o.personOf(provider, userID)
+	turn := usageTurn{source: admin.UsageChat, provider: provider, channelID: channelID, userID: userID, person: person}
+	ctx, blocked := o.checkBudgets(ctx, turn)
+	if blocked != nil {
+		logger.Warn("Turn blocked: %v", blocked)
+		return &chat.ChatResponse{Text: blocked.reply()}, nil
+	}
+
+	// Get or create the session, per channel or per person
+	scope, scopeID := sessionScope(provider, channelID, person)
+	sessionID := o.GetChannelSession(scope, scopeID)
 	if sessionID == "" {
 		session, err := o.engine.CreateSession()
 		if err != nil {
 			return nil, fmt.Errorf("failed to create session: %w", err)
 		}
 		sessionID = session.ID
-		o.SetChannelSession(provider, channelID, sessionID)
-		log.Printf("Created new session %s for %s:%s", sessionID, provider, channelID)
+		o.SetChannelSession(scope, scopeID, sessionID)
+		logger.Info("Created new session %s", sessionID)
 	}
+	logger = logger.WithField("session", sessionID)
 
 	// Look up the channel's detail mode
 	mode := o.GetChannelMode(provider, channelID)
 	wantTools := mode == chat.ModeTools || mode == chat.ModeFull
 	wantThinking := mode == chat.ModeThinking || mode == chat.ModeFull
 
-	// Prepend source context so the AI knows the origin
-	contextPrefix := fmt.Sprintf("[via %s, channel:%s, user:%s]\n", provider, channelID, userID)
+	// Prepend source context so the AI knows the origin and the sender
+	role := o.roleOf(provider, userID, person)
+	contextPrefix := sourceContext(provider, channelID, userID, person, role)
+
+	// Attachments are downloaded before the turn starts; the AI is told
+	// where they were saved and which were refused.
+	files, note := o.receiveAttachments(ctx, logger, provider, attachments)
 
 	messages := []engine.Message{
-		{Role: "user", Content: contextPrefix + content},
+		{Role: "user", Content: contextPrefix + content + note, Files: files},
 	}
 
-	ctx := context.Background()
+	o.beginTurn(cid, sessionID)
+	o.setTurnOrigin(cid, turnOrigin{provider: provider, channelID: channelID, userID: userID, role: role})
+	defer o.endTurn(cid)
+
+	unlock := o.lockSession(sessionID)
+	defer unlock()
+	before := o.sessionUsage(ctx, sessionID)
 	responses, err := o.engine.Send(ctx, sessionID, messages)
 	if err != nil {
 		return nil, fmt.Errorf("engine error: %w", err)
@@ -603,48 +861,73 @@ func (o *Orchestrator) handleChatMessage(provider, channelID, userID, content st
 	// replace rather than duplicate content.
 	textParts := make(map[string]string)     // partID → full text
 	thinkingParts := make(map[string]string) // partID → thinking text
+	var textOrder, thinkingOrder []string    // part IDs in arrival order
 	var untaggedText string                  // fallback for responses without part IDs
+	var runningTool string                   // tool currently running, for stream updates
 	firstContent := true
 
 	for resp := range responses {
 		if resp.Content != "" && firstContent {
-			log.Printf("[%s] AI response started for session %s", provider, sessionID)
+			logger.Debug("AI response started")
 			firstContent = false
 		}
 
+		changed := false
+
 		// Accumulate text content
 		if resp.Content != "" {
 			if resp.PartID != "" {
+				if _, seen := textParts[resp.PartID]; !seen {
+					textOrder = append(textOrder, resp.PartID)
+				}
 				textParts[resp.PartID] = resp.Content
 			} else {
 				untaggedText += resp.Content
 			}
+			changed = true
 		}
 
 		// Accumulate thinking content
 		if wantThinking && resp.Thinking != "" {
 			if resp.PartID != "" {
+				if _, seen := thinkingParts[resp.PartID]; !seen {
+					thinkingOrder = append(thinkingOrder, resp.PartID)
+				}
 				thinkingParts[resp.PartID] = resp.Thinking
 			}
 		}
+
+		// Track running tools so the live message can show progress
+		if resp.PartType == "tool" {
+			for _, raw := range resp.Parts {
+				if name, running := toolPartStatus(raw); running {
+					runningTool = name
+				} else if name == runningTool {
+					runningTool = ""
+				}
+				changed = true
+			}
+		}
+
+		if onUpdate != nil && changed {
+			onUpdate(chat.StreamUpdate{
+				Text: joinParts(textOrder, textParts) + untaggedText,
+				Tool: runningTool,
+			})
+		}
 	}
 
+	o.recordUsage(ctx, turn, sessionID, before)
+
 	// Build final text from deduplicated parts + any untagged content
-	var responseText string
-	for _, text := range textParts {
-		responseText += text
-	}
-	responseText += untaggedText
+	responseText := joinParts(textOrder, textParts) + untaggedText
 
 	// Construct ChatResponse
-	result := &chat.ChatResponse{Text: responseText}
+	result = &chat.ChatResponse{Text: responseText}
 
 	// Build thinking from deduplicated parts
 	if wantThinking {
-		var thinkingText string
-		for _, text := range thinkingParts {
-			thinkingText += text
-		}
+		thinkingText := joinParts(thinkingOrder, thinkingParts)
 		// Some models (e.g. Gemini) emit literal \n escape sequences in reasoning text — strip them.
 		result.Thinking = strings.ReplaceAll(thinkingText, `\n`, "")
 	}
@@ -653,7 +936,7 @@ func (o *Orchestrator) handleChatMessage(provider, channelID, userID, content st
 	// SSE streaming only provides partial tool info (no input/output),
 	// so we fetch the complete resolved message parts after the stream ends.
 	if wantTools {
-		toolCalls := o.fetchResolvedToolCalls(sessionID)
+		toolCalls := o.fetchResolvedToolCalls(logger, sessionID)
 		if len(toolCalls) > 0 {
 			result.ToolCalls = toolCalls
 		}
@@ -662,17 +945,39 @@ func (o *Orchestrator) handleChatMessage(provider, channelID, userID, content st
 	return result, nil
 }
 
+// slowDownReply checks the per-user and per-channel rate limits. It returns a
+// friendly reply if either is exhausted, or "" if the message may proceed.
+// A token is taken from both limits only when both allow the message.
+func (o *Orchestrator) slowDownReply(provider, channelID, userID string) string {
+	ok, scope, wait := o.limits.CheckAll(
+		ratelimit.Key{Scope: ratelimit.ScopeUser, Key: sessionKey(provider, userID)},
+		ratelimit.Key{Scope: ratelimit.ScopeChannel, Key: sessionKey(provider, channelID)},
+	)
+	if ok {
+		return ""
+	}
+
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	if scope == ratelimit.ScopeChannel {
+		return fmt.Sprintf("This channel is sending messages faster than I can keep up. Please slow down and try again in %ds.", seconds)
+	}
+	return fmt.Sprintf("You're sending messages faster than I can keep up. Please slow down and try again in %ds.", seconds)
+}
+
 // fetchResolvedToolCalls fetches the most recent assistant message from the
 // engine and extracts tool call details from its resolved parts. This gives us
 // the full tool data (name, input, output) that SSE streaming doesn't include.
-func (o *Orchestrator) fetchResolvedToolCalls(sessionID string) []chat.ToolCallInfo {
+func (o *Orchestrator) fetchResolvedToolCalls(logger *logging.Logger, sessionID string) []chat.ToolCallInfo {
 	messages, err := o.engine.GetMessages(sessionID, 10)
 	if err != nil {
-		log.Printf("[tools] Failed to fetch messages for session %s: %v", sessionID, err)
+		logger.Warn("Failed to fetch messages for tool calls: %v", err)
 		return nil
 	}
 
-	log.Printf("[tools] Session %s: found %d messages", sessionID, len(messages))
+	logger.Debug("Tool call lookup found %d messages", len(messages))
 
 	// Find the last user message, then scan all assistant messages after it.
 	// OpenCode splits tool-calling turns into separate assistant messages:
@@ -702,10 +1007,46 @@ func (o *Orchestrator) fetchResolvedToolCalls(sessionID string) []chat.ToolCallI
 		}
 	}
 
-	log.Printf("[tools] Session %s: extracted %d tool calls from %d assistant messages", sessionID, len(toolCalls), len(messages)-start)
+	logger.Debug("Extracted %d tool calls from %d assistant messages", len(toolCalls), len(messages)-start)
 	return toolCalls
 }
 
+// joinParts concatenates part texts in arrival order.
+func joinParts(order []string, parts map[string]string) string {
+	var b strings.Builder
+	for _, id := range order {
+		b.WriteString(parts[id])
+	}
+	return b.String()
+}
+
+// toolPartStatus returns the tool name of a streamed tool part and whether the
+// call is still in progress. OpenCode reports "pending" then "running"; the
+// OpenAI engine reports "running".
+func toolPartStatus(raw json.RawMessage) (name string, running bool) {
+	var part struct {
+		Tool  json.RawMessage `json:"tool"`
+		State struct {
+			Status string `json:"status"`
+		} `json:"state"`
+	}
+	if err := json.Unmarshal(raw, &part); err != nil {
+		return "", false
+	}
+	if json.Unmarshal(part.Tool, &name) != nil {
+		var toolObj struct {
+			Name string `json:"name"`
+		}
+		json.Unmarshal(part.Tool, &toolObj)
+		name = toolObj.Name
+	}
+	switch part.State.Status {
+	case "pending", "running":
+		return name, name != ""
+	}
+	return name, false
+}
+
 // extractToolCall parses a raw resolved message part JSON to extract tool call info.
 // OpenCode's resolved parts use this structure:
 //
@@ -754,7 +1095,14 @@ func extractToolCall(raw json.RawMessage) (chat.ToolCallInfo, bool) {
 
 // handleChatCommand processes slash/bot commands from any provider.
 func (o *Orchestrator) handleChatCommand(provider, channelID, userID, command, args string) (string, error) {
-	log.Printf("[%s] Command from %s in %s: /%s %s", provider, userID, channelID, command, args)
+	o.log.WithFields(map[string]any{
+		"provider": provider,
+		"channel":  channelID,
+		"user":     userID,
+	}).Info("Command: /%s %s", command, args)
+
+	// Session commands act on the sender's own session if it follows them
+	scope, scopeID := sessionScope(provider, channelID, o.personOf(provider, userID))
 
 	switch command {
 	case "new":
@@ -762,7 +1110,7 @@ func (o *Orchestrator) handleChatCommand(provider, channelID, userID, command, a
 		if err != nil {
 			return "", fmt.Errorf("failed to create session: %w", err)
 		}
-		o.SetChannelSession(provider, channelID, session.ID)
+		o.SetChannelSession(scope, scopeID, session.ID)
 		title := session.Title
 		if title == "" {
 			title = "New session"
@@ -777,7 +1125,7 @@ func (o *Orchestrator) handleChatCommand(provider, channelID, userID, command, a
 		if len(sessions) == 0 {
 			return "No sessions found.", nil
 		}
-		activeID := o.GetChannelSession(provider, channelID)
+		activeID := o.GetChannelSession(scope, scopeID)
 		result := "**Sessions:**\n"
 		for _, s := range sessions {
 			marker := ""
@@ -800,7 +1148,7 @@ func (o *Orchestrator) handleChatCommand(provider, channelID, userID, command, a
 		if err != nil {
 			return fmt.Sprintf("Session not found: %s", args), nil
 		}
-		o.SetChannelSession(provider, channelID, session.ID)
+		o.SetChannelSession(scope, scopeID, session.ID)
 		title := session.Title
 		if title == "" {
 			title = "(untitled)"
@@ -808,7 +1156,7 @@ func (o *Orchestrator) handleChatCommand(provider, channelID, userID, command, a
 		return fmt.Sprintf("Switched to session: `%s` - %s", session.ID, title), nil
 
 	case "context":
-		sessionID := o.GetChannelSession(provider, channelID)
+		sessionID := o.GetChannelSession(scope, scopeID)
 		if sessionID == "" {
 			return "No active session in this channel. Send a message or use /new first.", nil
 		}
@@ -834,6 +1182,15 @@ func (o *Orchestrator) handleChatCommand(provider, channelID, userID, command, a
 		o.SetChannelMode(provider, channelID, chat.ModeFull)
 		return "Detail mode set to **full** — responses will include thinking blocks and tool call details.", nil
 
+	case "link":
+		return o.linkAccount(provider, channelID, userID, strings.TrimSpace(args))
+
+	case "pair":
+		return o.pairCommand(provider, userID, args)
+
+	case "usage":
+		return o.usageCommand(provider, channelID, userID)
+
 	default:
 		return fmt.Sprintf("Unknown command: %s", command), nil
 	}
@@ -854,6 +1211,12 @@ func (o *Orchestrator) SetChannelSession(provider, channelID, sessionID string)
 	o.saveChannelSessions()
 }
 
+// ResolvedToolCalls returns the tool calls of a session's latest turn
+// (implements scheduler.SessionAPI).
+func (o *Orchestrator) ResolvedToolCalls(sessionID string) []chat.ToolCallInfo {
+	return o.fetchResolvedToolCalls(o.log.WithField("session", sessionID), sessionID)
+}
+
 // Engine returns the engine instance (for admin API wiring).
 func (o *Orchestrator) Engine() engine.Engine {
 	return o.engine
@@ -884,9 +1247,43 @@ func (o *Orchestrator) GetMessages(sessionID string, limit int) ([]engine.Messag
 	return o.engine.GetMessages(sessionID, limit)
 }
 
-// Send delegates to the engine.
+// Send delegates to the engine, attaching a correlation ID to ctx if the
+// caller did not and tracking the turn until its response channel closes.
+// Turns of agent schedules (see scheduler.WithSchedule) and of the admin UI
+// are checked against the usage budgets and recorded in the usage ledger.
 func (o *Orchestrator) Send(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
-	return o.engine.Send(ctx, sessionID, messages)
+	ctx, cid := logging.EnsureCorrelationID(ctx)
+
+	turn := usageTurn{source: admin.UsageAdmin}
+	if sched := scheduler.ScheduleFromContext(ctx); sched != nil {
+		turn = usageTurn{source: admin.UsageSchedule, schedule: sched}
+	}
+	ctx, blocked := o.checkBudgets(ctx, turn)
+	if blocked != nil {
+		return nil, blocked
+	}
+
+	o.beginTurn(cid, sessionID)
+	unlock := o.lockSession(sessionID)
+	before := o.sessionUsage(ctx, sessionID)
+	responses, err := o.engine.Send(ctx, sessionID, messages)
+	if err != nil {
+		unlock()
+		o.endTurn(cid)
+		return nil, err
+	}
+
+	out := make(chan engine.Response)
+	go func() {
+		defer close(out)
+		defer o.endTurn(cid)
+		for resp := range responses {
+			out <- resp
+		}
+		o.recordUsage(ctx, turn, sessionID, before)
+		unlock()
+	}()
+	return out, nil
 }
 
 // loadChannelSessions reads per-channel session mappings from disk.
@@ -907,7 +1304,7 @@ func (o *Orchestrator) loadChannelSessions() {
 		o.channelSessions[k] = v
 	}
 	o.sessionMu.Unlock()
-	log.Printf("Restored %d channel sessions", len(f.Sessions))
+	o.log.Info("Restored %d channel sessions", len(f.Sessions))
 }
 
 // saveChannelSessions persists per-channel session mappings to disk.
@@ -916,7 +1313,7 @@ func (o *Orchestrator) saveChannelSessions() {
 
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		log.Printf("Warning: failed to create data dir for channel sessions: %v", err)
+		o.log.Warn("failed to create data dir for channel sessions: %v", err)
 		return
 	}
 
@@ -929,12 +1326,12 @@ func (o *Orchestrator) saveChannelSessions() {
 
 	data, err := json.Marshal(channelSessionsFile{Sessions: sessions})
 	if err != nil {
-		log.Printf("Warning: failed to marshal channel sessions: %v", err)
+		o.log.Warn("failed to marshal channel sessions: %v", err)
 		return
 	}
 
 	if err := os.WriteFile(path, data, 0644); err != nil {
-		log.Printf("Warning: failed to save channel sessions: %v", err)
+		o.log.Warn("failed to save channel sessions: %v", err)
 	}
 }
 
@@ -992,7 +1389,7 @@ func (o *Orchestrator) loadChannelModes() {
 		o.channelModes[k] = v
 	}
 	o.modeMu.Unlock()
-	log.Printf("Restored %d channel modes", len(f.Modes))
+	o.log.Info("Restored %d channel modes", len(f.Modes))
 }
 
 // saveChannelModes persists per-channel mode settings to disk.
@@ -1001,7 +1398,7 @@ func (o *Orchestrator) saveChannelModes() {
 
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		log.Printf("Warning: failed to create data dir for channel modes: %v", err)
+		o.log.Warn("failed to create data dir for channel modes: %v", err)
 		return
 	}
 
@@ -1014,12 +1411,12 @@ func (o *Orchestrator) saveChannelModes() {
 
 	data, err := json.Marshal(channelModesFile{Modes: modes})
 	if err != nil {
-		log.Printf("Warning: failed to marshal channel modes: %v", err)
+		o.log.Warn("failed to marshal channel modes: %v", err)
 		return
 	}
 
 	if err := os.WriteFile(path, data, 0644); err != nil {
-		log.Printf("Warning: failed to save channel modes: %v", err)
+		o.log.Warn("failed to save channel modes: %v", err)
 	}
 }
 
@@ -1118,14 +1515,15 @@ func (o *Orchestrator) startMCPHTTPServer() error {
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s: %w", addr, err)
 	}
+	o.mcpListener = ln
 
 	go func() {
 		if err := o.mcpHTTPServer.Serve(ln); err != nil && err != http.ErrServerClosed {
-			log.Printf("MCP HTTP server error: %v", err)
+			o.log.Error("MCP HTTP server error: %v", err)
 		}
 	}()
 
-	log.Printf("MCP HTTP server listening on %s", ln.Addr().String())
+	o.log.Info("MCP HTTP server listening on %s", ln.Addr().String())
 	return nil
 }
 
@@ -1134,6 +1532,11 @@ func (o *Orchestrator) closeMCPHTTPServer() {
 	if o.mcpHTTPServer != nil {
 		o.mcpHTTPServer.Close()
 	}
+	// Serve may not have taken ownership of the listener yet; close it
+	// directly so the port is released before this returns.
+	if o.mcpListener != nil {
+		o.mcpListener.Close()
+	}
 }
 
 // loadOrGenerateMCPToken reads the MCP token from secure/data/mcp_token.
@@ -1143,7 +1546,7 @@ func (o *Orchestrator) loadOrGenerateMCPToken() (string, error) {
 	data, err := os.ReadFile(tokenPath)
 	if err == nil && len(data) > 0 {
 		token := strings.TrimSpace(string(data))
-		log.Printf("Loaded MCP token from %s", tokenPath)
+		o.log.Info("Loaded MCP token from %s", tokenPath)
 		return token, nil
 	}
 
@@ -1152,7 +1555,7 @@ func (o *Orchestrator) loadOrGenerateMCPToken() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	log.Printf("Generated ephemeral MCP token (no token file at %s)", tokenPath)
+	o.log.Info("Generated ephemeral MCP token (no token file at %s)", tokenPath)
 	return token, nil
 }
 
@@ -1172,7 +1575,7 @@ func (o *Orchestrator) SetDefaultModel(provider, model string) error {
 	if err := o.modelStore.Set(provider, model); err != nil {
 		return fmt.Errorf("failed to persist model preference: %w", err)
 	}
-	log.Printf("Default model set to %s/%s", provider, model)
+	o.log.Info("Default model set to %s/%s", provider, model)
 	return nil
 }
 
@@ -1226,11 +1629,56 @@ func (o *Orchestrator) SetEnabled(id string, enabled bool) error {
 	return nil
 }
 
+// ListRuns returns a schedule's run history, newest first.
+func (o *Orchestrator) ListRuns(id string, limit int) ([]*admin.ScheduleRun, error) {
+	return o.scheduler.Store().ListRuns(id, limit)
+}
+
 // RunNow triggers immediate execution of a schedule.
 func (o *Orchestrator) RunNow(id string) error {
 	return o.scheduler.RunNow(id)
 }
 
+// RunScript runs an approved script for the admin test endpoint.
+func (o *Orchestrator) RunScript(ctx context.Context, name string, args map[string]any) (starlark.Result, error) {
+	return o.scheduler.RunScript(ctx, name, args)
+}
+
+// Fire starts an event-triggered run of a schedule.
+func (o *Orchestrator) Fire(id, trigger string, payload map[string]any) error {
+	return o.scheduler.Fire(id, trigger, payload)
+}
+
+// CalendarEvents reads the configured calendar feeds for calendar-triggered
+// schedules. A feed that fails is skipped unless every feed fails.
+func (o *Orchestrator) CalendarEvents(ctx context.Context, from, to time.Time) ([]scheduler.CalendarEvent, error) {
+	var events []scheduler.CalendarEvent
+	var lastErr error
+	for _, cal := range o.cfg.Calendars {
+		calEvents, err := mcp.FetchCalendarEvents(ctx, cal.URL, from, to)
+		if err != nil {
+			o.log.Warn("Failed to read calendar %q: %v", cal.Name, err)
+			lastErr = err
+			continue
+		}
+		for _, e := range calEvents {
+			events = append(events, scheduler.CalendarEvent{
+				Calendar:    cal.Name,
+				Summary:     e.Summary,
+				Description: e.Description,
+				Location:    e.Location,
+				Start:       e.Start,
+				End:         e.End,
+				AllDay:      e.AllDay,
+			})
+		}
+	}
+	if events == nil && lastErr != nil {
+		return nil, lastErr
+	}
+	return events, nil
+}
+
 // Reload reloads the scheduler's cron entries from the store.
 func (o *Orchestrator) Reload() error {
 	return o.scheduler.Reload()
@@ -1249,6 +1697,6 @@ func (o *Orchestrator) ReloadContext() error {
 	}
 
 	o.engine.SetSystemPrompt(systemPrompt)
-	log.Println("Context reloaded successfully")
+	o.log.Info("Context reloaded successfully")
 	return nil
 }
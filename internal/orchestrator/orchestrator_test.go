@@ -1,6 +1,7 @@
 package orchestrator
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -511,3 +512,107 @@ func TestExtractToolCallRunningState(t *testing.T) {
 		t.Errorf("output = %q, want empty for running tool", tc.Output)
 	}
 }
+
+func TestChatRateLimiting(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Engine: config.EngineConfig{
+			Type: "opencode",
+		},
+		Workspace: config.WorkspaceConfig{
+			Path: tmpDir,
+		},
+		Server: config.ServerConfig{
+			RateLimit: config.RateLimitConfig{
+				User:    config.RateLimitRule{Rate: 0.001, Burst: 1},
+				Channel: config.RateLimitRule{Rate: 0.001, Burst: 2},
+			},
+		},
+	}
+	cfg.Workspace.EnsureDirs()
+
+	o, err := New(cfg, nil)
+	if err != nil {
+		t.Fatalf("failed to create orchestrator: %v", err)
+	}
+	defer o.closeMCPHTTPServer()
+
+	if reply := o.slowDownReply("discord", "chan1", "user1"); reply != "" {
+		t.Fatalf("first message should pass, got %q", reply)
+	}
+	if reply := o.slowDownReply("discord", "chan1", "user1"); !strings.Contains(reply, "slow down") {
+		t.Errorf("second message from same user should be limited, got %q", reply)
+	}
+
+	// A different user in the same channel exhausts the channel bucket; the
+	// rejection is answered without touching the engine.
+	if reply := o.slowDownReply("discord", "chan1", "user2"); reply != "" {
+		t.Fatalf("first message from user2 should pass, got %q", reply)
+	}
+	resp, err := o.handleChatMessage("discord", "chan1", "user3", "hello", nil)
+	if err != nil {
+		t.Fatalf("rate-limited message should not error: %v", err)
+	}
+	if !strings.Contains(resp.Text, "This channel") {
+		t.Errorf("expected channel slow-down reply, got %q", resp.Text)
+	}
+
+	// The busy channel's rejection did not use up user3's own limit
+	if reply := o.slowDownReply("discord", "chan2", "user3"); reply != "" {
+		t.Errorf("user3 should still be able to send elsewhere, got %q", reply)
+	}
+
+	if got := o.health.GetMetrics().MessagesReceived; got != 1 {
+		t.Errorf("MessagesReceived = %d, want 1", got)
+	}
+}
+
+func TestChatMessageStreamUpdates(t *testing.T) {
+	o := newTestOrchestrator(t)
+	o.engine = &stubEngine{
+		send: func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+			ch := make(chan engine.Response, 8)
+			ch <- engine.Response{Content: "Let me check.", PartID: "p1", PartType: "text"}
+			ch <- engine.Response{PartID: "t1", PartType: "tool", Parts: []json.RawMessage{
+				json.RawMessage(`{"type":"tool","tool":"workspace_read","state":{"status":"running"}}`),
+			}}
+			ch <- engine.Response{PartID: "t1", PartType: "tool", IsUpdate: true, Parts: []json.RawMessage{
+				json.RawMessage(`{"type":"tool","tool":"workspace_read","state":{"status":"completed","output":"x"}}`),
+			}}
+			ch <- engine.Response{Content: " The file", PartID: "p2", PartType: "text"}
+			ch <- engine.Response{Content: " The file is empty.", PartID: "p2", PartType: "text", IsUpdate: true}
+			ch <- engine.Response{Done: true}
+			close(ch)
+			return ch, nil
+		},
+	}
+
+	var updates []chat.StreamUpdate
+	resp, err := o.handleChatMessageStream("discord", "chan1", "user1", "read it", nil, func(u chat.StreamUpdate) {
+		updates = append(updates, u)
+	})
+	if err != nil {
+		t.Fatalf("handleChatMessageStream: %v", err)
+	}
+
+	want := []chat.StreamUpdate{
+		{Text: "Let me check."},
+		{Text: "Let me check.", Tool: "workspace_read"},
+		{Text: "Let me check."},
+		{Text: "Let me check. The file"},
+		{Text: "Let me check. The file is empty."},
+	}
+	if len(updates) != len(want) {
+		t.Fatalf("updates = %+v, want %+v", updates, want)
+	}
+	for i := range want {
+		if updates[i] != want[i] {
+			t.Errorf("update %d = %+v, want %+v", i, updates[i], want[i])
+		}
+	}
+
+	// Parts are joined in arrival order, not map order
+	if resp.Text != "Let me check. The file is empty." {
+		t.Errorf("final text = %q", resp.Text)
+	}
+}
@@ -0,0 +1,222 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/open-pact/openpact/internal/admin"
+	"github.com/open-pact/openpact/internal/chat"
+)
+
+// pairingSweepInterval is how often users whose access has expired are taken
+// off the allowlists.
+const pairingSweepInterval = time.Minute
+
+// isPairingApprover reports whether a chat user may answer pairing requests.
+func (o *Orchestrator) isPairingApprover(provider, userID string) bool {
+	account := admin.AccountKey(provider, userID)
+	for _, approver := range o.cfg.Pairing.Approvers {
+		if approver == account {
+			return true
+		}
+	}
+	return false
+}
+
+// handlePairingRequest is called when a user who isn't on a provider's
+// allowlist messages the bot directly. It records a pairing request, tells
+// the approvers about new ones, and returns the reply with the user's code.
+func (o *Orchestrator) handlePairingRequest(provider, channelID, userID, userName string) string {
+	if !o.cfg.Pairing.Enabled || o.providerStore == nil {
+		return ""
+	}
+
+	ttl := time.Duration(o.cfg.Pairing.CodeTTL) * time.Minute
+	req, created, err := o.providerStore.RequestPairing(provider, channelID, userID, userName, ttl, o.cfg.Pairing.MaxPending)
+	if errors.Is(err, admin.ErrTooManyPairings) {
+		o.log.Warn("Ignoring pairing request from %s: too many pending", admin.AccountKey(provider, userID))
+		return "This bot isn't taking new access requests right now. Try again later."
+	}
+	if err != nil {
+		o.log.Error("Failed to record pairing request from %s: %v", admin.AccountKey(provider, userID), err)
+		return ""
+	}
+
+	if created {
+		o.log.Info("Pairing request %s from %s", req.Code, admin.AccountKey(provider, userID))
+		o.notifyPairingApprovers(req)
+	}
+	return fmt.Sprintf("You're not on this bot's allowlist yet. Your pairing code is `%s`; the owner has been asked to let you in. The request expires at %s.",
+		req.Code, req.ExpiresAt.Format("2006-01-02 15:04 MST"))
+}
+
+// notifyPairingApprovers sends a new pairing request to each approver by
+// direct message.
+func (o *Orchestrator) notifyPairingApprovers(req admin.PairingRequest) {
+	who := admin.AccountKey(req.Provider, req.UserID)
+	if req.UserName != "" {
+		who = fmt.Sprintf("%s (%s)", req.UserName, who)
+	}
+	text := fmt.Sprintf("%s asks for access with pairing code `%s`.\nReply `/pair approve %s [days]` or `/pair deny %s`, or answer it in the admin UI.",
+		who, req.Code, req.Code, req.Code)
+
+	for _, approver := range o.cfg.Pairing.Approvers {
+		provider, userID, ok := strings.Cut(approver, ":")
+		if !ok {
+			o.log.Warn("Ignoring pairing approver %q: expected provider:userID", approver)
+			continue
+		}
+		if err := o.SendViaProvider(provider, "user:"+userID, text); err != nil {
+			o.log.Warn("Failed to notify pairing approver %s: %v", approver, err)
+		}
+	}
+}
+
+// pairCommand handles the /pair command. Without arguments it lists the
+// pending requests; "approve CODE [days]" and "deny CODE" answer one.
+func (o *Orchestrator) pairCommand(provider, userID, args string) (string, error) {
+	if o.providerStore == nil {
+		return "Pairing is not available.", nil
+	}
+	if !o.isPairingApprover(provider, userID) {
+		return "Only pairing approvers can answer pairing requests.", nil
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		pending, err := o.providerStore.PendingPairings()
+		if err != nil {
+			return "", fmt.Errorf("failed to list pairing requests: %w", err)
+		}
+		if len(pending) == 0 {
+			return "No pairing requests are waiting.", nil
+		}
+		result := "**Pairing requests:**\n"
+		for _, req := range pending {
+			who := admin.AccountKey(req.Provider, req.UserID)
+			if req.UserName != "" {
+				who = req.UserName + " (" + who + ")"
+			}
+			result += fmt.Sprintf("- `%s` — %s, expires %s\n", req.Code, who, req.ExpiresAt.Format("2006-01-02 15:04 MST"))
+		}
+		return result, nil
+	}
+
+	usage := "Usage: /pair [approve <code> [days] | deny <code>]"
+	by := admin.AccountKey(provider, userID)
+	switch {
+	case fields[0] == "approve" && (len(fields) == 2 || len(fields) == 3):
+		var expires time.Time
+		if len(fields) == 3 {
+			days, err := strconv.Atoi(fields[2])
+			if err != nil || days <= 0 {
+				return usage, nil
+			}
+			expires = time.Now().Add(time.Duration(days) * 24 * time.Hour)
+		}
+		req, allowed, err := o.providerStore.ApprovePairing(fields[1], by, expires)
+		if errors.Is(err, admin.ErrPairingNotFound) {
+			return fmt.Sprintf("No pairing request with code %s. It may have expired or been answered.", fields[1]), nil
+		}
+		if errors.Is(err, admin.ErrAllowlistIsEmpty) {
+			return "That provider's allowlist is empty, so everyone is already allowed.", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to approve pairing request: %w", err)
+		}
+		o.PairingAnswered(req, true, allowed)
+		return fmt.Sprintf("Approved %s.", admin.AccountKey(req.Provider, req.UserID)), nil
+
+	case fields[0] == "deny" && len(fields) == 2:
+		req, err := o.providerStore.DenyPairing(fields[1], by)
+		if errors.Is(err, admin.ErrPairingNotFound) {
+			return fmt.Sprintf("No pairing request with code %s. It may have expired or been answered.", fields[1]), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to deny pairing request: %w", err)
+		}
+		o.PairingAnswered(req, false, nil)
+		return fmt.Sprintf("Denied %s.", admin.AccountKey(req.Provider, req.UserID)), nil
+
+	default:
+		return usage, nil
+	}
+}
+
+// PairingAnswered applies the answer to a pairing request: an approved user
+// is let in by the running provider at once, and the user is told the
+// outcome (implements admin.PairingNotifier).
+func (o *Orchestrator) PairingAnswered(req admin.PairingRequest, approved bool, allowedUsers []string) {
+	account := admin.AccountKey(req.Provider, req.UserID)
+	text := "Your request for access was denied."
+	if approved {
+		o.log.Info("Pairing request %s approved; %s allowed", req.Code, account)
+		o.applyAllowlist(req.Provider, allowedUsers)
+		text = "Your request for access was approved. You can talk to me now."
+	} else {
+		o.log.Info("Pairing request %s denied for %s", req.Code, account)
+	}
+
+	if err := o.SendViaProvider(req.Provider, req.ChannelID, text); err != nil {
+		o.log.Warn("Failed to tell %s about their pairing request: %v", account, err)
+	}
+}
+
+// applyAllowlist replaces a running provider's allowlist. An empty allowlist
+// lets everyone in, so a provider whose last allowed user expired is stopped
+// instead.
+func (o *Orchestrator) applyAllowlist(name string, users []string) {
+	o.providerMu.RLock()
+	provider, running := o.providers[name]
+	o.providerMu.RUnlock()
+	if !running {
+		return
+	}
+
+	if len(users) == 0 {
+		o.log.Warn("The %s allowlist is now empty; stopping the provider so it isn't open to everyone", name)
+		if err := o.StopProvider(name); err != nil {
+			o.log.Warn("error stopping %s: %v", name, err)
+		}
+		o.setProviderError(name, "disabled because its allowlist became empty when access expired; add allowed users to enable it again")
+		return
+	}
+
+	if pp, ok := provider.(chat.PairingProvider); ok {
+		pp.SetAllowedUsers(users)
+		return
+	}
+	o.log.Info("The %s allowlist changed; restart the provider to apply it", name)
+}
+
+// sweepPairings takes away access that has expired, until ctx is done.
+func (o *Orchestrator) sweepPairings(ctx context.Context) {
+	ticker := time.NewTicker(pairingSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			o.expireAccess(now)
+		}
+	}
+}
+
+// expireAccess removes users whose access expired before now from the
+// allowlists, and applies the new allowlists to the running providers.
+func (o *Orchestrator) expireAccess(now time.Time) {
+	allowlists, err := o.providerStore.ExpireAccess(now)
+	if err != nil {
+		o.log.Warn("Failed to expire pairing access: %v", err)
+		return
+	}
+	for name, users := range allowlists {
+		o.log.Info("Access expired on %s; %d users still allowed", name, len(users))
+		o.applyAllowlist(name, users)
+	}
+}
@@ -0,0 +1,113 @@
+package orchestrator
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-pact/openpact/internal/admin"
+	"github.com/open-pact/openpact/internal/chat"
+)
+
+// pairingProvider records messages and allowlist changes.
+type pairingProvider struct {
+	chat.Provider
+	sent    map[string]string // target -> last message
+	allowed []string
+}
+
+func (p *pairingProvider) SendMessage(target, content string) error {
+	p.sent[target] = content
+	return nil
+}
+
+func (p *pairingProvider) SetPairingHandler(h chat.PairingHandler) {}
+
+func (p *pairingProvider) SetAllowedUsers(users []string) { p.allowed = users }
+
+func (p *pairingProvider) Stop() error { return nil }
+
+func newPairingOrchestrator(t *testing.T) (*Orchestrator, *pairingProvider) {
+	t.Helper()
+	o := newTestOrchestrator(t)
+	o.providerStore = admin.NewProviderStore(t.TempDir())
+	if err := o.providerStore.Set("telegram", admin.ProviderConfig{Enabled: true, AllowedUsers: []string{"1"}}); err != nil {
+		t.Fatal(err)
+	}
+	o.cfg.Pairing.Enabled = true
+	o.cfg.Pairing.Approvers = []string{"telegram:1"}
+	o.cfg.Pairing.CodeTTL = 60
+	o.cfg.Pairing.MaxPending = 10
+
+	provider := &pairingProvider{sent: make(map[string]string)}
+	o.providers["telegram"] = provider
+	return o, provider
+}
+
+func TestPairingApprovedFromChat(t *testing.T) {
+	o, provider := newPairingOrchestrator(t)
+
+	reply := o.handlePairingRequest("telegram", "42", "42", "alice")
+	code := regexp.MustCompile("`([A-Z0-9]{8})`").FindStringSubmatch(reply)
+	if code == nil {
+		t.Fatalf("expected a pairing code in %q", reply)
+	}
+	if !strings.Contains(provider.sent["user:1"], code[1]) {
+		t.Errorf("expected the approver to be told the code, got %q", provider.sent["user:1"])
+	}
+
+	if reply, _ := o.handleChatCommand("telegram", "42", "42", "pair", "approve "+code[1]); !strings.HasPrefix(reply, "Only pairing approvers") {
+		t.Errorf("expected a non-approver to be refused, got %q", reply)
+	}
+
+	reply, err := o.handleChatCommand("telegram", "1", "1", "pair", "")
+	if err != nil || !strings.Contains(reply, code[1]) {
+		t.Fatalf("/pair = %q, %v; want the pending request listed", reply, err)
+	}
+
+	reply, err = o.handleChatCommand("telegram", "1", "1", "pair", "approve "+strings.ToLower(code[1])+" 7")
+	if err != nil || reply != "Approved telegram:42." {
+		t.Fatalf("/pair approve = %q, %v", reply, err)
+	}
+	if len(provider.allowed) != 2 || provider.allowed[1] != "42" {
+		t.Errorf("expected the running provider to allow 42, got %v", provider.allowed)
+	}
+	if !strings.Contains(provider.sent["42"], "approved") {
+		t.Errorf("expected the user to be told, got %q", provider.sent["42"])
+	}
+	cfg, _ := o.providerStore.Get("telegram")
+	if expires := cfg.UserExpiry["42"]; expires.Before(time.Now().Add(6 * 24 * time.Hour)) {
+		t.Errorf("expected access to expire in 7 days, got %v", expires)
+	}
+}
+
+func TestPairingDisabled(t *testing.T) {
+	o, provider := newPairingOrchestrator(t)
+	o.cfg.Pairing.Enabled = false
+
+	if reply := o.handlePairingRequest("telegram", "42", "42", ""); reply != "" {
+		t.Errorf("expected strangers to be ignored, got %q", reply)
+	}
+	if len(provider.sent) != 0 {
+		t.Errorf("expected no messages, got %v", provider.sent)
+	}
+}
+
+func TestExpireAccessStopsEmptiedProvider(t *testing.T) {
+	o, _ := newPairingOrchestrator(t)
+	cfg, _ := o.providerStore.Get("telegram")
+	cfg.UserExpiry = map[string]time.Time{"1": time.Now().Add(time.Hour)}
+	if err := o.providerStore.Set("telegram", cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	o.expireAccess(time.Now().Add(2 * time.Hour))
+
+	if _, running := o.providers["telegram"]; running {
+		t.Error("expected telegram to be stopped rather than opened to everyone")
+	}
+	if status, _ := o.GetProviderStatus("telegram"); status.State != "error" {
+		t.Errorf("expected an error status, got %+v", status)
+	}
+}
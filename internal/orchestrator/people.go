@@ -0,0 +1,139 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/open-pact/openpact/internal/admin"
+	"github.com/open-pact/openpact/internal/chat"
+	"github.com/open-pact/openpact/internal/mcp"
+)
+
+// personScope is the session key prefix for people who follow their
+// sessions across accounts. It can't clash with a provider name.
+const personScope = "person"
+
+// personOf returns the person a chat user belongs to, or nil if their
+// account isn't linked to anyone.
+func (o *Orchestrator) personOf(provider, userID string) *admin.Person {
+	if o.people == nil {
+		return nil
+	}
+	person, err := o.people.FindByAccount(provider, userID)
+	if err != nil {
+		if !errors.Is(err, admin.ErrPersonNotFound) {
+			o.log.Warn("Failed to look up person for %s: %v", admin.AccountKey(provider, userID), err)
+		}
+		return nil
+	}
+	return person
+}
+
+// roleOf returns the role that limits a chat user's tool calls. The role set
+// on their person comes first, then permissions.users and default_role.
+func (o *Orchestrator) roleOf(provider, userID string, person *admin.Person) *mcp.Role {
+	if person != nil && person.Role != "" {
+		if role := o.perms.named(person.Role); role != nil {
+			return role
+		}
+		o.log.Warn("Person %s has unknown role %q; using the configured role", person.Name, person.Role)
+	}
+	return o.perms.roleOf(provider, userID)
+}
+
+// sessionScope returns the provider and channel a message's session is kept
+// under. People who follow their sessions share one session across all their
+// accounts and channels; everyone else gets one per channel.
+func sessionScope(provider, channelID string, person *admin.Person) (string, string) {
+	if person != nil && person.FollowSessions {
+		return personScope, person.ID
+	}
+	return provider, channelID
+}
+
+// repliesPrivately reports whether the reply to command, sent in channelID,
+// is shown only to its sender. Providers that can't tell are taken to reply
+// in public.
+func (o *Orchestrator) repliesPrivately(provider, channelID, command string) bool {
+	o.providerMu.RLock()
+	p := o.providers[provider]
+	o.providerMu.RUnlock()
+	replier, ok := p.(chat.PrivateReplier)
+	return ok && replier.RepliesPrivately(channelID, command)
+}
+
+// sourceContext builds the line prepended to each chat message so the AI
+// knows where it came from, who sent it, and the sender's role so it doesn't
+// plan around tools they can't use.
+func sourceContext(provider, channelID, userID string, person *admin.Person, role *mcp.Role) string {
+	fields := []string{"via " + provider, "channel:" + channelID, "user:" + userID}
+	if person != nil {
+		fields = append(fields, "person:"+person.Name)
+		if person.Timezone != "" {
+			fields = append(fields, "timezone:"+person.Timezone)
+		}
+		if person.Profile != "" {
+			fields = append(fields, "profile:"+person.Profile)
+		}
+	}
+	if role != nil {
+		fields = append(fields, "role:"+role.Name)
+	}
+	return "[" + strings.Join(fields, ", ") + "]\n"
+}
+
+// linkAccount handles the /link command. Without a code it returns a code
+// for linking another account to the sender, registering them as a person
+// first if needed. With a code it links the sender's account to the person
+// the code was made for. Codes are only handed out and taken where no one
+// else can see them; a code sent in a group is revoked, since anyone there
+// could redeem it first.
+func (o *Orchestrator) linkAccount(provider, channelID, userID, code string) (string, error) {
+	if o.people == nil {
+		return "Linking accounts is not available.", nil
+	}
+
+	if !o.repliesPrivately(provider, channelID, "link") {
+		if code == "" {
+			return "Send /link to me in a direct message to get a link code.", nil
+		}
+		if err := o.people.RevokeLinkCode(code); err != nil {
+			return "", fmt.Errorf("failed to revoke link code: %w", err)
+		}
+		return "Link codes only work in a direct message, and this one was seen here, so it no longer works. Send /link from your other account in a direct message to get a new one.", nil
+	}
+
+	if code != "" {
+		person, err := o.people.Link(code, provider, userID)
+		if errors.Is(err, admin.ErrLinkCodeNotFound) {
+			return "That link code is invalid or has expired. Send /link from your other account to get a new one.", nil
+		}
+		if errors.Is(err, admin.ErrAccountLinked) {
+			return "This account is already linked to someone else. Ask the owner to unlink it in the admin UI.", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to link account: %w", err)
+		}
+		o.log.Info("Linked %s to person %s", admin.AccountKey(provider, userID), person.Name)
+		return fmt.Sprintf("Linked. This account now belongs to %s (%s).", person.Name, strings.Join(person.Accounts, ", ")), nil
+	}
+
+	person := o.personOf(provider, userID)
+	if person == nil {
+		account := admin.AccountKey(provider, userID)
+		created, err := o.people.Create(&admin.Person{Name: account, Accounts: []string{account}})
+		if err != nil {
+			return "", fmt.Errorf("failed to register person: %w", err)
+		}
+		o.log.Info("Registered person %s", account)
+		person = created
+	}
+
+	code, expires, err := o.people.CreateLinkCode(person.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to create link code: %w", err)
+	}
+	return fmt.Sprintf("Send `/link %s` from your other account before %s. The code works once.",
+		code, expires.Format("15:04 MST")), nil
+}
@@ -0,0 +1,151 @@
+package orchestrator
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/open-pact/openpact/internal/admin"
+	"github.com/open-pact/openpact/internal/chat"
+	"github.com/open-pact/openpact/internal/config"
+	"github.com/open-pact/openpact/internal/engine"
+)
+
+// dmProvider answers commands privately in channels whose ID starts with
+// "dm", as if they were direct messages.
+type dmProvider struct {
+	chat.Provider
+}
+
+func (dmProvider) RepliesPrivately(channelID, command string) bool {
+	return strings.HasPrefix(channelID, "dm")
+}
+
+func TestLinkCommand(t *testing.T) {
+	o := newTestOrchestrator(t)
+	for _, name := range []string{"discord", "telegram", "slack"} {
+		o.providers[name] = dmProvider{}
+	}
+
+	reply, err := o.handleChatCommand("discord", "dm1", "111", "link", "")
+	if err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	code := regexp.MustCompile("`/link ([A-Z0-9]+)`").FindStringSubmatch(reply)
+	if code == nil {
+		t.Fatalf("expected a link code in %q", reply)
+	}
+
+	reply, err = o.handleChatCommand("telegram", "dm42", "222", "link", " "+code[1]+" ")
+	if err != nil || !strings.HasPrefix(reply, "Linked.") {
+		t.Fatalf("link %s = %q, %v", code[1], reply, err)
+	}
+
+	discord, telegram := o.personOf("discord", "111"), o.personOf("telegram", "222")
+	if discord == nil || telegram == nil || discord.ID != telegram.ID {
+		t.Fatalf("expected both accounts to belong to one person, got %+v and %+v", discord, telegram)
+	}
+
+	reply, _ = o.handleChatCommand("slack", "dmU1", "U1", "link", code[1])
+	if !strings.Contains(reply, "invalid or has expired") {
+		t.Errorf("expected a used code to be refused, got %q", reply)
+	}
+}
+
+func TestLinkCommandInGroup(t *testing.T) {
+	o := newTestOrchestrator(t)
+	o.providers["telegram"] = dmProvider{}
+
+	// No code is posted where others can see it
+	reply, err := o.handleChatCommand("telegram", "-100", "222", "link", "")
+	if err != nil || strings.Contains(reply, "`/link ") || !strings.Contains(reply, "direct message") {
+		t.Fatalf("link in a group = %q, %v", reply, err)
+	}
+
+	// A code sent in a group is refused and revoked
+	reply, _ = o.handleChatCommand("telegram", "dm222", "222", "link", "")
+	code := regexp.MustCompile("`/link ([A-Z0-9]+)`").FindStringSubmatch(reply)
+	if code == nil {
+		t.Fatalf("expected a link code in %q", reply)
+	}
+	reply, _ = o.handleChatCommand("telegram", "-100", "333", "link", code[1])
+	if !strings.Contains(reply, "no longer works") || o.personOf("telegram", "333") != nil {
+		t.Errorf("expected the code to be refused in a group, got %q", reply)
+	}
+	reply, _ = o.handleChatCommand("telegram", "dm444", "444", "link", code[1])
+	if !strings.Contains(reply, "invalid or has expired") {
+		t.Errorf("expected the code seen in a group to be revoked, got %q", reply)
+	}
+
+	// Providers that can't tell are treated as public
+	if reply, _ := o.handleChatCommand("discord", "dm1", "111", "link", ""); strings.Contains(reply, "`/link ") {
+		t.Errorf("expected no code without a running provider, got %q", reply)
+	}
+}
+
+func TestPersonFollowsSessions(t *testing.T) {
+	o := newTestOrchestrator(t)
+	var prompt, sent string
+	o.engine = &stubEngine{
+		send: func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+			prompt, sent = messages[0].Content, sessionID
+			ch := make(chan engine.Response)
+			close(ch)
+			return ch, nil
+		},
+	}
+
+	perms, err := newPermissions(config.PermissionsConfig{
+		DefaultRole: "guest",
+		Roles:       map[string]config.RoleConfig{"guest": {}, "owner": {}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.perms = perms
+
+	person, err := o.people.Create(&admin.Person{
+		Name:           "Alice",
+		Timezone:       "Europe/London",
+		Role:           "owner",
+		Accounts:       []string{"discord:111", "telegram:222"},
+		FollowSessions: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := o.handleChatMessage("discord", "c1", "111", "hello", nil); err != nil {
+		t.Fatalf("handleChatMessage: %v", err)
+	}
+	if want := "[via discord, channel:c1, user:111, person:Alice, timezone:Europe/London, role:owner]\n"; !strings.HasPrefix(prompt, want) {
+		t.Errorf("prompt = %q, want prefix %q", prompt, want)
+	}
+
+	// The session is kept under the person, so it follows them to Telegram
+	if got := o.GetChannelSession(personScope, person.ID); got != "sess-1" {
+		t.Errorf("person session = %q, want sess-1", got)
+	}
+	if got := o.GetChannelSession("discord", "c1"); got != "" {
+		t.Errorf("expected no channel session, got %q", got)
+	}
+	o.SetChannelSession(personScope, person.ID, "sess-alice")
+	if _, err := o.handleChatMessage("telegram", "42", "222", "hello again", nil); err != nil {
+		t.Fatalf("handleChatMessage: %v", err)
+	}
+	if sent != "sess-alice" {
+		t.Errorf("expected the session to follow Alice to Telegram, got %q", sent)
+	}
+
+	// Unlinked users keep per-channel sessions and the default role
+	if _, err := o.handleChatMessage("discord", "c1", "999", "hi", nil); err != nil {
+		t.Fatalf("handleChatMessage: %v", err)
+	}
+	if want := "[via discord, channel:c1, user:999, role:guest]\n"; !strings.HasPrefix(prompt, want) {
+		t.Errorf("prompt = %q, want prefix %q", prompt, want)
+	}
+	if got := o.GetChannelSession("discord", "c1"); got != "sess-1" {
+		t.Errorf("channel session = %q, want sess-1", got)
+	}
+}
@@ -0,0 +1,111 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/open-pact/openpact/internal/config"
+	"github.com/open-pact/openpact/internal/logging"
+	"github.com/open-pact/openpact/internal/mcp"
+)
+
+// permissions maps chat users to the roles that limit their tool calls.
+type permissions struct {
+	defaultRole *mcp.Role            // nil if unlisted users are not limited
+	users       map[string]*mcp.Role // by "provider:userID"
+	roles       map[string]*mcp.Role // by name
+}
+
+// newPermissions converts the permissions config section, checking that
+// every role referred to exists and that its patterns are valid.
+func newPermissions(cfg config.PermissionsConfig) (*permissions, error) {
+	roles := make(map[string]*mcp.Role, len(cfg.Roles))
+	for name, rc := range cfg.Roles {
+		role := &mcp.Role{Name: name, Tools: rc.Tools, Deny: rc.DenyTools, Args: rc.Args}
+		if err := role.Validate(); err != nil {
+			return nil, fmt.Errorf("permissions: %w", err)
+		}
+		roles[name] = role
+	}
+
+	lookup := func(field, name string) (*mcp.Role, error) {
+		role, ok := roles[name]
+		if !ok {
+			return nil, fmt.Errorf("permissions.%s: unknown role %q", field, name)
+		}
+		return role, nil
+	}
+
+	p := &permissions{users: make(map[string]*mcp.Role, len(cfg.Users)), roles: roles}
+	if cfg.DefaultRole != "" {
+		role, err := lookup("default_role", cfg.DefaultRole)
+		if err != nil {
+			return nil, err
+		}
+		p.defaultRole = role
+	}
+	for user, name := range cfg.Users {
+		role, err := lookup("users."+user, name)
+		if err != nil {
+			return nil, err
+		}
+		p.users[user] = role
+	}
+	return p, nil
+}
+
+// roleOf returns the role of a chat user, or nil if they are not limited.
+func (p *permissions) roleOf(provider, userID string) *mcp.Role {
+	if p == nil {
+		return nil
+	}
+	if role, ok := p.users[sessionKey(provider, userID)]; ok {
+		return role
+	}
+	return p.defaultRole
+}
+
+// named returns the role with the given name, or nil if there is none.
+func (p *permissions) named(name string) *mcp.Role {
+	if p == nil {
+		return nil
+	}
+	return p.roles[name]
+}
+
+// toolCallRoles returns the roles that limit a tool call (implements
+// mcp.RoleResolver). A call made during a chat turn is limited by the role
+// of the user who sent the message, resolved when the turn began; calls
+// from other turns, such as scheduled agent jobs, are not limited. A call
+// that cannot be tied to a turn, which happens when several turns run at
+// once, is limited by the roles of every chat turn in flight, so that a
+// limited user cannot slip a call through while someone else's turn is
+// running.
+func (o *Orchestrator) toolCallRoles(ctx context.Context) []*mcp.Role {
+	cid := logging.CorrelationID(ctx)
+
+	o.inflightMu.Lock()
+	defer o.inflightMu.Unlock()
+
+	if origin, ok := o.origins[cid]; ok {
+		if origin.role != nil {
+			return []*mcp.Role{origin.role}
+		}
+		return nil
+	}
+	if _, ok := o.inflight[cid]; ok {
+		return nil
+	}
+
+	seen := make(map[*mcp.Role]bool)
+	var roles []*mcp.Role
+	for _, origin := range o.origins {
+		if origin.role != nil && !seen[origin.role] {
+			seen[origin.role] = true
+			roles = append(roles, origin.role)
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles
+}
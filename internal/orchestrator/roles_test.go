@@ -0,0 +1,78 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/open-pact/openpact/internal/config"
+	"github.com/open-pact/openpact/internal/logging"
+)
+
+func TestToolCallRoles(t *testing.T) {
+	o := newTestOrchestrator(t)
+	perms, err := newPermissions(config.PermissionsConfig{
+		DefaultRole: "guest",
+		Users:       map[string]string{"discord:owner": "owner"},
+		Roles: map[string]config.RoleConfig{
+			"owner": {},
+			"guest": {DenyTools: []string{"vault_*"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("newPermissions: %v", err)
+	}
+	o.perms = perms
+
+	rolesFor := func(cid string) []string {
+		var names []string
+		for _, r := range o.toolCallRoles(logging.WithCorrelationID(context.Background(), cid)) {
+			names = append(names, r.Name)
+		}
+		return names
+	}
+
+	o.beginTurn("owner-turn", "s1")
+	o.setTurnOrigin("owner-turn", turnOrigin{provider: "discord", channelID: "c1", userID: "owner", role: o.roleOf("discord", "owner", nil)})
+	o.beginTurn("guest-turn", "s2")
+	o.setTurnOrigin("guest-turn", turnOrigin{provider: "telegram", channelID: "c2", userID: "stranger", role: o.roleOf("telegram", "stranger", nil)})
+	o.beginTurn("schedule-turn", "s3")
+
+	if got := rolesFor("owner-turn"); len(got) != 1 || got[0] != "owner" {
+		t.Errorf("owner turn roles = %v", got)
+	}
+	if got := rolesFor("guest-turn"); len(got) != 1 || got[0] != "guest" {
+		t.Errorf("guest turn roles = %v", got)
+	}
+	if got := rolesFor("schedule-turn"); len(got) != 0 {
+		t.Errorf("expected a scheduled turn to be unlimited, got %v", got)
+	}
+	// Unattributed calls get the limits of every chat turn in flight
+	if got := rolesFor("unknown"); len(got) != 2 || got[0] != "guest" || got[1] != "owner" {
+		t.Errorf("unattributed call roles = %v", got)
+	}
+
+	o.endTurn("guest-turn")
+	if got := rolesFor("unknown"); len(got) != 1 || got[0] != "owner" {
+		t.Errorf("unattributed call roles after the guest turn = %v", got)
+	}
+}
+
+func TestNewPermissionsErrors(t *testing.T) {
+	if _, err := newPermissions(config.PermissionsConfig{DefaultRole: "nobody"}); err == nil || !strings.Contains(err.Error(), `unknown role "nobody"`) {
+		t.Errorf("expected an unknown role error, got %v", err)
+	}
+	_, err := newPermissions(config.PermissionsConfig{
+		Users: map[string]string{"slack:U1": "guest"},
+		Roles: map[string]config.RoleConfig{"guest": {Tools: []string{"vault_["}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "bad tool pattern") {
+		t.Errorf("expected a bad pattern error, got %v", err)
+	}
+
+	// Without any permissions config nobody is limited
+	p, err := newPermissions(config.PermissionsConfig{})
+	if err != nil || p.roleOf("discord", "anyone") != nil {
+		t.Errorf("expected no limits, got %v, %v", p, err)
+	}
+}
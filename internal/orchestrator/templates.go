@@ -1,19 +1,19 @@
 package orchestrator
 
 import (
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/open-pact/openpact/internal/admin"
+	"github.com/open-pact/openpact/internal/logging"
 )
 
 // seedContextTemplates copies default context templates into the workspace
 // if they don't already exist. This ensures the AI has initial SOUL.md,
 // USER.md, and MEMORY.md files to work with.
 // Placeholder tokens ({{AGENT_NAME}}, etc.) are replaced with generic defaults.
-func seedContextTemplates(workspacePath string) {
+func seedContextTemplates(logger *logging.Logger, workspacePath string) {
 	templates := map[string]string{
 		"SOUL.md":   admin.DefaultSoulTemplate,
 		"USER.md":   admin.DefaultUserTemplate,
@@ -33,9 +33,9 @@ func seedContextTemplates(workspacePath string) {
 		if _, err := os.Stat(path); os.IsNotExist(err) {
 			resolved := replacer.Replace(content)
 			if err := os.WriteFile(path, []byte(resolved), 0644); err != nil {
-				log.Printf("Warning: failed to seed %s: %v", name, err)
+				logger.Warn("failed to seed %s: %v", name, err)
 			} else {
-				log.Printf("Seeded %s from template", name)
+				logger.Info("Seeded %s from template", name)
 			}
 		}
 	}
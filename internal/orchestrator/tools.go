@@ -0,0 +1,36 @@
+package orchestrator
+
+import (
+	"context"
+	"sort"
+
+	"github.com/open-pact/openpact/internal/engine"
+	"github.com/open-pact/openpact/internal/mcp"
+)
+
+// mcpToolRunner exposes the MCP server's tools to engines that execute tool
+// calls in-process (implements engine.ToolRunner).
+type mcpToolRunner struct {
+	srv *mcp.Server
+}
+
+// Tools returns the tools the turn's roles allow, sorted by name so the tool
+// list sent to the model is stable between requests.
+func (r mcpToolRunner) Tools(ctx context.Context) []engine.ToolSpec {
+	tools := r.srv.ListToolsFor(ctx)
+	specs := make([]engine.ToolSpec, 0, len(tools))
+	for _, t := range tools {
+		specs = append(specs, engine.ToolSpec{
+			Name:        t.Name,
+			Description: t.Description,
+			InputSchema: t.InputSchema,
+		})
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// CallTool runs a tool through the MCP server.
+func (r mcpToolRunner) CallTool(ctx context.Context, name string, args map[string]interface{}) (string, error) {
+	return r.srv.CallTool(ctx, name, args)
+}
@@ -0,0 +1,595 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-pact/openpact/internal/admin"
+	"github.com/open-pact/openpact/internal/config"
+	"github.com/open-pact/openpact/internal/engine"
+)
+
+// Budget actions.
+const (
+	budgetBlock     = "block"
+	budgetDowngrade = "downgrade"
+)
+
+// usageTurn is who an engine turn is spent on.
+type usageTurn struct {
+	source    string // one of the admin.Usage constants
+	provider  string
+	channelID string
+	userID    string
+	person    *admin.Person
+	schedule  *admin.Schedule
+}
+
+// budget is a usage limit over one UTC day or month, with the key of the
+// running totals that count against it (see usageKeys).
+type budget struct {
+	admin.BudgetStatus
+	totals string
+}
+
+// budgets holds the usage config section.
+type budgets struct {
+	cfg               config.UsageConfig
+	downgradeProvider string
+	downgradeModel    string
+}
+
+// newBudgets converts the usage config section, checking the actions and
+// that the downgrade action has a model to downgrade to.
+func newBudgets(cfg config.UsageConfig) (*budgets, error) {
+	if cfg.Action == "" {
+		cfg.Action = budgetBlock
+	}
+	b := &budgets{cfg: cfg}
+
+	downgrades := false
+	check := func(field string, bc config.BudgetConfig) error {
+		switch b.action(bc) {
+		case budgetBlock:
+		case budgetDowngrade:
+			downgrades = true
+		default:
+			return fmt.Errorf("usage.%s: unknown action %q (want block or downgrade)", field, bc.Action)
+		}
+		return nil
+	}
+	if err := check("action", config.BudgetConfig{}); err != nil {
+		return nil, err
+	}
+	if err := check("global.action", cfg.Global); err != nil {
+		return nil, err
+	}
+	if err := check("default_user.action", cfg.DefaultUser); err != nil {
+		return nil, err
+	}
+	if err := check("default_schedule.action", cfg.DefaultSchedule); err != nil {
+		return nil, err
+	}
+	for field, m := range map[string]map[string]config.BudgetConfig{"users": cfg.Users, "channels": cfg.Channels, "schedules": cfg.Schedules} {
+		for key, bc := range m {
+			if err := check(field+"."+key+".action", bc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if cfg.DowngradeModel != "" {
+		if provider, model, ok := strings.Cut(cfg.DowngradeModel, "/"); ok {
+			b.downgradeProvider, b.downgradeModel = provider, model
+		} else {
+			b.downgradeModel = cfg.DowngradeModel
+		}
+	}
+	if downgrades && b.downgradeModel == "" {
+		return nil, fmt.Errorf("usage.downgrade_model is required for the downgrade action")
+	}
+	return b, nil
+}
+
+// action returns the action of a budget, falling back to the section's.
+func (b *budgets) action(bc config.BudgetConfig) string {
+	if bc.Action != "" {
+		return bc.Action
+	}
+	return b.cfg.Action
+}
+
+// limits returns the daily and monthly budgets set by bc.
+func (b *budgets) limits(scope, key string, bc config.BudgetConfig, totals string) []budget {
+	var out []budget
+	if bc.DailyCost > 0 || bc.DailyTokens > 0 {
+		out = append(out, budget{
+			BudgetStatus: admin.BudgetStatus{Scope: scope, Key: key, Period: "day", MaxCost: bc.DailyCost, MaxTokens: bc.DailyTokens, Action: b.action(bc)},
+			totals:       totals,
+		})
+	}
+	if bc.MonthlyCost > 0 || bc.MonthlyTokens > 0 {
+		out = append(out, budget{
+			BudgetStatus: admin.BudgetStatus{Scope: scope, Key: key, Period: "month", MaxCost: bc.MonthlyCost, MaxTokens: bc.MonthlyTokens, Action: b.action(bc)},
+			totals:       totals,
+		})
+	}
+	return out
+}
+
+// Keys of the running totals, one per thing a budget can be set on.
+const totalsAll = "global"
+
+func totalsAccount(provider, userID string) string {
+	return "account:" + admin.AccountKey(provider, userID)
+}
+
+func totalsPerson(name string) string { return "person:" + name }
+
+func totalsChannel(provider, channelID string) string {
+	return "channel:" + provider + ":" + channelID
+}
+
+func totalsSchedule(name string) string { return "schedule:" + name }
+
+// totalsUser returns the totals key of a users key: "person:<name>" or
+// "provider:userID".
+func totalsUser(key string) string {
+	if name, ok := strings.CutPrefix(key, personScope+":"); ok {
+		return totalsPerson(name)
+	}
+	provider, userID, _ := strings.Cut(key, ":")
+	return totalsAccount(provider, userID)
+}
+
+// usageKeys returns the keys of the running totals a record counts towards.
+func usageKeys(r *admin.UsageRecord) []string {
+	keys := []string{totalsAll}
+	if r.UserID != "" {
+		keys = append(keys, totalsAccount(r.Provider, r.UserID))
+	}
+	if r.Person != "" {
+		keys = append(keys, totalsPerson(r.Person))
+	}
+	if r.ChannelID != "" {
+		keys = append(keys, totalsChannel(r.Provider, r.ChannelID))
+	}
+	if r.Schedule != "" {
+		keys = append(keys, totalsSchedule(r.Schedule))
+	}
+	return keys
+}
+
+// forTurn returns the budgets a turn counts against. A user listed in users
+// by their person or their account is held to those budgets; anyone else to
+// default_user, with all the accounts of a person counted together.
+func (b *budgets) forTurn(t usageTurn) []budget {
+	out := b.limits("global", "", b.cfg.Global, totalsAll)
+
+	if t.userID != "" {
+		account := admin.AccountKey(t.provider, t.userID)
+		listed := false
+		if t.person != nil {
+			key := personScope + ":" + t.person.Name
+			if bc, ok := b.cfg.Users[key]; ok {
+				out = append(out, b.limits("user", key, bc, totalsPerson(t.person.Name))...)
+				listed = true
+			}
+		}
+		if bc, ok := b.cfg.Users[account]; ok {
+			out = append(out, b.limits("user", account, bc, totalsAccount(t.provider, t.userID))...)
+			listed = true
+		}
+		if !listed {
+			if t.person != nil {
+				out = append(out, b.limits("user", personScope+":"+t.person.Name, b.cfg.DefaultUser, totalsPerson(t.person.Name))...)
+			} else {
+				out = append(out, b.limits("user", account, b.cfg.DefaultUser, totalsAccount(t.provider, t.userID))...)
+			}
+		}
+	}
+
+	if t.channelID != "" {
+		key := t.provider + ":" + t.channelID
+		if bc, ok := b.cfg.Channels[key]; ok {
+			out = append(out, b.limits("channel", key, bc, totalsChannel(t.provider, t.channelID))...)
+		}
+	}
+
+	if t.schedule != nil {
+		bc, ok := b.cfg.Schedules[t.schedule.Name]
+		if !ok {
+			bc = b.cfg.DefaultSchedule
+		}
+		out = append(out, b.limits("schedule", t.schedule.Name, bc, totalsSchedule(t.schedule.Name))...)
+	}
+	return out
+}
+
+// evaluate fills in how much of each budget is used.
+func evaluate(list []budget, totals *usageSnapshot) []admin.BudgetStatus {
+	statuses := make([]admin.BudgetStatus, 0, len(list))
+	for _, b := range list {
+		used := totals.monthly[b.totals]
+		if b.Period == "day" {
+			used = totals.daily[b.totals]
+		}
+		status := b.BudgetStatus
+		status.Cost, status.Tokens = used.Cost, used.Tokens
+		status.Exceeded = (status.MaxCost > 0 && used.Cost >= status.MaxCost) ||
+			(status.MaxTokens > 0 && used.Tokens >= status.MaxTokens)
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// usageTotals are the running totals of today's and this month's usage,
+// so that budgets are checked without reading the ledger. They are read from
+// the ledger once a day and kept up to date by recordUsage.
+type usageTotals struct {
+	mu        sync.Mutex
+	day       time.Time // day the totals are for; zero until read
+	daily     map[string]*admin.UsageTotals
+	monthly   map[string]*admin.UsageTotals
+	accounts  map[string]*admin.UsageRecord // an account's last record this month
+	schedules map[string]bool               // schedules run this month
+}
+
+// usageSnapshot is a copy of the running totals.
+type usageSnapshot struct {
+	daily     map[string]admin.UsageTotals
+	monthly   map[string]admin.UsageTotals
+	accounts  []*admin.UsageRecord // by account key
+	schedules []string             // sorted
+}
+
+// add counts a record towards the totals.
+func (t *usageTotals) add(r *admin.UsageRecord) {
+	for _, key := range usageKeys(r) {
+		if t.monthly[key] == nil {
+			t.monthly[key] = &admin.UsageTotals{}
+		}
+		t.monthly[key].Add(r)
+		if !r.At.Before(t.day) {
+			if t.daily[key] == nil {
+				t.daily[key] = &admin.UsageTotals{}
+			}
+			t.daily[key].Add(r)
+		}
+	}
+	if r.UserID != "" {
+		t.accounts[admin.AccountKey(r.Provider, r.UserID)] = r
+	}
+	if r.Schedule != "" {
+		t.schedules[r.Schedule] = true
+	}
+}
+
+// usageTotals returns a copy of the running totals for now, reading this
+// month's ledger first if the totals are for another day.
+func (o *Orchestrator) usageTotals(now time.Time) (*usageSnapshot, error) {
+	t := &o.totals
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if day := admin.DayStart(now); !t.day.Equal(day) {
+		month := admin.MonthStart(now)
+		records, err := o.usage.Between(month, month.AddDate(0, 1, 0))
+		if err != nil {
+			return nil, err
+		}
+		t.day = day
+		t.daily = make(map[string]*admin.UsageTotals)
+		t.monthly = make(map[string]*admin.UsageTotals)
+		t.accounts = make(map[string]*admin.UsageRecord)
+		t.schedules = make(map[string]bool)
+		for _, r := range records {
+			t.add(r)
+		}
+	}
+
+	snap := &usageSnapshot{
+		daily:   make(map[string]admin.UsageTotals, len(t.daily)),
+		monthly: make(map[string]admin.UsageTotals, len(t.monthly)),
+	}
+	for key, totals := range t.daily {
+		snap.daily[key] = *totals
+	}
+	for key, totals := range t.monthly {
+		snap.monthly[key] = *totals
+	}
+	for _, key := range sortedKeys(t.accounts) {
+		snap.accounts = append(snap.accounts, t.accounts[key])
+	}
+	snap.schedules = sortedKeys(t.schedules)
+	return snap, nil
+}
+
+// addUsage counts a record just added to the ledger towards the running
+// totals. Totals for another day are left to be read from the ledger.
+func (o *Orchestrator) addUsage(r *admin.UsageRecord) {
+	t := &o.totals
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.day.Equal(admin.DayStart(r.At)) {
+		t.add(r)
+	}
+}
+
+// sessionLocks holds a lock per session with a metered turn.
+type sessionLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sessionLock
+}
+
+type sessionLock struct {
+	sync.Mutex
+	waiters int
+}
+
+// lockSession waits for the session's other metered turns, and returns the
+// function that lets the next one go. A turn's usage is the growth of its
+// session's usage while it ran, so two turns running at once in one session
+// would each be charged for both.
+func (o *Orchestrator) lockSession(sessionID string) (unlock func()) {
+	l := &o.sessionTurns
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*sessionLock)
+	}
+	lock := l.locks[sessionID]
+	if lock == nil {
+		lock = &sessionLock{}
+		l.locks[sessionID] = lock
+	}
+	lock.waiters++
+	l.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		l.mu.Lock()
+		if lock.waiters--; lock.waiters == 0 {
+			delete(l.locks, sessionID)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// budgetError is returned for turns blocked by a used up budget.
+type budgetError struct {
+	status admin.BudgetStatus
+}
+
+func (e *budgetError) Error() string {
+	return "usage budget exceeded: " + describeBudget(e.status)
+}
+
+// reply is the chat reply to a blocked message.
+func (e *budgetError) reply() string {
+	resets := "at midnight UTC"
+	if e.status.Period == "month" {
+		resets = "at the start of next month (UTC)"
+	}
+	return fmt.Sprintf("I can't answer right now: %s is used up. It resets %s.", describeBudget(e.status), resets)
+}
+
+// describeBudget names a budget and its limits, e.g. "the daily budget of
+// user discord:123 ($1.00)".
+func describeBudget(s admin.BudgetStatus) string {
+	period := "daily"
+	if s.Period == "month" {
+		period = "monthly"
+	}
+	var limits []string
+	if s.MaxCost > 0 {
+		limits = append(limits, fmt.Sprintf("$%.2f", s.MaxCost))
+	}
+	if s.MaxTokens > 0 {
+		limits = append(limits, fmt.Sprintf("%d tokens", s.MaxTokens))
+	}
+	name := fmt.Sprintf("the %s %s budget", s.Scope, period)
+	if s.Key != "" {
+		name = fmt.Sprintf("the %s budget of %s %s", period, s.Scope, s.Key)
+	}
+	return fmt.Sprintf("%s (%s)", name, strings.Join(limits, ", "))
+}
+
+// checkBudgets checks a turn against its budgets before it is sent. If a
+// used up budget blocks it, the error says which. If one downgrades it, the
+// returned context makes the engine use the downgrade model. The turn goes
+// ahead if the ledger can't be read. Budgets aren't checked again while the
+// turn runs, so the turn that uses up a budget is finished, however much it
+// spends.
+func (o *Orchestrator) checkBudgets(ctx context.Context, turn usageTurn) (context.Context, *budgetError) {
+	list := o.budgets.forTurn(turn)
+	if len(list) == 0 {
+		return ctx, nil
+	}
+
+	totals, err := o.usageTotals(time.Now())
+	if err != nil {
+		o.log.WithContext(ctx).Warn("Failed to read usage ledger; not checking budgets: %v", err)
+		return ctx, nil
+	}
+
+	downgrade := false
+	for _, status := range evaluate(list, totals) {
+		if !status.Exceeded {
+			continue
+		}
+		if status.Action == budgetBlock {
+			return ctx, &budgetError{status: status}
+		}
+		downgrade = true
+	}
+	if downgrade {
+		o.log.WithContext(ctx).Info("Usage budget used up; answering with %s", o.cfg.Usage.DowngradeModel)
+		ctx = engine.WithModel(ctx, o.budgets.downgradeProvider, o.budgets.downgradeModel)
+	}
+	return ctx, nil
+}
+
+// sessionUsage returns a session's usage so far, or nil if the engine can't
+// report it.
+func (o *Orchestrator) sessionUsage(ctx context.Context, sessionID string) *engine.ContextUsage {
+	usage, err := o.engine.GetContextUsage(sessionID)
+	if err != nil {
+		o.log.WithContext(ctx).Warn("Failed to read usage of session %s: %v", sessionID, err)
+		return nil
+	}
+	return usage
+}
+
+// recordUsage adds a finished turn to the usage ledger: the difference in
+// the session's usage from before the turn. The caller holds the session's
+// lock (see lockSession).
+func (o *Orchestrator) recordUsage(ctx context.Context, turn usageTurn, sessionID string, before *engine.ContextUsage) {
+	if before == nil {
+		return
+	}
+	after := o.sessionUsage(ctx, sessionID)
+	if after == nil {
+		return
+	}
+
+	rec := &admin.UsageRecord{
+		Source:           turn.source,
+		SessionID:        sessionID,
+		Model:            after.Model,
+		Provider:         turn.provider,
+		ChannelID:        turn.channelID,
+		UserID:           turn.userID,
+		InputTokens:      after.TotalInput - before.TotalInput,
+		OutputTokens:     after.TotalOutput - before.TotalOutput,
+		ReasoningTokens:  after.TotalReasoning - before.TotalReasoning,
+		CacheReadTokens:  after.CacheRead - before.CacheRead,
+		CacheWriteTokens: after.CacheWrite - before.CacheWrite,
+		Cost:             after.TotalCost - before.TotalCost,
+	}
+	if rec.Tokens() <= 0 && rec.Cost <= 0 {
+		return // Nothing reached the model
+	}
+	_, _, rec.Downgraded = engine.ModelFromContext(ctx)
+	if turn.person != nil {
+		rec.Person = turn.person.Name
+	}
+	if turn.schedule != nil {
+		rec.ScheduleID, rec.Schedule = turn.schedule.ID, turn.schedule.Name
+	}
+
+	if err := o.usage.Append(rec); err != nil {
+		o.log.WithContext(ctx).Warn("Failed to record usage: %v", err)
+		return
+	}
+	o.addUsage(rec)
+}
+
+// BudgetStatuses reports how much of each budget is used (implements
+// admin.BudgetReporter). Budgets from default_user and default_schedule are
+// listed for each user and schedule with usage this month.
+func (o *Orchestrator) BudgetStatuses(now time.Time) ([]admin.BudgetStatus, error) {
+	totals, err := o.usageTotals(now)
+	if err != nil {
+		return nil, err
+	}
+	b := o.budgets
+
+	list := b.limits("global", "", b.cfg.Global, totalsAll)
+	for _, key := range sortedKeys(b.cfg.Users) {
+		list = append(list, b.limits("user", key, b.cfg.Users[key], totalsUser(key))...)
+	}
+	for _, key := range sortedKeys(b.cfg.Channels) {
+		provider, channelID, _ := strings.Cut(key, ":")
+		list = append(list, b.limits("channel", key, b.cfg.Channels[key], totalsChannel(provider, channelID))...)
+	}
+	for _, name := range sortedKeys(b.cfg.Schedules) {
+		list = append(list, b.limits("schedule", name, b.cfg.Schedules[name], totalsSchedule(name))...)
+	}
+
+	// Users and schedules held to the defaults
+	seen := make(map[string]bool)
+	for _, r := range totals.accounts {
+		key := admin.AccountKey(r.Provider, r.UserID)
+		if r.Person != "" {
+			key = personScope + ":" + r.Person
+		}
+		if seen[key] || b.listedUser(r) {
+			continue
+		}
+		seen[key] = true
+		list = append(list, b.limits("user", key, b.cfg.DefaultUser, totalsUser(key))...)
+	}
+	for _, name := range totals.schedules {
+		if _, listed := b.cfg.Schedules[name]; !listed {
+			list = append(list, b.limits("schedule", name, b.cfg.DefaultSchedule, totalsSchedule(name))...)
+		}
+	}
+
+	return evaluate(list, totals), nil
+}
+
+// listedUser reports whether the user of a record has their own budget.
+func (b *budgets) listedUser(r *admin.UsageRecord) bool {
+	if _, ok := b.cfg.Users[admin.AccountKey(r.Provider, r.UserID)]; ok {
+		return true
+	}
+	if r.Person == "" {
+		return false
+	}
+	_, ok := b.cfg.Users[personScope+":"+r.Person]
+	return ok
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// usageCommand handles the /usage command: the sender's and the channel's
+// usage today and this month, and the budgets that apply to them.
+func (o *Orchestrator) usageCommand(provider, channelID, userID string) (string, error) {
+	totals, err := o.usageTotals(time.Now())
+	if err != nil {
+		return "", fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+
+	person := o.personOf(provider, userID)
+	you := totalsAccount(provider, userID)
+	youName := admin.AccountKey(provider, userID)
+	if person != nil {
+		you, youName = totalsPerson(person.Name), person.Name
+	}
+
+	summary := func(key string) string {
+		today, month := totals.daily[key], totals.monthly[key]
+		return fmt.Sprintf("today %d tokens, $%.2f; this month %d tokens, $%.2f",
+			today.Tokens, today.Cost, month.Tokens, month.Cost)
+	}
+
+	result := "**Usage** (days and months in UTC)\n"
+	result += fmt.Sprintf("- You (%s): %s\n", youName, summary(you))
+	result += fmt.Sprintf("- This channel: %s\n", summary(totalsChannel(provider, channelID)))
+
+	turn := usageTurn{source: admin.UsageChat, provider: provider, channelID: channelID, userID: userID, person: person}
+	for _, status := range evaluate(o.budgets.forTurn(turn), totals) {
+		used := fmt.Sprintf("$%.2f", status.Cost)
+		if status.MaxCost == 0 {
+			used = fmt.Sprintf("%d tokens", status.Tokens)
+		}
+		line := fmt.Sprintf("- %s: %s used", strings.TrimPrefix(describeBudget(status), "the "), used)
+		if status.Exceeded {
+			line += fmt.Sprintf(" — used up, turns are %s", map[string]string{budgetBlock: "blocked", budgetDowngrade: "downgraded"}[status.Action])
+		}
+		result += line + "\n"
+	}
+	return result, nil
+}
@@ -0,0 +1,242 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/open-pact/openpact/internal/admin"
+	"github.com/open-pact/openpact/internal/config"
+	"github.com/open-pact/openpact/internal/engine"
+	"github.com/open-pact/openpact/internal/scheduler"
+)
+
+// newUsageOrchestrator returns an orchestrator whose engine spends 100 input
+// tokens, 50 output tokens and $0.50 on each turn.
+func newUsageOrchestrator(t *testing.T, cfg config.UsageConfig) (*Orchestrator, *int) {
+	t.Helper()
+	o := newTestOrchestrator(t)
+	var err error
+	if o.budgets, err = newBudgets(cfg); err != nil {
+		t.Fatal(err)
+	}
+	o.cfg.Usage = cfg
+
+	turns := 0
+	o.engine = &stubEngine{
+		send: func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+			turns++
+			ch := make(chan engine.Response, 1)
+			ch <- engine.Response{Content: "hi", PartID: "p1"}
+			close(ch)
+			return ch, nil
+		},
+		usage: func(sessionID string) (*engine.ContextUsage, error) {
+			return &engine.ContextUsage{
+				Model:       "claude-sonnet",
+				TotalInput:  100 * turns,
+				TotalOutput: 50 * turns,
+				TotalCost:   0.5 * float64(turns),
+			}, nil
+		},
+	}
+	return o, &turns
+}
+
+func TestChatUsageRecordedAndBlocked(t *testing.T) {
+	o, turns := newUsageOrchestrator(t, config.UsageConfig{
+		DefaultUser: config.BudgetConfig{DailyCost: 1},
+	})
+
+	for i := 0; i < 2; i++ {
+		if resp, err := o.handleChatMessage("discord", "c1", "111", "hello", nil); err != nil || resp.Text != "hi" {
+			t.Fatalf("turn %d = %+v, %v", i+1, resp, err)
+		}
+	}
+
+	now := time.Now()
+	records, err := o.usage.Between(admin.DayStart(now), now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 recorded turns, got %d", len(records))
+	}
+	rec := records[1]
+	if rec.Source != admin.UsageChat || rec.Provider != "discord" || rec.ChannelID != "c1" || rec.UserID != "111" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	if rec.InputTokens != 100 || rec.OutputTokens != 50 || rec.Cost != 0.5 || rec.Model != "claude-sonnet" {
+		t.Errorf("expected the turn's usage, got %+v", rec)
+	}
+
+	resp, err := o.handleChatMessage("discord", "c1", "111", "hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp.Text, "daily budget of user discord:111") || *turns != 2 {
+		t.Errorf("expected the third turn to be blocked, got %q after %d turns", resp.Text, *turns)
+	}
+
+	// Other users have their own budget
+	if resp, _ := o.handleChatMessage("discord", "c1", "222", "hello", nil); resp.Text != "hi" {
+		t.Errorf("expected another user to be answered, got %q", resp.Text)
+	}
+
+	statuses, err := o.BudgetStatuses(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exceeded := map[string]bool{}
+	for _, s := range statuses {
+		exceeded[s.Key] = s.Exceeded
+	}
+	if !exceeded["discord:111"] || exceeded["discord:222"] {
+		t.Errorf("unexpected budget statuses %+v", statuses)
+	}
+
+	reply, err := o.handleChatCommand("discord", "c1", "111", "usage", "")
+	if err != nil || !strings.Contains(reply, "today 300 tokens, $1.00") || !strings.Contains(reply, "used up") {
+		t.Errorf("/usage = %q, %v", reply, err)
+	}
+}
+
+func TestScheduleUsageDowngraded(t *testing.T) {
+	o, _ := newUsageOrchestrator(t, config.UsageConfig{
+		DowngradeModel: "anthropic/claude-haiku",
+		Schedules: map[string]config.BudgetConfig{
+			"nightly": {Action: "downgrade", DailyTokens: 100},
+		},
+	})
+	sched := &admin.Schedule{ID: "s1", Name: "nightly"}
+	if err := o.usage.Append(&admin.UsageRecord{Source: admin.UsageSchedule, ScheduleID: "s1", Schedule: "nightly", InputTokens: 150}); err != nil {
+		t.Fatal(err)
+	}
+
+	var provider, model string
+	var downgraded bool
+	stub := o.engine.(*stubEngine)
+	send := stub.send
+	stub.send = func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+		provider, model, downgraded = engine.ModelFromContext(ctx)
+		return send(ctx, sessionID, messages)
+	}
+
+	responses, err := o.Send(scheduler.WithSchedule(context.Background(), sched), "sess-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range responses {
+	}
+	if !downgraded || provider != "anthropic" || model != "claude-haiku" {
+		t.Errorf("expected a downgraded turn, got %v %q/%q", downgraded, provider, model)
+	}
+
+	now := time.Now()
+	records, _ := o.usage.Between(admin.DayStart(now), now.Add(time.Minute))
+	if len(records) != 2 || !records[1].Downgraded || records[1].Schedule != "nightly" || records[1].Source != admin.UsageSchedule {
+		t.Errorf("expected a downgraded schedule turn to be recorded, got %+v", records)
+	}
+
+	// Turns from the admin UI aren't held to the schedule's budget
+	responses, err = o.Send(context.Background(), "sess-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range responses {
+	}
+	if downgraded {
+		t.Error("expected an admin turn to use the default model")
+	}
+}
+
+func TestScheduleUsageBlocked(t *testing.T) {
+	o, turns := newUsageOrchestrator(t, config.UsageConfig{
+		DefaultSchedule: config.BudgetConfig{MonthlyCost: 0.5},
+	})
+	ctx := scheduler.WithSchedule(context.Background(), &admin.Schedule{ID: "s1", Name: "loop"})
+
+	responses, err := o.Send(ctx, "sess-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range responses {
+	}
+	if _, err := o.Send(ctx, "sess-1", nil); err == nil || !strings.Contains(err.Error(), "monthly budget of schedule loop") {
+		t.Errorf("expected the runaway schedule to be blocked, got %v", err)
+	}
+	if *turns != 1 {
+		t.Errorf("expected 1 turn, got %d", *turns)
+	}
+}
+
+func TestConcurrentTurnsInSessionCountedOnce(t *testing.T) {
+	o, turns := newUsageOrchestrator(t, config.UsageConfig{})
+	var mu sync.Mutex
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	stub := o.engine.(*stubEngine)
+	stub.send = func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+		mu.Lock()
+		*turns++
+		mu.Unlock()
+		started <- struct{}{}
+		ch := make(chan engine.Response)
+		go func() {
+			<-release
+			close(ch)
+		}()
+		return ch, nil
+	}
+	usage := stub.usage
+	stub.usage = func(sessionID string) (*engine.ContextUsage, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return usage(sessionID)
+	}
+
+	// Both messages go to the channel's session
+	done := make(chan struct{}, 2)
+	for _, user := range []string{"111", "222"} {
+		user := user
+		go func() {
+			o.handleChatMessage("discord", "c1", user, "hello", nil)
+			done <- struct{}{}
+		}()
+	}
+	<-started
+	select {
+	case <-started:
+		t.Fatal("expected the second turn to wait for the first")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-started
+	<-done
+	<-done
+
+	now := time.Now()
+	records, _ := o.usage.Between(admin.DayStart(now), now.Add(time.Minute))
+	if len(records) != 2 || records[0].InputTokens != 100 || records[1].InputTokens != 100 {
+		t.Errorf("expected each turn to be charged its own usage, got %+v", records)
+	}
+	totals, err := o.usageTotals(now)
+	if err != nil || totals.daily[totalsAll].InputTokens != 200 || totals.monthly[totalsAccount("discord", "222")].Turns != 1 {
+		t.Errorf("unexpected running totals %+v, %v", totals, err)
+	}
+}
+
+func TestNewBudgetsValidation(t *testing.T) {
+	if _, err := newBudgets(config.UsageConfig{Action: "downgrade"}); err == nil {
+		t.Error("expected downgrade without a downgrade model to be refused")
+	}
+	if _, err := newBudgets(config.UsageConfig{Channels: map[string]config.BudgetConfig{"discord:1": {Action: "warn"}}}); err == nil {
+		t.Error("expected an unknown action to be refused")
+	}
+	b, err := newBudgets(config.UsageConfig{DowngradeModel: "gpt-4o-mini"})
+	if err != nil || b.downgradeProvider != "" || b.downgradeModel != "gpt-4o-mini" || b.cfg.Action != budgetBlock {
+		t.Errorf("newBudgets = %+v, %v", b, err)
+	}
+}
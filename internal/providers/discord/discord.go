@@ -3,26 +3,41 @@ package discord
 
 import (
 	"fmt"
-	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/open-pact/openpact/internal/chat"
+	"github.com/open-pact/openpact/internal/logging"
 )
 
-var _ chat.Provider = (*Bot)(nil)
+var (
+	_ chat.StreamingProvider = (*Bot)(nil)
+	_ chat.FileSender        = (*Bot)(nil)
+	_ chat.ApprovalPrompter  = (*Bot)(nil)
+)
+
+// maxMessageLen is Discord's limit on message content length.
+const maxMessageLen = 2000
 
 // Bot represents a Discord bot
 type Bot struct {
 	session        *discordgo.Session
 	handler        chat.MessageHandler
+	streamHandler  chat.StreamingMessageHandler
 	commandHandler chat.CommandHandler
 	allowedUsers   map[string]bool // User IDs allowed to DM
 	allowedChans   map[string]bool // Channel IDs allowed
 	botUserID string
+	approvals chat.ApprovalHandler
+	pairing   chat.PairingHandler
 	mu        sync.RWMutex
+	log       *logging.Logger
 }
 
 // Config holds Discord bot configuration
@@ -30,6 +45,7 @@ type Config struct {
 	Token        string
 	AllowedUsers []string
 	AllowedChans []string
+	Logger       *logging.Logger // Optional; defaults to logging.Standard()
 }
 
 // New creates a new Discord bot
@@ -50,10 +66,16 @@ func New(cfg Config) (*Bot, error) {
 		allowedChans[c] = true
 	}
 
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logging.Standard()
+	}
+
 	bot := &Bot{
 		session:      session,
 		allowedUsers: allowedUsers,
 		allowedChans: allowedChans,
+		log:          logger.WithField("provider", "discord"),
 	}
 
 	// Add handlers
@@ -79,6 +101,14 @@ func (b *Bot) SetMessageHandler(h chat.MessageHandler) {
 	b.handler = h
 }
 
+// SetStreamingMessageHandler sets the message handler callback used to show
+// replies as they are written. It takes precedence over SetMessageHandler.
+func (b *Bot) SetStreamingMessageHandler(h chat.StreamingMessageHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.streamHandler = h
+}
+
 // SetCommandHandler sets the slash command handler callback
 func (b *Bot) SetCommandHandler(h chat.CommandHandler) {
 	b.mu.Lock()
@@ -86,6 +116,32 @@ func (b *Bot) SetCommandHandler(h chat.CommandHandler) {
 	b.commandHandler = h
 }
 
+// SetPairingHandler registers the callback for direct messages from users
+// who aren't allowed.
+func (b *Bot) SetPairingHandler(h chat.PairingHandler) {
+	b.mu.Lock()
+	b.pairing = h
+	b.mu.Unlock()
+}
+
+// SetAllowedUsers replaces the users allowed to talk to the bot.
+func (b *Bot) SetAllowedUsers(users []string) {
+	allowed := make(map[string]bool, len(users))
+	for _, u := range users {
+		allowed[u] = true
+	}
+	b.mu.Lock()
+	b.allowedUsers = allowed
+	b.mu.Unlock()
+}
+
+// SetApprovalHandler sets the callback for approval prompt buttons
+func (b *Bot) SetApprovalHandler(h chat.ApprovalHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.approvals = h
+}
+
 // Start connects the bot to Discord and registers slash commands
 func (b *Bot) Start() error {
 	if err := b.session.Open(); err != nil {
@@ -99,11 +155,11 @@ func (b *Bot) Start() error {
 	}
 	b.botUserID = user.ID
 
-	log.Printf("Discord bot connected as %s#%s", user.Username, user.Discriminator)
+	b.log.Info("Discord bot connected as %s#%s", user.Username, user.Discriminator)
 
 	// Register slash commands
 	if err := b.registerCommands(); err != nil {
-		log.Printf("Warning: failed to register slash commands: %v", err)
+		b.log.Warn("failed to register slash commands: %v", err)
 	}
 
 	return nil
@@ -144,6 +200,10 @@ func (b *Bot) registerCommands() error {
 			Name:        "context",
 			Description: "Show context window usage for the current session",
 		},
+		{
+			Name:        "usage",
+			Description: "Show your and this channel's token usage and cost, and their budgets",
+		},
 		{
 			Name:        "mode-simple",
 			Description: "Set response detail mode to simple (text only)",
@@ -160,21 +220,61 @@ func (b *Bot) registerCommands() error {
 			Name:        "mode-full",
 			Description: "Set response detail mode to show thinking and tool calls",
 		},
+		{
+			Name:        "link",
+			Description: "Link this account to your other chat accounts",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "code",
+					Description: "A link code from your other account; leave empty to get one",
+				},
+			},
+		},
+		{
+			Name:        "pair",
+			Description: "List or answer pairing requests from new users (approvers only)",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "action",
+					Description: "Approve or deny a request; leave empty to list them",
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "approve", Value: "approve"},
+						{Name: "deny", Value: "deny"},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "code",
+					Description: "The pairing code",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "days",
+					Description: "Take access away again after this many days",
+				},
+			},
+		},
 	}
 
 	for _, cmd := range commands {
 		if _, err := b.session.ApplicationCommandCreate(b.session.State.User.ID, "", cmd); err != nil {
 			return fmt.Errorf("failed to register command %s: %w", cmd.Name, err)
 		}
-		log.Printf("Registered Discord command: /%s", cmd.Name)
+		b.log.Debug("Registered Discord command: /%s", cmd.Name)
 	}
 
 	return nil
 }
 
 
-// onInteractionCreate handles slash command interactions
+// onInteractionCreate handles slash command and button interactions
 func (b *Bot) onInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type == discordgo.InteractionMessageComponent {
+		b.onApprovalButton(s, i)
+		return
+	}
 	if i.Type != discordgo.InteractionApplicationCommand {
 		return
 	}
@@ -190,17 +290,23 @@ func (b *Bot) onInteractionCreate(s *discordgo.Session, i *discordgo.Interaction
 	}
 
 	// Defer the response (we have 3 seconds to acknowledge)
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+	deferred := &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
+	}
+	if b.RepliesPrivately(i.ChannelID, data.Name) {
+		deferred.Data = &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral}
+	}
+	err := s.InteractionRespond(i.Interaction, deferred)
 	if err != nil {
-		log.Printf("Failed to defer interaction response: %v", err)
+		b.log.Error("Failed to defer interaction response: %v", err)
 		return
 	}
 
 	// Extract args
 	var args string
-	if len(data.Options) > 0 {
+	if data.Name == "pair" {
+		args = pairArgs(data.Options)
+	} else if len(data.Options) > 0 {
 		args = data.Options[0].StringValue()
 	}
 
@@ -226,10 +332,101 @@ func (b *Bot) onInteractionCreate(s *discordgo.Session, i *discordgo.Interaction
 		Content: &response,
 	})
 	if err != nil {
-		log.Printf("Failed to edit interaction response: %v", err)
+		b.log.Error("Failed to edit interaction response: %v", err)
 	}
 }
 
+// pairArgs turns the /pair options into "approve CODE [days]", "deny CODE",
+// or "" to list the requests. Discord may send options in any order.
+func pairArgs(options []*discordgo.ApplicationCommandInteractionDataOption) string {
+	var action, code, days string
+	for _, opt := range options {
+		switch opt.Name {
+		case "action":
+			action = opt.StringValue()
+		case "code":
+			code = opt.StringValue()
+		case "days":
+			days = strconv.FormatInt(opt.IntValue(), 10)
+		}
+	}
+	return strings.TrimSpace(strings.Join([]string{action, code, days}, " "))
+}
+
+// onApprovalButton answers an approval prompt. The prompt is replaced with
+// the outcome; errors are shown only to the user who pressed the button.
+func (b *Bot) onApprovalButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	approvalID, approved, ok := chat.ParseApprovalAction(i.MessageComponentData().CustomID)
+	if !ok {
+		return
+	}
+
+	b.mu.RLock()
+	handler := b.approvals
+	b.mu.RUnlock()
+	if handler == nil {
+		return
+	}
+
+	userID := ""
+	if i.Member != nil {
+		userID = i.Member.User.ID
+	} else if i.User != nil {
+		userID = i.User.ID
+	}
+
+	result, err := handler("discord", userID, approvalID, approved)
+	response := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    result,
+			Components: []discordgo.MessageComponent{},
+		},
+	}
+	if err != nil {
+		response = &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: err.Error(),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}
+	}
+	if err := s.InteractionRespond(i.Interaction, response); err != nil {
+		b.log.Error("Failed to answer approval button: %v", err)
+	}
+}
+
+// SendApprovalPrompt posts an approval prompt with Approve and Deny buttons
+func (b *Bot) SendApprovalPrompt(target string, prompt chat.ApprovalPrompt) error {
+	channelID := strings.TrimPrefix(target, "channel:")
+	if strings.HasPrefix(target, "user:") {
+		channel, err := b.session.UserChannelCreate(strings.TrimPrefix(target, "user:"))
+		if err != nil {
+			return fmt.Errorf("failed to create DM channel: %w", err)
+		}
+		channelID = channel.ID
+	}
+
+	_, err := b.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content: truncate(prompt.Text, maxMessageLen),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Approve", Style: discordgo.SuccessButton, CustomID: chat.ApprovalActionID(prompt.ID, true)},
+				discordgo.Button{Label: "Deny", Style: discordgo.DangerButton, CustomID: chat.ApprovalActionID(prompt.ID, false)},
+			}},
+		},
+	})
+	return err
+}
+
+// RepliesPrivately reports whether the reply to a command is only shown to
+// the user who sent it (implements chat.PrivateReplier). Link codes, pairing
+// requests and usage are answered ephemerally, wherever they are asked for.
+func (b *Bot) RepliesPrivately(channelID, command string) bool {
+	return command == "link" || command == "pair" || command == "usage"
+}
+
 // SendMessage sends a message to a channel or user
 func (b *Bot) SendMessage(target, content string) error {
 	// Handle user: or channel: prefixes
@@ -246,6 +443,49 @@ func (b *Bot) SendMessage(target, content string) error {
 	return err
 }
 
+// SendFile uploads a file to a channel or user, with an optional caption.
+func (b *Bot) SendFile(target, path, caption string) error {
+	channelID := strings.TrimPrefix(target, "channel:")
+	if strings.HasPrefix(target, "user:") {
+		channel, err := b.session.UserChannelCreate(strings.TrimPrefix(target, "user:"))
+		if err != nil {
+			return fmt.Errorf("failed to create DM channel: %w", err)
+		}
+		channelID = channel.ID
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	name := filepath.Base(path)
+	_, err = b.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content: caption,
+		Files: []*discordgo.File{{
+			Name:        name,
+			ContentType: mime.TypeByExtension(filepath.Ext(name)),
+			Reader:      f,
+		}},
+	})
+	return err
+}
+
+// SendResponse delivers a response to a channel or user the same way replies
+// are sent: split into chunks, with thinking and tool calls as embeds.
+func (b *Bot) SendResponse(target string, resp *chat.ChatResponse) error {
+	channelID := strings.TrimPrefix(target, "channel:")
+	if strings.HasPrefix(target, "user:") {
+		channel, err := b.session.UserChannelCreate(strings.TrimPrefix(target, "user:"))
+		if err != nil {
+			return fmt.Errorf("failed to create DM channel: %w", err)
+		}
+		channelID = channel.ID
+	}
+	return b.sendRichResponse(b.session, channelID, "", resp)
+}
+
 // sendDM sends a direct message to a user
 func (b *Bot) sendDM(userID, content string) error {
 	channel, err := b.session.UserChannelCreate(userID)
@@ -264,10 +504,19 @@ func (b *Bot) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate)
 		return
 	}
 
-	// Check if user is allowed (empty map = all allowed)
+	// Check if user is allowed (empty map = all allowed). Other users may
+	// ask for access in a DM.
 	b.mu.RLock()
 	if len(b.allowedUsers) > 0 && !b.allowedUsers[m.Author.ID] {
+		pairing := b.pairing
 		b.mu.RUnlock()
+		if pairing != nil && m.GuildID == "" {
+			if reply := pairing("discord", m.ChannelID, m.Author.ID, m.Author.Username); reply != "" {
+				if _, err := s.ChannelMessageSend(m.ChannelID, reply); err != nil {
+					b.log.Warn("Failed to reply to pairing request: %v", err)
+				}
+			}
+		}
 		return
 	}
 
@@ -280,54 +529,85 @@ func (b *Bot) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate)
 	}
 
 	handler := b.handler
+	streamHandler := b.streamHandler
 	b.mu.RUnlock()
 
-	if handler == nil {
+	if handler == nil && streamHandler == nil {
 		return
 	}
 
 	// Show typing indicator while waiting for the AI to respond.
 	// Discord typing indicators last ~10 seconds, so we re-send every 8s
-	// in a background goroutine until the handler returns.
-	stopTyping := make(chan struct{})
-	go func() {
-		// Send initial typing indicator immediately
-		if err := s.ChannelTyping(m.ChannelID); err != nil {
-			log.Printf("Error sending typing indicator: %v", err)
-		}
-		ticker := time.NewTicker(8 * time.Second)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-stopTyping:
-				return
-			case <-ticker.C:
-				if err := s.ChannelTyping(m.ChannelID); err != nil {
-					log.Printf("Error sending typing indicator: %v", err)
-				}
-			}
+	// until the handler returns.
+	stopTyping := chat.KeepTyping(func() error {
+		return s.ChannelTyping(m.ChannelID)
+	}, 8*time.Second, b.log)
+
+	// With a streaming handler, post a placeholder and edit it as the reply
+	// is written. If the placeholder can't be posted, the reply is sent as a
+	// new message at the end as usual.
+	var placeholderID string
+	var live *chat.LiveMessage
+	if streamHandler != nil {
+		if msg, err := s.ChannelMessageSend(m.ChannelID, chat.Placeholder); err != nil {
+			b.log.Warn("Failed to post placeholder message: %v", err)
+		} else {
+			placeholderID = msg.ID
+			live = chat.NewLiveMessage(func(text string) error {
+				_, err := s.ChannelMessageEdit(m.ChannelID, placeholderID, text)
+				return err
+			}, chat.DefaultEditInterval, maxMessageLen, b.log)
 		}
-	}()
+	}
 
 	// Call the message handler with provider name
-	response, err := handler("discord", m.ChannelID, m.Author.ID, m.Content)
-	close(stopTyping)
-	if err != nil {
-		log.Printf("Error handling message: %v", err)
+	attachments := messageAttachments(m.Message)
+	var response *chat.ChatResponse
+	var err error
+	if streamHandler != nil {
+		response, err = streamHandler("discord", m.ChannelID, m.Author.ID, m.Content, attachments, live.Update)
+	} else {
+		response, err = handler("discord", m.ChannelID, m.Author.ID, m.Content, attachments)
+	}
+	live.Stop()
+	stopTyping()
+
+	if err != nil || response == nil || response.Text == "" {
+		if err != nil {
+			b.log.Error("Error handling message: %v", err)
+		}
+		if placeholderID != "" {
+			if err := s.ChannelMessageDelete(m.ChannelID, placeholderID); err != nil {
+				b.log.Debug("Failed to delete placeholder message: %v", err)
+			}
+		}
 		return
 	}
 
-	// Send response if not empty
-	if response != nil && response.Text != "" {
-		if err := b.sendRichResponse(s, m.ChannelID, response); err != nil {
-			log.Printf("Error sending response: %v", err)
-		}
+	if err := b.sendRichResponse(s, m.ChannelID, placeholderID, response); err != nil {
+		chat.ResponseLogger(b.log, response).Error("Error sending response: %v", err)
 	}
 }
 
+// messageAttachments converts a message's attachments. Discord attachment
+// URLs are signed CDN links that need no authentication.
+func messageAttachments(m *discordgo.Message) []chat.Attachment {
+	var atts []chat.Attachment
+	for _, a := range m.Attachments {
+		atts = append(atts, chat.Attachment{
+			Name:        a.Filename,
+			ContentType: a.ContentType,
+			Size:        int64(a.Size),
+			Fetch:       chat.FetchURL(a.URL, nil),
+		})
+	}
+	return atts
+}
+
 // sendRichResponse sends a ChatResponse to Discord with optional embeds for
-// thinking blocks and tool calls.
-func (b *Bot) sendRichResponse(s *discordgo.Session, channelID string, resp *chat.ChatResponse) error {
+// thinking blocks and tool calls. If replaceID is set, the first message
+// replaces that (placeholder) message instead of being sent anew.
+func (b *Bot) sendRichResponse(s *discordgo.Session, channelID, replaceID string, resp *chat.ChatResponse) error {
 	// Build embeds from thinking and tool call data
 	var embeds []*discordgo.MessageEmbed
 
@@ -364,7 +644,7 @@ func (b *Bot) sendRichResponse(s *discordgo.Session, channelID string, resp *cha
 
 	// Discord limits: 2000 chars per message content, 10 embeds per message.
 	// Split text into chunks if needed.
-	textChunks := splitText(resp.Text, 2000)
+	textChunks := splitText(resp.Text, maxMessageLen)
 
 	if len(textChunks) == 0 {
 		textChunks = []string{""}
@@ -379,10 +659,19 @@ func (b *Bot) sendRichResponse(s *discordgo.Session, channelID string, resp *cha
 	}
 
 	// Send the first message with embeds
-	_, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
-		Content: textChunks[0],
-		Embeds:  firstEmbeds,
-	})
+	var err error
+	if replaceID != "" {
+		edit := discordgo.NewMessageEdit(channelID, replaceID).SetContent(textChunks[0])
+		if len(firstEmbeds) > 0 {
+			edit.Embeds = &firstEmbeds
+		}
+		_, err = s.ChannelMessageEditComplex(edit)
+	} else {
+		_, err = s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+			Content: textChunks[0],
+			Embeds:  firstEmbeds,
+		})
+	}
 	if err != nil {
 		return fmt.Errorf("failed to send message: %w", err)
 	}
@@ -2,6 +2,8 @@ package discord
 
 import (
 	"testing"
+
+	"github.com/bwmarrin/discordgo"
 )
 
 func TestConfigAllowedMaps(t *testing.T) {
@@ -163,3 +165,18 @@ func TestSplitText(t *testing.T) {
 		})
 	}
 }
+
+func TestPairArgs(t *testing.T) {
+	// Discord sends options in the order the user filled them in
+	options := []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "days", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(7)},
+		{Name: "code", Type: discordgo.ApplicationCommandOptionString, Value: "ABCD1234"},
+		{Name: "action", Type: discordgo.ApplicationCommandOptionString, Value: "approve"},
+	}
+	if got := pairArgs(options); got != "approve ABCD1234 7" {
+		t.Errorf("pairArgs = %q, want %q", got, "approve ABCD1234 7")
+	}
+	if got := pairArgs(nil); got != "" {
+		t.Errorf("pairArgs(nil) = %q, want empty", got)
+	}
+}
@@ -2,8 +2,11 @@
 package slack
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 
@@ -12,9 +15,18 @@ import (
 	"github.com/slack-go/slack/socketmode"
 
 	"github.com/open-pact/openpact/internal/chat"
+	"github.com/open-pact/openpact/internal/logging"
 )
 
-var _ chat.Provider = (*Bot)(nil)
+var (
+	_ chat.StreamingProvider = (*Bot)(nil)
+	_ chat.FileSender        = (*Bot)(nil)
+	_ chat.ApprovalPrompter  = (*Bot)(nil)
+)
+
+// maxLiveLen caps the live message text; Slack recommends keeping message
+// text under 4,000 characters.
+const maxLiveLen = 4000
 
 // Config holds Slack bot configuration.
 type Config struct {
@@ -22,6 +34,7 @@ type Config struct {
 	AppToken     string
 	AllowedUsers []string
 	AllowedChans []string
+	Logger       *logging.Logger // Optional; defaults to logging.Standard()
 }
 
 // Bot represents a Slack bot using Socket Mode.
@@ -29,13 +42,18 @@ type Bot struct {
 	client       *slacklib.Client
 	socketClient *socketmode.Client
 	handler      chat.MessageHandler
+	streamer     chat.StreamingMessageHandler
 	cmdHandler   chat.CommandHandler
+	approvals    chat.ApprovalHandler
+	pairing      chat.PairingHandler
 	allowedUsers map[string]bool
 	allowedChans map[string]bool
 	botUserID    string
 	stopCh       chan struct{}
 	done         chan struct{}
+	turns        chat.Queue // Messages by channel, handled in order
 	mu           sync.RWMutex
+	log          *logging.Logger
 }
 
 // New creates a new Slack bot.
@@ -55,11 +73,17 @@ func New(cfg Config) (*Bot, error) {
 		allowedChans[c] = true
 	}
 
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logging.Standard()
+	}
+
 	return &Bot{
 		client:       client,
 		socketClient: socketClient,
 		allowedUsers: allowed,
 		allowedChans: allowedChans,
+		log:          logger.WithField("provider", "slack"),
 	}, nil
 }
 
@@ -73,6 +97,14 @@ func (b *Bot) SetMessageHandler(h chat.MessageHandler) {
 	b.mu.Unlock()
 }
 
+// SetStreamingMessageHandler registers the callback used to show replies as
+// they are written. It takes precedence over SetMessageHandler.
+func (b *Bot) SetStreamingMessageHandler(h chat.StreamingMessageHandler) {
+	b.mu.Lock()
+	b.streamer = h
+	b.mu.Unlock()
+}
+
 // SetCommandHandler registers the callback for incoming commands.
 func (b *Bot) SetCommandHandler(h chat.CommandHandler) {
 	b.mu.Lock()
@@ -80,6 +112,32 @@ func (b *Bot) SetCommandHandler(h chat.CommandHandler) {
 	b.mu.Unlock()
 }
 
+// SetApprovalHandler registers the callback for approval prompt buttons.
+func (b *Bot) SetApprovalHandler(h chat.ApprovalHandler) {
+	b.mu.Lock()
+	b.approvals = h
+	b.mu.Unlock()
+}
+
+// SetPairingHandler registers the callback for direct messages from users
+// who aren't allowed.
+func (b *Bot) SetPairingHandler(h chat.PairingHandler) {
+	b.mu.Lock()
+	b.pairing = h
+	b.mu.Unlock()
+}
+
+// SetAllowedUsers replaces the users allowed to talk to the bot.
+func (b *Bot) SetAllowedUsers(users []string) {
+	allowed := make(map[string]bool, len(users))
+	for _, u := range users {
+		allowed[u] = true
+	}
+	b.mu.Lock()
+	b.allowedUsers = allowed
+	b.mu.Unlock()
+}
+
 // Start connects to Slack via Socket Mode and begins listening.
 func (b *Bot) Start() error {
 	authResp, err := b.client.AuthTest()
@@ -87,7 +145,7 @@ func (b *Bot) Start() error {
 		return fmt.Errorf("slack auth test failed: %w", err)
 	}
 	b.botUserID = authResp.UserID
-	log.Printf("Slack bot connected as %s", authResp.User)
+	b.log.Info("Slack bot connected as %s", authResp.User)
 
 	b.stopCh = make(chan struct{})
 	b.done = make(chan struct{})
@@ -95,7 +153,7 @@ func (b *Bot) Start() error {
 	go b.handleEvents()
 	go func() {
 		if err := b.socketClient.Run(); err != nil {
-			log.Printf("Slack socket mode error: %v", err)
+			b.log.Error("Slack socket mode error: %v", err)
 		}
 	}()
 
@@ -136,6 +194,14 @@ func (b *Bot) handleEvents() {
 					continue
 				}
 				b.handleSlashCommand(cmd, evt)
+
+			case socketmode.EventTypeInteractive:
+				callback, ok := evt.Data.(slacklib.InteractionCallback)
+				if !ok {
+					continue
+				}
+				b.socketClient.Ack(*evt.Request)
+				b.handleInteraction(callback)
 			}
 		}
 	}
@@ -148,13 +214,20 @@ func (b *Bot) handleEventsAPI(event slackevents.EventsAPIEvent) {
 
 	switch ev := event.InnerEvent.Data.(type) {
 	case *slackevents.MessageEvent:
-		if ev.User == b.botUserID || ev.SubType != "" {
+		// Messages with files arrive with the "file_share" subtype; other
+		// subtypes are edits, joins, bot messages and the like
+		if ev.User == b.botUserID || (ev.SubType != "" && ev.SubType != "file_share") {
 			return
 		}
 
 		b.mu.RLock()
 		if len(b.allowedUsers) > 0 && !b.allowedUsers[ev.User] {
+			// Other users may ask for access in a DM
+			pairing := b.pairing
 			b.mu.RUnlock()
+			if pairing != nil && ev.ChannelType == "im" {
+				go b.replyToPairing(pairing, ev)
+			}
 			return
 		}
 		if len(b.allowedChans) > 0 && !b.allowedChans[ev.Channel] {
@@ -162,23 +235,104 @@ func (b *Bot) handleEventsAPI(event slackevents.EventsAPIEvent) {
 			return
 		}
 		handler := b.handler
+		streamer := b.streamer
 		b.mu.RUnlock()
 
-		if handler == nil {
+		if handler == nil && streamer == nil {
 			return
 		}
 
-		response, err := handler("slack", ev.Channel, ev.User, ev.Text)
+		// Each channel's turns run in order, off the event loop so that it
+		// still sees approval buttons pressed while a turn waits on them
+		b.turns.Run(ev.Channel, func() { b.handleMessage(ev, handler, streamer) })
+	}
+}
+
+// replyToPairing passes a DM from a user who isn't allowed to the pairing
+// handler and posts its reply.
+func (b *Bot) replyToPairing(pairing chat.PairingHandler, ev *slackevents.MessageEvent) {
+	reply := pairing("slack", ev.Channel, ev.User, ev.Username)
+	if reply == "" {
+		return
+	}
+	if _, _, err := b.client.PostMessage(ev.Channel, slacklib.MsgOptionText(reply, false)); err != nil {
+		b.log.Warn("Failed to reply to pairing request: %v", err)
+	}
+}
+
+// handleMessage runs the message handler and posts the reply. With a
+// streaming handler, a placeholder is posted and updated as the reply is
+// written; it also stands in for a typing indicator, which Slack does not
+// offer to bots over the Web API.
+func (b *Bot) handleMessage(ev *slackevents.MessageEvent, handler chat.MessageHandler, streamer chat.StreamingMessageHandler) {
+	var placeholderTS string
+	var live *chat.LiveMessage
+	if streamer != nil {
+		if _, ts, err := b.client.PostMessage(ev.Channel, slacklib.MsgOptionText(chat.Placeholder, false)); err != nil {
+			b.log.Warn("Failed to post placeholder message: %v", err)
+		} else {
+			placeholderTS = ts
+			live = chat.NewLiveMessage(func(text string) error {
+				_, _, _, err := b.client.UpdateMessage(ev.Channel, placeholderTS, slacklib.MsgOptionText(text, false))
+				return err
+			}, chat.DefaultEditInterval, maxLiveLen, b.log)
+		}
+	}
+
+	attachments := b.messageAttachments(ev)
+	var response *chat.ChatResponse
+	var err error
+	if streamer != nil {
+		response, err = streamer("slack", ev.Channel, ev.User, ev.Text, attachments, live.Update)
+	} else {
+		response, err = handler("slack", ev.Channel, ev.User, ev.Text, attachments)
+	}
+	live.Stop()
+
+	if err != nil || response == nil || response.Text == "" {
 		if err != nil {
-			log.Printf("Error handling Slack message: %v", err)
-			return
+			b.log.Error("Error handling Slack message: %v", err)
 		}
-		if response != nil && response.Text != "" {
-			if _, _, err := b.client.PostMessage(ev.Channel, slacklib.MsgOptionText(response.Text, false)); err != nil {
-				log.Printf("Error sending Slack response: %v", err)
+		if placeholderTS != "" {
+			if _, _, err := b.client.DeleteMessage(ev.Channel, placeholderTS); err != nil {
+				b.log.Debug("Failed to delete placeholder message: %v", err)
 			}
 		}
+		return
+	}
+
+	if placeholderTS != "" {
+		_, _, _, err = b.client.UpdateMessage(ev.Channel, placeholderTS, slacklib.MsgOptionText(response.Text, false))
+	} else {
+		_, _, err = b.client.PostMessage(ev.Channel, slacklib.MsgOptionText(response.Text, false))
+	}
+	if err != nil {
+		chat.ResponseLogger(b.log, response).Error("Error sending Slack response: %v", err)
+	}
+}
+
+// messageAttachments converts the files shared with a message. Slack file
+// URLs are private and are downloaded with the bot token.
+func (b *Bot) messageAttachments(ev *slackevents.MessageEvent) []chat.Attachment {
+	if ev.Message == nil {
+		return nil
+	}
+	var atts []chat.Attachment
+	for _, f := range ev.Message.Files {
+		url := f.URLPrivateDownload
+		if url == "" {
+			url = f.URLPrivate
+		}
+		atts = append(atts, chat.Attachment{
+			Name:        f.Name,
+			ContentType: f.Mimetype,
+			Size:        int64(f.Size),
+			Fetch: func(ctx context.Context, w io.Writer) error {
+				return b.client.GetFileContext(ctx, url, w)
+			},
+		})
 	}
+	return atts
 }
 
 func (b *Bot) handleSlashCommand(cmd slacklib.SlashCommand, evt socketmode.Event) {
@@ -209,6 +363,76 @@ func (b *Bot) handleSlashCommand(cmd slacklib.SlashCommand, evt socketmode.Event
 	})
 }
 
+// handleInteraction answers approval prompt buttons. The prompt is replaced
+// with the outcome; errors are shown only to the user who pressed the button.
+func (b *Bot) handleInteraction(callback slacklib.InteractionCallback) {
+	if callback.Type != slacklib.InteractionTypeBlockActions {
+		return
+	}
+
+	b.mu.RLock()
+	handler := b.approvals
+	b.mu.RUnlock()
+	if handler == nil {
+		return
+	}
+
+	for _, action := range callback.ActionCallback.BlockActions {
+		approvalID, approved, ok := chat.ParseApprovalAction(action.ActionID)
+		if !ok {
+			continue
+		}
+
+		channelID := callback.Channel.ID
+		result, err := handler("slack", callback.User.ID, approvalID, approved)
+		if err != nil {
+			if _, err := b.client.PostEphemeral(channelID, callback.User.ID, slacklib.MsgOptionText(err.Error(), false)); err != nil {
+				b.log.Warn("Failed to post approval error: %v", err)
+			}
+			return
+		}
+		_, _, _, err = b.client.UpdateMessage(channelID, callback.Container.MessageTs,
+			slacklib.MsgOptionText(result, false),
+			slacklib.MsgOptionBlocks(slacklib.NewSectionBlock(slacklib.NewTextBlockObject(slacklib.MarkdownType, result, false, false), nil, nil)),
+		)
+		if err != nil {
+			b.log.Warn("Failed to update approval prompt: %v", err)
+		}
+		return
+	}
+}
+
+// SendApprovalPrompt posts an approval prompt with Approve and Deny buttons.
+func (b *Bot) SendApprovalPrompt(target string, prompt chat.ApprovalPrompt) error {
+	target = strings.TrimPrefix(target, "user:")
+	target = strings.TrimPrefix(target, "channel:")
+
+	text := prompt.Text
+	if len(text) > 3000 {
+		text = text[:2999] + "…" // Section text limit
+	}
+	approve := slacklib.NewButtonBlockElement(chat.ApprovalActionID(prompt.ID, true), "approve",
+		slacklib.NewTextBlockObject(slacklib.PlainTextType, "Approve", false, false)).WithStyle(slacklib.StylePrimary)
+	deny := slacklib.NewButtonBlockElement(chat.ApprovalActionID(prompt.ID, false), "deny",
+		slacklib.NewTextBlockObject(slacklib.PlainTextType, "Deny", false, false)).WithStyle(slacklib.StyleDanger)
+
+	_, _, err := b.client.PostMessage(target,
+		slacklib.MsgOptionText(prompt.Text, false),
+		slacklib.MsgOptionBlocks(
+			slacklib.NewSectionBlock(slacklib.NewTextBlockObject(slacklib.MarkdownType, text, false, false), nil, nil),
+			slacklib.NewActionBlock("openpact_approval", approve, deny),
+		),
+	)
+	return err
+}
+
+// RepliesPrivately reports whether the reply to a command is only shown to
+// the user who sent it (implements chat.PrivateReplier). Slash commands are
+// always answered ephemerally.
+func (b *Bot) RepliesPrivately(channelID, command string) bool {
+	return true
+}
+
 // SendMessage sends a message to a Slack channel or user.
 func (b *Bot) SendMessage(target, content string) error {
 	target = strings.TrimPrefix(target, "user:")
@@ -216,3 +440,31 @@ func (b *Bot) SendMessage(target, content string) error {
 	_, _, err := b.client.PostMessage(target, slacklib.MsgOptionText(content, false))
 	return err
 }
+
+// SendFile uploads a file to a Slack channel or user, with an optional
+// caption. Uploads need a conversation ID, so a DM is opened for user targets.
+func (b *Bot) SendFile(target, path, caption string) error {
+	channelID := strings.TrimPrefix(target, "channel:")
+	if strings.HasPrefix(target, "user:") {
+		channel, _, _, err := b.client.OpenConversation(&slacklib.OpenConversationParameters{
+			Users: []string{strings.TrimPrefix(target, "user:")},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to open DM: %w", err)
+		}
+		channelID = channel.ID
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	_, err = b.client.UploadFile(slacklib.UploadFileParameters{
+		File:           path,
+		FileSize:       int(info.Size()),
+		Filename:       filepath.Base(path),
+		InitialComment: caption,
+		Channel:        channelID,
+	})
+	return err
+}
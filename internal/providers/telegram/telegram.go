@@ -2,32 +2,53 @@
 package telegram
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/open-pact/openpact/internal/chat"
+	"github.com/open-pact/openpact/internal/logging"
 )
 
-var _ chat.Provider = (*Bot)(nil)
+var (
+	_ chat.StreamingProvider = (*Bot)(nil)
+	_ chat.FileSender        = (*Bot)(nil)
+	_ chat.ApprovalPrompter  = (*Bot)(nil)
+)
+
+// maxMessageLen is Telegram's limit on message text length.
+const maxMessageLen = 4096
+
+// editInterval is the minimum time between live message edits. Telegram
+// limits bots to about 20 messages per minute in groups, and edits count.
+const editInterval = 3 * time.Second
 
 // Config holds Telegram bot configuration.
 type Config struct {
 	Token        string
 	AllowedUsers []string
+	Logger       *logging.Logger // Optional; defaults to logging.Standard()
 }
 
 // Bot represents a Telegram bot.
 type Bot struct {
 	api            *tgbotapi.BotAPI
 	handler        chat.MessageHandler
+	streamHandler  chat.StreamingMessageHandler
 	commandHandler chat.CommandHandler
+	approvals      chat.ApprovalHandler
+	pairing        chat.PairingHandler
 	allowedUsers   map[string]bool
 	stopCh         chan struct{}
+	turns          chat.Queue // Messages by chat, handled in order
 	mu             sync.RWMutex
+	log            *logging.Logger
 }
 
 // New creates a new Telegram bot.
@@ -42,10 +63,16 @@ func New(cfg Config) (*Bot, error) {
 		allowed[u] = true
 	}
 
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logging.Standard()
+	}
+
 	return &Bot{
 		api:          api,
 		allowedUsers: allowed,
 		stopCh:       make(chan struct{}),
+		log:          logger.WithField("provider", "telegram"),
 	}, nil
 }
 
@@ -59,6 +86,14 @@ func (b *Bot) SetMessageHandler(h chat.MessageHandler) {
 	b.mu.Unlock()
 }
 
+// SetStreamingMessageHandler registers the callback used to show replies as
+// they are written. It takes precedence over SetMessageHandler.
+func (b *Bot) SetStreamingMessageHandler(h chat.StreamingMessageHandler) {
+	b.mu.Lock()
+	b.streamHandler = h
+	b.mu.Unlock()
+}
+
 // SetCommandHandler registers the callback for incoming commands.
 func (b *Bot) SetCommandHandler(h chat.CommandHandler) {
 	b.mu.Lock()
@@ -66,22 +101,55 @@ func (b *Bot) SetCommandHandler(h chat.CommandHandler) {
 	b.mu.Unlock()
 }
 
+// SetApprovalHandler registers the callback for approval prompt buttons.
+func (b *Bot) SetApprovalHandler(h chat.ApprovalHandler) {
+	b.mu.Lock()
+	b.approvals = h
+	b.mu.Unlock()
+}
+
+// SetPairingHandler registers the callback for direct messages from users
+// who aren't allowed.
+func (b *Bot) SetPairingHandler(h chat.PairingHandler) {
+	b.mu.Lock()
+	b.pairing = h
+	b.mu.Unlock()
+}
+
+// SetAllowedUsers replaces the users allowed to talk to the bot.
+func (b *Bot) SetAllowedUsers(users []string) {
+	allowed := make(map[string]bool, len(users))
+	for _, u := range users {
+		allowed[u] = true
+	}
+	b.mu.Lock()
+	b.allowedUsers = allowed
+	b.mu.Unlock()
+}
+
 // Start connects to Telegram and begins listening for updates.
 func (b *Bot) Start() error {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 	updates := b.api.GetUpdatesChan(u)
 
-	log.Printf("Telegram bot connected as @%s", b.api.Self.UserName)
+	b.log.Info("Telegram bot connected as @%s", b.api.Self.UserName)
 
 	go func() {
 		for {
 			select {
 			case update := <-updates:
+				if update.CallbackQuery != nil {
+					b.handleCallbackQuery(update.CallbackQuery)
+					continue
+				}
 				if update.Message == nil {
 					continue
 				}
-				b.handleUpdate(update)
+				// Each chat's turns run in order, off this loop so that
+				// approval buttons pressed while a turn waits on them are
+				// still received
+				b.turns.Run(strconv.FormatInt(update.Message.Chat.ID, 10), func() { b.handleUpdate(update) })
 			case <-b.stopCh:
 				return
 			}
@@ -105,7 +173,14 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 
 	b.mu.RLock()
 	if len(b.allowedUsers) > 0 && !b.allowedUsers[userID] && !b.allowedUsers[msg.From.UserName] {
+		// Other users may ask for access in a private chat
+		pairing := b.pairing
 		b.mu.RUnlock()
+		if pairing != nil && msg.Chat.IsPrivate() {
+			if reply := pairing("telegram", chatID, userID, msg.From.UserName); reply != "" {
+				b.sendReply(b.log, msg.Chat.ID, reply)
+			}
+		}
 		return
 	}
 
@@ -120,42 +195,187 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 			response = fmt.Sprintf("Error: %v", err)
 		}
 		if response != "" {
-			b.sendReply(msg.Chat.ID, response)
+			b.sendReply(b.log, msg.Chat.ID, response)
 		}
 		return
 	}
 
 	handler := b.handler
+	streamHandler := b.streamHandler
+	b.mu.RUnlock()
+	if handler == nil && streamHandler == nil {
+		return
+	}
+
+	// Typing indicators expire after 5 seconds, so refresh every 4s
+	stopTyping := chat.KeepTyping(func() error {
+		_, err := b.api.Request(tgbotapi.NewChatAction(msg.Chat.ID, tgbotapi.ChatTyping))
+		return err
+	}, 4*time.Second, b.log)
+
+	// With a streaming handler, post a placeholder and edit it as the reply
+	// is written. If the placeholder can't be posted, the reply is sent as
+	// new messages at the end as usual.
+	placeholderID := 0
+	var live *chat.LiveMessage
+	if streamHandler != nil {
+		if sent, err := b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, chat.Placeholder)); err != nil {
+			b.log.Warn("Failed to post placeholder message: %v", err)
+		} else {
+			placeholderID = sent.MessageID
+			live = chat.NewLiveMessage(func(text string) error {
+				_, err := b.api.Send(tgbotapi.NewEditMessageText(msg.Chat.ID, placeholderID, text))
+				return err
+			}, editInterval, maxMessageLen, b.log)
+		}
+	}
+
+	// Media messages carry their text in the caption
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+	attachments := b.messageAttachments(msg)
+
+	var response *chat.ChatResponse
+	var err error
+	if streamHandler != nil {
+		response, err = streamHandler("telegram", chatID, userID, text, attachments, live.Update)
+	} else {
+		response, err = handler("telegram", chatID, userID, text, attachments)
+	}
+	live.Stop()
+	stopTyping()
+
+	if err != nil || response == nil || response.Text == "" {
+		if err != nil {
+			b.log.Error("Error handling Telegram message: %v", err)
+		}
+		if placeholderID != 0 {
+			if _, err := b.api.Request(tgbotapi.NewDeleteMessage(msg.Chat.ID, placeholderID)); err != nil {
+				b.log.Debug("Failed to delete placeholder message: %v", err)
+			}
+		}
+		return
+	}
+
+	logger := chat.ResponseLogger(b.log, response)
+	if placeholderID == 0 {
+		b.sendReply(logger, msg.Chat.ID, response.Text)
+		return
+	}
+
+	// Replace the placeholder with the first chunk and send the rest
+	first, rest := splitFirst(response.Text, maxMessageLen)
+	if _, err := b.api.Send(tgbotapi.NewEditMessageText(msg.Chat.ID, placeholderID, first)); err != nil && !strings.Contains(err.Error(), "message is not modified") {
+		logger.Error("Error editing Telegram message: %v", err)
+	}
+	b.sendReply(logger, msg.Chat.ID, rest)
+}
+
+// handleCallbackQuery answers approval prompt buttons. The prompt is edited
+// to show the outcome, which also removes its keyboard; errors are shown as
+// a notification to the user who pressed the button.
+func (b *Bot) handleCallbackQuery(query *tgbotapi.CallbackQuery) {
+	approvalID, approved, ok := chat.ParseApprovalAction(query.Data)
+	if !ok || query.From == nil {
+		return
+	}
+	userID := strconv.FormatInt(query.From.ID, 10)
+
+	b.mu.RLock()
+	handler := b.approvals
+	allowed := len(b.allowedUsers) == 0 || b.allowedUsers[userID] || b.allowedUsers[query.From.UserName]
 	b.mu.RUnlock()
-	if handler == nil {
+	if handler == nil || !allowed {
 		return
 	}
 
-	response, err := handler("telegram", chatID, userID, msg.Text)
+	result, err := handler("telegram", userID, approvalID, approved)
 	if err != nil {
-		log.Printf("Error handling Telegram message: %v", err)
+		if _, err := b.api.Request(tgbotapi.NewCallback(query.ID, err.Error())); err != nil {
+			b.log.Warn("Failed to answer callback query: %v", err)
+		}
 		return
 	}
-	if response != nil && response.Text != "" {
-		b.sendReply(msg.Chat.ID, response.Text)
+	if _, err := b.api.Request(tgbotapi.NewCallback(query.ID, "")); err != nil {
+		b.log.Debug("Failed to answer callback query: %v", err)
+	}
+	if query.Message != nil {
+		edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, result)
+		if _, err := b.api.Send(edit); err != nil {
+			b.log.Warn("Failed to update approval prompt: %v", err)
+		}
+	}
+}
+
+// messageAttachments converts the photo, document, voice note or audio file
+// of a message. Telegram sends photos in several sizes; only the largest is
+// kept.
+func (b *Bot) messageAttachments(msg *tgbotapi.Message) []chat.Attachment {
+	var atts []chat.Attachment
+	if n := len(msg.Photo); n > 0 {
+		p := msg.Photo[n-1]
+		atts = append(atts, b.attachment(p.FileID, "photo.jpg", "image/jpeg", p.FileSize))
+	}
+	if d := msg.Document; d != nil {
+		atts = append(atts, b.attachment(d.FileID, d.FileName, d.MimeType, d.FileSize))
+	}
+	if v := msg.Voice; v != nil {
+		atts = append(atts, b.attachment(v.FileID, "voice.ogg", v.MimeType, v.FileSize))
+	}
+	if a := msg.Audio; a != nil {
+		atts = append(atts, b.attachment(a.FileID, a.FileName, a.MimeType, a.FileSize))
 	}
+	return atts
 }
 
-func (b *Bot) sendReply(chatID int64, content string) {
+// attachment describes a Telegram file. The download URL contains the bot
+// token, so it is only resolved when the file is fetched and never logged.
+func (b *Bot) attachment(fileID, name, contentType string, size int) chat.Attachment {
+	return chat.Attachment{
+		Name:        name,
+		ContentType: contentType,
+		Size:        int64(size),
+		Fetch: func(ctx context.Context, w io.Writer) error {
+			url, err := b.api.GetFileDirectURL(fileID)
+			if err != nil {
+				return fmt.Errorf("failed to resolve file: %w", err)
+			}
+			return chat.FetchURL(url, nil)(ctx, w)
+		},
+	}
+}
+
+func (b *Bot) sendReply(logger *logging.Logger, chatID int64, content string) {
 	// Telegram 4096-char limit — split if needed
 	for len(content) > 0 {
-		chunk := content
-		if len(chunk) > 4096 {
-			chunk = chunk[:4096]
-		}
-		content = content[len(chunk):]
+		var chunk string
+		chunk, content = splitFirst(content, maxMessageLen)
 		reply := tgbotapi.NewMessage(chatID, chunk)
 		if _, err := b.api.Send(reply); err != nil {
-			log.Printf("Error sending Telegram message: %v", err)
+			logger.Error("Error sending Telegram message: %v", err)
 		}
 	}
 }
 
+// splitFirst splits s after its first max bytes.
+func splitFirst(s string, max int) (first, rest string) {
+	if len(s) <= max {
+		return s, ""
+	}
+	return s[:max], s[max:]
+}
+
+// RepliesPrivately reports whether the reply to a command is only shown to
+// the user who sent it (implements chat.PrivateReplier). Commands are
+// answered in the chat they were sent in, which is private when its ID is a
+// user's; groups have negative IDs.
+func (b *Bot) RepliesPrivately(channelID, command string) bool {
+	id, err := strconv.ParseInt(channelID, 10, 64)
+	return err == nil && id > 0
+}
+
 // SendMessage sends a message to a Telegram chat.
 func (b *Bot) SendMessage(target, content string) error {
 	target = strings.TrimPrefix(target, "user:")
@@ -164,6 +384,51 @@ func (b *Bot) SendMessage(target, content string) error {
 	if err != nil {
 		return fmt.Errorf("invalid Telegram chat ID %q: %w", target, err)
 	}
-	b.sendReply(chatID, content)
+	b.sendReply(b.log, chatID, content)
 	return nil
 }
+
+// SendApprovalPrompt posts an approval prompt with an inline keyboard of
+// Approve and Deny buttons.
+func (b *Bot) SendApprovalPrompt(target string, prompt chat.ApprovalPrompt) error {
+	target = strings.TrimPrefix(target, "user:")
+	target = strings.TrimPrefix(target, "channel:")
+	chatID, err := strconv.ParseInt(target, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid Telegram chat ID %q: %w", target, err)
+	}
+
+	text, _ := splitFirst(prompt.Text, maxMessageLen)
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Approve", chat.ApprovalActionID(prompt.ID, true)),
+		tgbotapi.NewInlineKeyboardButtonData("Deny", chat.ApprovalActionID(prompt.ID, false)),
+	))
+	_, err = b.api.Send(msg)
+	return err
+}
+
+// SendFile uploads a file to a Telegram chat, with an optional caption.
+// Images are sent as photos; everything else as a document.
+func (b *Bot) SendFile(target, path, caption string) error {
+	target = strings.TrimPrefix(target, "user:")
+	target = strings.TrimPrefix(target, "channel:")
+	chatID, err := strconv.ParseInt(target, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid Telegram chat ID %q: %w", target, err)
+	}
+
+	var upload tgbotapi.Chattable
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".png", ".webp":
+		photo := tgbotapi.NewPhoto(chatID, tgbotapi.FilePath(path))
+		photo.Caption = caption
+		upload = photo
+	default:
+		doc := tgbotapi.NewDocument(chatID, tgbotapi.FilePath(path))
+		doc.Caption = caption
+		upload = doc
+	}
+	_, err = b.api.Send(upload)
+	return err
+}
@@ -139,6 +139,12 @@ func (kl *KeyedLimiter) AllowN(key string, n int) bool {
 	return limiter.AllowN(n)
 }
 
+// Reserve returns how long to wait before n requests for the given key would be allowed
+func (kl *KeyedLimiter) Reserve(key string, n int) time.Duration {
+	limiter := kl.getLimiter(key)
+	return limiter.Reserve(n)
+}
+
 // getLimiter gets or creates a limiter for a key
 func (kl *KeyedLimiter) getLimiter(key string) *Limiter {
 	kl.mu.RLock()
@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Scope identifies what a keyed limiter is applied to.
+type Scope string
+
+const (
+	ScopeUser    Scope = "user"    // per chat provider user (key: "provider:userID")
+	ScopeChannel Scope = "channel" // per chat channel (key: "provider:channelID")
+	ScopeTool    Scope = "tool"    // per MCP tool (key: tool name)
+)
+
+// RejectHook is called whenever a request is rejected.
+type RejectHook func(scope Scope, key string)
+
+// Registry holds one keyed limiter per scope. Scopes without a limiter
+// (rate <= 0 in the config) always allow.
+type Registry struct {
+	mu       sync.RWMutex
+	limiters map[Scope]*KeyedLimiter
+	onReject RejectHook
+	takeAll  sync.Mutex // serializes CheckAll, so its check and take are not interleaved
+}
+
+// Key names the bucket of one scope that a request is counted against.
+type Key struct {
+	Scope Scope
+	Key   string
+}
+
+// NewRegistry creates a registry from per-scope configs. A scope whose Rate is
+// zero or negative is left unlimited.
+func NewRegistry(configs map[Scope]Config) *Registry {
+	r := &Registry{limiters: make(map[Scope]*KeyedLimiter)}
+	for scope, cfg := range configs {
+		if cfg.Rate <= 0 {
+			continue
+		}
+		r.limiters[scope] = NewKeyed(cfg)
+	}
+	return r
+}
+
+// SetRejectHook sets the callback invoked on every rejection (e.g. for metrics).
+func (r *Registry) SetRejectHook(fn RejectHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onReject = fn
+}
+
+// Allow consumes a token for key in scope and reports whether the request may proceed.
+func (r *Registry) Allow(scope Scope, key string) bool {
+	allowed, _ := r.Check(scope, key)
+	return allowed
+}
+
+// Check is like Allow but also returns how long the caller should wait before
+// retrying when the request is rejected.
+func (r *Registry) Check(scope Scope, key string) (bool, time.Duration) {
+	if r == nil {
+		return true, 0
+	}
+
+	r.mu.RLock()
+	kl, ok := r.limiters[scope]
+	hook := r.onReject
+	r.mu.RUnlock()
+
+	if !ok {
+		return true, 0
+	}
+
+	if kl.Allow(key) {
+		return true, 0
+	}
+
+	if hook != nil {
+		hook(scope, key)
+	}
+	return false, kl.Reserve(key, 1)
+}
+
+// CheckAll is like Check for a request counted against several buckets. A
+// token is taken from every bucket only if all of them have one, so a
+// request rejected by one scope does not use up the others. When it is
+// rejected, CheckAll returns the first scope without a token and how long to
+// wait for it.
+func (r *Registry) CheckAll(keys ...Key) (bool, Scope, time.Duration) {
+	if r == nil {
+		return true, "", 0
+	}
+
+	r.mu.RLock()
+	limiters := make([]*KeyedLimiter, len(keys))
+	for i, k := range keys {
+		limiters[i] = r.limiters[k.Scope]
+	}
+	hook := r.onReject
+	r.mu.RUnlock()
+
+	r.takeAll.Lock()
+	defer r.takeAll.Unlock()
+
+	for i, k := range keys {
+		if limiters[i] == nil {
+			continue
+		}
+		if wait := limiters[i].Reserve(k.Key, 1); wait > 0 {
+			if hook != nil {
+				hook(k.Scope, k.Key)
+			}
+			return false, k.Scope, wait
+		}
+	}
+	for i, k := range keys {
+		if limiters[i] != nil {
+			limiters[i].Allow(k.Key)
+		}
+	}
+	return true, "", 0
+}
+
+// Limited reports whether a limiter is configured for scope.
+func (r *Registry) Limited(scope Scope) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.limiters[scope]
+	return ok
+}
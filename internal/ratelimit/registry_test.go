@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"testing"
+)
+
+func TestRegistryScopesAreIndependent(t *testing.T) {
+	r := NewRegistry(map[Scope]Config{
+		ScopeUser:    {Rate: 0.001, Burst: 2},
+		ScopeChannel: {Rate: 0.001, Burst: 1},
+	})
+
+	if !r.Allow(ScopeUser, "discord:u1") || !r.Allow(ScopeUser, "discord:u1") {
+		t.Fatal("first two user requests should be allowed")
+	}
+	if r.Allow(ScopeUser, "discord:u1") {
+		t.Error("third user request should be rate limited")
+	}
+	if !r.Allow(ScopeUser, "discord:u2") {
+		t.Error("a different user should have its own bucket")
+	}
+
+	if !r.Allow(ScopeChannel, "discord:c1") {
+		t.Error("first channel request should be allowed")
+	}
+	if r.Allow(ScopeChannel, "discord:c1") {
+		t.Error("second channel request should be rate limited")
+	}
+}
+
+func TestRegistryUnconfiguredScopeAllows(t *testing.T) {
+	r := NewRegistry(map[Scope]Config{
+		ScopeTool: {Rate: 0, Burst: 1}, // disabled
+	})
+
+	for i := 0; i < 100; i++ {
+		if !r.Allow(ScopeTool, "workspace_read") {
+			t.Fatalf("request %d should be allowed when scope is disabled", i)
+		}
+	}
+	if r.Limited(ScopeTool) {
+		t.Error("tool scope should not be limited")
+	}
+
+	var nilRegistry *Registry
+	if !nilRegistry.Allow(ScopeUser, "x") {
+		t.Error("nil registry should allow everything")
+	}
+}
+
+func TestRegistryCheckAndRejectHook(t *testing.T) {
+	r := NewRegistry(map[Scope]Config{
+		ScopeTool: {Rate: 1, Burst: 1},
+	})
+
+	var rejected []string
+	r.SetRejectHook(func(scope Scope, key string) {
+		rejected = append(rejected, string(scope)+":"+key)
+	})
+
+	if ok, wait := r.Check(ScopeTool, "web_fetch"); !ok || wait != 0 {
+		t.Fatalf("first call: ok=%v wait=%v, want true/0", ok, wait)
+	}
+
+	ok, wait := r.Check(ScopeTool, "web_fetch")
+	if ok {
+		t.Fatal("second call should be rejected")
+	}
+	if wait <= 0 {
+		t.Errorf("expected positive retry wait, got %v", wait)
+	}
+
+	if len(rejected) != 1 || rejected[0] != "tool:web_fetch" {
+		t.Errorf("reject hook calls = %v, want [tool:web_fetch]", rejected)
+	}
+}
+
+func TestRegistryCheckAllTakesAllOrNothing(t *testing.T) {
+	r := NewRegistry(map[Scope]Config{
+		ScopeUser:    {Rate: 0.001, Burst: 2},
+		ScopeChannel: {Rate: 0.001, Burst: 1},
+	})
+
+	var rejected []string
+	r.SetRejectHook(func(scope Scope, key string) {
+		rejected = append(rejected, string(scope)+":"+key)
+	})
+
+	user := Key{ScopeUser, "discord:alice"}
+	if ok, _, _ := r.CheckAll(user, Key{ScopeChannel, "discord:busy"}); !ok {
+		t.Fatal("first message should pass")
+	}
+
+	// The channel is out of tokens, so the user's token is kept
+	ok, scope, wait := r.CheckAll(user, Key{ScopeChannel, "discord:busy"})
+	if ok || scope != ScopeChannel || wait <= 0 {
+		t.Fatalf("expected a channel rejection, got ok=%v scope=%q wait=%v", ok, scope, wait)
+	}
+	if ok, _, _ := r.CheckAll(user, Key{ScopeChannel, "discord:quiet"}); !ok {
+		t.Error("rejected message used up the user's token")
+	}
+	if ok, scope, _ := r.CheckAll(user, Key{ScopeChannel, "discord:other"}); ok || scope != ScopeUser {
+		t.Errorf("expected a user rejection, got ok=%v scope=%q", ok, scope)
+	}
+
+	// Unconfigured scopes always allow
+	if ok, _, _ := r.CheckAll(Key{ScopeTool, "web_fetch"}); !ok {
+		t.Error("unconfigured scope should allow")
+	}
+
+	if len(rejected) != 2 || rejected[0] != "channel:discord:busy" || rejected[1] != "user:discord:alice" {
+		t.Errorf("reject hook calls = %v", rejected)
+	}
+}
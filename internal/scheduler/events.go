@@ -0,0 +1,237 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/open-pact/openpact/internal/admin"
+)
+
+// Event watcher timing. eventPollInterval is shortened in tests.
+var (
+	eventPollInterval       = 15 * time.Second
+	calendarRefreshInterval = 5 * time.Minute
+)
+
+// maxWatchedFiles caps the files scanned per watch root, so a huge vault
+// cannot stall the watcher.
+const maxWatchedFiles = 20000
+
+// calendarFetchTimeout bounds one read of the calendar feeds.
+const calendarFetchTimeout = 30 * time.Second
+
+// watchEvents polls for file changes and upcoming calendar events until the
+// scheduler stops. Webhook runs arrive through Fire instead.
+func (s *Scheduler) watchEvents() {
+	ticker := time.NewTicker(eventPollInterval)
+	defer ticker.Stop()
+	for {
+		s.checkFileTriggers()
+		s.checkCalendarTriggers(time.Now())
+		select {
+		case <-ticker.C:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// eventSchedules returns the enabled schedules using the given trigger.
+func (s *Scheduler) eventSchedules(trigger string) []*admin.Schedule {
+	schedules, err := s.store.List()
+	if err != nil {
+		s.log.Error("Failed to load schedules for %s triggers: %v", trigger, err)
+		return nil
+	}
+	var matched []*admin.Schedule
+	for _, sched := range schedules {
+		if sched.Enabled && sched.Trigger == trigger {
+			matched = append(matched, sched)
+		}
+	}
+	return matched
+}
+
+// checkFileTriggers scans the watch roots used by file_change schedules and
+// fires each schedule whose watch paths match a file added, modified or
+// removed since the previous scan. The first scan of a root only records
+// its state.
+func (s *Scheduler) checkFileTriggers() {
+	schedules := s.eventSchedules(admin.TriggerFileChange)
+
+	s.eventMu.Lock()
+	defer s.eventMu.Unlock()
+
+	used := map[string]bool{}
+	for _, sched := range schedules {
+		for _, p := range sched.WatchPaths {
+			root, _, _ := strings.Cut(p, "/")
+			used[root] = true
+		}
+	}
+
+	var changes []map[string]any
+	next := map[string]map[string]string{}
+	for root := range used {
+		dir := s.watchRoots[root]
+		if dir == "" {
+			continue
+		}
+		files, err := scanWatchRoot(root, dir)
+		if err != nil {
+			s.log.Warn("Failed to scan %s for file triggers: %v", root, err)
+			if prev, ok := s.fileState[root]; ok {
+				next[root] = prev
+			}
+			continue
+		}
+		next[root] = files
+		prev, ok := s.fileState[root]
+		if !ok {
+			continue
+		}
+		for name, version := range files {
+			if old, ok := prev[name]; !ok {
+				changes = append(changes, map[string]any{"path": name, "change": "added"})
+			} else if old != version {
+				changes = append(changes, map[string]any{"path": name, "change": "modified"})
+			}
+		}
+		for name := range prev {
+			if _, ok := files[name]; !ok {
+				changes = append(changes, map[string]any{"path": name, "change": "removed"})
+			}
+		}
+	}
+	s.fileState = next
+	if len(changes) == 0 {
+		return
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i]["path"].(string) < changes[j]["path"].(string)
+	})
+
+	for _, sched := range schedules {
+		var files []any
+		for _, change := range changes {
+			for _, pattern := range sched.WatchPaths {
+				if admin.MatchWatchPath(pattern, change["path"].(string)) {
+					files = append(files, change)
+					break
+				}
+			}
+		}
+		if len(files) > 0 {
+			s.fireEvent(sched, admin.TriggerFileChange, map[string]any{"files": files})
+		}
+	}
+}
+
+// scanWatchRoot returns a version (modification time and size) of every
+// regular file under dir, keyed by its path prefixed with the root name.
+// Hidden files and directories are skipped.
+func scanWatchRoot(root, dir string) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir && os.IsNotExist(err) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if len(files) >= maxWatchedFiles {
+			return fs.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // removed while scanning
+		}
+		rel, _ := filepath.Rel(dir, p)
+		files[path.Join(root, filepath.ToSlash(rel))] = fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+		return nil
+	})
+	return files, err
+}
+
+// checkCalendarTriggers fires each calendar schedule for the events whose
+// lead time (start minus lead_minutes) passed since the previous check. The
+// first check only records the time, so a restart does not repeat runs.
+// Feeds are re-read every calendarRefreshInterval.
+func (s *Scheduler) checkCalendarTriggers(now time.Time) {
+	schedules := s.eventSchedules(admin.TriggerCalendar)
+
+	s.mu.Lock()
+	api := s.calendarAPI
+	s.mu.Unlock()
+
+	s.eventMu.Lock()
+	defer s.eventMu.Unlock()
+
+	since := s.calCheckedAt
+	s.calCheckedAt = now
+	if len(schedules) == 0 || api == nil || since.IsZero() {
+		return
+	}
+
+	maxLead := time.Duration(0)
+	for _, sched := range schedules {
+		maxLead = max(maxLead, time.Duration(sched.LeadMinutes)*time.Minute)
+	}
+	horizon := now.Add(maxLead + calendarRefreshInterval)
+	if now.Sub(s.calFetchedAt) >= calendarRefreshInterval || s.calHorizon.Before(horizon) {
+		ctx, cancel := context.WithTimeout(context.Background(), calendarFetchTimeout)
+		to := horizon.Add(calendarRefreshInterval)
+		events, err := api.CalendarEvents(ctx, since, to)
+		cancel()
+		if err != nil {
+			s.log.Warn("Failed to read calendars for calendar triggers: %v", err)
+		} else {
+			s.calEvents, s.calFetchedAt, s.calHorizon = events, now, to
+		}
+	}
+
+	for _, sched := range schedules {
+		lead := time.Duration(sched.LeadMinutes) * time.Minute
+		for _, ev := range s.calEvents {
+			if sched.Calendar != "" && !strings.EqualFold(sched.Calendar, ev.Calendar) {
+				continue
+			}
+			due := ev.Start.Add(-lead)
+			if !due.After(since) || due.After(now) {
+				continue
+			}
+			event := map[string]any{
+				"calendar":       ev.Calendar,
+				"summary":        ev.Summary,
+				"description":    ev.Description,
+				"location":       ev.Location,
+				"start":          ev.Start.Format(time.RFC3339),
+				"all_day":        ev.AllDay,
+				"minutes_before": sched.LeadMinutes,
+			}
+			if !ev.End.IsZero() {
+				event["end"] = ev.End.Format(time.RFC3339)
+			}
+			s.fireEvent(sched, admin.TriggerCalendar, map[string]any{"event": event})
+		}
+	}
+}
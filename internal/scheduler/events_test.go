@@ -0,0 +1,208 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-pact/openpact/internal/admin"
+	"github.com/open-pact/openpact/internal/engine"
+)
+
+func TestScheduler_FireWebhook(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+
+	script := `result = args["trigger"] + ": " + args["json"]["status"]`
+	if err := os.WriteFile(dir+"/scripts/ci.star", []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sched, err := s.store.Create(&admin.Schedule{
+		Name:       "ci",
+		Trigger:    admin.TriggerWebhook,
+		Type:       "script",
+		ScriptName: "ci.star",
+		Enabled:    true,
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if err := s.Fire(sched.ID, admin.TriggerCalendar, nil); err == nil {
+		t.Error("expected Fire with the wrong trigger to fail")
+	}
+	if err := s.Fire(sched.ID, admin.TriggerWebhook, map[string]any{"json": map[string]any{"status": "failed"}}); err != nil {
+		t.Fatalf("Fire failed: %v", err)
+	}
+
+	runs := waitForRuns(t, s, sched.ID, 1)
+	if runs[0].Trigger != admin.TriggerWebhook || runs[0].Output != "webhook: failed" {
+		t.Errorf("unexpected run: %+v", runs[0])
+	}
+}
+
+func TestScheduler_EventsNotRegisteredWithCron(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+
+	s.store.Create(&admin.Schedule{Name: "hook", Trigger: admin.TriggerWebhook, Type: "script", ScriptName: "x.star", Enabled: true})
+	s.store.Create(&admin.Schedule{Name: "nightly", CronExpr: "0 0 * * *", Type: "script", ScriptName: "x.star", Enabled: true})
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer s.Stop()
+
+	if len(s.entries) != 1 {
+		t.Errorf("expected only the cron schedule to be registered, got %d entries", len(s.entries))
+	}
+}
+
+func TestScheduler_FileTrigger(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+
+	aiData := filepath.Join(dir, "ai-data")
+	os.MkdirAll(filepath.Join(aiData, "inbox"), 0755)
+	os.WriteFile(filepath.Join(aiData, "inbox", "old.md"), []byte("old"), 0644)
+	s.watchRoots = map[string]string{"ai-data": aiData}
+
+	prompts := make(chan string, 10)
+	s.SetEngineAPI(&mockEngine{
+		createSessionFn: func() (*engine.Session, error) {
+			return &engine.Session{ID: "inbox-session"}, nil
+		},
+		sendFn: func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+			prompts <- messages[0].Content
+			ch := make(chan engine.Response, 1)
+			ch <- engine.Response{Content: "filed"}
+			close(ch)
+			return ch, nil
+		},
+	})
+
+	sched, err := s.store.Create(&admin.Schedule{
+		Name:       "inbox",
+		Trigger:    admin.TriggerFileChange,
+		WatchPaths: []string{"ai-data/inbox/*.md"},
+		Type:       "agent",
+		Prompt:     "File the new notes",
+		Enabled:    true,
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// The first scan records the current files
+	s.checkFileTriggers()
+	if runs, _ := s.store.ListRuns(sched.ID, 0); len(runs) != 0 {
+		t.Fatalf("first scan should not fire, got %d runs", len(runs))
+	}
+
+	os.WriteFile(filepath.Join(aiData, "inbox", "new.md"), []byte("new"), 0644)
+	os.WriteFile(filepath.Join(aiData, "unwatched.md"), []byte("x"), 0644)
+	os.Remove(filepath.Join(aiData, "inbox", "old.md"))
+	s.checkFileTriggers()
+
+	runs := waitForRuns(t, s, sched.ID, 1)
+	if runs[0].Trigger != admin.TriggerFileChange {
+		t.Errorf("expected file_change trigger, got %q", runs[0].Trigger)
+	}
+	prompt := <-prompts
+	if !strings.HasPrefix(prompt, "File the new notes") ||
+		!strings.Contains(prompt, `"path": "ai-data/inbox/new.md"`) ||
+		!strings.Contains(prompt, `"change": "removed"`) ||
+		strings.Contains(prompt, "unwatched.md") {
+		t.Errorf("unexpected prompt:\n%s", prompt)
+	}
+
+	// Nothing changed since the last scan
+	s.checkFileTriggers()
+	time.Sleep(50 * time.Millisecond)
+	if runs, _ := s.store.ListRuns(sched.ID, 0); len(runs) != 1 {
+		t.Errorf("expected no further runs, got %d", len(runs))
+	}
+}
+
+// fakeCalendar implements CalendarAPI for testing.
+type fakeCalendar struct {
+	events []CalendarEvent
+}
+
+func (f *fakeCalendar) CalendarEvents(ctx context.Context, from, to time.Time) ([]CalendarEvent, error) {
+	var events []CalendarEvent
+	for _, ev := range f.events {
+		if ev.Start.Before(to) && ev.End.After(from) {
+			events = append(events, ev)
+		}
+	}
+	return events, nil
+}
+
+func TestScheduler_CalendarTrigger(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+
+	script := `result = args["event"]["summary"] + " in " + str(args["event"]["minutes_before"]) + "m"`
+	os.WriteFile(dir+"/scripts/brief.star", []byte(script), 0644)
+
+	now := time.Now().Truncate(time.Minute)
+	start := now.Add(20 * time.Minute)
+	s.SetCalendarAPI(&fakeCalendar{events: []CalendarEvent{
+		{Calendar: "Work", Summary: "Standup", Start: start, End: start.Add(15 * time.Minute)},
+	}})
+
+	work, _ := s.store.Create(&admin.Schedule{
+		Name:        "brief",
+		Trigger:     admin.TriggerCalendar,
+		Calendar:    "work",
+		LeadMinutes: 15,
+		Type:        "script",
+		ScriptName:  "brief.star",
+		Enabled:     true,
+	})
+	home, _ := s.store.Create(&admin.Schedule{
+		Name:        "home",
+		Trigger:     admin.TriggerCalendar,
+		Calendar:    "Home",
+		LeadMinutes: 15,
+		Type:        "script",
+		ScriptName:  "brief.star",
+		Enabled:     true,
+	})
+
+	s.checkCalendarTriggers(now)                      // first check only records the time
+	s.checkCalendarTriggers(now.Add(4 * time.Minute)) // 1 minute before the lead time
+	if runs, _ := s.store.ListRuns(work.ID, 0); len(runs) != 0 {
+		t.Fatalf("fired before the lead time: %+v", runs)
+	}
+
+	s.checkCalendarTriggers(now.Add(6 * time.Minute))
+	runs := waitForRuns(t, s, work.ID, 1)
+	if runs[0].Trigger != admin.TriggerCalendar || runs[0].Output != "Standup in 15m" {
+		t.Errorf("unexpected run: %+v", runs[0])
+	}
+
+	s.checkCalendarTriggers(now.Add(7 * time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if runs, _ := s.store.ListRuns(work.ID, 0); len(runs) != 1 {
+		t.Errorf("expected one run per event, got %d", len(runs))
+	}
+	if runs, _ := s.store.ListRuns(home.ID, 0); len(runs) != 0 {
+		t.Errorf("expected the Home schedule to ignore Work events, got %d runs", len(runs))
+	}
+}
+
+func TestEventPrompt(t *testing.T) {
+	if got := eventPrompt("Check the build", admin.TriggerCron, nil); got != "Check the build" {
+		t.Errorf("cron prompt changed: %q", got)
+	}
+	got := eventPrompt("Check the build", admin.TriggerWebhook, map[string]any{"body": "failed"})
+	if !strings.HasPrefix(got, "Check the build\n\n---\nThis run was triggered by a webhook event:") ||
+		!strings.Contains(got, `"body": "failed"`) {
+		t.Errorf("unexpected prompt:\n%s", got)
+	}
+}
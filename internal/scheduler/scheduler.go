@@ -1,11 +1,15 @@
-// Package scheduler runs cron-based scheduled jobs.
+// Package scheduler runs scheduled jobs, fired by cron expressions or by
+// events: signed webhooks, file changes and upcoming calendar events.
 // Jobs can execute Starlark scripts or start AI agent sessions.
 package scheduler
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"math/rand/v2"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -13,13 +17,16 @@ import (
 	"github.com/robfig/cron/v3"
 
 	"github.com/open-pact/openpact/internal/admin"
+	"github.com/open-pact/openpact/internal/chat"
 	"github.com/open-pact/openpact/internal/engine"
+	"github.com/open-pact/openpact/internal/logging"
 	"github.com/open-pact/openpact/internal/starlark"
 )
 
 // ChatAPI sends messages via chat providers.
 type ChatAPI interface {
 	SendViaProvider(provider, target, content string) error
+	SendResponseViaProvider(provider, target string, resp *chat.ChatResponse) error
 }
 
 // EngineAPI creates sessions and sends messages.
@@ -28,6 +35,87 @@ type EngineAPI interface {
 	Send(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error)
 }
 
+type scheduleKey struct{}
+
+// WithSchedule returns a context carrying the schedule an agent run belongs
+// to, so the engine API can tell whose turn it sends.
+func WithSchedule(ctx context.Context, sched *admin.Schedule) context.Context {
+	return context.WithValue(ctx, scheduleKey{}, sched)
+}
+
+// ScheduleFromContext returns the schedule carried by ctx, or nil if the
+// turn isn't an agent run.
+func ScheduleFromContext(ctx context.Context) *admin.Schedule {
+	sched, _ := ctx.Value(scheduleKey{}).(*admin.Schedule)
+	return sched
+}
+
+// SessionAPI gives agent jobs the chat channels' sessions and detail modes.
+type SessionAPI interface {
+	GetChannelSession(provider, channelID string) string
+	SetChannelSession(provider, channelID, sessionID string)
+	GetChannelMode(provider, channelID string) string
+	ResolvedToolCalls(sessionID string) []chat.ToolCallInfo
+}
+
+// MetricsAPI records per-schedule run metrics.
+type MetricsAPI interface {
+	ObserveScheduleRun(id, name, jobType string, success bool, d time.Duration)
+}
+
+// CalendarAPI reads events from the configured calendar feeds.
+type CalendarAPI interface {
+	CalendarEvents(ctx context.Context, from, to time.Time) ([]CalendarEvent, error)
+}
+
+// CalendarEvent is an event read from a calendar feed.
+type CalendarEvent struct {
+	Calendar    string // name of the calendar in the config
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+}
+
+// ErrJobRunning is returned by RunNow when a schedule is already running and
+// its overlap policy neither queues nor allows another run.
+var ErrJobRunning = errors.New("job is already running")
+
+// Run time limits used when a schedule sets no timeout.
+const (
+	defaultScriptTimeout = 5 * time.Minute
+	defaultAgentTimeout  = 10 * time.Minute
+)
+
+// maxCatchUpRuns caps the missed runs of one schedule replayed on startup.
+const maxCatchUpRuns = 24
+
+// Retry backoff defaults. retryBackoffUnit scales backoff_seconds and is
+// shortened in tests.
+var (
+	defaultRetryBackoff = 30 * time.Second
+	maxRetryDelay       = time.Hour
+	retryBackoffUnit    = time.Second
+)
+
+// maxQueuedRuns is how many runs the "queue" overlap policy holds behind the
+// current one; further runs are skipped.
+const maxQueuedRuns = 1
+
+// runState tracks the in-progress runs of one schedule.
+type runState struct {
+	running int
+	queued  []queuedRun // runs waiting for the current one
+}
+
+// queuedRun is a run held back by the "queue" overlap policy.
+type queuedRun struct {
+	trigger string
+	payload map[string]any
+}
+
 // Scheduler manages cron-based job scheduling.
 type Scheduler struct {
 	cron    *cron.Cron
@@ -35,6 +123,14 @@ type Scheduler struct {
 	entries map[string]cron.EntryID // schedule ID -> cron entry ID
 	mu      sync.Mutex
 
+	// Overlap control, keyed by schedule ID
+	runs  map[string]*runState
+	runMu sync.Mutex
+
+	// Closed by Stop to abandon jitter delays and pending catch-up runs
+	stop     chan struct{}
+	stopOnce sync.Once
+
 	// Script execution
 	sandbox        *starlark.Sandbox
 	loader         *starlark.Loader
@@ -46,20 +142,47 @@ type Scheduler struct {
 
 	// Output delivery (set via setter)
 	chatAPI ChatAPI
+
+	// Channel sessions and detail modes (set via setter, optional)
+	sessionAPI SessionAPI
+
+	// Run metrics (set via setter, optional)
+	metricsAPI MetricsAPI
+
+	// Calendar feeds for calendar triggers (set via setter, optional)
+	calendarAPI CalendarAPI
+
+	// Event trigger state, guarded by eventMu
+	watchRoots   map[string]string
+	eventMu      sync.Mutex
+	fileState    map[string]map[string]string // watch root -> path -> version
+	calEvents    []CalendarEvent
+	calFetchedAt time.Time
+	calHorizon   time.Time
+	calCheckedAt time.Time
+
+	log *logging.Logger
 }
 
 // Config holds scheduler configuration.
 type Config struct {
 	ScriptsDir     string
 	MaxExecutionMs int64
+	MaxSteps       uint64 // Starlark computation steps per run
+	MaxMemoryMB    int    // Memory built-ins may allocate per run
 	Secrets        map[string]string
 	ScriptStore    *admin.ScriptStore
+	WatchRoots     map[string]string // admin.WatchRoots name -> directory, for file_change triggers
+	Logger         *logging.Logger   // Optional; defaults to logging.Standard()
 }
 
 // New creates a new Scheduler.
 func New(store *admin.ScheduleStore, cfg Config) *Scheduler {
 	sandbox := starlark.New(starlark.Config{
 		MaxExecutionMs: cfg.MaxExecutionMs,
+		MaxSteps:       cfg.MaxSteps,
+		MaxMemoryMB:    cfg.MaxMemoryMB,
+		LibDir:         filepath.Join(cfg.ScriptsDir, starlark.LibDirName),
 	})
 	loader := starlark.NewLoader(cfg.ScriptsDir, sandbox)
 
@@ -69,14 +192,23 @@ func New(store *admin.ScheduleStore, cfg Config) *Scheduler {
 	}
 	sandbox.InjectSecrets(secretProvider)
 
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logging.Standard()
+	}
+
 	return &Scheduler{
 		cron:           cron.New(),
 		store:          store,
 		entries:        make(map[string]cron.EntryID),
+		runs:           make(map[string]*runState),
+		stop:           make(chan struct{}),
 		sandbox:        sandbox,
 		loader:         loader,
 		secretProvider: secretProvider,
 		scriptStore:    cfg.ScriptStore,
+		watchRoots:     cfg.WatchRoots,
+		log:            logger.WithField("component", "scheduler"),
 	}
 }
 
@@ -94,31 +226,64 @@ func (s *Scheduler) SetChatAPI(api ChatAPI) {
 	s.chatAPI = api
 }
 
-// Start loads all enabled schedules and starts the cron runner.
+// SetSessionAPI wires the channel session lookup for agent jobs.
+func (s *Scheduler) SetSessionAPI(api SessionAPI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessionAPI = api
+}
+
+// SetMetricsAPI wires the metrics recorder for job runs.
+func (s *Scheduler) SetMetricsAPI(api MetricsAPI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metricsAPI = api
+}
+
+// SetCalendarAPI wires the calendar feeds for calendar triggers.
+func (s *Scheduler) SetCalendarAPI(api CalendarAPI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calendarAPI = api
+}
+
+// Start loads all enabled schedules, starts the cron runner and the event
+// watcher, and replays runs missed while the scheduler was down, per each
+// schedule's catch-up policy.
 func (s *Scheduler) Start(ctx context.Context) error {
 	schedules, err := s.store.List()
 	if err != nil {
 		return fmt.Errorf("failed to load schedules: %w", err)
 	}
 
+	now := time.Now()
+	events := 0
 	for _, sched := range schedules {
 		if !sched.Enabled {
 			continue
 		}
+		if !sched.IsCron() {
+			events++
+			continue
+		}
 		if err := s.addCronEntry(sched); err != nil {
-			log.Printf("[scheduler] Failed to register schedule %q (%s): %v", sched.Name, sched.ID, err)
+			s.log.Error("Failed to register schedule %q (%s): %v", sched.Name, sched.ID, err)
+			continue
 		}
+		s.catchUp(sched, now)
 	}
 
 	s.cron.Start()
-	log.Printf("[scheduler] Started with %d enabled schedules", len(s.entries))
+	go s.watchEvents()
+	s.log.Info("Started with %d enabled cron schedules and %d event schedules", len(s.entries), events)
 	return nil
 }
 
-// Stop halts the cron runner.
+// Stop halts the cron runner. Runs already executing are not interrupted.
 func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
 	s.cron.Stop()
-	log.Println("[scheduler] Stopped")
+	s.log.Info("Stopped")
 }
 
 // Reload re-reads all schedules from the store and updates the cron entries.
@@ -139,26 +304,57 @@ func (s *Scheduler) Reload() error {
 	}
 
 	for _, sched := range schedules {
-		if !sched.Enabled {
+		if !sched.Enabled || !sched.IsCron() {
 			continue
 		}
 		if err := s.addCronEntryLocked(sched); err != nil {
-			log.Printf("[scheduler] Failed to register schedule %q (%s): %v", sched.Name, sched.ID, err)
+			s.log.Error("Failed to register schedule %q (%s): %v", sched.Name, sched.ID, err)
 		}
 	}
 
-	log.Printf("[scheduler] Reloaded: %d enabled schedules", len(s.entries))
+	s.log.Info("Reloaded: %d enabled schedules", len(s.entries))
 	return nil
 }
 
-// RunNow triggers a schedule immediately in a background goroutine.
+// RunNow triggers a schedule immediately in a background goroutine. If the
+// schedule is already running, its overlap policy decides whether the run
+// is queued, started anyway or refused with ErrJobRunning.
 func (s *Scheduler) RunNow(id string) error {
 	sched, err := s.store.Get(id)
 	if err != nil {
 		return err
 	}
 
-	go s.executeJob(sched)
+	start, err := s.acquire(sched, admin.TriggerRunNow, nil)
+	if err != nil || !start {
+		return err
+	}
+	go s.run(sched, admin.TriggerRunNow, nil)
+	return nil
+}
+
+// Fire starts an event-triggered run of a schedule in a background
+// goroutine, with payload passed to the job. The schedule must be enabled
+// and use the given trigger. Like RunNow, it honours the overlap policy.
+func (s *Scheduler) Fire(id, trigger string, payload map[string]any) error {
+	sched, err := s.store.Get(id)
+	if err != nil {
+		return err
+	}
+	if !sched.Enabled || sched.Trigger != trigger {
+		return fmt.Errorf("schedule %q is not an enabled %s schedule", sched.Name, trigger)
+	}
+	return s.fireEvent(sched, trigger, payload)
+}
+
+// fireEvent starts an event-triggered run, subject to the overlap policy.
+func (s *Scheduler) fireEvent(sched *admin.Schedule, trigger string, payload map[string]any) error {
+	s.log.Info("Event %s fired for %q", trigger, sched.Name)
+	start, err := s.acquire(sched, trigger, payload)
+	if err != nil || !start {
+		return err
+	}
+	go s.run(sched, trigger, payload)
 	return nil
 }
 
@@ -179,39 +375,205 @@ func (s *Scheduler) addCronEntryLocked(sched *admin.Schedule) error {
 	}
 
 	schedCopy := *sched
-	entryID, err := s.cron.AddFunc(sched.CronExpr, func() {
-		s.executeJob(&schedCopy)
+	entryID, err := s.cron.AddFunc(sched.CronSpec(), func() {
+		s.fire(&schedCopy, admin.TriggerCron)
 	})
 	if err != nil {
-		return fmt.Errorf("invalid cron expression %q: %w", sched.CronExpr, err)
+		return fmt.Errorf("invalid cron expression %q: %w", sched.CronSpec(), err)
 	}
 
 	s.entries[sched.ID] = entryID
-	log.Printf("[scheduler] Registered %q (%s) with cron %q", sched.Name, sched.ID, sched.CronExpr)
+	s.log.Debug("Registered %q (%s) with cron %q", sched.Name, sched.ID, sched.CronSpec())
 	return nil
 }
 
-// executeJob runs a single scheduled job with panic recovery.
-func (s *Scheduler) executeJob(sched *admin.Schedule) {
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("[scheduler] Panic in job %q (%s): %v", sched.Name, sched.ID, r)
-			s.store.UpdateLastRun(sched.ID, "error", fmt.Sprintf("panic: %v", r), "")
+// fire starts a cron or catch-up run after the schedule's jitter delay,
+// subject to its overlap policy. It returns when the run and any runs
+// queued behind it are done.
+func (s *Scheduler) fire(sched *admin.Schedule, trigger string) {
+	if sched.JitterSeconds > 0 {
+		delay := rand.N(time.Duration(sched.JitterSeconds) * time.Second)
+		select {
+		case <-time.After(delay):
+		case <-s.stop:
+			return
+		}
+	}
+
+	if start, _ := s.acquire(sched, trigger, nil); start {
+		s.run(sched, trigger, nil)
+	}
+}
+
+// acquire claims a run slot for a schedule according to its overlap policy.
+// It returns true if the run should start now. Otherwise the run was either
+// queued (nil error) or skipped (ErrJobRunning), and skipped runs are
+// recorded in the history.
+func (s *Scheduler) acquire(sched *admin.Schedule, trigger string, payload map[string]any) (bool, error) {
+	s.runMu.Lock()
+	state := s.runs[sched.ID]
+	if state == nil {
+		state = &runState{}
+		s.runs[sched.ID] = state
+	}
+	if state.running == 0 || sched.Overlap == admin.OverlapAllow {
+		state.running++
+		s.runMu.Unlock()
+		return true, nil
+	}
+	if sched.Overlap == admin.OverlapQueue && len(state.queued) < maxQueuedRuns {
+		state.queued = append(state.queued, queuedRun{trigger: trigger, payload: payload})
+		s.runMu.Unlock()
+		s.log.Info("Queued %s run of %q behind the run in progress", trigger, sched.Name)
+		return false, nil
+	}
+	s.runMu.Unlock()
+
+	s.log.Warn("Skipping %s run of %q: previous run still in progress", trigger, sched.Name)
+	err := s.store.AppendRun(&admin.ScheduleRun{
+		ScheduleID: sched.ID,
+		Trigger:    trigger,
+		StartedAt:  time.Now().UTC(),
+		Status:     "skipped",
+		Error:      ErrJobRunning.Error(),
+	})
+	if err != nil {
+		s.log.Error("Failed to record skipped run of %q: %v", sched.Name, err)
+	}
+	return false, ErrJobRunning
+}
+
+// run executes a job whose run slot is held, then the runs queued behind it,
+// and releases the slot.
+func (s *Scheduler) run(sched *admin.Schedule, trigger string, payload map[string]any) {
+	for {
+		s.executeJob(sched, trigger, payload)
+
+		s.runMu.Lock()
+		state := s.runs[sched.ID]
+		if len(state.queued) == 0 {
+			state.running--
+			if state.running == 0 {
+				delete(s.runs, sched.ID)
+			}
+			s.runMu.Unlock()
+			return
+		}
+		next := state.queued[0]
+		trigger, payload = next.trigger, next.payload
+		state.queued = state.queued[1:]
+		s.runMu.Unlock()
+	}
+}
+
+// catchUp replays the runs a schedule missed while the scheduler was down:
+// none, the latest one, or all of them (up to maxCatchUpRuns), depending on
+// its catch-up policy. Runs are started in the background, one at a time.
+func (s *Scheduler) catchUp(sched *admin.Schedule, now time.Time) {
+	if sched.CatchUp == "" || sched.CatchUp == admin.CatchUpNone {
+		return
+	}
+	missed, err := missedRuns(sched, now)
+	if err != nil {
+		s.log.Error("Failed to check missed runs of %q: %v", sched.Name, err)
+		return
+	}
+	if len(missed) == 0 {
+		return
+	}
+
+	n := 1
+	if sched.CatchUp == admin.CatchUpAll {
+		n = len(missed)
+	}
+	s.log.Info("Catching up %d run(s) of %q missed since %s", n, sched.Name, missed[0].Format(time.RFC3339))
+
+	schedCopy := *sched
+	go func() {
+		for range n {
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+			s.fire(&schedCopy, admin.TriggerCatchUp)
 		}
 	}()
+}
 
-	log.Printf("[scheduler] Executing job %q (%s) type=%s", sched.Name, sched.ID, sched.Type)
+// missedRuns returns the times a schedule was due between its last activity
+// and now, oldest first, at most maxCatchUpRuns. Its last activity is the
+// latest of its last run, creation and last update, so editing or
+// re-enabling a schedule does not replay runs from before the change.
+func missedRuns(sched *admin.Schedule, now time.Time) ([]time.Time, error) {
+	cronSched, err := cron.ParseStandard(sched.CronSpec())
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", sched.CronSpec(), err)
+	}
+
+	since := sched.CreatedAt
+	if sched.UpdatedAt.After(since) {
+		since = sched.UpdatedAt
+	}
+	if sched.LastRunAt != nil && sched.LastRunAt.After(since) {
+		since = *sched.LastRunAt
+	}
+
+	var missed []time.Time
+	for t := cronSched.Next(since); !t.IsZero() && !t.After(now); t = cronSched.Next(t) {
+		missed = append(missed, t)
+		if len(missed) > maxCatchUpRuns {
+			missed = missed[1:] // keep the most recent
+		}
+	}
+	return missed, nil
+}
+
+// jobTimeout returns how long a run of the schedule may take.
+func jobTimeout(sched *admin.Schedule) time.Duration {
+	if sched.TimeoutSeconds > 0 {
+		return time.Duration(sched.TimeoutSeconds) * time.Second
+	}
+	if sched.Type == "agent" {
+		return defaultAgentTimeout
+	}
+	return defaultScriptTimeout
+}
+
+// executeJob runs a single scheduled job, retrying it per the schedule's
+// retry policy. Each run gets its own correlation ID, which is carried into
+// the engine for agent jobs, and each attempt is appended to the schedule's
+// run history. Output and failure alerts are delivered once the final
+// attempt is done. payload holds the event data of event-triggered runs.
+func (s *Scheduler) executeJob(sched *admin.Schedule, trigger string, payload map[string]any) {
+	ctx, _ := logging.EnsureCorrelationID(context.Background())
+	logger := s.log.WithContext(ctx).WithField("schedule", sched.ID)
+
+	logger.Info("Executing job %q type=%s trigger=%s", sched.Name, sched.Type, trigger)
+	start := time.Now()
+
+	maxAttempts := 1
+	if sched.Retry != nil && sched.Retry.MaxAttempts > 1 {
+		maxAttempts = sched.Retry.MaxAttempts
+	}
 
 	var output string
+	var reply *chat.ChatResponse
 	var execErr error
-
-	switch sched.Type {
-	case "script":
-		output, execErr = s.executeScript(sched)
-	case "agent":
-		output, execErr = s.executeAgent(sched)
-	default:
-		execErr = fmt.Errorf("unknown job type: %s", sched.Type)
+	for attempt := 1; ; attempt++ {
+		output, reply, execErr = s.executeAttempt(ctx, logger, sched, trigger, payload, attempt)
+		if execErr == nil || attempt >= maxAttempts {
+			break
+		}
+		delay := retryDelay(sched.Retry, attempt)
+		logger.Warn("Job %q attempt %d/%d failed, retrying in %v: %v", sched.Name, attempt, maxAttempts, delay, execErr)
+		select {
+		case <-time.After(delay):
+			continue
+		case <-s.stop:
+			logger.Info("Scheduler stopping, abandoning retries of %q", sched.Name)
+		}
+		break
 	}
 
 	status := "success"
@@ -219,36 +581,155 @@ func (s *Scheduler) executeJob(sched *admin.Schedule) {
 	if execErr != nil {
 		status = "error"
 		errMsg = execErr.Error()
-		log.Printf("[scheduler] Job %q (%s) failed: %v", sched.Name, sched.ID, execErr)
+		logger.Error("Job %q failed: %v", sched.Name, execErr)
 	} else {
-		log.Printf("[scheduler] Job %q (%s) completed successfully", sched.Name, sched.ID)
+		logger.Info("Job %q completed successfully", sched.Name)
 	}
 
+	s.mu.Lock()
+	metrics := s.metricsAPI
+	s.mu.Unlock()
+	if metrics != nil {
+		metrics.ObserveScheduleRun(sched.ID, sched.Name, sched.Type, execErr == nil, time.Since(start))
+	}
+
+	previous, _ := s.store.Get(sched.ID)
 	if err := s.store.UpdateLastRun(sched.ID, status, errMsg, output); err != nil {
-		log.Printf("[scheduler] Failed to update last run for %q: %v", sched.Name, err)
+		logger.Error("Failed to update last run for %q: %v", sched.Name, err)
 	}
 
-	// Auto-disable run-once schedules after execution.
 	// Re-read from store to get the current state (not the cached copy).
-	if current, err := s.store.Get(sched.ID); err == nil && current.RunOnce {
-		log.Printf("[scheduler] Run-once job %q (%s) completed, auto-disabling", sched.Name, sched.ID)
+	current, err := s.store.Get(sched.ID)
+	if err != nil {
+		return
+	}
+
+	// Auto-disable run-once schedules after execution.
+	if current.RunOnce {
+		logger.Info("Run-once job %q completed, auto-disabling", sched.Name)
 		if err := s.store.SetEnabled(sched.ID, false); err != nil {
-			log.Printf("[scheduler] Failed to auto-disable run-once job %q: %v", sched.Name, err)
+			logger.Error("Failed to auto-disable run-once job %q: %v", sched.Name, err)
 		} else {
 			s.Reload()
 		}
 	}
 
-	// Send output to target if configured and there's output
-	if sched.OutputTarget != nil && output != "" {
-		s.sendOutput(sched, output, execErr)
+	// Send output to target if configured and the output mode allows it
+	if sched.OutputTarget != nil && shouldSendOutput(sched.OutputMode, previous, current, output, execErr) {
+		s.sendOutput(logger, sched, output, reply, execErr)
 	}
+
+	s.sendFailureAlert(logger, sched, previous, current, execErr)
 }
 
-// executeScript runs a Starlark script.
-func (s *Scheduler) executeScript(sched *admin.Schedule) (string, error) {
-	scriptName := sched.ScriptName
+// executeAttempt makes one attempt at a job with panic recovery, and records
+// it in the run history. Agent jobs also return the full reply for delivery.
+func (s *Scheduler) executeAttempt(ctx context.Context, logger *logging.Logger, sched *admin.Schedule, trigger string, payload map[string]any, attempt int) (output string, reply *chat.ChatResponse, execErr error) {
+	start := time.Now()
+	run := &admin.ScheduleRun{
+		ScheduleID:    sched.ID,
+		Trigger:       trigger,
+		Attempt:       attempt,
+		StartedAt:     start.UTC(),
+		CorrelationID: logging.CorrelationID(ctx),
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Panic in job %q: %v", sched.Name, r)
+			output, reply, execErr = "", nil, fmt.Errorf("panic: %v", r)
+		}
+		run.Status = "success"
+		if execErr != nil {
+			run.Status = "error"
+			run.Error = execErr.Error()
+		}
+		run.Output = output
+		s.recordRun(logger, run, start)
+	}()
+
+	switch sched.Type {
+	case "script":
+		output, run.Logs, execErr = s.executeScript(sched, trigger, payload)
+	case "agent":
+		reply, run.SessionID, execErr = s.executeAgent(ctx, sched, trigger, payload)
+		if reply != nil {
+			output = reply.Text
+		}
+	default:
+		execErr = fmt.Errorf("unknown job type: %s", sched.Type)
+	}
+	return output, reply, execErr
+}
 
+// retryDelay returns how long to wait after the given failed attempt: the
+// policy's backoff, doubled for each further attempt, at most maxRetryDelay.
+func retryDelay(policy *admin.RetryPolicy, attempt int) time.Duration {
+	delay := defaultRetryBackoff
+	if policy != nil && policy.BackoffSeconds > 0 {
+		delay = time.Duration(policy.BackoffSeconds) * retryBackoffUnit
+	}
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// shouldSendOutput applies a schedule's output mode to a finished run.
+// previous and current are the schedule before and after the run was
+// recorded; previous is nil if it could not be read.
+func shouldSendOutput(mode string, previous, current *admin.Schedule, output string, execErr error) bool {
+	switch mode {
+	case admin.OutputOnError:
+		return execErr != nil
+	case admin.OutputOnChange:
+		if previous == nil || previous.LastOutputHash != current.LastOutputHash {
+			return output != "" || execErr != nil
+		}
+		return false
+	default:
+		return output != ""
+	}
+}
+
+// recordRun appends a finished run to the schedule's history.
+func (s *Scheduler) recordRun(logger *logging.Logger, run *admin.ScheduleRun, start time.Time) {
+	run.DurationMs = time.Since(start).Milliseconds()
+	if err := s.store.AppendRun(run); err != nil {
+		logger.Error("Failed to record run of %s: %v", run.ScheduleID, err)
+	}
+}
+
+// executeScript runs a Starlark script. The script sees the trigger and the
+// event payload in its "args" global. It also returns the script's logs.
+func (s *Scheduler) executeScript(sched *admin.Schedule, trigger string, payload map[string]any) (string, []starlark.LogEntry, error) {
+	// Execute with timeout
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout(sched))
+	defer cancel()
+
+	args := map[string]any{"trigger": trigger}
+	for k, v := range payload {
+		args[k] = v
+	}
+	result, err := s.RunScript(ctx, sched.ScriptName, args)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if result.ErrorKind != "" {
+		return "", result.Logs, fmt.Errorf("script error (%s): %s", result.ErrorKind, result.Error)
+	}
+	if result.Error != "" {
+		return "", result.Logs, fmt.Errorf("script error: %s", result.Error)
+	}
+
+	return fmt.Sprintf("%v", result.Value), result.Logs, nil
+}
+
+// RunScript runs an approved script with args in its "args" global, within
+// the script's capabilities, and returns the sanitized result. Errors are
+// returned for scripts that can't be run; script failures are in the result.
+func (s *Scheduler) RunScript(ctx context.Context, scriptName string, args map[string]any) (starlark.Result, error) {
 	// Check approval (uses full filename with .star extension)
 	if s.scriptStore != nil {
 		approvalName := scriptName
@@ -256,89 +737,209 @@ func (s *Scheduler) executeScript(sched *admin.Schedule) (string, error) {
 			approvalName += ".star"
 		}
 		if err := s.scriptStore.CanExecute(approvalName); err != nil {
-			return "", fmt.Errorf("script not approved: %w", err)
+			return starlark.Result{}, fmt.Errorf("script not approved: %w", err)
 		}
 	}
 
 	// loader.Load expects name without .star extension
-	loadName := scriptName
-	if strings.HasSuffix(loadName, ".star") {
-		loadName = loadName[:len(loadName)-5]
-	}
+	loadName := strings.TrimSuffix(scriptName, ".star")
 
 	// Load script
 	script, err := s.loader.Load(loadName)
 	if err != nil {
-		return "", fmt.Errorf("failed to load script %q: %w", sched.ScriptName, err)
+		return starlark.Result{}, fmt.Errorf("failed to load script %q: %w", scriptName, err)
 	}
 
-	// Execute with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
+	caps, err := starlark.ParseCapabilities(script.Source)
+	if err != nil {
+		return starlark.Result{}, fmt.Errorf("script %q has an invalid capability manifest: %w", scriptName, err)
+	}
 
-	result := s.sandbox.Execute(ctx, script.Name, script.Source)
+	result := s.sandbox.ExecuteWithArgs(starlark.WithCapabilities(ctx, caps), script.Name, script.Source, args)
 
 	// Sanitize output
-	result = starlark.SanitizeResult(result, s.secretProvider)
-
-	if result.Error != "" {
-		return "", fmt.Errorf("script error: %s", result.Error)
-	}
-
-	return fmt.Sprintf("%v", result.Value), nil
+	return starlark.SanitizeResult(result, s.secretProvider), nil
 }
 
-// executeAgent creates a new AI session and sends the prompt.
-func (s *Scheduler) executeAgent(sched *admin.Schedule) (string, error) {
+// executeAgent sends the prompt to the schedule's agent session, with the
+// event payload appended for event-triggered runs. The context carries the
+// run's correlation ID and the schedule. It returns the reply, with thinking and tool calls
+// when the output channel's detail mode shows them, and the session ID.
+func (s *Scheduler) executeAgent(parent context.Context, sched *admin.Schedule, trigger string, payload map[string]any) (*chat.ChatResponse, string, error) {
 	s.mu.Lock()
 	eng := s.engineAPI
+	sessions := s.sessionAPI
 	s.mu.Unlock()
 
 	if eng == nil {
-		return "", fmt.Errorf("engine API not available")
+		return nil, "", fmt.Errorf("engine API not available")
 	}
 
-	session, err := eng.CreateSession()
+	sessionID, err := s.agentSession(eng, sessions, sched)
 	if err != nil {
-		return "", fmt.Errorf("failed to create session: %w", err)
+		return nil, "", err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	ctx, cancel := context.WithTimeout(WithSchedule(parent, sched), jobTimeout(sched))
 	defer cancel()
 
+	// A continued session is shared with earlier runs or with people in the
+	// channel, so the message says where it came from.
+	prompt := eventPrompt(sched.Prompt, trigger, payload)
+	if sched.Session != "" {
+		prompt = fmt.Sprintf("[scheduled job: %s]\n%s", sched.Name, prompt)
+	}
 	messages := []engine.Message{
-		{Role: "user", Content: sched.Prompt},
+		{Role: "user", Content: prompt},
+	}
+
+	responses, err := eng.Send(ctx, sessionID, messages)
+	if err != nil {
+		return nil, sessionID, fmt.Errorf("failed to send message: %w", err)
+	}
+
+	mode := chat.ModeSimple
+	if sessions != nil && sched.OutputTarget != nil {
+		mode = sessions.GetChannelMode(sched.OutputTarget.Provider, sched.OutputTarget.ChannelID)
+	}
+	reply := collectReply(responses, mode == chat.ModeThinking || mode == chat.ModeFull)
+	if sessions != nil && (mode == chat.ModeTools || mode == chat.ModeFull) {
+		reply.ToolCalls = sessions.ResolvedToolCalls(sessionID)
+	}
+	return reply, sessionID, nil
+}
+
+// agentSession returns the session an agent run is sent to: a new one, the
+// session bound to the output channel, or a named session kept in the
+// store. Channel and named sessions are started on first use.
+func (s *Scheduler) agentSession(eng EngineAPI, sessions SessionAPI, sched *admin.Schedule) (string, error) {
+	var sessionID string
+	switch sched.Session {
+	case "":
+	case admin.SessionChannel:
+		if sessions == nil || sched.OutputTarget == nil {
+			return "", fmt.Errorf("channel session not available")
+		}
+		sessionID = sessions.GetChannelSession(sched.OutputTarget.Provider, sched.OutputTarget.ChannelID)
+	default:
+		id, err := s.store.NamedSession(sched.Session)
+		if err != nil {
+			return "", fmt.Errorf("failed to look up session %q: %w", sched.Session, err)
+		}
+		sessionID = id
+	}
+	if sessionID != "" {
+		return sessionID, nil
 	}
 
-	responses, err := eng.Send(ctx, session.ID, messages)
+	session, err := eng.CreateSession()
 	if err != nil {
-		return "", fmt.Errorf("failed to send message: %w", err)
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	switch sched.Session {
+	case "":
+	case admin.SessionChannel:
+		sessions.SetChannelSession(sched.OutputTarget.Provider, sched.OutputTarget.ChannelID, session.ID)
+	default:
+		if err := s.store.SetNamedSession(sched.Session, session.ID); err != nil {
+			return "", fmt.Errorf("failed to save session %q: %w", sched.Session, err)
+		}
 	}
+	return session.ID, nil
+}
 
-	// Drain response channel and collect text
-	var textParts []string
+// collectReply drains a response stream into a ChatResponse. Streamed parts
+// carry their full text so far, so each part ID keeps its latest text and
+// parts are joined in arrival order.
+func collectReply(responses <-chan engine.Response, wantThinking bool) *chat.ChatResponse {
+	var text, thinking partText
+	var untagged strings.Builder
 	for resp := range responses {
 		if resp.Content != "" {
-			textParts = append(textParts, resp.Content)
+			if resp.PartID != "" {
+				text.set(resp.PartID, resp.Content)
+			} else {
+				untagged.WriteString(resp.Content)
+			}
+		}
+		if wantThinking && resp.Thinking != "" && resp.PartID != "" {
+			thinking.set(resp.PartID, resp.Thinking)
 		}
 	}
+	return &chat.ChatResponse{
+		Text:     text.String() + untagged.String(),
+		Thinking: strings.ReplaceAll(thinking.String(), `\n`, ""),
+	}
+}
 
-	// The last text part should contain the full response (SSE streaming sends full text)
-	if len(textParts) > 0 {
-		return textParts[len(textParts)-1], nil
+// partText accumulates streamed parts by ID.
+type partText struct {
+	order []string
+	parts map[string]string
+}
+
+func (p *partText) set(id, text string) {
+	if p.parts == nil {
+		p.parts = make(map[string]string)
+	}
+	if _, seen := p.parts[id]; !seen {
+		p.order = append(p.order, id)
 	}
+	p.parts[id] = text
+}
 
-	return "", nil
+func (p *partText) String() string {
+	var b strings.Builder
+	for _, id := range p.order {
+		b.WriteString(p.parts[id])
+	}
+	return b.String()
 }
 
-// sendOutput delivers job output to the configured chat channel.
-func (s *Scheduler) sendOutput(sched *admin.Schedule, output string, execErr error) {
+// maxEventPromptLen caps the event data appended to an agent prompt.
+const maxEventPromptLen = 32 << 10
+
+// eventPrompt appends the payload of an event-triggered run to an agent
+// prompt as a JSON block.
+func eventPrompt(prompt, trigger string, payload map[string]any) string {
+	if payload == nil {
+		return prompt
+	}
+	data, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return prompt
+	}
+	text := string(data)
+	if len(text) > maxEventPromptLen {
+		text = text[:maxEventPromptLen] + "\n... (truncated)"
+	}
+	return fmt.Sprintf("%s\n\n---\nThis run was triggered by a %s event:\n```json\n%s\n```", prompt, trigger, text)
+}
+
+// sendOutput delivers job output to the configured chat channel. A
+// successful agent reply goes through the provider's reply path, so it is
+// split rather than truncated and shows thinking and tool calls per the
+// channel's detail mode. Replies in the channel's own session are sent as
+// they are, like any other reply in that conversation.
+func (s *Scheduler) sendOutput(logger *logging.Logger, sched *admin.Schedule, output string, reply *chat.ChatResponse, execErr error) {
 	s.mu.Lock()
-	chat := s.chatAPI
+	api := s.chatAPI
 	s.mu.Unlock()
 
-	if chat == nil {
-		log.Printf("[scheduler] Chat API not available, cannot deliver output for %q", sched.Name)
+	if api == nil {
+		logger.Warn("Chat API not available, cannot deliver output for %q", sched.Name)
+		return
+	}
+
+	target := sched.OutputTarget
+	if execErr == nil && reply != nil && reply.Text != "" {
+		resp := *reply
+		if sched.Session != admin.SessionChannel {
+			resp.Text = fmt.Sprintf("**Scheduled Job: %s**\n%s", sched.Name, resp.Text)
+		}
+		if err := api.SendResponseViaProvider(target.Provider, target.ChannelID, &resp); err != nil {
+			logger.Error("Failed to deliver output for %q: %v", sched.Name, err)
+		}
 		return
 	}
 
@@ -357,8 +958,46 @@ func (s *Scheduler) sendOutput(sched *admin.Schedule, output string, execErr err
 		msg.WriteString(fmt.Sprintf("Output:\n```\n%s\n```", output))
 	}
 
-	if err := chat.SendViaProvider(sched.OutputTarget.Provider, sched.OutputTarget.ChannelID, msg.String()); err != nil {
-		log.Printf("[scheduler] Failed to deliver output for %q: %v", sched.Name, err)
+	if err := api.SendViaProvider(target.Provider, target.ChannelID, msg.String()); err != nil {
+		logger.Error("Failed to deliver output for %q: %v", sched.Name, err)
+	}
+}
+
+// sendFailureAlert notifies the schedule's failure target when its run of
+// consecutive failures reaches the threshold, and again when a run succeeds
+// after such an alert.
+func (s *Scheduler) sendFailureAlert(logger *logging.Logger, sched *admin.Schedule, previous, current *admin.Schedule, execErr error) {
+	if sched.FailureTarget == nil {
+		return
+	}
+	threshold := max(sched.FailureThreshold, 1)
+
+	var msg string
+	switch {
+	case execErr != nil && current.ConsecutiveFailures == threshold:
+		msg = fmt.Sprintf("**Scheduled Job Failed: %s**\n", sched.Name)
+		if threshold > 1 {
+			msg += fmt.Sprintf("Failed %d runs in a row.\n", threshold)
+		}
+		if sched.Retry != nil && sched.Retry.MaxAttempts > 1 {
+			msg += fmt.Sprintf("Gave up after %d attempts.\n", sched.Retry.MaxAttempts)
+		}
+		msg += fmt.Sprintf("Error: %s", execErr.Error())
+	case execErr == nil && previous != nil && previous.ConsecutiveFailures >= threshold:
+		msg = fmt.Sprintf("**Scheduled Job Recovered: %s**\nSucceeded after %d failed runs.", sched.Name, previous.ConsecutiveFailures)
+	default:
+		return
+	}
+
+	s.mu.Lock()
+	api := s.chatAPI
+	s.mu.Unlock()
+	if api == nil {
+		logger.Warn("Chat API not available, cannot send failure alert for %q", sched.Name)
+		return
+	}
+	if err := api.SendViaProvider(sched.FailureTarget.Provider, sched.FailureTarget.ChannelID, msg); err != nil {
+		logger.Error("Failed to send failure alert for %q: %v", sched.Name, err)
 	}
 }
 
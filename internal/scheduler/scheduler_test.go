@@ -2,12 +2,17 @@ package scheduler
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/open-pact/openpact/internal/admin"
+	"github.com/open-pact/openpact/internal/chat"
 	"github.com/open-pact/openpact/internal/engine"
+	"github.com/open-pact/openpact/internal/logging"
 )
 
 func setupTestScheduler(t *testing.T) (*Scheduler, string) {
@@ -133,7 +138,7 @@ func TestScheduler_ExecuteScript(t *testing.T) {
 	}
 
 	// Execute directly
-	s.executeJob(sched)
+	s.executeJob(sched, admin.TriggerCron, nil)
 
 	// Check last run status
 	got, _ := s.store.Get(sched.ID)
@@ -145,6 +150,39 @@ func TestScheduler_ExecuteScript(t *testing.T) {
 	}
 }
 
+func TestScheduler_ScriptLogs(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+
+	script := "print(\"checking\", args[\"trigger\"])\nlog.warn(\"feed is stale\")\nresult = \"ok\"\n"
+	if err := os.WriteFile(dir+"/scripts/check.star", []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sched, _ := s.store.Create(&admin.Schedule{
+		Name:       "check",
+		CronExpr:   "0 0 * * *",
+		Type:       "script",
+		Enabled:    true,
+		ScriptName: "check.star",
+	})
+	s.executeJob(sched, admin.TriggerCron, nil)
+
+	runs, _ := s.store.ListRuns(sched.ID, 0)
+	if len(runs) != 1 || len(runs[0].Logs) != 2 {
+		t.Fatalf("expected one run with 2 log entries, got %+v", runs)
+	}
+	if runs[0].Logs[0].Message != "checking cron" || runs[0].Logs[1].Level != "warn" {
+		t.Errorf("unexpected logs: %+v", runs[0].Logs)
+	}
+
+	// RunScript returns the same result directly
+	result, err := s.RunScript(context.Background(), "check", map[string]any{"trigger": "test"})
+	if err != nil || result.Value != "ok" || len(result.Logs) != 2 || result.Logs[0].Message != "checking test" {
+		t.Errorf("unexpected RunScript result: %+v, %v", result, err)
+	}
+}
+
 func TestScheduler_ExecuteScriptError(t *testing.T) {
 	s, dir := setupTestScheduler(t)
 	defer os.RemoveAll(dir)
@@ -157,7 +195,7 @@ func TestScheduler_ExecuteScriptError(t *testing.T) {
 		ScriptName: "nonexistent.star",
 	})
 
-	s.executeJob(sched)
+	s.executeJob(sched, admin.TriggerCron, nil)
 
 	got, _ := s.store.Get(sched.ID)
 	if got.LastRunStatus != "error" {
@@ -186,11 +224,15 @@ func TestScheduler_ExecuteAgent(t *testing.T) {
 	s, dir := setupTestScheduler(t)
 	defer os.RemoveAll(dir)
 
+	var correlationID string
+	var ctxSchedule *admin.Schedule
 	mock := &mockEngine{
 		createSessionFn: func() (*engine.Session, error) {
 			return &engine.Session{ID: "test-session"}, nil
 		},
 		sendFn: func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+			correlationID = logging.CorrelationID(ctx)
+			ctxSchedule = ScheduleFromContext(ctx)
 			ch := make(chan engine.Response, 1)
 			ch <- engine.Response{Content: "agent response"}
 			close(ch)
@@ -207,7 +249,7 @@ func TestScheduler_ExecuteAgent(t *testing.T) {
 		Prompt:   "Hello agent",
 	})
 
-	s.executeJob(sched)
+	s.executeJob(sched, admin.TriggerCron, nil)
 
 	got, _ := s.store.Get(sched.ID)
 	if got.LastRunStatus != "success" {
@@ -216,6 +258,70 @@ func TestScheduler_ExecuteAgent(t *testing.T) {
 	if got.LastRunOutput != "agent response" {
 		t.Errorf("expected output 'agent response', got %q", got.LastRunOutput)
 	}
+	if correlationID == "" {
+		t.Error("expected agent job context to carry a correlation ID")
+	}
+	if ctxSchedule == nil || ctxSchedule.ID != sched.ID {
+		t.Errorf("expected agent job context to carry the schedule, got %+v", ctxSchedule)
+	}
+}
+
+func TestScheduler_RunHistory(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+
+	calls := 0
+	s.SetEngineAPI(&mockEngine{
+		createSessionFn: func() (*engine.Session, error) {
+			calls++
+			return &engine.Session{ID: fmt.Sprintf("session-%d", calls)}, nil
+		},
+		sendFn: func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+			if calls < 3 {
+				return nil, fmt.Errorf("engine busy")
+			}
+			ch := make(chan engine.Response, 1)
+			ch <- engine.Response{Content: "nightly report"}
+			close(ch)
+			return ch, nil
+		},
+	})
+
+	sched, _ := s.store.Create(&admin.Schedule{
+		Name:     "nightly",
+		CronExpr: "0 2 * * *",
+		Type:     "agent",
+		Enabled:  true,
+		Prompt:   "Write the report",
+	})
+
+	s.executeJob(sched, admin.TriggerCron, nil)
+	s.executeJob(sched, admin.TriggerCron, nil)
+	s.executeJob(sched, admin.TriggerRunNow, nil)
+
+	runs, err := s.store.ListRuns(sched.ID, 0)
+	if err != nil {
+		t.Fatalf("ListRuns failed: %v", err)
+	}
+	if len(runs) != 3 {
+		t.Fatalf("expected 3 runs, got %d", len(runs))
+	}
+
+	// Newest first: the successful manual run, then both failures
+	if runs[0].Status != "success" || runs[0].Trigger != admin.TriggerRunNow || runs[0].Output != "nightly report" {
+		t.Errorf("unexpected latest run: %+v", runs[0])
+	}
+	if runs[0].SessionID != "session-3" || runs[0].CorrelationID == "" {
+		t.Errorf("expected session and correlation IDs, got %+v", runs[0])
+	}
+	for _, run := range runs[1:] {
+		if run.Status != "error" || run.Trigger != admin.TriggerCron || !strings.Contains(run.Error, "engine busy") {
+			t.Errorf("unexpected failed run: %+v", run)
+		}
+		if run.SessionID == "" {
+			t.Errorf("failed run should keep its session ID: %+v", run)
+		}
+	}
 }
 
 func TestScheduler_ExecuteAgentNoEngine(t *testing.T) {
@@ -230,7 +336,7 @@ func TestScheduler_ExecuteAgentNoEngine(t *testing.T) {
 		Prompt:   "Hello agent",
 	})
 
-	s.executeJob(sched)
+	s.executeJob(sched, admin.TriggerCron, nil)
 
 	got, _ := s.store.Get(sched.ID)
 	if got.LastRunStatus != "error" {
@@ -286,6 +392,10 @@ func (m *mockChat) SendViaProvider(provider, target, content string) error {
 	return nil
 }
 
+func (m *mockChat) SendResponseViaProvider(provider, target string, resp *chat.ChatResponse) error {
+	return m.SendViaProvider(provider, target, resp.Text)
+}
+
 func TestScheduler_OutputDelivery(t *testing.T) {
 	s, dir := setupTestScheduler(t)
 	defer os.RemoveAll(dir)
@@ -311,7 +421,7 @@ func TestScheduler_OutputDelivery(t *testing.T) {
 		},
 	})
 
-	s.executeJob(sched)
+	s.executeJob(sched, admin.TriggerCron, nil)
 
 	if chat.lastProvider != "discord" {
 		t.Errorf("expected provider 'discord', got %q", chat.lastProvider)
@@ -323,3 +433,592 @@ func TestScheduler_OutputDelivery(t *testing.T) {
 		t.Error("expected non-empty content")
 	}
 }
+
+// blockingEngine returns an engine whose responses are held until release
+// is closed, and a channel that receives each session ID as a run starts.
+func blockingEngine(release chan struct{}) (*mockEngine, chan string) {
+	started := make(chan string, 10)
+	calls := 0
+	return &mockEngine{
+		createSessionFn: func() (*engine.Session, error) {
+			calls++
+			return &engine.Session{ID: fmt.Sprintf("session-%d", calls)}, nil
+		},
+		sendFn: func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+			started <- sessionID
+			ch := make(chan engine.Response, 1)
+			go func() {
+				defer close(ch)
+				select {
+				case <-release:
+					ch <- engine.Response{Content: "done"}
+				case <-ctx.Done():
+				}
+			}()
+			return ch, nil
+		},
+	}, started
+}
+
+// waitForRuns polls the run history until it has n runs.
+func waitForRuns(t *testing.T, s *Scheduler, id string, n int) []*admin.ScheduleRun {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		runs, _ := s.store.ListRuns(id, 0)
+		if len(runs) >= n {
+			return runs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d runs, got %d", n, len(runs))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestScheduler_OverlapSkip(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+
+	release := make(chan struct{})
+	eng, started := blockingEngine(release)
+	s.SetEngineAPI(eng)
+
+	sched, _ := s.store.Create(&admin.Schedule{
+		Name:     "slow",
+		CronExpr: "* * * * *",
+		Type:     "agent",
+		Prompt:   "Take your time",
+	})
+
+	if err := s.RunNow(sched.ID); err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+	<-started
+
+	if err := s.RunNow(sched.ID); err != ErrJobRunning {
+		t.Errorf("expected ErrJobRunning, got %v", err)
+	}
+	s.fire(sched, admin.TriggerCron)
+
+	close(release)
+	runs := waitForRuns(t, s, sched.ID, 3)
+
+	statuses := map[string]int{}
+	for _, run := range runs {
+		statuses[run.Status]++
+	}
+	if statuses["skipped"] != 2 || statuses["success"] != 1 {
+		t.Errorf("expected 2 skipped runs and 1 success, got %v", statuses)
+	}
+}
+
+func TestScheduler_OverlapQueue(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+
+	release := make(chan struct{})
+	eng, started := blockingEngine(release)
+	s.SetEngineAPI(eng)
+
+	sched, _ := s.store.Create(&admin.Schedule{
+		Name:     "queued",
+		CronExpr: "* * * * *",
+		Type:     "agent",
+		Prompt:   "One at a time",
+		Overlap:  admin.OverlapQueue,
+	})
+
+	if err := s.RunNow(sched.ID); err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+	<-started
+
+	// Queued behind the first run
+	if err := s.RunNow(sched.ID); err != nil {
+		t.Errorf("expected second run to be queued, got %v", err)
+	}
+	// The queue holds one run; a third is skipped
+	if err := s.RunNow(sched.ID); err != ErrJobRunning {
+		t.Errorf("expected ErrJobRunning for a full queue, got %v", err)
+	}
+
+	select {
+	case id := <-started:
+		t.Fatalf("queued run %s started while the first was running", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	runs := waitForRuns(t, s, sched.ID, 3)
+	successes := 0
+	for _, run := range runs {
+		if run.Status == "success" {
+			successes++
+		}
+	}
+	if successes != 2 {
+		t.Errorf("expected both runs to succeed one after the other, got %+v", runs)
+	}
+}
+
+func TestScheduler_JobTimeout(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+
+	eng, _ := blockingEngine(make(chan struct{}))
+	s.SetEngineAPI(eng)
+
+	sched, _ := s.store.Create(&admin.Schedule{
+		Name:           "stuck",
+		CronExpr:       "0 0 * * *",
+		Type:           "agent",
+		Prompt:         "Never finish",
+		TimeoutSeconds: 1,
+	})
+
+	start := time.Now()
+	s.executeJob(sched, admin.TriggerCron, nil)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the run to stop after its 1s timeout, took %v", elapsed)
+	}
+
+	if got := jobTimeout(&admin.Schedule{Type: "script"}); got != defaultScriptTimeout {
+		t.Errorf("expected default script timeout, got %v", got)
+	}
+	if got := jobTimeout(&admin.Schedule{Type: "agent"}); got != defaultAgentTimeout {
+		t.Errorf("expected default agent timeout, got %v", got)
+	}
+}
+
+func TestMissedRuns(t *testing.T) {
+	ny, _ := time.LoadLocation("America/New_York")
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, ny)
+	created := now.Add(-72 * time.Hour)
+
+	sched := &admin.Schedule{
+		CronExpr:  "0 2 * * *",
+		Timezone:  "America/New_York",
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+	missed, err := missedRuns(sched, now)
+	if err != nil {
+		t.Fatalf("missedRuns failed: %v", err)
+	}
+	if len(missed) != 3 {
+		t.Fatalf("expected 3 missed runs, got %v", missed)
+	}
+	for _, m := range missed {
+		if m.In(ny).Hour() != 2 {
+			t.Errorf("expected runs at 02:00 New York time, got %v", m.In(ny))
+		}
+	}
+
+	lastRun := now.Add(-time.Hour)
+	sched.LastRunAt = &lastRun
+	if missed, _ := missedRuns(sched, now); len(missed) != 0 {
+		t.Errorf("expected no missed runs after a recent run, got %v", missed)
+	}
+
+	every := &admin.Schedule{CronExpr: "* * * * *", CreatedAt: created}
+	if missed, _ := missedRuns(every, now); len(missed) != maxCatchUpRuns || !missed[len(missed)-1].Equal(now) {
+		t.Errorf("expected the %d most recent missed runs, got %d", maxCatchUpRuns, len(missed))
+	}
+}
+
+func TestScheduler_CatchUp(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(dir+"/scripts/nightly.star", []byte(`result = "caught up"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	sched, _ := s.store.Create(&admin.Schedule{
+		Name:       "nightly",
+		CronExpr:   "0 2 * * *",
+		Type:       "script",
+		ScriptName: "nightly.star",
+		CatchUp:    admin.CatchUpOnce,
+	})
+	sched.CreatedAt = now.Add(-72 * time.Hour)
+	sched.UpdatedAt = sched.CreatedAt
+
+	s.catchUp(sched, now)
+
+	waitForRuns(t, s, sched.ID, 1)
+	time.Sleep(50 * time.Millisecond)
+	runs, _ := s.store.ListRuns(sched.ID, 0)
+	if len(runs) != 1 || runs[0].Trigger != admin.TriggerCatchUp || runs[0].Output != "caught up" {
+		t.Errorf("expected a single catch-up run, got %+v", runs)
+	}
+
+	none := *sched
+	none.ID = "other"
+	none.CatchUp = admin.CatchUpNone
+	s.catchUp(&none, now)
+	time.Sleep(50 * time.Millisecond)
+	if runs, _ := s.store.ListRuns("other", 0); len(runs) != 0 {
+		t.Errorf("expected no catch-up with policy none, got %+v", runs)
+	}
+}
+
+func TestScheduler_StopCancelsJitter(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+
+	sched, _ := s.store.Create(&admin.Schedule{
+		Name:          "jittery",
+		CronExpr:      "* * * * *",
+		Type:          "script",
+		ScriptName:    "missing.star",
+		JitterSeconds: 3600,
+	})
+
+	done := make(chan struct{})
+	go func() {
+		s.fire(sched, admin.TriggerCron)
+		close(done)
+	}()
+	s.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Stop to abandon the jitter delay")
+	}
+	if runs, _ := s.store.ListRuns(sched.ID, 0); len(runs) != 0 {
+		t.Errorf("expected no run after Stop, got %+v", runs)
+	}
+}
+
+// recordingChat implements ChatAPI and keeps every message sent.
+type recordingChat struct {
+	mu        sync.Mutex
+	messages  []string // "provider target: content"
+	responses []*chat.ChatResponse
+}
+
+func (m *recordingChat) SendViaProvider(provider, target, content string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, provider+" "+target+": "+content)
+	return nil
+}
+
+func (m *recordingChat) SendResponseViaProvider(provider, target string, resp *chat.ChatResponse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, provider+" "+target+": "+resp.Text)
+	m.responses = append(m.responses, resp)
+	return nil
+}
+
+func (m *recordingChat) take() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	msgs := m.messages
+	m.messages = nil
+	return msgs
+}
+
+func TestScheduler_Retry(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+	defer func(unit time.Duration) { retryBackoffUnit = unit }(retryBackoffUnit)
+	retryBackoffUnit = time.Millisecond
+
+	calls := 0
+	s.SetEngineAPI(&mockEngine{
+		createSessionFn: func() (*engine.Session, error) {
+			calls++
+			return &engine.Session{ID: fmt.Sprintf("session-%d", calls)}, nil
+		},
+		sendFn: func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+			if calls < 3 {
+				return nil, fmt.Errorf("engine busy")
+			}
+			ch := make(chan engine.Response, 1)
+			ch <- engine.Response{Content: "third time lucky"}
+			close(ch)
+			return ch, nil
+		},
+	})
+
+	sched, _ := s.store.Create(&admin.Schedule{
+		Name:     "flaky",
+		CronExpr: "0 2 * * *",
+		Type:     "agent",
+		Prompt:   "Try hard",
+		Retry:    &admin.RetryPolicy{MaxAttempts: 3, BackoffSeconds: 1},
+	})
+
+	s.executeJob(sched, admin.TriggerCron, nil)
+
+	got, _ := s.store.Get(sched.ID)
+	if got.LastRunStatus != "success" || got.ConsecutiveFailures != 0 {
+		t.Errorf("expected the job to succeed on retry, got %+v", got)
+	}
+	runs, _ := s.store.ListRuns(sched.ID, 0)
+	if len(runs) != 3 || runs[0].Attempt != 3 || runs[2].Attempt != 1 || runs[2].Status != "error" {
+		t.Errorf("expected 3 recorded attempts, got %+v", runs)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := &admin.RetryPolicy{MaxAttempts: 5, BackoffSeconds: 10}
+	for attempt, want := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 3: 40 * time.Second} {
+		if got := retryDelay(policy, attempt); got != want {
+			t.Errorf("attempt %d: expected %v, got %v", attempt, want, got)
+		}
+	}
+	if got := retryDelay(&admin.RetryPolicy{MaxAttempts: 2}, 1); got != defaultRetryBackoff {
+		t.Errorf("expected default backoff, got %v", got)
+	}
+	if got := retryDelay(&admin.RetryPolicy{BackoffSeconds: 3600}, 10); got != maxRetryDelay {
+		t.Errorf("expected backoff capped at %v, got %v", maxRetryDelay, got)
+	}
+}
+
+func TestScheduler_OutputModes(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+
+	chat := &recordingChat{}
+	s.SetChatAPI(chat)
+
+	reply := "sunny"
+	s.SetEngineAPI(&mockEngine{
+		createSessionFn: func() (*engine.Session, error) {
+			return &engine.Session{ID: "briefing-session"}, nil
+		},
+		sendFn: func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+			if reply == "" {
+				return nil, fmt.Errorf("engine down")
+			}
+			ch := make(chan engine.Response, 1)
+			ch <- engine.Response{Content: reply}
+			close(ch)
+			return ch, nil
+		},
+	})
+
+	target := &admin.OutputTarget{Provider: "discord", ChannelID: "channel:briefing"}
+	onChange, _ := s.store.Create(&admin.Schedule{
+		Name:         "briefing",
+		CronExpr:     "0 7 * * *",
+		Type:         "agent",
+		Prompt:       "What's the weather?",
+		OutputTarget: target,
+		OutputMode:   admin.OutputOnChange,
+	})
+	onError, _ := s.store.Create(&admin.Schedule{
+		Name:         "quiet",
+		CronExpr:     "0 7 * * *",
+		Type:         "agent",
+		Prompt:       "What's the weather?",
+		OutputTarget: target,
+		OutputMode:   admin.OutputOnError,
+	})
+
+	s.executeJob(onChange, admin.TriggerCron, nil)
+	s.executeJob(onChange, admin.TriggerCron, nil)
+	if msgs := chat.take(); len(msgs) != 1 {
+		t.Errorf("on_change: expected only the first run to be sent, got %v", msgs)
+	}
+
+	reply = "rain"
+	s.executeJob(onChange, admin.TriggerCron, nil)
+	if msgs := chat.take(); len(msgs) != 1 || !strings.Contains(msgs[0], "rain") {
+		t.Errorf("on_change: expected changed output to be sent, got %v", msgs)
+	}
+
+	s.executeJob(onError, admin.TriggerCron, nil)
+	if msgs := chat.take(); len(msgs) != 0 {
+		t.Errorf("on_error: expected successful run not to be sent, got %v", msgs)
+	}
+	reply = ""
+	s.executeJob(onError, admin.TriggerCron, nil)
+	if msgs := chat.take(); len(msgs) != 1 || !strings.Contains(msgs[0], "Status: error") {
+		t.Errorf("on_error: expected failed run to be sent, got %v", msgs)
+	}
+}
+
+func TestScheduler_FailureAlert(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+
+	chat := &recordingChat{}
+	s.SetChatAPI(chat)
+
+	script := dir + "/scripts/backup.star"
+	sched, _ := s.store.Create(&admin.Schedule{
+		Name:             "backup",
+		CronExpr:         "0 3 * * *",
+		Type:             "script",
+		ScriptName:       "backup.star",
+		FailureTarget:    &admin.OutputTarget{Provider: "slack", ChannelID: "C-ALERTS"},
+		FailureThreshold: 2,
+	})
+
+	// The script is missing: the first failure is below the threshold
+	s.executeJob(sched, admin.TriggerCron, nil)
+	if msgs := chat.take(); len(msgs) != 0 {
+		t.Errorf("expected no alert after one failure, got %v", msgs)
+	}
+
+	s.executeJob(sched, admin.TriggerCron, nil)
+	msgs := chat.take()
+	if len(msgs) != 1 || !strings.HasPrefix(msgs[0], "slack C-ALERTS: **Scheduled Job Failed: backup**") {
+		t.Fatalf("expected one failure alert, got %v", msgs)
+	}
+
+	// Alerted once per streak
+	s.executeJob(sched, admin.TriggerCron, nil)
+	if msgs := chat.take(); len(msgs) != 0 {
+		t.Errorf("expected no repeat alert, got %v", msgs)
+	}
+
+	os.WriteFile(script, []byte(`result = "ok"`), 0644)
+	s.executeJob(sched, admin.TriggerCron, nil)
+	msgs = chat.take()
+	if len(msgs) != 1 || !strings.Contains(msgs[0], "Recovered") || !strings.Contains(msgs[0], "3 failed runs") {
+		t.Errorf("expected a recovery notice, got %v", msgs)
+	}
+}
+
+// fakeSessions implements SessionAPI for testing.
+type fakeSessions struct {
+	mu       sync.Mutex
+	channels map[string]string
+	mode     string
+	tools    []chat.ToolCallInfo
+}
+
+func (f *fakeSessions) GetChannelSession(provider, channelID string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.channels[provider+":"+channelID]
+}
+
+func (f *fakeSessions) SetChannelSession(provider, channelID, sessionID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.channels[provider+":"+channelID] = sessionID
+}
+
+func (f *fakeSessions) GetChannelMode(provider, channelID string) string {
+	return f.mode
+}
+
+func (f *fakeSessions) ResolvedToolCalls(sessionID string) []chat.ToolCallInfo {
+	return f.tools
+}
+
+func TestScheduler_AgentSessions(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+
+	created := 0
+	var sentTo []string
+	s.SetEngineAPI(&mockEngine{
+		createSessionFn: func() (*engine.Session, error) {
+			created++
+			return &engine.Session{ID: fmt.Sprintf("session-%d", created)}, nil
+		},
+		sendFn: func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+			sentTo = append(sentTo, sessionID)
+			ch := make(chan engine.Response, 1)
+			ch <- engine.Response{Content: "ok"}
+			close(ch)
+			return ch, nil
+		},
+	})
+	sessions := &fakeSessions{channels: map[string]string{"discord:standup": "chat-session"}}
+	s.SetSessionAPI(sessions)
+
+	target := &admin.OutputTarget{Provider: "discord", ChannelID: "standup"}
+	fresh, _ := s.store.Create(&admin.Schedule{Name: "fresh", CronExpr: "0 9 * * *", Type: "agent", Prompt: "Hi"})
+	named, _ := s.store.Create(&admin.Schedule{Name: "named", CronExpr: "0 9 * * *", Type: "agent", Prompt: "Hi", Session: "standup-notes"})
+	channel, _ := s.store.Create(&admin.Schedule{Name: "channel", CronExpr: "0 9 * * *", Type: "agent", Prompt: "Hi", Session: admin.SessionChannel, OutputTarget: target})
+
+	s.executeJob(fresh, admin.TriggerCron, nil)
+	s.executeJob(fresh, admin.TriggerCron, nil)
+	s.executeJob(named, admin.TriggerCron, nil)
+	s.executeJob(named, admin.TriggerCron, nil)
+	s.executeJob(channel, admin.TriggerCron, nil)
+
+	want := []string{"session-1", "session-2", "session-3", "session-3", "chat-session"}
+	if strings.Join(sentTo, ",") != strings.Join(want, ",") {
+		t.Errorf("expected runs sent to %v, got %v", want, sentTo)
+	}
+	if id, _ := s.store.NamedSession("standup-notes"); id != "session-3" {
+		t.Errorf("expected named session to be saved, got %q", id)
+	}
+
+	// A channel without a session gets a new one bound to it
+	sessions.channels = map[string]string{}
+	s.executeJob(channel, admin.TriggerCron, nil)
+	if got := sessions.GetChannelSession("discord", "standup"); got != "session-4" {
+		t.Errorf("expected the new session to be bound to the channel, got %q", got)
+	}
+}
+
+func TestScheduler_RichOutput(t *testing.T) {
+	s, dir := setupTestScheduler(t)
+	defer os.RemoveAll(dir)
+
+	recorder := &recordingChat{}
+	s.SetChatAPI(recorder)
+	s.SetSessionAPI(&fakeSessions{
+		channels: map[string]string{},
+		mode:     chat.ModeFull,
+		tools:    []chat.ToolCallInfo{{Name: "calendar_read", Output: "2 events"}},
+	})
+
+	long := strings.Repeat("x", 5000)
+	var prompt string
+	s.SetEngineAPI(&mockEngine{
+		createSessionFn: func() (*engine.Session, error) {
+			return &engine.Session{ID: "standup-session"}, nil
+		},
+		sendFn: func(ctx context.Context, sessionID string, messages []engine.Message) (<-chan engine.Response, error) {
+			prompt = messages[0].Content
+			ch := make(chan engine.Response, 4)
+			ch <- engine.Response{Thinking: "Checking the calendar", PartID: "r1"}
+			ch <- engine.Response{Content: "Good morning", PartID: "t1"}
+			ch <- engine.Response{Content: "Good morning. " + long, PartID: "t1"}
+			close(ch)
+			return ch, nil
+		},
+	})
+
+	target := &admin.OutputTarget{Provider: "discord", ChannelID: "standup"}
+	channel, _ := s.store.Create(&admin.Schedule{Name: "standup", CronExpr: "0 9 * * *", Type: "agent", Prompt: "Run the standup", Session: admin.SessionChannel, OutputTarget: target})
+	report, _ := s.store.Create(&admin.Schedule{Name: "report", CronExpr: "0 9 * * *", Type: "agent", Prompt: "Write the report", OutputTarget: target})
+
+	s.executeJob(channel, admin.TriggerCron, nil)
+	if prompt != "[scheduled job: standup]\nRun the standup" {
+		t.Errorf("unexpected prompt for a continued session: %q", prompt)
+	}
+	if len(recorder.responses) != 1 {
+		t.Fatalf("expected one rich response, got %d", len(recorder.responses))
+	}
+	resp := recorder.responses[0]
+	if resp.Text != "Good morning. "+long {
+		t.Errorf("expected the full reply without a header, got %d chars", len(resp.Text))
+	}
+	if resp.Thinking != "Checking the calendar" || len(resp.ToolCalls) != 1 {
+		t.Errorf("expected thinking and tool calls for full mode, got %+v", resp)
+	}
+
+	s.executeJob(report, admin.TriggerCron, nil)
+	if prompt != "Write the report" {
+		t.Errorf("a new session should get the plain prompt, got %q", prompt)
+	}
+	if text := recorder.responses[1].Text; !strings.HasPrefix(text, "**Scheduled Job: report**\nGood morning.") {
+		t.Errorf("expected a header on a one-off session's reply, got %.60q", text)
+	}
+}
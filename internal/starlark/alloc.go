@@ -0,0 +1,637 @@
+package starlark
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Starlark evaluates operators like + and * without calling a built-in, so a
+// script doubling a string in a loop would reach gigabytes within a few dozen
+// steps. Before a file is compiled, checkAllocations rewrites the operators,
+// methods and built-ins that can build large values into calls to checked
+// versions, which charge the size of the new value to the run's memory budget
+// before building it. Values that grow one element per step (list literals,
+// comprehensions) are still bounded by the step limit alone.
+
+// Names of the checked operations scripts are rewritten to call.
+const (
+	checkedBinaryName = "__openpact_binary" // (op, x, y): x op y
+	checkedIndexName  = "__openpact_index"  // (op, x, i, y): x[i] op= y
+	checkedFieldName  = "__openpact_field"  // (op, x, name, y): x.name op= y
+	checkedMethodName = "__openpact_method" // (x, name, *args, **kwargs): x.name(...)
+	checkedSliceName  = "__openpact_slice"  // (x[lo:hi:step], 1 if stepped)
+)
+
+// checkedOps maps the operators that can grow a value to the op passed to
+// the checked built-ins. "+=" extends lists in place, as Starlark does.
+var checkedOps = map[syntax.Token]string{
+	syntax.PLUS:       "+",
+	syntax.STAR:       "*",
+	syntax.PERCENT:    "%",
+	syntax.PLUS_EQ:    "+=",
+	syntax.STAR_EQ:    "*",
+	syntax.PERCENT_EQ: "%",
+}
+
+var opTokens = map[string]syntax.Token{
+	"+": syntax.PLUS,
+	"*": syntax.STAR,
+	"%": syntax.PERCENT,
+}
+
+// checkedMethods are the methods that build or grow a value.
+var checkedMethods = map[string]bool{
+	"append": true, "capitalize": true, "extend": true, "format": true,
+	"insert": true, "items": true, "join": true, "keys": true, "lower": true,
+	"replace": true, "rsplit": true, "split": true, "splitlines": true,
+	"title": true, "update": true, "upper": true, "values": true,
+}
+
+// checkedUniverse are the universal built-ins that copy their arguments.
+var checkedUniverse = []string{"bytes", "dict", "enumerate", "list", "repr", "reversed", "sorted", "str", "tuple", "zip"}
+
+// allocBuiltins are predeclared in every file the sandbox compiles. The
+// checked universal built-ins shadow the originals under the same names.
+var allocBuiltins = func() starlark.StringDict {
+	d := starlark.StringDict{
+		checkedBinaryName: starlark.NewBuiltin("binary", checkedBinary),
+		checkedIndexName:  starlark.NewBuiltin("index", checkedIndex),
+		checkedFieldName:  starlark.NewBuiltin("field", checkedField),
+		checkedMethodName: starlark.NewBuiltin("method", checkedMethod),
+		checkedSliceName:  starlark.NewBuiltin("slice", checkedSlice),
+	}
+	for _, name := range checkedUniverse {
+		d[name] = checkedBuiltin(name, starlark.Universe[name])
+	}
+	return d
+}()
+
+// execFile is starlark.ExecFile with the file's allocations checked against
+// the run's memory budget.
+func execFile(thread *starlark.Thread, filename string, source any, predeclared starlark.StringDict) (starlark.StringDict, error) {
+	f, err := syntax.LegacyFileOptions().Parse(filename, source, 0)
+	if err != nil {
+		return nil, err
+	}
+	checkAllocations(f)
+
+	env := make(starlark.StringDict, len(allocBuiltins)+len(predeclared))
+	for k, v := range allocBuiltins {
+		env[k] = v
+	}
+	for k, v := range predeclared {
+		env[k] = v
+	}
+	prog, err := starlark.FileProgram(f, env.Has)
+	if err != nil {
+		return nil, err
+	}
+	globals, err := prog.Init(thread, env)
+	globals.Freeze()
+	return globals, err
+}
+
+// checkAllocations rewrites f to build values through the checked built-ins.
+func checkAllocations(f *syntax.File) {
+	rewriteStmts(f.Stmts)
+}
+
+func rewriteStmts(stmts []syntax.Stmt) {
+	for i, stmt := range stmts {
+		stmts[i] = rewriteStmt(stmt)
+	}
+}
+
+func rewriteStmt(stmt syntax.Stmt) syntax.Stmt {
+	switch stmt := stmt.(type) {
+	case *syntax.AssignStmt:
+		stmt.RHS = rewriteExpr(stmt.RHS)
+		op, ok := checkedOps[stmt.Op]
+		if !ok {
+			stmt.LHS = rewriteTarget(stmt.LHS)
+			return stmt
+		}
+		pos := stmt.OpPos
+		switch lhs := unparen(stmt.LHS).(type) {
+		case *syntax.Ident:
+			// x += y becomes x = binary("+=", x, y)
+			x := &syntax.Ident{NamePos: lhs.NamePos, Name: lhs.Name}
+			stmt.Op = syntax.EQ
+			stmt.RHS = checkedCall(checkedBinaryName, pos, str(op, pos), x, stmt.RHS)
+		case *syntax.IndexExpr:
+			return &syntax.ExprStmt{X: checkedCall(checkedIndexName, pos, str(op, pos), rewriteExpr(lhs.X), rewriteExpr(lhs.Y), stmt.RHS)}
+		case *syntax.DotExpr:
+			return &syntax.ExprStmt{X: checkedCall(checkedFieldName, pos, str(op, pos), rewriteExpr(lhs.X), str(lhs.Name.Name, pos), stmt.RHS)}
+		}
+	case *syntax.DefStmt:
+		rewriteParams(stmt.Params)
+		rewriteStmts(stmt.Body)
+	case *syntax.ExprStmt:
+		stmt.X = rewriteExpr(stmt.X)
+	case *syntax.ForStmt:
+		stmt.X = rewriteExpr(stmt.X)
+		rewriteStmts(stmt.Body)
+	case *syntax.WhileStmt:
+		stmt.Cond = rewriteExpr(stmt.Cond)
+		rewriteStmts(stmt.Body)
+	case *syntax.IfStmt:
+		stmt.Cond = rewriteExpr(stmt.Cond)
+		rewriteStmts(stmt.True)
+		rewriteStmts(stmt.False)
+	case *syntax.ReturnStmt:
+		if stmt.Result != nil {
+			stmt.Result = rewriteExpr(stmt.Result)
+		}
+	}
+	return stmt
+}
+
+// rewriteTarget rewrites the expressions inside an assignment target.
+func rewriteTarget(e syntax.Expr) syntax.Expr {
+	switch e := e.(type) {
+	case *syntax.IndexExpr:
+		e.X = rewriteExpr(e.X)
+		e.Y = rewriteExpr(e.Y)
+	case *syntax.DotExpr:
+		e.X = rewriteExpr(e.X)
+	case *syntax.ParenExpr:
+		e.X = rewriteTarget(e.X)
+	case *syntax.TupleExpr:
+		for i, x := range e.List {
+			e.List[i] = rewriteTarget(x)
+		}
+	case *syntax.ListExpr:
+		for i, x := range e.List {
+			e.List[i] = rewriteTarget(x)
+		}
+	}
+	return e
+}
+
+// rewriteParams rewrites parameter defaults and call arguments, which may
+// be name=value.
+func rewriteParams(params []syntax.Expr) {
+	for i, param := range params {
+		if b, ok := param.(*syntax.BinaryExpr); ok && b.Op == syntax.EQ {
+			b.Y = rewriteExpr(b.Y)
+			continue
+		}
+		params[i] = rewriteExpr(param)
+	}
+}
+
+func rewriteExpr(e syntax.Expr) syntax.Expr {
+	switch e := e.(type) {
+	case *syntax.BinaryExpr:
+		e.X = rewriteExpr(e.X)
+		e.Y = rewriteExpr(e.Y)
+		if op, ok := checkedOps[e.Op]; ok {
+			return checkedCall(checkedBinaryName, e.OpPos, str(op, e.OpPos), e.X, e.Y)
+		}
+	case *syntax.CallExpr:
+		e.Fn = rewriteExpr(e.Fn)
+		rewriteParams(e.Args)
+		if dot, ok := e.Fn.(*syntax.DotExpr); ok && checkedMethods[dot.Name.Name] {
+			// x.name(args) becomes method(x, "name", args)
+			e.Fn = &syntax.Ident{NamePos: dot.NamePos, Name: checkedMethodName}
+			e.Args = append([]syntax.Expr{dot.X, str(dot.Name.Name, dot.NamePos)}, e.Args...)
+		}
+	case *syntax.Comprehension:
+		e.Body = rewriteExpr(e.Body)
+		for _, clause := range e.Clauses {
+			switch clause := clause.(type) {
+			case *syntax.ForClause:
+				clause.X = rewriteExpr(clause.X)
+			case *syntax.IfClause:
+				clause.Cond = rewriteExpr(clause.Cond)
+			}
+		}
+	case *syntax.CondExpr:
+		e.Cond = rewriteExpr(e.Cond)
+		e.True = rewriteExpr(e.True)
+		e.False = rewriteExpr(e.False)
+	case *syntax.DictExpr:
+		for _, entry := range e.List {
+			entry := entry.(*syntax.DictEntry)
+			entry.Key = rewriteExpr(entry.Key)
+			entry.Value = rewriteExpr(entry.Value)
+		}
+	case *syntax.ListExpr:
+		for i, x := range e.List {
+			e.List[i] = rewriteExpr(x)
+		}
+	case *syntax.TupleExpr:
+		for i, x := range e.List {
+			e.List[i] = rewriteExpr(x)
+		}
+	case *syntax.ParenExpr:
+		e.X = rewriteExpr(e.X)
+	case *syntax.DotExpr:
+		e.X = rewriteExpr(e.X)
+	case *syntax.IndexExpr:
+		e.X = rewriteExpr(e.X)
+		e.Y = rewriteExpr(e.Y)
+	case *syntax.SliceExpr:
+		e.X = rewriteExpr(e.X)
+		for _, bound := range []*syntax.Expr{&e.Lo, &e.Hi, &e.Step} {
+			if *bound != nil {
+				*bound = rewriteExpr(*bound)
+			}
+		}
+		stepped := &syntax.Literal{Token: syntax.INT, TokenPos: e.Lbrack, Raw: "0", Value: int64(0)}
+		if e.Step != nil {
+			stepped.Raw, stepped.Value = "1", int64(1)
+		}
+		return checkedCall(checkedSliceName, e.Lbrack, e, stepped)
+	case *syntax.UnaryExpr:
+		if e.X != nil {
+			e.X = rewriteExpr(e.X)
+		}
+	case *syntax.LambdaExpr:
+		rewriteParams(e.Params)
+		e.Body = rewriteExpr(e.Body)
+	}
+	return e
+}
+
+func unparen(e syntax.Expr) syntax.Expr {
+	for {
+		p, ok := e.(*syntax.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
+
+func checkedCall(name string, pos syntax.Position, args ...syntax.Expr) *syntax.CallExpr {
+	return &syntax.CallExpr{
+		Fn:     &syntax.Ident{NamePos: pos, Name: name},
+		Lparen: pos,
+		Args:   args,
+		Rparen: pos,
+	}
+}
+
+func str(s string, pos syntax.Position) *syntax.Literal {
+	return &syntax.Literal{Token: syntax.STRING, TokenPos: pos, Raw: strconv.Quote(s), Value: s}
+}
+
+// checkedBinary computes x op y.
+func checkedBinary(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return binary(thread, string(args[0].(starlark.String)), args[1], args[2])
+}
+
+func binary(thread *starlark.Thread, op string, x, y starlark.Value) (starlark.Value, error) {
+	if op == "+=" {
+		if list, ok := x.(*starlark.List); ok {
+			if iter, ok := y.(starlark.Iterable); ok {
+				return list, extend(thread, list, iter)
+			}
+		}
+		op = "+"
+	}
+	tok := opTokens[op]
+	if err := charge(thread, binarySize(thread, tok, x, y)); err != nil {
+		return nil, err
+	}
+	return starlark.Binary(tok, x, y)
+}
+
+// binarySize returns the bytes x op y allocates.
+func binarySize(thread *starlark.Thread, op syntax.Token, x, y starlark.Value) int64 {
+	switch op {
+	case syntax.PLUS:
+		if x, ok := x.(starlark.Int); ok {
+			if y, ok := y.(starlark.Int); ok {
+				return intSize(x) + intSize(y)
+			}
+		}
+		return itemsSize(x) + itemsSize(y)
+	case syntax.STAR:
+		if n, ok := y.(starlark.Int); ok {
+			if x, ok := x.(starlark.Int); ok {
+				return intSize(x) + intSize(n)
+			}
+			return repeatSize(x, n)
+		}
+		if n, ok := x.(starlark.Int); ok {
+			return repeatSize(y, n)
+		}
+	case syntax.PERCENT:
+		if format, ok := x.(starlark.String); ok {
+			return int64(len(format)) + sizeUpTo(y, remaining(thread))
+		}
+	}
+	return 0
+}
+
+// itemsSize returns the bytes a copy of a string or sequence allocates.
+func itemsSize(v starlark.Value) int64 {
+	switch v := v.(type) {
+	case starlark.String:
+		return int64(len(v))
+	case starlark.Bytes:
+		return int64(len(v))
+	case *starlark.List:
+		return 16 * int64(v.Len())
+	case starlark.Tuple:
+		return 16 * int64(len(v))
+	}
+	return 0
+}
+
+// repeatSize returns the bytes v * n allocates.
+func repeatSize(v starlark.Value, n starlark.Int) int64 {
+	count, ok := n.Int64()
+	size := itemsSize(v)
+	if !ok || count <= 0 || size == 0 {
+		return 0
+	}
+	if count > math.MaxInt64/size {
+		return math.MaxInt64
+	}
+	return size * count
+}
+
+func intSize(i starlark.Int) int64 {
+	if _, ok := i.Int64(); ok {
+		return 0
+	}
+	return int64(i.BigInt().BitLen() / 8)
+}
+
+// extend appends the elements of iter to list, as list += iter does.
+func extend(thread *starlark.Thread, list *starlark.List, iter starlark.Iterable) error {
+	if seq, ok := iter.(starlark.Sequence); ok {
+		if err := charge(thread, 16*int64(seq.Len())); err != nil {
+			return err
+		}
+	}
+	// Collected first, so a list can be added to itself
+	var elems []starlark.Value
+	it := iter.Iterate()
+	var x starlark.Value
+	for it.Next(&x) {
+		elems = append(elems, x)
+	}
+	it.Done()
+	for _, x := range elems {
+		if err := list.Append(x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkedIndex computes x[i] op= y.
+func checkedIndex(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	op, x, i, y := string(args[0].(starlark.String)), args[1], args[2], args[3]
+	switch x := x.(type) {
+	case starlark.HasSetKey:
+		old, found, err := x.Get(i)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("key %v not in %s", i, x.Type())
+		}
+		z, err := binary(thread, op, old, y)
+		if err != nil {
+			return nil, err
+		}
+		return starlark.None, x.SetKey(i, z)
+	case starlark.HasSetIndex:
+		n, err := starlark.AsInt32(i)
+		if err != nil {
+			return nil, fmt.Errorf("%s index: %s", x.Type(), err)
+		}
+		index := n
+		if index < 0 {
+			index += x.Len()
+		}
+		if index < 0 || index >= x.Len() {
+			return nil, fmt.Errorf("%s index %d out of range [%d:%d]", x.Type(), n, -x.Len(), x.Len()-1)
+		}
+		z, err := binary(thread, op, x.Index(index), y)
+		if err != nil {
+			return nil, err
+		}
+		return starlark.None, x.SetIndex(index, z)
+	default:
+		return nil, fmt.Errorf("%s value does not support item assignment", x.Type())
+	}
+}
+
+// checkedField computes x.name op= y.
+func checkedField(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	op, x, name, y := string(args[0].(starlark.String)), args[1], string(args[2].(starlark.String)), args[3]
+	old, err := attr(x, name)
+	if err != nil {
+		return nil, err
+	}
+	z, err := binary(thread, op, old, y)
+	if err != nil {
+		return nil, err
+	}
+	setter, ok := x.(starlark.HasSetField)
+	if !ok {
+		return nil, fmt.Errorf("can't assign to .%s field of %s", name, x.Type())
+	}
+	return starlark.None, setter.SetField(name, z)
+}
+
+func attr(x starlark.Value, name string) (starlark.Value, error) {
+	if x, ok := x.(starlark.HasAttrs); ok {
+		v, err := x.Attr(name)
+		if err != nil || v != nil {
+			return v, err
+		}
+	}
+	return nil, fmt.Errorf("%s has no .%s field or method", x.Type(), name)
+}
+
+// checkedMethod calls x.name(args).
+func checkedMethod(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	x, name := args[0], string(args[1].(starlark.String))
+	args = args[2:]
+	method, err := attr(x, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := charge(thread, methodSize(thread, x, name, args, kwargs)); err != nil {
+		return nil, err
+	}
+	result, err := starlark.Call(thread, method, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := x.(starlark.String); ok && (strings.HasSuffix(name, "split") || name == "splitlines") {
+		// The parts share the string's bytes; only the list is new
+		if err := charge(thread, itemsSize(result)); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// methodSize returns the bytes x.name(args) allocates.
+func methodSize(thread *starlark.Thread, x starlark.Value, name string, args starlark.Tuple, kwargs []starlark.Tuple) int64 {
+	switch x := x.(type) {
+	case starlark.String:
+		switch name {
+		case "capitalize", "lower", "title", "upper":
+			return int64(len(x))
+		case "format":
+			n := int64(len(x)) + sizeUpTo(args, remaining(thread))
+			for _, kv := range kwargs {
+				n += sizeUpTo(kv[1], remaining(thread))
+			}
+			return n
+		case "join":
+			if len(args) == 1 {
+				return joinSize(thread, x, args[0])
+			}
+		case "replace":
+			if len(args) >= 2 {
+				return replaceSize(x, args)
+			}
+		}
+	case *starlark.List:
+		switch name {
+		case "append", "insert":
+			return 16
+		case "extend":
+			if len(args) == 1 {
+				if seq, ok := args[0].(starlark.Sequence); ok {
+					return 16 * int64(seq.Len())
+				}
+			}
+		}
+	case *starlark.Dict:
+		switch name {
+		case "keys", "values":
+			return 16 * int64(x.Len())
+		case "items":
+			return 64 * int64(x.Len())
+		case "update":
+			n := 48 * int64(len(kwargs))
+			if len(args) == 1 {
+				if seq, ok := args[0].(starlark.Sequence); ok {
+					n += 48 * int64(seq.Len())
+				}
+			}
+			return n
+		}
+	}
+	return 0
+}
+
+// joinSize returns the bytes sep.join(iterable) allocates, or 0 if the
+// iterable holds something other than strings, which join refuses.
+func joinSize(thread *starlark.Thread, sep starlark.String, iterable starlark.Value) int64 {
+	iter, ok := iterable.(starlark.Iterable)
+	if !ok {
+		return 0
+	}
+	limit := remaining(thread)
+	it := iter.Iterate()
+	defer it.Done()
+	var n, count int64
+	var x starlark.Value
+	for it.Next(&x) && n <= limit {
+		s, ok := x.(starlark.String)
+		if !ok {
+			return 0
+		}
+		if count > 0 {
+			n += int64(len(sep))
+		}
+		n += int64(len(s))
+		count++
+	}
+	return n
+}
+
+// replaceSize returns the bytes s.replace(old, new, count) allocates.
+func replaceSize(s starlark.String, args starlark.Tuple) int64 {
+	old, ok1 := args[0].(starlark.String)
+	repl, ok2 := args[1].(starlark.String)
+	if !ok1 || !ok2 || len(repl) <= len(old) {
+		return int64(len(s))
+	}
+	count := int64(strings.Count(string(s), string(old)))
+	if len(old) == 0 {
+		count = int64(utf8.RuneCountInString(string(s))) + 1
+	}
+	if len(args) == 3 {
+		if n, err := starlark.AsInt32(args[2]); err == nil && n >= 0 && int64(n) < count {
+			count = int64(n)
+		}
+	}
+	return int64(len(s)) + count*int64(len(repl)-len(old))
+}
+
+// checkedBuiltin wraps a universal built-in that copies its arguments.
+func checkedBuiltin(name string, fn starlark.Value) *starlark.Builtin {
+	return starlark.NewBuiltin(name, func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if len(args) > 0 {
+			if err := charge(thread, builtinSize(thread, name, args)); err != nil {
+				return nil, err
+			}
+		}
+		return starlark.Call(thread, fn, args, kwargs)
+	})
+}
+
+// builtinSize returns the bytes the universal built-in name allocates.
+func builtinSize(thread *starlark.Thread, name string, args starlark.Tuple) int64 {
+	switch name {
+	case "str", "repr":
+		if _, ok := args[0].(starlark.String); ok && name == "str" {
+			return 0
+		}
+		return sizeUpTo(args[0], remaining(thread))
+	case "bytes":
+		return itemsSize(args[0])
+	case "zip":
+		var n int64 = -1
+		for _, arg := range args {
+			if seq, ok := arg.(starlark.Sequence); ok && (n < 0 || int64(seq.Len()) < n) {
+				n = int64(seq.Len())
+			}
+		}
+		if n < 0 {
+			return 0
+		}
+		return 16 * (int64(len(args)) + 1) * n
+	}
+	seq, ok := args[0].(starlark.Sequence)
+	if !ok {
+		return 0
+	}
+	switch name {
+	case "dict", "enumerate":
+		return 48 * int64(seq.Len())
+	default:
+		return 16 * int64(seq.Len())
+	}
+}
+
+// checkedSlice accounts a slice of a list, or a stepped slice of a string,
+// which copy the sliced elements.
+func checkedSlice(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	result := args[0]
+	if _, ok := result.(*starlark.List); ok || args[1].Truth() == starlark.True {
+		if err := charge(thread, itemsSize(result)); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
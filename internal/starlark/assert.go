@@ -0,0 +1,134 @@
+package starlark
+
+import (
+	"fmt"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// assertModule returns the assert module available to test files. Each
+// function fails the calling test with an error when its check fails; msg,
+// if given, is added to the error.
+func assertModule() starlark.Value {
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"eq":       starlark.NewBuiltin("assert.eq", assertEq),
+		"ne":       starlark.NewBuiltin("assert.ne", assertNe),
+		"true":     starlark.NewBuiltin("assert.true", assertTrue),
+		"false":    starlark.NewBuiltin("assert.false", assertFalse),
+		"contains": starlark.NewBuiltin("assert.contains", assertContains),
+		"fails":    starlark.NewBuiltin("assert.fails", assertFails),
+	})
+}
+
+func assertFailure(b *starlark.Builtin, msg string, format string, args ...any) error {
+	err := fmt.Sprintf(format, args...)
+	if msg != "" {
+		err += ": " + msg
+	}
+	return fmt.Errorf("%s: %s", b.Name(), err)
+}
+
+// assert.eq(got, want, msg="")
+func assertEq(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var got, want starlark.Value
+	var msg string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "got", &got, "want", &want, "msg?", &msg); err != nil {
+		return starlark.None, err
+	}
+	eq, err := starlark.Equal(got, want)
+	if err != nil {
+		return starlark.None, err
+	}
+	if !eq {
+		return starlark.None, assertFailure(b, msg, "got %s, want %s", got, want)
+	}
+	return starlark.None, nil
+}
+
+// assert.ne(got, unwanted, msg="")
+func assertNe(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var got, unwanted starlark.Value
+	var msg string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "got", &got, "unwanted", &unwanted, "msg?", &msg); err != nil {
+		return starlark.None, err
+	}
+	eq, err := starlark.Equal(got, unwanted)
+	if err != nil {
+		return starlark.None, err
+	}
+	if eq {
+		return starlark.None, assertFailure(b, msg, "got %s, want a different value", got)
+	}
+	return starlark.None, nil
+}
+
+// assert.true(cond, msg="")
+func assertTrue(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var cond starlark.Value
+	var msg string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "cond", &cond, "msg?", &msg); err != nil {
+		return starlark.None, err
+	}
+	if !cond.Truth() {
+		return starlark.None, assertFailure(b, msg, "%s is not true", cond)
+	}
+	return starlark.None, nil
+}
+
+// assert.false(cond, msg="")
+func assertFalse(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var cond starlark.Value
+	var msg string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "cond", &cond, "msg?", &msg); err != nil {
+		return starlark.None, err
+	}
+	if cond.Truth() {
+		return starlark.None, assertFailure(b, msg, "%s is not false", cond)
+	}
+	return starlark.None, nil
+}
+
+// assert.contains(container, item, msg="")
+func assertContains(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var container, item starlark.Value
+	var msg string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "container", &container, "item", &item, "msg?", &msg); err != nil {
+		return starlark.None, err
+	}
+	in, err := starlark.Binary(syntax.IN, item, container)
+	if err != nil {
+		return starlark.None, err
+	}
+	if !in.Truth() {
+		return starlark.None, assertFailure(b, msg, "%s not in %s", item, container)
+	}
+	return starlark.None, nil
+}
+
+// assert.fails(fn, pattern="") calls fn and returns its error message,
+// failing unless fn fails with an error containing pattern.
+func assertFails(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var fn starlark.Callable
+	var pattern string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "fn", &fn, "pattern?", &pattern); err != nil {
+		return starlark.None, err
+	}
+	_, err := starlark.Call(thread, fn, nil, nil)
+	if err == nil {
+		return starlark.None, fmt.Errorf("%s: %s did not fail", b.Name(), fn.Name())
+	}
+	if limits, ok := thread.Local(budgetLocal).(*budget); ok && limits.errorKind(err) != "" {
+		return starlark.None, err // Limits are not the failure under test
+	}
+	errMsg := err.Error()
+	if evalErr, ok := err.(*starlark.EvalError); ok {
+		errMsg = evalErr.Msg
+	}
+	if !strings.Contains(errMsg, pattern) {
+		return starlark.None, fmt.Errorf("%s: error %q does not contain %q", b.Name(), errMsg, pattern)
+	}
+	return starlark.String(errMsg), nil
+}
@@ -0,0 +1,175 @@
+package starlark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+// Capabilities limit what a single execution may do. Scripts declare them
+// in header comments next to @description:
+//
+//	# @hosts: api.github.com, *.weatherapi.com
+//	# @secrets: GITHUB_TOKEN
+//	# @max_runtime: 10s
+//	# @max_response_bytes: 1048576
+//
+// An execution without capabilities in its context is unrestricted.
+type Capabilities struct {
+	Hosts            []string `json:"hosts"`                        // Hosts http.get/http.post may reach; "*.example.com" matches subdomains
+	Secrets          []string `json:"secrets"`                      // Secrets secrets.get may read
+	MaxRuntimeMs     int64    `json:"max_runtime_ms,omitempty"`     // Lowers the sandbox timeout; 0 keeps it
+	MaxResponseBytes int64    `json:"max_response_bytes,omitempty"` // Largest HTTP response body; 0 keeps the 10MB default
+}
+
+// ParseCapabilities reads the capability manifest from a script's header
+// comments. A script without one gets an empty set: no hosts and no secrets.
+func ParseCapabilities(source string) (*Capabilities, error) {
+	caps := &Capabilities{Hosts: []string{}, Secrets: []string{}}
+	for _, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(strings.TrimPrefix(line, "#")), ":")
+		if !ok || !strings.HasPrefix(key, "@") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "@hosts":
+			for _, host := range splitList(value) {
+				if strings.Contains(host, "/") || strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+					return nil, fmt.Errorf("invalid @hosts entry %q: use a host name or *.domain", host)
+				}
+				caps.Hosts = append(caps.Hosts, strings.ToLower(host))
+			}
+		case "@secrets", "@secret":
+			caps.Secrets = append(caps.Secrets, splitList(value)...)
+		case "@max_runtime":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid @max_runtime %q: use a duration such as 10s", value)
+			}
+			caps.MaxRuntimeMs = d.Milliseconds()
+		case "@max_response_bytes":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid @max_response_bytes %q: use a positive number of bytes", value)
+			}
+			caps.MaxResponseBytes = n
+		}
+	}
+	return caps, nil
+}
+
+// splitList splits a comma-separated header value. "none" is an empty list.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" && !strings.EqualFold(item, "none") {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// AllowsHost reports whether host may be reached.
+func (c *Capabilities) AllowsHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range c.Hosts {
+		if pattern == host {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok && strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsSecret reports whether the named secret may be read.
+func (c *Capabilities) AllowsSecret(name string) bool {
+	for _, s := range c.Secrets {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+type capabilitiesKey struct{}
+
+// capabilitiesLocal is the thread-local key built-ins read capabilities from.
+const capabilitiesLocal = "openpact.capabilities"
+
+// WithCapabilities returns a context under which Execute, ExecuteWithArgs
+// and ExecuteFunction enforce caps.
+func WithCapabilities(ctx context.Context, caps *Capabilities) context.Context {
+	return context.WithValue(ctx, capabilitiesKey{}, caps)
+}
+
+// capabilitiesFrom returns the capabilities set on ctx, or nil.
+func capabilitiesFrom(ctx context.Context) *Capabilities {
+	caps, _ := ctx.Value(capabilitiesKey{}).(*Capabilities)
+	return caps
+}
+
+// threadCapabilities returns the capabilities of the execution running on
+// thread, or nil when it is unrestricted.
+func threadCapabilities(thread *starlark.Thread) *Capabilities {
+	caps, _ := thread.Local(capabilitiesLocal).(*Capabilities)
+	return caps
+}
+
+// executionTimeout applies a capability runtime limit to the sandbox timeout.
+func (s *Sandbox) executionTimeout(caps *Capabilities) time.Duration {
+	ms := s.maxExecutionMs
+	if caps != nil && caps.MaxRuntimeMs > 0 && caps.MaxRuntimeMs < ms {
+		ms = caps.MaxRuntimeMs
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// checkHost rejects URLs whose host the execution may not reach.
+func checkHost(caps *Capabilities, u *url.URL) error {
+	if caps != nil && !caps.AllowsHost(u.Hostname()) {
+		return fmt.Errorf("host %q is not allowed by the script's @hosts", u.Hostname())
+	}
+	return nil
+}
+
+// checkRedirect keeps redirects within the hosts of the request's
+// capabilities.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return checkHost(capabilitiesFrom(req.Context()), req.URL)
+}
+
+// readBody reads an HTTP response body up to the 10MB default, or up to
+// the capability limit, which is an error to exceed.
+func readBody(r io.Reader, caps *Capabilities) ([]byte, error) {
+	if caps == nil || caps.MaxResponseBytes <= 0 || caps.MaxResponseBytes >= maxResponseBytes {
+		return io.ReadAll(io.LimitReader(r, maxResponseBytes))
+	}
+	body, err := io.ReadAll(io.LimitReader(r, caps.MaxResponseBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > caps.MaxResponseBytes {
+		return nil, fmt.Errorf("response exceeds the script's @max_response_bytes (%d)", caps.MaxResponseBytes)
+	}
+	return body, nil
+}
@@ -0,0 +1,139 @@
+package starlark
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCapabilities(t *testing.T) {
+	caps, err := ParseCapabilities(`# @description: Weather
+# @hosts: api.weatherapi.com, *.Example.com
+# @secrets: WEATHER_API_KEY
+# @max_runtime: 10s
+# @max_response_bytes: 4096
+result = 1
+`)
+	if err != nil {
+		t.Fatalf("ParseCapabilities failed: %v", err)
+	}
+	if len(caps.Hosts) != 2 || caps.Hosts[1] != "*.example.com" {
+		t.Errorf("hosts = %v", caps.Hosts)
+	}
+	if len(caps.Secrets) != 1 || caps.Secrets[0] != "WEATHER_API_KEY" {
+		t.Errorf("secrets = %v", caps.Secrets)
+	}
+	if caps.MaxRuntimeMs != 10000 || caps.MaxResponseBytes != 4096 {
+		t.Errorf("limits = %d ms, %d bytes", caps.MaxRuntimeMs, caps.MaxResponseBytes)
+	}
+
+	if !caps.AllowsHost("API.weatherapi.com") || !caps.AllowsHost("a.b.example.com") {
+		t.Error("expected declared hosts to be allowed")
+	}
+	if caps.AllowsHost("example.com") || caps.AllowsHost("evil.com") || caps.AllowsHost("weatherapi.com") {
+		t.Error("expected undeclared hosts to be denied")
+	}
+
+	empty, err := ParseCapabilities("# @secrets: none\nresult = 1\n")
+	if err != nil || len(empty.Hosts) != 0 || len(empty.Secrets) != 0 {
+		t.Errorf("expected an empty set, got %+v, %v", empty, err)
+	}
+
+	for _, bad := range []string{
+		"# @hosts: https://example.com/path",
+		"# @hosts: api.*.com",
+		"# @max_runtime: forever",
+		"# @max_response_bytes: -1",
+	} {
+		if _, err := ParseCapabilities(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestCapabilitiesHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://localhost:1/elsewhere", http.StatusFound)
+			return
+		}
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+
+	s := New(Config{})
+	script := `result = http.get("` + server.URL + `")["body"]`
+
+	// Unrestricted without capabilities
+	if result := s.Execute(context.Background(), "t.star", script); result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+
+	denied := WithCapabilities(context.Background(), &Capabilities{})
+	if result := s.Execute(denied, "t.star", script); !strings.Contains(result.Error, "not allowed") {
+		t.Errorf("expected host to be denied, got %v / %q", result.Value, result.Error)
+	}
+
+	allowed := WithCapabilities(context.Background(), &Capabilities{Hosts: []string{"127.0.0.1"}})
+	if result := s.Execute(allowed, "t.star", script); result.Error != "" {
+		t.Errorf("expected declared host to be allowed, got %q", result.Error)
+	}
+
+	redirect := `result = http.get("` + server.URL + `/redirect")`
+	if result := s.Execute(allowed, "t.star", redirect); !strings.Contains(result.Error, "not allowed") {
+		t.Errorf("expected redirect to an undeclared host to be denied, got %q", result.Error)
+	}
+
+	limited := WithCapabilities(context.Background(), &Capabilities{Hosts: []string{"127.0.0.1"}, MaxResponseBytes: 50})
+	if result := s.Execute(limited, "t.star", script); !strings.Contains(result.Error, "max_response_bytes") {
+		t.Errorf("expected response limit error, got %q", result.Error)
+	}
+}
+
+func TestCapabilitiesSecrets(t *testing.T) {
+	s := New(Config{})
+	sp := NewSecretProvider()
+	sp.Set("WEATHER_API_KEY", "weather-secret")
+	sp.Set("GITHUB_TOKEN", "github-secret")
+	s.InjectSecrets(sp)
+
+	ctx := WithCapabilities(context.Background(), &Capabilities{Secrets: []string{"WEATHER_API_KEY"}})
+
+	result := s.Execute(ctx, "t.star", `result = secrets.get("WEATHER_API_KEY")`)
+	if result.Value != "weather-secret" {
+		t.Errorf("expected declared secret, got %v / %q", result.Value, result.Error)
+	}
+
+	result = s.Execute(ctx, "t.star", `result = secrets.get("GITHUB_TOKEN")`)
+	if !strings.Contains(result.Error, "not declared") {
+		t.Errorf("expected undeclared secret to be denied, got %v / %q", result.Value, result.Error)
+	}
+
+	result = s.Execute(ctx, "t.star", `result = secrets.list()`)
+	if names, ok := result.Value.([]any); !ok || len(names) != 1 || names[0] != "WEATHER_API_KEY" {
+		t.Errorf("expected only declared secrets to be listed, got %v", result.Value)
+	}
+}
+
+func TestCapabilitiesMaxRuntime(t *testing.T) {
+	s := New(Config{MaxExecutionMs: 5000})
+	ctx := WithCapabilities(context.Background(), &Capabilities{MaxRuntimeMs: 50})
+
+	start := time.Now()
+	result := s.Execute(ctx, "t.star", `
+def main():
+    x = 0
+    for i in range(100000000):
+        x += i
+    return x
+`)
+	if result.Error == "" {
+		t.Error("expected timeout error")
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("expected max_runtime to cut the run short, took %v", time.Since(start))
+	}
+}
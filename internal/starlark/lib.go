@@ -0,0 +1,158 @@
+package starlark
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// LibPrefix starts the module name of a shared library, e.g.
+// load("//lib/github.star", "gh") loads github.star from the lib directory.
+const LibPrefix = "//lib/"
+
+// LibDirName is the directory under the scripts directory holding libraries.
+const LibDirName = "lib"
+
+// LibPath returns the file a library module resolves to under libDir.
+// Modules must start with LibPrefix, end in .star and stay inside libDir.
+func LibPath(libDir, module string) (string, error) {
+	rel, ok := strings.CutPrefix(module, LibPrefix)
+	if !ok {
+		return "", fmt.Errorf("cannot load %q: modules must start with %s", module, LibPrefix)
+	}
+	if !strings.HasSuffix(rel, ".star") {
+		return "", fmt.Errorf("cannot load %q: modules must end in .star", module)
+	}
+	if path.Clean(rel) != rel || strings.HasPrefix(rel, "../") || strings.HasPrefix(rel, "/") {
+		return "", fmt.Errorf("cannot load %q: invalid module path", module)
+	}
+	return filepath.Join(libDir, filepath.FromSlash(rel)), nil
+}
+
+// Loads returns the modules a script loads, in order of appearance.
+func Loads(name, source string) ([]string, error) {
+	f, err := syntax.Parse(name, source, 0)
+	if err != nil {
+		return nil, err
+	}
+	var modules []string
+	for _, stmt := range f.Stmts {
+		if load, ok := stmt.(*syntax.LoadStmt); ok {
+			modules = append(modules, load.ModuleName())
+		}
+	}
+	return modules, nil
+}
+
+// LibraryClosure returns every library a script loads, directly or through
+// other libraries, mapped to the hash of its source ("sha256:<hex>", as
+// used for script approvals). Missing libraries and load cycles are errors.
+func LibraryClosure(libDir, name, source string) (map[string]string, error) {
+	closure := make(map[string]string)
+	if err := libraryClosure(libDir, name, source, closure, []string{name}); err != nil {
+		return nil, err
+	}
+	return closure, nil
+}
+
+func libraryClosure(libDir, name, source string, closure map[string]string, stack []string) error {
+	modules, err := Loads(name, source)
+	if err != nil {
+		return err
+	}
+	for _, module := range modules {
+		for _, m := range stack {
+			if m == module {
+				return fmt.Errorf("load cycle: %s -> %s", strings.Join(stack, " -> "), module)
+			}
+		}
+		if _, done := closure[module]; done {
+			continue
+		}
+		p, err := LibPath(libDir, module)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("cannot load %q: %w", module, err)
+		}
+		closure[module] = hashSource(string(data))
+		if err := libraryClosure(libDir, module, string(data), closure, append(stack, module)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashSource hashes script source the same way script approvals do.
+func hashSource(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// loadModule implements Thread.Load for //lib/ modules. A module's globals
+// are cached under the hashes of its source and of every library it loads,
+// so editing any of them re-executes it. Modules see the sandbox built-ins
+// but not a script's args.
+func (s *Sandbox) loadModule(thread *starlark.Thread, module string) (starlark.StringDict, error) {
+	p, err := LibPath(s.libDir, module)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load %q: %w", module, err)
+	}
+	source := string(data)
+	closure, err := LibraryClosure(s.libDir, module, source)
+	if err != nil {
+		return nil, err
+	}
+	key := moduleKey(module, source, closure)
+
+	s.modMu.Lock()
+	globals, ok := s.modules[key]
+	s.modMu.Unlock()
+	if ok {
+		return globals, nil
+	}
+
+	// Runs on the script's thread, so the timeout covers library code too.
+	// The caller holds s.mu, which guards predeclared.
+	globals, err = execFile(thread, module, source, s.predeclared)
+	if err != nil {
+		return nil, err
+	}
+
+	s.modMu.Lock()
+	s.modules[key] = globals
+	s.modMu.Unlock()
+	return globals, nil
+}
+
+// moduleKey identifies a module by its name, source and library closure.
+func moduleKey(module, source string, closure map[string]string) string {
+	deps := make([]string, 0, len(closure))
+	for m, h := range closure {
+		deps = append(deps, m+"="+h)
+	}
+	sort.Strings(deps)
+	return hashSource(module + "\x00" + source + "\x00" + strings.Join(deps, "\x00"))
+}
+
+// clearModules drops cached library globals, which hold the built-ins they
+// were executed with.
+func (s *Sandbox) clearModules() {
+	s.modMu.Lock()
+	s.modules = make(map[string]starlark.StringDict)
+	s.modMu.Unlock()
+}
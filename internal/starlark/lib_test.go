@@ -0,0 +1,140 @@
+package starlark
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeLib(t *testing.T, dir, name, source string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLibPath(t *testing.T) {
+	dir := "/scripts/lib"
+	tests := []struct {
+		module string
+		want   string
+	}{
+		{"//lib/github.star", "/scripts/lib/github.star"},
+		{"//lib/util/strings.star", "/scripts/lib/util/strings.star"},
+		{"github.star", ""},
+		{"//lib/github.py", ""},
+		{"//lib/../secrets.star", ""},
+		{"//lib/a/../b.star", ""},
+		{"//lib//etc/x.star", ""},
+	}
+	for _, tt := range tests {
+		got, err := LibPath(dir, tt.module)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("LibPath(%q) = %q, want error", tt.module, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("LibPath(%q) = %q, %v, want %q", tt.module, got, err, tt.want)
+		}
+	}
+}
+
+func TestLibraryClosure(t *testing.T) {
+	dir := t.TempDir()
+	writeLib(t, dir, "github.star", `load("//lib/http_util.star", "get_json")
+def issues(repo):
+    return get_json(repo)
+`)
+	writeLib(t, dir, "http_util.star", `def get_json(url):
+    return url
+`)
+
+	closure, err := LibraryClosure(dir, "report.star", `load("//lib/github.star", "issues")`)
+	if err != nil {
+		t.Fatalf("LibraryClosure failed: %v", err)
+	}
+	if len(closure) != 2 || !strings.HasPrefix(closure["//lib/github.star"], "sha256:") || closure["//lib/http_util.star"] == "" {
+		t.Errorf("unexpected closure: %v", closure)
+	}
+
+	if _, err := LibraryClosure(dir, "report.star", `load("//lib/missing.star", "x")`); err == nil {
+		t.Error("expected error for a missing library")
+	}
+
+	writeLib(t, dir, "a.star", `load("//lib/b.star", "b")
+a = 1
+`)
+	writeLib(t, dir, "b.star", `load("//lib/a.star", "a")
+b = 1
+`)
+	if _, err := LibraryClosure(dir, "report.star", `load("//lib/a.star", "a")`); err == nil || !strings.Contains(err.Error(), "load cycle") {
+		t.Errorf("expected load cycle error, got %v", err)
+	}
+}
+
+func TestExecuteLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeLib(t, dir, "greet.star", `load("//lib/util/punct.star", "exclaim")
+def greet(name):
+    return exclaim("Hello, " + name)
+`)
+	writeLib(t, dir, "util/punct.star", `def exclaim(s):
+    return s + "!"
+`)
+
+	s := New(Config{LibDir: dir})
+	ctx := context.Background()
+	script := `load("//lib/greet.star", "greet")
+result = greet("Ada")
+`
+
+	result := s.Execute(ctx, "hello.star", script)
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	if result.Value != "Hello, Ada!" {
+		t.Errorf("result = %v, want 'Hello, Ada!'", result.Value)
+	}
+	if len(s.modules) != 2 {
+		t.Errorf("expected both modules to be cached, got %d", len(s.modules))
+	}
+
+	// Running again reuses the cache
+	if result := s.Execute(ctx, "hello.star", script); result.Value != "Hello, Ada!" || len(s.modules) != 2 {
+		t.Errorf("unexpected rerun: %v, %d cached modules", result.Value, len(s.modules))
+	}
+
+	// Editing a nested library is picked up by the modules loading it
+	writeLib(t, dir, "util/punct.star", `def exclaim(s):
+    return s + "?"
+`)
+	if result := s.Execute(ctx, "hello.star", script); result.Value != "Hello, Ada?" {
+		t.Errorf("expected the edited library to be used, got %v (error: %s)", result.Value, result.Error)
+	}
+
+	result = s.Execute(ctx, "bad.star", `load("//lib/missing.star", "x")`)
+	if result.Error == "" {
+		t.Error("expected error loading a missing library")
+	}
+
+	result = s.Execute(ctx, "bad.star", `load("/etc/passwd", "x")`)
+	if result.Error == "" {
+		t.Error("expected error loading outside the lib directory")
+	}
+}
+
+func TestExecuteLoadDisabled(t *testing.T) {
+	s := New(Config{})
+	result := s.Execute(context.Background(), "hello.star", `load("//lib/greet.star", "greet")`)
+	if result.Error == "" {
+		t.Error("expected load to fail without a lib directory")
+	}
+}
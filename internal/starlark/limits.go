@@ -0,0 +1,228 @@
+package starlark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"go.starlark.net/starlark"
+)
+
+// Error kinds reported in Result.ErrorKind when a run exceeds a limit.
+const (
+	ErrorKindTimeout     = "timeout"      // max_execution_ms or @max_runtime
+	ErrorKindStepLimit   = "step_limit"   // max_steps
+	ErrorKindMemoryLimit = "memory_limit" // max_memory_mb
+	ErrorKindDepthLimit  = "depth_limit"  // json.decode nesting
+)
+
+// maxJSONDepth is the deepest nesting json.decode accepts.
+const maxJSONDepth = 64
+
+// LimitError reports that a run exceeded a sandbox limit.
+type LimitError struct {
+	Kind string
+	Msg  string
+}
+
+func (e *LimitError) Error() string { return e.Msg }
+
+// budgetLocal is the thread-local key holding a run's budget.
+const budgetLocal = "openpact.budget"
+
+// budget tracks the resources one run has used. Built-ins and the checked
+// operations scripts are compiled to (see checkAllocations) charge the
+// values they build against maxBytes.
+type budget struct {
+	maxBytes int64
+	bytes    int64 // only touched on the run's thread
+
+	mu       sync.Mutex
+	exceeded *LimitError // limit that cancelled the thread
+}
+
+// limitThread applies the sandbox step and memory limits to thread.
+func (s *Sandbox) limitThread(thread *starlark.Thread) *budget {
+	b := &budget{maxBytes: s.maxMemoryBytes}
+	thread.SetLocal(budgetLocal, b)
+	thread.SetMaxExecutionSteps(s.maxSteps)
+	thread.OnMaxSteps = func(thread *starlark.Thread) {
+		msg := fmt.Sprintf("step limit exceeded (%d steps)", s.maxSteps)
+		b.exceed(&LimitError{Kind: ErrorKindStepLimit, Msg: msg})
+		thread.Cancel(msg)
+	}
+	return b
+}
+
+// cancel stops thread when ctx is done, recording a timeout.
+func (b *budget) cancel(thread *starlark.Thread, ctx context.Context) {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		b.exceed(&LimitError{Kind: ErrorKindTimeout, Msg: "execution timeout"})
+	}
+	thread.Cancel("execution timeout or cancelled")
+}
+
+func (b *budget) exceed(err *LimitError) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.exceeded == nil {
+		b.exceeded = err
+	}
+}
+
+// errorKind classifies a run error, returning "" for ordinary errors.
+func (b *budget) errorKind(err error) string {
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		return limitErr.Kind
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.exceeded != nil {
+		return b.exceeded.Kind
+	}
+	return ""
+}
+
+// allocate accounts v, built by a built-in, against the run's memory
+// budget and returns it, or a memory_limit error once the budget is spent.
+func allocate(thread *starlark.Thread, v starlark.Value) (starlark.Value, error) {
+	if err := charge(thread, sizeOf(v)); err != nil {
+		return starlark.None, err
+	}
+	return v, nil
+}
+
+// charge accounts n bytes against the run's memory budget, returning a
+// memory_limit error once the budget is spent.
+func charge(thread *starlark.Thread, n int64) error {
+	b, _ := thread.Local(budgetLocal).(*budget)
+	if b == nil || b.maxBytes <= 0 || n <= 0 {
+		return nil
+	}
+	if n > b.maxBytes-b.bytes {
+		b.bytes = b.maxBytes + 1
+		return &LimitError{
+			Kind: ErrorKindMemoryLimit,
+			Msg:  fmt.Sprintf("memory limit exceeded (%d MB)", b.maxBytes/(1024*1024)),
+		}
+	}
+	b.bytes += n
+	return nil
+}
+
+// remaining returns the bytes left in the run's memory budget.
+func remaining(thread *starlark.Thread) int64 {
+	b, _ := thread.Local(budgetLocal).(*budget)
+	if b == nil || b.maxBytes <= 0 {
+		return math.MaxInt64
+	}
+	return b.maxBytes - b.bytes
+}
+
+// sizeOf estimates the bytes held by a value.
+func sizeOf(v starlark.Value) int64 {
+	return sizeUpTo(v, math.MaxInt64)
+}
+
+// sizeUpTo is sizeOf, but stops counting once the size passes limit, so
+// that sizing a list holding many references to one big value stays cheap.
+// Values shared within v count once per reference, as they would when v is
+// printed or encoded; a list or dict that contains itself counts once.
+func sizeUpTo(v starlark.Value, limit int64) int64 {
+	s := sizer{limit: limit, path: make(map[starlark.Value]bool)}
+	return s.size(v)
+}
+
+type sizer struct {
+	limit int64
+	n     int64
+	path  map[starlark.Value]bool // lists and dicts being counted
+}
+
+func (s *sizer) size(v starlark.Value) int64 {
+	start := s.n
+	s.add(v)
+	return s.n - start
+}
+
+func (s *sizer) add(v starlark.Value) {
+	if s.n > s.limit {
+		return
+	}
+	switch v := v.(type) {
+	case starlark.String:
+		s.n += 16 + int64(len(v))
+	case starlark.Bytes:
+		s.n += 16 + int64(len(v))
+	case *starlark.List:
+		if s.enter(v) {
+			s.n += 32
+			for i := 0; i < v.Len() && s.n <= s.limit; i++ {
+				s.n += 16
+				s.add(v.Index(i))
+			}
+			delete(s.path, v)
+		}
+	case starlark.Tuple:
+		s.n += 24
+		for _, item := range v {
+			s.n += 16
+			s.add(item)
+		}
+	case *starlark.Dict:
+		if s.enter(v) {
+			s.n += 64
+			for _, item := range v.Items() {
+				s.n += 48
+				s.add(item[0])
+				s.add(item[1])
+			}
+			delete(s.path, v)
+		}
+	case starlark.Int:
+		s.n += 16 + intSize(v)
+	default:
+		s.n += 16
+	}
+}
+
+func (s *sizer) enter(v starlark.Value) bool {
+	if s.path[v] {
+		s.n += 16
+		return false
+	}
+	s.path[v] = true
+	return true
+}
+
+// jsonDepth returns the deepest nesting of arrays and objects in data.
+func jsonDepth(data string) int {
+	depth, max := 0, 0
+	inString, escaped := false, false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '[' || c == '{':
+			depth++
+			if depth > max {
+				max = depth
+			}
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return max
+}
@@ -0,0 +1,168 @@
+package starlark
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStepLimit(t *testing.T) {
+	s := New(Config{MaxSteps: 10000})
+	result := s.Execute(context.Background(), "loop.star", `
+def main():
+    x = []
+    for i in range(1000000):
+        x.append(i)
+    return len(x)
+`)
+	if result.ErrorKind != ErrorKindStepLimit {
+		t.Errorf("expected step_limit, got %q (%s)", result.ErrorKind, result.Error)
+	}
+
+	// Small scripts run well within the default
+	result = New(Config{}).Execute(context.Background(), "small.star", `result = len([i * i for i in range(1000)])`)
+	if result.Error != "" || result.ErrorKind != "" {
+		t.Errorf("unexpected error: %s (%s)", result.Error, result.ErrorKind)
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	s := New(Config{MaxMemoryMB: 1})
+	result := s.Execute(context.Background(), "mem.star", `
+def main():
+    doc = "[" + ",".join(["1"] * 1000) + "]"
+    return [json.decode(doc) for i in range(100)]
+`)
+	if result.ErrorKind != ErrorKindMemoryLimit {
+		t.Errorf("expected memory_limit, got %q (%s)", result.ErrorKind, result.Error)
+	}
+
+	// The budget is per run
+	result = s.Execute(context.Background(), "mem.star", `result = len(json.decode("[1, 2, 3]"))`)
+	if result.Error != "" || result.Value != int64(3) {
+		t.Errorf("unexpected result: %v (%s)", result.Value, result.Error)
+	}
+}
+
+func TestMemoryLimitScriptValues(t *testing.T) {
+	s := New(Config{MaxMemoryMB: 1})
+	scripts := map[string]string{
+		"concat": "def main():\n    s = \"ab\"\n" + strings.Repeat("    s = s + s\n", 27),
+		"augmented": `
+def main():
+    s = "ab"
+    for i in range(27):
+        s += s
+`,
+		"repeat": `result = len("x" * (1 << 30))`,
+		"list":   `result = len(list(range(1 << 30)))`,
+		"join": `
+def main():
+    parts = ["x" * 1000] * 100
+    for i in range(20):
+        parts = [",".join(parts)] * 100
+`,
+		"index": `
+def main():
+    d = {"s": "ab"}
+    for i in range(27):
+        d["s"] += d["s"]
+`,
+		"bigint": `
+def main():
+    x = 3
+    for i in range(30):
+        x = x * x
+`,
+	}
+	for name, source := range scripts {
+		result := s.Execute(context.Background(), name+".star", source)
+		if result.ErrorKind != ErrorKindMemoryLimit {
+			t.Errorf("%s: expected memory_limit, got %q (%s)", name, result.ErrorKind, result.Error)
+		}
+	}
+}
+
+func TestCheckedOperations(t *testing.T) {
+	s := New(Config{})
+	result := s.Execute(context.Background(), "ops.star", `
+def main():
+    l = [1, 2]
+    l += l
+    l *= 2
+    d = {"n": 1, "s": "a"}
+    d["n"] += 2
+    d["s"] *= 3
+    words = "a b c".split(" ")
+    return "%s %d %s %s %s" % (l, d["n"], d["s"], "-".join(words), l[1:3] + l[::-1][:1])
+`)
+	want := "[1, 2, 1, 2, 1, 2, 1, 2] 3 aaa a-b-c [2, 1, 2]"
+	if result.Error != "" || result.Value != want {
+		t.Errorf("expected %q, got %v (%s)", want, result.Value, result.Error)
+	}
+
+	// Errors read as they would without the checks
+	result = s.Execute(context.Background(), "bad.star", `result = 1 + "a"`)
+	if result.Error != "unknown binary op: int + string" {
+		t.Errorf("unexpected error %q", result.Error)
+	}
+}
+
+func TestJSONDepthLimit(t *testing.T) {
+	s := New(Config{})
+	deep := strings.Repeat("[", 100) + strings.Repeat("]", 100)
+	result := s.Execute(context.Background(), "deep.star", `result = json.decode("`+deep+`")`)
+	if result.ErrorKind != ErrorKindDepthLimit {
+		t.Errorf("expected depth_limit, got %q (%s)", result.ErrorKind, result.Error)
+	}
+
+	// Brackets inside strings don't count
+	result = s.Execute(context.Background(), "str.star", `result = json.decode('{"a": "`+deep+`"}')["a"]`)
+	if result.Error != "" || result.Value != deep {
+		t.Errorf("unexpected result: %v (%s)", result.Value, result.Error)
+	}
+}
+
+func TestTimeoutErrorKind(t *testing.T) {
+	s := New(Config{MaxExecutionMs: 50, MaxSteps: 1 << 62})
+	result := s.Execute(context.Background(), "spin.star", `
+def main():
+    x = 0
+    for i in range(1000000000):
+        x += i
+    return x
+`)
+	if result.ErrorKind != ErrorKindTimeout {
+		t.Errorf("expected timeout, got %q (%s)", result.ErrorKind, result.Error)
+	}
+
+	result = s.Execute(context.Background(), "bad.star", `result = 1 / 0`)
+	if result.Error == "" || result.ErrorKind != "" {
+		t.Errorf("expected an ordinary error, got %q (%s)", result.ErrorKind, result.Error)
+	}
+}
+
+func TestTimeoutAbortsHTTP(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	s := New(Config{MaxExecutionMs: 100})
+	start := time.Now()
+	result := s.Execute(context.Background(), "slow.star", `resp = http.get("`+server.URL+`")`)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request was not aborted by the timeout (took %v)", elapsed)
+	}
+	if result.ErrorKind != ErrorKindTimeout {
+		t.Errorf("expected timeout, got %q (%s)", result.ErrorKind, result.Error)
+	}
+}
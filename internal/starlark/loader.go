@@ -120,7 +120,7 @@ func (l *Loader) List() ([]*Script, error) {
 		if entry.IsDir() {
 			continue
 		}
-		if !strings.HasSuffix(entry.Name(), ".star") {
+		if !strings.HasSuffix(entry.Name(), ".star") || IsTestFile(entry.Name()) {
 			continue
 		}
 
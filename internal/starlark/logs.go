@@ -0,0 +1,90 @@
+package starlark
+
+import (
+	"fmt"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// Caps on the print and log output kept per run.
+const (
+	MaxLogBytes   = 64 << 10
+	MaxLogEntries = 1000
+)
+
+// Log levels of a LogEntry.
+const (
+	LogPrint = "print"
+	LogInfo  = "info"
+	LogWarn  = "warn"
+	LogError = "error"
+)
+
+// LogEntry is a line a script printed or logged.
+type LogEntry struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// logsLocal is the thread-local key holding a run's logBuffer.
+const logsLocal = "openpact.logs"
+
+// logBuffer collects a run's log entries up to MaxLogBytes and MaxLogEntries.
+type logBuffer struct {
+	entries   []LogEntry
+	size      int
+	truncated bool
+}
+
+func (b *logBuffer) add(level, msg string) {
+	if b.truncated {
+		return
+	}
+	if b.size+len(msg) > MaxLogBytes || len(b.entries) == MaxLogEntries {
+		b.truncated = true
+		return
+	}
+	b.size += len(msg)
+	b.entries = append(b.entries, LogEntry{Level: level, Message: msg})
+}
+
+// captureLogs collects print() and log.* output on thread.
+func captureLogs(thread *starlark.Thread) *logBuffer {
+	b := &logBuffer{}
+	thread.SetLocal(logsLocal, b)
+	thread.Print = func(_ *starlark.Thread, msg string) {
+		b.add(LogPrint, msg)
+	}
+	return b
+}
+
+// logModule returns the log module: log.info, log.warn and log.error take
+// any values and log them separated by spaces, as print does.
+func logModule() starlark.Value {
+	level := func(name string) *starlark.Builtin {
+		return starlark.NewBuiltin("log."+name, func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			if len(kwargs) > 0 {
+				return starlark.None, fmt.Errorf("%s: unexpected keyword arguments", b.Name())
+			}
+			parts := make([]string, len(args))
+			for i, arg := range args {
+				if s, ok := arg.(starlark.String); ok {
+					parts[i] = string(s)
+				} else {
+					parts[i] = arg.String()
+				}
+			}
+			if logs, ok := thread.Local(logsLocal).(*logBuffer); ok {
+				logs.add(name, strings.Join(parts, " "))
+			}
+			return starlark.None, nil
+		})
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"info":  level(LogInfo),
+		"warn":  level(LogWarn),
+		"error": level(LogError),
+	})
+}
@@ -0,0 +1,81 @@
+package starlark
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExecuteCapturesLogs(t *testing.T) {
+	s := New(Config{})
+	result := s.Execute(context.Background(), "logs.star", `
+print("starting", 1)
+log.info("fetched", 3, "items")
+log.warn("slow response")
+log.error("giving up")
+result = "done"
+`)
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	want := []LogEntry{
+		{Level: LogPrint, Message: "starting 1"},
+		{Level: LogInfo, Message: "fetched 3 items"},
+		{Level: LogWarn, Message: "slow response"},
+		{Level: LogError, Message: "giving up"},
+	}
+	if len(result.Logs) != len(want) {
+		t.Fatalf("logs = %+v, want %+v", result.Logs, want)
+	}
+	for i := range want {
+		if result.Logs[i] != want[i] {
+			t.Errorf("logs[%d] = %+v, want %+v", i, result.Logs[i], want[i])
+		}
+	}
+
+	// Logs written before an error are kept
+	result = s.ExecuteFunction(context.Background(), "fn.star", `
+def run():
+    print("before")
+    return 1 / 0
+`, "run", nil)
+	if result.Error == "" || len(result.Logs) != 1 || result.Logs[0].Message != "before" {
+		t.Errorf("expected the print before the error, got %+v (%s)", result.Logs, result.Error)
+	}
+}
+
+func TestExecuteLogsCapped(t *testing.T) {
+	s := New(Config{})
+	result := s.Execute(context.Background(), "spam.star", `
+def main():
+    line = "x" * 1000
+    for i in range(200):
+        print(line)
+`)
+	if !result.LogsTruncated {
+		t.Error("expected logs to be truncated")
+	}
+	size := 0
+	for _, entry := range result.Logs {
+		size += len(entry.Message)
+	}
+	if size > MaxLogBytes {
+		t.Errorf("kept %d bytes of logs, cap is %d", size, MaxLogBytes)
+	}
+}
+
+func TestSanitizeResultLogs(t *testing.T) {
+	sp := NewSecretProvider()
+	sp.Set("API_KEY", "super-secret-value")
+
+	s := New(Config{})
+	s.InjectSecrets(sp)
+	result := s.Execute(context.Background(), "leak.star", `
+print("key is " + secrets.get("API_KEY"))
+result = 1
+`)
+	result = SanitizeResult(result, sp)
+	if len(result.Logs) != 1 || strings.Contains(result.Logs[0].Message, "super-secret-value") {
+		t.Errorf("expected the secret to be redacted from logs, got %+v", result.Logs)
+	}
+}
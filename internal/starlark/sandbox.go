@@ -7,7 +7,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -18,26 +17,44 @@ import (
 	"go.starlark.net/starlarkstruct"
 )
 
+// maxResponseBytes caps HTTP response bodies read by http.get/http.post.
+const maxResponseBytes = 10 * 1024 * 1024
+
 // Sandbox executes Starlark scripts in a restricted environment
 type Sandbox struct {
 	mu             sync.Mutex
 	maxExecutionMs int64
+	maxSteps       uint64
+	maxMemoryBytes int64
 	enableHTTP     bool
 	httpClient     *http.Client
 	predeclared    starlark.StringDict
+
+	// Shared libraries for load(), cached by content (see loadModule)
+	libDir  string
+	modules map[string]starlark.StringDict
+	modMu   sync.Mutex
 }
 
 // Config configures the sandbox
 type Config struct {
-	MaxExecutionMs int64 // Maximum script execution time
-	DisableHTTP    bool  // Disable HTTP requests (default: false, HTTP enabled)
+	MaxExecutionMs int64  // Maximum script execution time
+	MaxSteps       uint64 // Maximum Starlark computation steps per run
+	MaxMemoryMB    int    // Maximum memory a run may allocate for the values it builds
+	DisableHTTP    bool   // Disable HTTP requests (default: false, HTTP enabled)
+	LibDir         string // Directory load("//lib/...") resolves to; empty disables load()
 }
 
 // Result is the result of script execution
 type Result struct {
-	Value    any           `json:"value,omitempty"`
-	Error    string        `json:"error,omitempty"`
-	Duration time.Duration `json:"duration"`
+	Value     any           `json:"value,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	ErrorKind string        `json:"error_kind,omitempty"` // Set when a limit was exceeded (see ErrorKindTimeout)
+	Duration  time.Duration `json:"duration"`
+
+	// Output of print() and log.*, capped at MaxLogBytes
+	Logs          []LogEntry `json:"logs,omitempty"`
+	LogsTruncated bool       `json:"logs_truncated,omitempty"`
 }
 
 // New creates a new Starlark sandbox
@@ -45,14 +62,25 @@ func New(cfg Config) *Sandbox {
 	if cfg.MaxExecutionMs <= 0 {
 		cfg.MaxExecutionMs = 30000 // 30 seconds default
 	}
+	if cfg.MaxSteps == 0 {
+		cfg.MaxSteps = 10_000_000
+	}
+	if cfg.MaxMemoryMB <= 0 {
+		cfg.MaxMemoryMB = 128
+	}
 
 	s := &Sandbox{
 		maxExecutionMs: cfg.MaxExecutionMs,
+		maxSteps:       cfg.MaxSteps,
+		maxMemoryBytes: int64(cfg.MaxMemoryMB) * 1024 * 1024,
 		enableHTTP:     !cfg.DisableHTTP, // Enable HTTP by default unless disabled
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:       30 * time.Second,
+			CheckRedirect: checkRedirect,
 		},
 		predeclared: make(starlark.StringDict),
+		libDir:      cfg.LibDir,
+		modules:     make(map[string]starlark.StringDict),
 	}
 
 	// Add safe built-in functions
@@ -86,33 +114,42 @@ func (s *Sandbox) addBuiltins() {
 		s.predeclared["http"] = httpModule
 	}
 
+	// Script logging, captured into Result.Logs
+	s.predeclared["log"] = logModule()
+
 	// String utilities
 	s.predeclared["format"] = starlark.NewBuiltin("format", formatString)
 }
 
 // Execute runs a Starlark script and returns the result
 func (s *Sandbox) Execute(ctx context.Context, name, source string) Result {
+	return s.ExecuteWithArgs(ctx, name, source, nil)
+}
+
+// ExecuteWithArgs runs a Starlark script with args available to it as the
+// global dict "args". A nil args map leaves "args" undefined, as Execute does.
+func (s *Sandbox) ExecuteWithArgs(ctx context.Context, name, source string, args map[string]any) (res Result) {
 	start := time.Now()
 
 	// Create a cancellable context with timeout
-	timeout := time.Duration(s.maxExecutionMs) * time.Millisecond
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+	caps := capabilitiesFrom(ctx)
+	ctx, cancel := context.WithTimeout(ctx, s.executionTimeout(caps))
 	defer cancel()
 
 	// Create thread with cancel checking
-	thread := &starlark.Thread{
-		Name: name,
-		Print: func(_ *starlark.Thread, msg string) {
-			// Silently ignore print statements for security
-		},
-	}
+	thread := &starlark.Thread{Name: name, Load: s.loader()}
+	thread.SetLocal(capabilitiesLocal, caps)
+	thread.SetLocal(contextLocal, ctx)
+	limits := s.limitThread(thread)
+	logs := captureLogs(thread)
+	defer func() { res.Logs, res.LogsTruncated = logs.entries, logs.truncated }()
 
 	// Set up cancellation
 	done := make(chan struct{})
 	go func() {
 		select {
 		case <-ctx.Done():
-			thread.Cancel("execution timeout or cancelled")
+			limits.cancel(thread, ctx)
 		case <-done:
 		}
 	}()
@@ -120,15 +157,24 @@ func (s *Sandbox) Execute(ctx context.Context, name, source string) Result {
 
 	// Execute the script
 	s.mu.Lock()
-	globals, err := starlark.ExecFile(thread, name, source, s.predeclared)
+	predeclared := s.predeclared
+	if args != nil {
+		predeclared = make(starlark.StringDict, len(s.predeclared)+1)
+		for k, v := range s.predeclared {
+			predeclared[k] = v
+		}
+		predeclared["args"] = goToStarlark(args)
+	}
+	globals, err := execFile(thread, name, source, predeclared)
 	s.mu.Unlock()
 
 	duration := time.Since(start)
 
 	if err != nil {
 		return Result{
-			Error:    err.Error(),
-			Duration: duration,
+			Error:     err.Error(),
+			ErrorKind: limits.errorKind(err),
+			Duration:  duration,
 		}
 	}
 
@@ -141,8 +187,9 @@ func (s *Sandbox) Execute(ctx context.Context, name, source string) Result {
 			ret, err := starlark.Call(thread, fn, nil, nil)
 			if err != nil {
 				return Result{
-					Error:    fmt.Sprintf("main() error: %v", err),
-					Duration: duration,
+					Error:     fmt.Sprintf("main() error: %v", err),
+					ErrorKind: limits.errorKind(err),
+					Duration:  duration,
 				}
 			}
 			result = starlarkToGo(ret)
@@ -170,20 +217,25 @@ func (s *Sandbox) Execute(ctx context.Context, name, source string) Result {
 }
 
 // ExecuteFunction runs a specific function in a script
-func (s *Sandbox) ExecuteFunction(ctx context.Context, name, source, funcName string, args []any) Result {
+func (s *Sandbox) ExecuteFunction(ctx context.Context, name, source, funcName string, args []any) (res Result) {
 	start := time.Now()
 
-	timeout := time.Duration(s.maxExecutionMs) * time.Millisecond
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+	caps := capabilitiesFrom(ctx)
+	ctx, cancel := context.WithTimeout(ctx, s.executionTimeout(caps))
 	defer cancel()
 
-	thread := &starlark.Thread{Name: name}
+	thread := &starlark.Thread{Name: name, Load: s.loader()}
+	thread.SetLocal(capabilitiesLocal, caps)
+	thread.SetLocal(contextLocal, ctx)
+	limits := s.limitThread(thread)
+	logs := captureLogs(thread)
+	defer func() { res.Logs, res.LogsTruncated = logs.entries, logs.truncated }()
 
 	done := make(chan struct{})
 	go func() {
 		select {
 		case <-ctx.Done():
-			thread.Cancel("execution timeout or cancelled")
+			limits.cancel(thread, ctx)
 		case <-done:
 		}
 	}()
@@ -191,13 +243,14 @@ func (s *Sandbox) ExecuteFunction(ctx context.Context, name, source, funcName st
 
 	// Parse and execute to get globals
 	s.mu.Lock()
-	globals, err := starlark.ExecFile(thread, name, source, s.predeclared)
+	globals, err := execFile(thread, name, source, s.predeclared)
 	s.mu.Unlock()
 
 	if err != nil {
 		return Result{
-			Error:    err.Error(),
-			Duration: time.Since(start),
+			Error:     err.Error(),
+			ErrorKind: limits.errorKind(err),
+			Duration:  time.Since(start),
 		}
 	}
 
@@ -228,8 +281,9 @@ func (s *Sandbox) ExecuteFunction(ctx context.Context, name, source, funcName st
 	ret, err := starlark.Call(thread, fn, starlarkArgs, nil)
 	if err != nil {
 		return Result{
-			Error:    err.Error(),
-			Duration: time.Since(start),
+			Error:     err.Error(),
+			ErrorKind: limits.errorKind(err),
+			Duration:  time.Since(start),
 		}
 	}
 
@@ -239,6 +293,15 @@ func (s *Sandbox) ExecuteFunction(ctx context.Context, name, source, funcName st
 	}
 }
 
+// loader returns the Thread.Load function for scripts, or nil when no lib
+// directory is configured, in which case load statements fail.
+func (s *Sandbox) loader() func(*starlark.Thread, string) (starlark.StringDict, error) {
+	if s.libDir == "" {
+		return nil
+	}
+	return s.loadModule
+}
+
 // AddFunction adds a custom function to the sandbox
 func (s *Sandbox) AddFunction(name string, fn func(args []any) (any, error)) {
 	s.mu.Lock()
@@ -255,10 +318,11 @@ func (s *Sandbox) AddFunction(name string, fn func(args []any) (any, error)) {
 			return starlark.None, err
 		}
 
-		return goToStarlark(result), nil
+		return allocate(thread, goToStarlark(result))
 	})
 
 	s.predeclared[name] = builtin
+	s.clearModules()
 }
 
 // Built-in function implementations
@@ -268,6 +332,10 @@ func jsonEncode(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tupl
 		return starlark.None, fmt.Errorf("json.encode: expected 1 argument, got %d", len(args))
 	}
 
+	// Charged up front, as values shared within args[0] are encoded once per reference
+	if err := charge(thread, sizeUpTo(args[0], remaining(thread))); err != nil {
+		return starlark.None, err
+	}
 	goVal := starlarkToGo(args[0])
 	data, err := json.Marshal(goVal)
 	if err != nil {
@@ -287,12 +355,19 @@ func jsonDecode(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tupl
 		return starlark.None, fmt.Errorf("json.decode: expected string, got %s", args[0].Type())
 	}
 
+	if depth := jsonDepth(string(str)); depth > maxJSONDepth {
+		return starlark.None, &LimitError{
+			Kind: ErrorKindDepthLimit,
+			Msg:  fmt.Sprintf("json.decode: nesting depth %d exceeds %d", depth, maxJSONDepth),
+		}
+	}
+
 	var goVal any
 	if err := json.Unmarshal([]byte(str), &goVal); err != nil {
 		return starlark.None, err
 	}
 
-	return goToStarlark(goVal), nil
+	return allocate(thread, goToStarlark(goVal))
 }
 
 func timeNow(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
@@ -336,6 +411,9 @@ func formatString(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tu
 		return starlark.None, fmt.Errorf("format: first argument must be string")
 	}
 
+	if err := charge(thread, sizeUpTo(args, remaining(thread))); err != nil {
+		return starlark.None, err
+	}
 	goArgs := make([]any, len(args)-1)
 	for i := 1; i < len(args); i++ {
 		goArgs[i-1] = starlarkToGo(args[i])
@@ -344,6 +422,18 @@ func formatString(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tu
 	return starlark.String(fmt.Sprintf(string(format), goArgs...)), nil
 }
 
+// contextLocal is the thread-local key of the execution's context, which
+// the HTTP built-ins use so a timeout or cancellation aborts their requests.
+const contextLocal = "openpact.context"
+
+// threadContext returns the context of the execution running on thread.
+func threadContext(thread *starlark.Thread) context.Context {
+	if ctx, ok := thread.Local(contextLocal).(context.Context); ok {
+		return ctx
+	}
+	return context.Background()
+}
+
 // httpGet performs an HTTP GET request
 // Usage: http.get(url, headers={}) -> {"status": 200, "body": "...", "headers": {...}}
 func (s *Sandbox) httpGet(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
@@ -365,7 +455,12 @@ func (s *Sandbox) httpGet(thread *starlark.Thread, b *starlark.Builtin, args sta
 		return starlark.None, fmt.Errorf("only http and https URLs are allowed")
 	}
 
-	req, err := http.NewRequest("GET", urlStr, nil)
+	caps := threadCapabilities(thread)
+	if err := checkHost(caps, parsedURL); err != nil {
+		return starlark.None, err
+	}
+
+	req, err := http.NewRequestWithContext(WithCapabilities(threadContext(thread), caps), "GET", urlStr, nil)
 	if err != nil {
 		return starlark.None, err
 	}
@@ -396,8 +491,8 @@ func (s *Sandbox) httpGet(thread *starlark.Thread, b *starlark.Builtin, args sta
 	}
 	defer resp.Body.Close()
 
-	// Read body (limit to 10MB)
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024*1024))
+	// Read body (limit to 10MB, or the script's @max_response_bytes)
+	body, err := readBody(resp.Body, caps)
 	if err != nil {
 		return starlark.None, fmt.Errorf("failed to read response: %w", err)
 	}
@@ -416,7 +511,7 @@ func (s *Sandbox) httpGet(thread *starlark.Thread, b *starlark.Builtin, args sta
 	result.SetKey(starlark.String("body"), starlark.String(body))
 	result.SetKey(starlark.String("headers"), respHeaders)
 
-	return result, nil
+	return allocate(thread, result)
 }
 
 // httpPost performs an HTTP POST request
@@ -445,7 +540,12 @@ func (s *Sandbox) httpPost(thread *starlark.Thread, b *starlark.Builtin, args st
 		return starlark.None, fmt.Errorf("only http and https URLs are allowed")
 	}
 
-	req, err := http.NewRequest("POST", urlStr, strings.NewReader(bodyStr))
+	caps := threadCapabilities(thread)
+	if err := checkHost(caps, parsedURL); err != nil {
+		return starlark.None, err
+	}
+
+	req, err := http.NewRequestWithContext(WithCapabilities(threadContext(thread), caps), "POST", urlStr, strings.NewReader(bodyStr))
 	if err != nil {
 		return starlark.None, err
 	}
@@ -477,7 +577,7 @@ func (s *Sandbox) httpPost(thread *starlark.Thread, b *starlark.Builtin, args st
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024*1024))
+	body, err := readBody(resp.Body, caps)
 	if err != nil {
 		return starlark.None, fmt.Errorf("failed to read response: %w", err)
 	}
@@ -494,7 +594,7 @@ func (s *Sandbox) httpPost(thread *starlark.Thread, b *starlark.Builtin, args st
 	result.SetKey(starlark.String("body"), starlark.String(body))
 	result.SetKey(starlark.String("headers"), respHeaders)
 
-	return result, nil
+	return allocate(thread, result)
 }
 
 // Type conversion helpers
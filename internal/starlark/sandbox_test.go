@@ -100,6 +100,30 @@ result = [1, 2, 3, 4, 5]
 	}
 }
 
+func TestExecuteWithArgs(t *testing.T) {
+	s := New(Config{})
+	ctx := context.Background()
+
+	result := s.ExecuteWithArgs(ctx, "test.star", `
+result = args["trigger"] + ":" + args["files"][0]["path"]
+`, map[string]any{
+		"trigger": "file_change",
+		"files":   []any{map[string]any{"path": "ai-data/notes.md"}},
+	})
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	if result.Value != "file_change:ai-data/notes.md" {
+		t.Errorf("result = %v", result.Value)
+	}
+
+	// Without args the global is undefined
+	result = s.Execute(ctx, "test.star", `result = args`)
+	if result.Error == "" {
+		t.Error("expected args to be undefined in Execute")
+	}
+}
+
 func TestExecuteSyntaxError(t *testing.T) {
 	s := New(Config{})
 	ctx := context.Background()
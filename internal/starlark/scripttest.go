@@ -0,0 +1,307 @@
+package starlark
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+// TestSuffix ends the name of a script's test file: weather_test.star holds
+// the tests for weather.star.
+const TestSuffix = "_test.star"
+
+// TestDataDirName is the directory under the scripts directory holding the
+// HTTP fixtures replayed to tests, one <script>.json file per script.
+const TestDataDirName = "testdata"
+
+// ErrNoTests is returned by RunTests for a script without a test file.
+var ErrNoTests = errors.New("script has no tests")
+
+// IsTestFile reports whether name is a test file rather than a script.
+func IsTestFile(name string) bool {
+	return strings.HasSuffix(name, TestSuffix)
+}
+
+// TestFileName returns the name of the test file for a script.
+func TestFileName(script string) string {
+	return strings.TrimSuffix(script, ".star") + TestSuffix
+}
+
+// Fixture is a recorded HTTP response replayed to scripts under test.
+type Fixture struct {
+	Method  string            `json:"method,omitempty"` // Defaults to GET
+	URL     string            `json:"url"`              // Matched exactly, query included
+	Status  int               `json:"status,omitempty"` // Defaults to 200
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"` // A JSON string is sent as is; other values are sent as JSON
+}
+
+// LoadFixtures reads the fixtures for a script from testdata/<script>.json
+// under scriptsDir. A script without a fixtures file has none.
+func LoadFixtures(scriptsDir, script string) ([]Fixture, error) {
+	path := filepath.Join(scriptsDir, TestDataDirName, strings.TrimSuffix(script, ".star")+".json")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []Fixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("invalid fixtures in %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// fixtureTransport answers requests from fixtures instead of the network.
+type fixtureTransport struct {
+	fixtures []Fixture
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for _, f := range t.fixtures {
+		method := f.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+		if !strings.EqualFold(method, req.Method) || f.URL != req.URL.String() {
+			continue
+		}
+
+		status := f.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		body := []byte(f.Body)
+		var s string
+		if json.Unmarshal(f.Body, &s) == nil {
+			body = []byte(s)
+		}
+		header := make(http.Header)
+		for k, v := range f.Headers {
+			header.Set(k, v)
+		}
+		return &http.Response{
+			StatusCode: status,
+			Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+			Header:     header,
+			Body:       io.NopCloser(bytes.NewReader(body)),
+			Request:    req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no fixture for %s %s", req.Method, req.URL)
+}
+
+// TestResult is the outcome of one test_* function.
+type TestResult struct {
+	Name       string     `json:"name"`
+	Passed     bool       `json:"passed"`
+	Error      string     `json:"error,omitempty"`
+	DurationMs int64      `json:"duration_ms"`
+	Logs       []LogEntry `json:"logs,omitempty"`
+}
+
+// TestReport is the outcome of running a script's tests.
+type TestReport struct {
+	Script     string       `json:"script"`
+	TestFile   string       `json:"test_file"`
+	Passed     bool         `json:"passed"`
+	Error      string       `json:"error,omitempty"` // The script or test file failed to load
+	Tests      []TestResult `json:"tests"`
+	DurationMs int64        `json:"duration_ms"`
+}
+
+// TestedScripts returns the scripts in scriptsDir that have a test file.
+func TestedScripts(scriptsDir string) ([]string, error) {
+	entries, err := os.ReadDir(scriptsDir)
+	if err != nil {
+		return nil, err
+	}
+	var scripts []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !IsTestFile(name) {
+			continue
+		}
+		script := strings.TrimSuffix(name, TestSuffix) + ".star"
+		if _, err := os.Stat(filepath.Join(scriptsDir, script)); err == nil {
+			scripts = append(scripts, script)
+		}
+	}
+	return scripts, nil
+}
+
+// RunTests runs the tests for a script in scriptsDir in dry-run mode: the
+// test_* functions of its test file are called in order of definition,
+// with the script's globals in scope. http.get and http.post answer from
+// the script's fixtures and never reach the network, and secrets.get
+// returns "placeholder-<NAME>" for the secrets the script uses. The
+// script's capability manifest still applies.
+//
+// It returns an error if the script cannot be read, or ErrNoTests.
+func RunTests(ctx context.Context, cfg Config, scriptsDir, script string) (*TestReport, error) {
+	if !strings.HasSuffix(script, ".star") {
+		script += ".star"
+	}
+	source, err := os.ReadFile(filepath.Join(scriptsDir, script))
+	if err != nil {
+		return nil, err
+	}
+	testFile := TestFileName(script)
+	testSource, err := os.ReadFile(filepath.Join(scriptsDir, testFile))
+	if os.IsNotExist(err) {
+		return nil, ErrNoTests
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	report := &TestReport{Script: script, TestFile: testFile, Tests: []TestResult{}}
+	defer func() { report.DurationMs = time.Since(start).Milliseconds() }()
+
+	caps, err := ParseCapabilities(string(source))
+	if err != nil {
+		report.Error = fmt.Sprintf("invalid capability manifest: %v", err)
+		return report, nil
+	}
+	fixtures, err := LoadFixtures(scriptsDir, script)
+	if err != nil {
+		report.Error = err.Error()
+		return report, nil
+	}
+
+	if cfg.LibDir == "" {
+		cfg.LibDir = filepath.Join(scriptsDir, LibDirName)
+	}
+	s := New(cfg)
+	s.httpClient = &http.Client{
+		Transport:     &fixtureTransport{fixtures: fixtures},
+		CheckRedirect: checkRedirect,
+	}
+	// As in a real run, only the secrets declared in @secrets can be read
+	placeholders := NewSecretProvider()
+	for _, name := range caps.Secrets {
+		placeholders.Set(name, "placeholder-"+name)
+	}
+	s.InjectSecrets(placeholders)
+	s.predeclared["assert"] = assertModule()
+
+	ctx = WithCapabilities(ctx, caps)
+
+	// Load the script, then the test file with the script's globals in scope
+	predeclared := make(starlark.StringDict, len(s.predeclared)+1)
+	for k, v := range s.predeclared {
+		predeclared[k] = v
+	}
+	predeclared["args"] = starlark.NewDict(0)
+	var globals starlark.StringDict
+	if res := s.run(ctx, script, func(thread *starlark.Thread) (err error) {
+		globals, err = execFile(thread, script, source, predeclared)
+		return err
+	}); res.Error != "" {
+		report.Error = fmt.Sprintf("%s: %s", script, res.Error)
+		return report, nil
+	}
+	for k, v := range globals {
+		predeclared[k] = v
+	}
+	var tests starlark.StringDict
+	if res := s.run(ctx, testFile, func(thread *starlark.Thread) (err error) {
+		tests, err = execFile(thread, testFile, testSource, predeclared)
+		return err
+	}); res.Error != "" {
+		report.Error = fmt.Sprintf("%s: %s", testFile, res.Error)
+		return report, nil
+	}
+
+	var fns []*starlark.Function
+	for name, v := range tests {
+		if fn, ok := v.(*starlark.Function); ok && strings.HasPrefix(name, "test_") {
+			fns = append(fns, fn)
+		}
+	}
+	sort.Slice(fns, func(i, j int) bool { return fns[i].Position().Line < fns[j].Position().Line })
+
+	report.Passed = true
+	for _, fn := range fns {
+		res := s.run(ctx, testFile, func(thread *starlark.Thread) error {
+			_, err := starlark.Call(thread, fn, nil, nil)
+			if evalErr, ok := err.(*starlark.EvalError); ok {
+				return fmt.Errorf("%s%s", failurePosition(evalErr, testFile), evalErr.Msg)
+			}
+			return err
+		})
+		report.Tests = append(report.Tests, TestResult{
+			Name:       fn.Name(),
+			Passed:     res.Error == "",
+			Error:      res.Error,
+			DurationMs: res.Duration.Milliseconds(),
+			Logs:       res.Logs,
+		})
+		if res.Error != "" {
+			report.Passed = false
+		}
+	}
+	return report, nil
+}
+
+// run calls fn on a new thread set up as Execute sets one up, with the
+// sandbox's limits and log capture, and reports how it went.
+func (s *Sandbox) run(ctx context.Context, name string, fn func(*starlark.Thread) error) (res Result) {
+	start := time.Now()
+
+	caps := capabilitiesFrom(ctx)
+	ctx, cancel := context.WithTimeout(ctx, s.executionTimeout(caps))
+	defer cancel()
+
+	thread := &starlark.Thread{Name: name, Load: s.loader()}
+	thread.SetLocal(capabilitiesLocal, caps)
+	thread.SetLocal(contextLocal, ctx)
+	limits := s.limitThread(thread)
+	logs := captureLogs(thread)
+	defer func() { res.Logs, res.LogsTruncated = logs.entries, logs.truncated }()
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			limits.cancel(thread, ctx)
+		case <-done:
+		}
+	}()
+	defer close(done)
+
+	if err := fn(thread); err != nil {
+		return Result{
+			Error:     err.Error(),
+			ErrorKind: limits.errorKind(err),
+			Duration:  time.Since(start),
+		}
+	}
+	return Result{Duration: time.Since(start)}
+}
+
+// failurePosition returns "file:line: " for the innermost frame of err in
+// the test file, or "" if there is none.
+func failurePosition(err *starlark.EvalError, testFile string) string {
+	for i := len(err.CallStack) - 1; i >= 0; i-- {
+		pos := err.CallStack[i].Pos
+		if pos.Filename() == testFile {
+			return fmt.Sprintf("%s:%d: ", testFile, pos.Line)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,168 @@
+package starlark
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeScriptFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	dir := t.TempDir()
+	writeScriptFiles(t, dir, map[string]string{
+		"weather.star": `# @hosts: api.example.com
+# @secrets: WEATHER_API_KEY
+
+def get_weather(city):
+    url = "https://api.example.com/current?key=" + secrets.get("WEATHER_API_KEY") + "&q=" + city
+    resp = http.get(url)
+    if resp["status"] != 200:
+        fail("weather API returned %d" % resp["status"])
+    return json.decode(resp["body"])
+
+def main():
+    return get_weather(args.get("city", "London"))
+`,
+		"weather_test.star": `def test_london():
+    print("checking London")
+    assert.eq(get_weather("London")["temp"], 15)
+
+def test_not_found():
+    assert.fails(lambda: get_weather("Atlantis"), "returned 404")
+
+def test_wrong():
+    assert.eq(get_weather("London")["temp"], 20, "temperature")
+
+def test_offline():
+    http.get("https://evil.com/")
+
+def helper():
+    fail("not a test")
+`,
+		"testdata/weather.json": `[
+  {"url": "https://api.example.com/current?key=placeholder-WEATHER_API_KEY&q=London", "body": {"temp": 15}},
+  {"url": "https://api.example.com/current?key=placeholder-WEATHER_API_KEY&q=Atlantis", "status": 404, "body": "not found"}
+]`,
+	})
+
+	report, err := RunTests(context.Background(), Config{}, dir, "weather.star")
+	if err != nil {
+		t.Fatalf("RunTests failed: %v", err)
+	}
+	if report.Error != "" {
+		t.Fatalf("unexpected load error: %s", report.Error)
+	}
+	if report.Passed {
+		t.Error("expected the report to fail")
+	}
+
+	want := []struct {
+		name   string
+		passed bool
+		err    string
+	}{
+		{"test_london", true, ""},
+		{"test_not_found", true, ""},
+		{"test_wrong", false, "weather_test.star:9: assert.eq: got 15.0, want 20: temperature"},
+		{"test_offline", false, "not allowed"},
+	}
+	if len(report.Tests) != len(want) {
+		t.Fatalf("tests = %+v", report.Tests)
+	}
+	for i, w := range want {
+		got := report.Tests[i]
+		if got.Name != w.name || got.Passed != w.passed || !strings.Contains(got.Error, w.err) {
+			t.Errorf("tests[%d] = %+v, want %s passed=%v error containing %q", i, got, w.name, w.passed, w.err)
+		}
+	}
+	if logs := report.Tests[0].Logs; len(logs) != 1 || logs[0].Message != "checking London" {
+		t.Errorf("expected test logs, got %+v", logs)
+	}
+}
+
+func TestRunTestsErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeScriptFiles(t, dir, map[string]string{
+		"plain.star":        "result = 1\n",
+		"broken.star":       "result = \n",
+		"broken_test.star":  "def test_nothing():\n    pass\n",
+		"unknown.star":      "# @hosts: api.example.com\ndef f():\n    return http.get(\"https://api.example.com/\")\n",
+		"unknown_test.star": "def test_f():\n    f()\n",
+		"secret.star":       "def f():\n    return secrets.get(\"TOKEN\")\n",
+		"secret_test.star":  "def test_f():\n    f()\n",
+	})
+
+	if _, err := RunTests(context.Background(), Config{}, dir, "plain.star"); !errors.Is(err, ErrNoTests) {
+		t.Errorf("expected ErrNoTests, got %v", err)
+	}
+	if _, err := RunTests(context.Background(), Config{}, dir, "missing.star"); !os.IsNotExist(err) {
+		t.Errorf("expected a not-exist error, got %v", err)
+	}
+
+	report, err := RunTests(context.Background(), Config{}, dir, "broken")
+	if err != nil || report.Passed || !strings.HasPrefix(report.Error, "broken.star:") {
+		t.Errorf("expected a load error, got %+v, %v", report, err)
+	}
+
+	// Requests without a fixture fail instead of reaching the network
+	report, err = RunTests(context.Background(), Config{}, dir, "unknown.star")
+	if err != nil || report.Passed || !strings.Contains(report.Tests[0].Error, "no fixture for GET https://api.example.com/") {
+		t.Errorf("expected a missing fixture error, got %+v, %v", report, err)
+	}
+
+	// Secrets missing from @secrets are refused, as they are in a real run
+	report, err = RunTests(context.Background(), Config{}, dir, "secret.star")
+	if err != nil || report.Passed || !strings.Contains(report.Tests[0].Error, "not declared in the script's @secrets") {
+		t.Errorf("expected an undeclared secret error, got %+v, %v", report, err)
+	}
+
+	scripts, err := TestedScripts(dir)
+	if err != nil || len(scripts) != 3 || scripts[0] != "broken.star" || scripts[1] != "secret.star" || scripts[2] != "unknown.star" {
+		t.Errorf("TestedScripts = %v, %v", scripts, err)
+	}
+}
+
+func TestAssertModule(t *testing.T) {
+	s := New(Config{})
+	s.predeclared["assert"] = assertModule()
+	for _, tc := range []struct {
+		code string
+		err  string
+	}{
+		{`assert.eq([1, 2], [1, 2])`, ""},
+		{`assert.eq(1, 2)`, "assert.eq: got 1, want 2"},
+		{`assert.ne("a", "b")`, ""},
+		{`assert.ne("a", "a")`, "want a different value"},
+		{`assert.true(1)`, ""},
+		{`assert.true([], "list")`, "assert.true: [] is not true: list"},
+		{`assert.false(None)`, ""},
+		{`assert.contains({"a": 1}, "a")`, ""},
+		{`assert.contains("abc", "z")`, `"z" not in "abc"`},
+		{`assert.eq(assert.fails(lambda: fail("boom")), "fail: boom")`, ""},
+		{`assert.fails(lambda: None)`, "did not fail"},
+		{`assert.fails(lambda: fail("boom"), "bang")`, `does not contain "bang"`},
+	} {
+		result := s.Execute(context.Background(), "t.star", tc.code)
+		if tc.err == "" && result.Error != "" {
+			t.Errorf("%s: unexpected error %s", tc.code, result.Error)
+		}
+		if tc.err != "" && !strings.Contains(result.Error, tc.err) {
+			t.Errorf("%s: expected error containing %q, got %q", tc.code, tc.err, result.Error)
+		}
+	}
+}
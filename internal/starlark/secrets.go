@@ -1,6 +1,7 @@
 package starlark
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 	"sync"
@@ -70,6 +71,10 @@ func (s *Sandbox) InjectSecrets(provider *SecretProvider) {
 			return starlark.None, err
 		}
 
+		if caps := threadCapabilities(thread); caps != nil && !caps.AllowsSecret(name) {
+			return starlark.None, fmt.Errorf("secrets.get: %q is not declared in the script's @secrets", name)
+		}
+
 		value, ok := provider.Get(name)
 		if !ok {
 			return starlark.None, nil
@@ -79,10 +84,12 @@ func (s *Sandbox) InjectSecrets(provider *SecretProvider) {
 
 	// List available secret names (not values)
 	listSecrets := func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
-		names := provider.Names()
-		list := make([]starlark.Value, len(names))
-		for i, name := range names {
-			list[i] = starlark.String(name)
+		caps := threadCapabilities(thread)
+		list := make([]starlark.Value, 0)
+		for _, name := range provider.Names() {
+			if caps == nil || caps.AllowsSecret(name) {
+				list = append(list, starlark.String(name))
+			}
 		}
 		return starlark.NewList(list), nil
 	}
@@ -95,6 +102,7 @@ func (s *Sandbox) InjectSecrets(provider *SecretProvider) {
 		"get":  starlark.NewBuiltin("secrets.get", getSecret),
 		"list": starlark.NewBuiltin("secrets.list", listSecrets),
 	})
+	s.clearModules()
 }
 
 // starlarkBuiltinModule creates a simple module from a dict of functions
@@ -145,6 +153,15 @@ func SanitizeResult(result Result, provider *SecretProvider) Result {
 		result.Value = sanitizeValue(result.Value, provider)
 	}
 
+	// Sanitize logs
+	if len(result.Logs) > 0 {
+		logs := make([]LogEntry, len(result.Logs))
+		for i, entry := range result.Logs {
+			logs[i] = LogEntry{Level: entry.Level, Message: sanitizeString(entry.Message, provider)}
+		}
+		result.Logs = logs
+	}
+
 	return result
 }
 